@@ -0,0 +1,196 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/directory"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/file"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/service"
+)
+
+// maxKnownDirectories bounds the directories an adlsClient remembers as created. Past it, they are
+// forgotten and created again on their next use, which the service treats as a no-op.
+const maxKnownDirectories = 10000
+
+// datalakeClient is the Data Lake Storage Gen2 API the adls_gen2 mode writes through.
+type datalakeClient interface {
+	// CreateDirectory creates the directory and its missing parents, succeeding when it already exists.
+	CreateDirectory(ctx context.Context, fileSystem, dir string) error
+	// UploadFile writes body as the file fileName in the directory dir, which is empty for the root of
+	// the file system. o carries the options of the block blob upload it replaces.
+	UploadFile(ctx context.Context, fileSystem, dir, fileName string, body io.Reader, o *azblob.UploadStreamOptions) error
+}
+
+// adlsClient writes block blobs as files of a hierarchical namespace account: the directories of a
+// blob name are created first, then the file is written in the last one, so directory ACLs and renames
+// apply to the exported data. Appends and container operations go through the Blob API, which these
+// accounts support as well.
+type adlsClient struct {
+	azblobClient
+	datalake datalakeClient
+
+	mu          sync.Mutex
+	directories map[string]bool
+}
+
+func newADLSClient(client azblobClient, datalake datalakeClient) *adlsClient {
+	return &adlsClient{azblobClient: client, datalake: datalake, directories: make(map[string]bool)}
+}
+
+// UploadStream writes the blob blobName of the container as a file of the file system of that name.
+func (c *adlsClient) UploadStream(ctx context.Context, containerName, blobName string, body io.Reader, o *azblob.UploadStreamOptions) (azblob.UploadStreamResponse, error) {
+	dir, fileName := splitBlobPath(blobName)
+	if dir != "" {
+		if err := c.createDirectory(ctx, containerName, dir); err != nil {
+			return azblob.UploadStreamResponse{}, fmt.Errorf("failed to create directory %q: %w", dir, err)
+		}
+	}
+	return azblob.UploadStreamResponse{}, c.datalake.UploadFile(ctx, containerName, dir, fileName, body, o)
+}
+
+// createDirectory creates the directory unless it was created before.
+func (c *adlsClient) createDirectory(ctx context.Context, fileSystem, dir string) error {
+	key := fileSystem + "/" + dir
+	c.mu.Lock()
+	known := c.directories[key]
+	c.mu.Unlock()
+	if known {
+		return nil
+	}
+	if err := c.datalake.CreateDirectory(ctx, fileSystem, dir); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if len(c.directories) >= maxKnownDirectories {
+		clear(c.directories)
+	}
+	c.directories[key] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// splitBlobPath splits a blob name at its last '/' into a directory and a file name. Leading,
+// trailing and repeated separators are dropped, as they would name empty directories.
+func splitBlobPath(blobName string) (dir, fileName string) {
+	var segments []string
+	for _, segment := range strings.Split(blobName, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	dir, fileName = path.Split(strings.Join(segments, "/"))
+	return strings.TrimSuffix(dir, "/"), fileName
+}
+
+// datalakeClientImpl implements datalakeClient with the azdatalake SDK.
+type datalakeClientImpl struct {
+	client *service.Client
+}
+
+// CreateDirectory creates the directory with an If-None-Match condition, so an existing directory
+// keeps its ACLs and properties.
+func (c *datalakeClientImpl) CreateDirectory(ctx context.Context, fileSystem, dir string) error {
+	anyETag := azcore.ETagAny
+	_, err := c.client.NewFileSystemClient(fileSystem).NewDirectoryClient(dir).Create(ctx, &directory.CreateOptions{
+		AccessConditions: &directory.AccessConditions{
+			ModifiedAccessConditions: &directory.ModifiedAccessConditions{IfNoneMatch: &anyETag},
+		},
+	})
+	if datalakeerror.HasCode(err, datalakeerror.PathAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// UploadFile creates the file, appends body and sets the metadata of o. The If-None-Match condition
+// of overwrite_policy and the customer-provided key apply to the file.
+func (c *datalakeClientImpl) UploadFile(ctx context.Context, fileSystem, dir, fileName string, body io.Reader, o *azblob.UploadStreamOptions) error {
+	fs := c.client.NewFileSystemClient(fileSystem)
+	fileClient := fs.NewFileClient(fileName)
+	if dir != "" {
+		var err error
+		if fileClient, err = fs.NewDirectoryClient(dir).NewFileClient(fileName); err != nil {
+			return err
+		}
+	}
+	if o == nil {
+		o = &azblob.UploadStreamOptions{}
+	}
+
+	cpk := datalakeCPK(o.CPKInfo)
+	createOptions := &file.CreateOptions{CPKInfo: cpk}
+	if o.AccessConditions != nil && o.AccessConditions.ModifiedAccessConditions != nil {
+		createOptions.AccessConditions = &file.AccessConditions{
+			ModifiedAccessConditions: &file.ModifiedAccessConditions{IfNoneMatch: o.AccessConditions.ModifiedAccessConditions.IfNoneMatch},
+		}
+	}
+	if _, err := fileClient.Create(ctx, createOptions); err != nil {
+		return err
+	}
+	if err := fileClient.UploadStream(ctx, body, &file.UploadStreamOptions{CPKInfo: cpk}); err != nil {
+		return err
+	}
+	if len(o.Metadata) > 0 {
+		if _, err := fileClient.SetMetadata(ctx, o.Metadata, &file.SetMetadataOptions{CPKInfo: cpk}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// datalakeCPK converts a customer-provided key of the Blob API to the Data Lake API.
+func datalakeCPK(cpk *blob.CPKInfo) *file.CPKInfo {
+	if cpk == nil {
+		return nil
+	}
+	return &file.CPKInfo{
+		EncryptionAlgorithm: (*file.EncryptionAlgorithmType)(cpk.EncryptionAlgorithm),
+		EncryptionKey:       cpk.EncryptionKey,
+		EncryptionKeySHA256: cpk.EncryptionKeySHA256,
+	}
+}
+
+// newDatalakeClient creates the Data Lake client of the adls_gen2 mode. cred is the credential of the
+// blob client, nil for connection strings and shared access signatures.
+func (e *azureBlobExporter) newDatalakeClient(cred azcore.TokenCredential) (datalakeClient, error) {
+	blobOptions, err := e.config.ClientOptions.azblobOptions()
+	if err != nil {
+		return nil, err
+	}
+	clientOptions := &service.ClientOptions{ClientOptions: blobOptions.ClientOptions}
+	endpoint := e.config.ADLSGen2.Endpoint
+	if endpoint == "" {
+		// The SDK maps the .blob. host of the account to its .dfs. host
+		endpoint = e.config.URL
+	}
+
+	var client *service.Client
+	switch e.config.Auth.Type {
+	case ConnectionString:
+		client, err = service.NewClientFromConnectionString(e.config.Auth.ConnectionString, clientOptions)
+	case SharedAccessSignature:
+		var serviceURL string
+		if serviceURL, err = sasURL(endpoint, e.config.Auth.SASToken); err != nil {
+			return nil, fmt.Errorf("failed to build shared access signature url: %w", err)
+		}
+		client, err = service.NewClientWithNoCredential(serviceURL, clientOptions)
+	default:
+		client, err = service.NewClient(endpoint, cred, clientOptions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data lake client: %w", err)
+	}
+	return &datalakeClientImpl{client: client}, nil
+}
@@ -0,0 +1,235 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// mockFile is a file written through a mockDatalake.
+type mockFile struct {
+	fileSystem string
+	dir        string
+	name       string
+	data       []byte
+	opts       *azblob.UploadStreamOptions
+}
+
+// mockDatalake records the directories and files of an adlsClient. fail decides, for every file,
+// whether its upload fails and with which error.
+type mockDatalake struct {
+	mu          sync.Mutex
+	directories []string
+	files       []mockFile
+	fail        func(dir, name string) error
+}
+
+func (m *mockDatalake) CreateDirectory(_ context.Context, fileSystem, dir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.directories = append(m.directories, fileSystem+"/"+dir)
+	return nil
+}
+
+func (m *mockDatalake) UploadFile(_ context.Context, fileSystem, dir, name string, body io.Reader, o *azblob.UploadStreamOptions) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail != nil {
+		if err = m.fail(dir, name); err != nil {
+			return err
+		}
+	}
+	m.files = append(m.files, mockFile{fileSystem: fileSystem, dir: dir, name: name, data: data, opts: o})
+	return nil
+}
+
+func TestSplitBlobPath(t *testing.T) {
+	tests := []struct {
+		blobName string
+		wantDir  string
+		wantFile string
+	}{
+		{blobName: "2025/01/02/logs_10_00_00.json_1", wantDir: "2025/01/02", wantFile: "logs_10_00_00.json_1"},
+		{blobName: "logs.json", wantDir: "", wantFile: "logs.json"},
+		{blobName: "tenant/acme/logs.json.gz", wantDir: "tenant/acme", wantFile: "logs.json.gz"},
+		{blobName: "/2025//01/logs.json", wantDir: "2025/01", wantFile: "logs.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.blobName, func(t *testing.T) {
+			dir, file := splitBlobPath(tt.blobName)
+			assert.Equal(t, tt.wantDir, dir)
+			assert.Equal(t, tt.wantFile, file)
+		})
+	}
+}
+
+func TestADLSClientUploadStream(t *testing.T) {
+	datalake := &mockDatalake{}
+	blobs := &mockClient{}
+	client := newADLSClient(blobs, datalake)
+	opts := &azblob.UploadStreamOptions{Metadata: map[string]*string{"tenant": nil}}
+
+	for _, name := range []string{"2025/01/02/a.json", "2025/01/02/b.json", "root.json"} {
+		_, err := client.UploadStream(context.Background(), "logs", name, strings.NewReader(name), opts)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"logs/2025/01/02"}, datalake.directories, "a directory is created once")
+	require.Len(t, datalake.files, 3)
+	assert.Equal(t, mockFile{fileSystem: "logs", dir: "2025/01/02", name: "a.json", data: []byte("2025/01/02/a.json"), opts: opts}, datalake.files[0])
+	assert.Equal(t, "b.json", datalake.files[1].name)
+	assert.Equal(t, mockFile{fileSystem: "logs", name: "root.json", data: []byte("root.json"), opts: opts}, datalake.files[2])
+	assert.Empty(t, blobs.recorded(), "block blobs are not written through the Blob API")
+
+	require.NoError(t, client.AppendBlock(context.Background(), "logs", "index.jsonl", []byte("entry"), nil))
+	require.Len(t, blobs.recorded(), 1, "appends go through the Blob API")
+}
+
+func TestConsumeLogsADLSGen2(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ADLSGen2.Enabled = true
+	exp, blobs := newTestExporter(t, cfg, pipeline.SignalLogs)
+	datalake := &mockDatalake{}
+	exp.client = newADLSClient(blobs, datalake)
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+
+	require.Len(t, datalake.files, 1)
+	file := datalake.files[0]
+	assert.Equal(t, "logs", file.fileSystem)
+	assert.Regexp(t, `^\d{4}/\d{2}/\d{2}$`, file.dir)
+	assert.Regexp(t, `^logs_\d{2}_\d{2}_\d{2}\.json_\d+$`, file.name)
+	assert.Equal(t, []string{"logs/" + file.dir}, datalake.directories)
+	assert.NotEmpty(t, file.data)
+}
+
+func TestConsumeLogsADLSGen2OverwritePolicy(t *testing.T) {
+	exists := &azcore.ResponseError{StatusCode: http.StatusConflict, ErrorCode: string(datalakeerror.PathAlreadyExists)}
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{policy: overwritePolicySkip},
+		{policy: overwritePolicyFail, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ADLSGen2.Enabled = true
+			cfg.OverwritePolicy = tt.policy
+			exp, blobs := newTestExporter(t, cfg, pipeline.SignalLogs)
+			datalake := &mockDatalake{fail: func(string, string) error { return exists }}
+			exp.client = newADLSClient(blobs, datalake)
+
+			err := exp.ConsumeLogs(context.Background(), newTestLogs(1))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, exists)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStartADLSGen2(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ADLSGen2.Enabled = true
+	require.NoError(t, cfg.Validate())
+	exp, err := newAzureBlobExporter(cfg, component.MustNewID("azureblob"), componenttest.NewNopTelemetrySettings(), pipeline.SignalLogs)
+	require.NoError(t, err)
+	require.NoError(t, exp.start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, exp.shutdown(context.Background())) })
+
+	client, ok := exp.client.(*adlsClient)
+	require.True(t, ok)
+	assert.IsType(t, &datalakeClientImpl{}, client.datalake)
+	assert.IsType(t, &azblobClientImpl{}, client.azblobClient)
+}
+
+func TestADLSGen2Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{
+			name:      "enabled",
+			configure: func(*Config) {},
+		},
+		{
+			name: "endpoint",
+			configure: func(cfg *Config) {
+				cfg.Auth = Authentication{Type: SystemManagedIdentity}
+				cfg.URL = "https://test.blob.core.windows.net/"
+				cfg.ADLSGen2.Endpoint = "https://test.dfs.core.windows.net/"
+			},
+		},
+		{
+			name:      "endpoint with connection string",
+			configure: func(cfg *Config) { cfg.ADLSGen2.Endpoint = "https://test.dfs.core.windows.net/" },
+			wantErr:   "adls_gen2.endpoint cannot be set when auth type is connection_string",
+		},
+		{
+			name: "relative endpoint",
+			configure: func(cfg *Config) {
+				cfg.Auth = Authentication{Type: SystemManagedIdentity}
+				cfg.URL = "https://test.blob.core.windows.net/"
+				cfg.ADLSGen2.Endpoint = "test.dfs.core.windows.net"
+			},
+			wantErr: `adls_gen2.endpoint "test.dfs.core.windows.net" must be an absolute URL`,
+		},
+		{
+			name:      "encryption scope",
+			configure: func(cfg *Config) { cfg.EncryptionScope = "scope" },
+			wantErr:   "encryption_scope cannot be used with adls_gen2",
+		},
+		{
+			name:      "access tier",
+			configure: func(cfg *Config) { cfg.AccessTier = "cool" },
+			wantErr:   "access_tier cannot be used with adls_gen2",
+		},
+		{
+			name:      "blob tags",
+			configure: func(cfg *Config) { cfg.BlobTags = map[string]string{"tenant": "tenant"} },
+			wantErr:   "blob_tags cannot be used with adls_gen2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ADLSGen2.Enabled = true
+			tt.configure(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestIsBlobExistsErrorADLSGen2(t *testing.T) {
+	assert.True(t, isBlobExistsError(&azcore.ResponseError{StatusCode: http.StatusConflict, ErrorCode: string(datalakeerror.PathAlreadyExists)}))
+	assert.False(t, isBlobExistsError(errors.New("other")))
+}
@@ -0,0 +1,157 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// rollup accumulates the data points of one metric.
+type rollup struct {
+	metric     pmetric.Metric
+	count      uint64
+	sum        float64
+	min, max   float64
+	hasMinMax  bool
+	start, end pcommon.Timestamp
+}
+
+// aggregateMetrics rolls the data points of every metric up into a single summary data point per
+// resource and scope, keyed by metric name. The summary holds the number of aggregated values,
+// their sum, and their minimum and maximum as the 0 and 1 quantiles. Data point attributes and
+// histogram buckets are dropped.
+func aggregateMetrics(md pmetric.Metrics) pmetric.Metrics {
+	aggregated := pmetric.NewMetrics()
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		aggregatedRM := aggregated.ResourceMetrics().AppendEmpty()
+		rm.Resource().CopyTo(aggregatedRM.Resource())
+		aggregatedRM.SetSchemaUrl(rm.SchemaUrl())
+
+		sms := rm.ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			sm := sms.At(j)
+			aggregatedSM := aggregatedRM.ScopeMetrics().AppendEmpty()
+			sm.Scope().CopyTo(aggregatedSM.Scope())
+			aggregatedSM.SetSchemaUrl(sm.SchemaUrl())
+
+			rollups := map[string]*rollup{}
+			var order []*rollup
+			metrics := sm.Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				m := metrics.At(k)
+				r, ok := rollups[m.Name()]
+				if !ok {
+					r = &rollup{metric: aggregatedSM.Metrics().AppendEmpty()}
+					r.metric.SetName(m.Name())
+					r.metric.SetDescription(m.Description())
+					r.metric.SetUnit(m.Unit())
+					r.metric.SetEmptySummary()
+					rollups[m.Name()] = r
+					order = append(order, r)
+				}
+				r.addMetric(m)
+			}
+			for _, r := range order {
+				r.writeDataPoint()
+			}
+		}
+	}
+	return aggregated
+}
+
+func (r *rollup) addMetric(m pmetric.Metric) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		r.addNumberDataPoints(m.Gauge().DataPoints())
+	case pmetric.MetricTypeSum:
+		r.addNumberDataPoints(m.Sum().DataPoints())
+	case pmetric.MetricTypeHistogram:
+		dps := m.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			r.addTimestamps(dp.StartTimestamp(), dp.Timestamp())
+			r.count += dp.Count()
+			r.sum += dp.Sum()
+			if dp.HasMin() && dp.HasMax() {
+				r.addMinMax(dp.Min(), dp.Max())
+			}
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := m.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			r.addTimestamps(dp.StartTimestamp(), dp.Timestamp())
+			r.count += dp.Count()
+			r.sum += dp.Sum()
+			if dp.HasMin() && dp.HasMax() {
+				r.addMinMax(dp.Min(), dp.Max())
+			}
+		}
+	case pmetric.MetricTypeSummary:
+		dps := m.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			r.addTimestamps(dp.StartTimestamp(), dp.Timestamp())
+			r.count += dp.Count()
+			r.sum += dp.Sum()
+			qs := dp.QuantileValues()
+			for q := 0; q < qs.Len(); q++ {
+				// the 0 and 1 quantiles are the minimum and maximum
+				if quantile := qs.At(q); quantile.Quantile() == 0 || quantile.Quantile() == 1 {
+					r.addMinMax(quantile.Value(), quantile.Value())
+				}
+			}
+		}
+	}
+}
+
+func (r *rollup) addNumberDataPoints(dps pmetric.NumberDataPointSlice) {
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		value := dp.DoubleValue()
+		if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+			value = float64(dp.IntValue())
+		}
+		r.addTimestamps(dp.StartTimestamp(), dp.Timestamp())
+		r.count++
+		r.sum += value
+		r.addMinMax(value, value)
+	}
+}
+
+func (r *rollup) addTimestamps(start, end pcommon.Timestamp) {
+	if start != 0 && (r.start == 0 || start < r.start) {
+		r.start = start
+	}
+	r.end = max(r.end, end)
+}
+
+func (r *rollup) addMinMax(minValue, maxValue float64) {
+	if !r.hasMinMax {
+		r.min, r.max, r.hasMinMax = minValue, maxValue, true
+		return
+	}
+	r.min = min(r.min, minValue)
+	r.max = max(r.max, maxValue)
+}
+
+// writeDataPoint appends the summary data point of r to its metric.
+func (r *rollup) writeDataPoint() {
+	dp := r.metric.Summary().DataPoints().AppendEmpty()
+	dp.SetStartTimestamp(r.start)
+	dp.SetTimestamp(r.end)
+	dp.SetCount(r.count)
+	dp.SetSum(r.sum)
+	if r.hasMinMax {
+		minQuantile := dp.QuantileValues().AppendEmpty()
+		minQuantile.SetQuantile(0)
+		minQuantile.SetValue(r.min)
+		maxQuantile := dp.QuantileValues().AppendEmpty()
+		maxQuantile.SetQuantile(1)
+		maxQuantile.SetValue(r.max)
+	}
+}
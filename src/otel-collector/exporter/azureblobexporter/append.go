@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"go.uber.org/zap"
+)
+
+// errAppendPositionMoved is returned when an append blob grew by other data after an append attempt
+// that may have committed, so retrying could append the chunk twice.
+var errAppendPositionMoved = errors.New("append blob position moved")
+
+// appendPosition tracks the offset a chunk is appended at across the attempts of an upload.
+type appendPosition struct {
+	offset int64
+	known  bool
+	// uncertain is set once an attempt failed in a way that may have committed the chunk
+	uncertain bool
+}
+
+// appendBlock appends data to the blob. With append_blob.idempotent_retries, data is appended at the
+// blob size read before the first attempt. While no attempt may have committed the chunk, a taken
+// position only means that another writer appended first, and the chunk is appended at the new end
+// of the blob. After an uncertain attempt, the chunk is assumed to be committed when the blob grew
+// by exactly its size, and the upload fails without retrying when it grew otherwise.
+func (e *azureBlobExporter) appendBlock(ctx context.Context, containerName, blobName string, data []byte, position *appendPosition) error {
+	if !e.config.AppendBlob.IdempotentRetries {
+		return e.client.AppendBlock(ctx, containerName, blobName, data, &appendblob.AppendBlockOptions{CPKInfo: e.cpk, CPKScopeInfo: e.cpkScope})
+	}
+
+	for {
+		if !position.known {
+			size, err := e.client.AppendBlobSize(ctx, containerName, blobName)
+			if err != nil {
+				return err
+			}
+			position.offset, position.known = size, true
+		}
+
+		offset := position.offset
+		err := e.client.AppendBlock(ctx, containerName, blobName, data, &appendblob.AppendBlockOptions{
+			CPKInfo:                        e.cpk,
+			CPKScopeInfo:                   e.cpkScope,
+			AppendPositionAccessConditions: &appendblob.AppendPositionAccessConditions{AppendPosition: &offset},
+		})
+		if !bloberror.HasCode(err, bloberror.AppendPositionConditionNotMet) {
+			if err != nil && !isAppendUnsupportedError(err) {
+				position.uncertain = true
+			}
+			return err
+		}
+
+		if !position.uncertain {
+			// The chunk was not committed, so it is safe to append it at the new end of the blob
+			position.known = false
+			continue
+		}
+
+		size, err := e.client.AppendBlobSize(ctx, containerName, blobName)
+		if err != nil {
+			return err
+		}
+		if size == offset+int64(len(data)) {
+			e.logger.Debug("Append was committed by an earlier attempt",
+				zap.String("container", containerName),
+				zap.String("blob", blobName),
+				zap.Int64("offset", offset))
+			return nil
+		}
+		return fmt.Errorf("%w: chunk of %d bytes expected at offset %d, blob is %d bytes",
+			errAppendPositionMoved, len(data), offset, size)
+	}
+}
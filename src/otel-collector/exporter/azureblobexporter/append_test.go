@@ -0,0 +1,183 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// existingData is held by the blob before the exporter appends to it, and foreignData is appended by
+// another writer of the blob.
+var (
+	existingData = []byte("existing\n")
+	foreignData  = []byte("other writer\n")
+)
+
+// positionClient is a mockClient that enforces append position conditions on blobs that start with
+// existingData. Before the attempts in foreignBefore another writer appends to the blob, the attempts
+// in failBefore fail without appending, and the attempts in failAfter append but still fail, like a
+// request whose response was lost.
+type positionClient struct {
+	*mockClient
+	attempts      int
+	foreignBefore map[int]bool
+	failBefore    map[int]bool
+	failAfter     map[int]bool
+}
+
+func (c *positionClient) AppendBlock(ctx context.Context, container, blob string, data []byte, o *appendblob.AppendBlockOptions) error {
+	c.attempts++
+	unavailable := &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable, ErrorCode: string(bloberror.ServerBusy)}
+	if c.foreignBefore[c.attempts] {
+		c.mu.Lock()
+		c.blobs[container+"/"+blob] = append(c.blobs[container+"/"+blob], foreignData...)
+		c.mu.Unlock()
+	}
+	if c.failBefore[c.attempts] {
+		return unavailable
+	}
+	if o.AppendPositionAccessConditions != nil {
+		size, err := c.AppendBlobSize(ctx, container, blob)
+		if err != nil {
+			return err
+		}
+		if *o.AppendPositionAccessConditions.AppendPosition != size {
+			return &azcore.ResponseError{StatusCode: http.StatusPreconditionFailed, ErrorCode: string(bloberror.AppendPositionConditionNotMet)}
+		}
+	}
+	if err := c.mockClient.AppendBlock(ctx, container, blob, data, o); err != nil {
+		return err
+	}
+	if c.failAfter[c.attempts] {
+		return unavailable
+	}
+	return nil
+}
+
+func (c *positionClient) AppendBlobSize(ctx context.Context, container, blob string) (int64, error) {
+	c.mu.Lock()
+	if c.blobs == nil {
+		c.blobs = map[string][]byte{}
+	}
+	if _, ok := c.blobs[container+"/"+blob]; !ok {
+		c.blobs[container+"/"+blob] = append([]byte(nil), existingData...)
+	}
+	c.mu.Unlock()
+	return c.mockClient.AppendBlobSize(ctx, container, blob)
+}
+
+// newIdempotentAppendExporter returns a logs exporter appending with idempotent retries through client.
+func newIdempotentAppendExporter(t *testing.T, client *positionClient) *azureBlobExporter {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.AppendBlob.Enabled = true
+	cfg.AppendBlob.IdempotentRetries = true
+	cfg.BackOffConfig.Enabled = true
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.RandomizationFactor = 0
+	cfg.BackOffConfig.MaxElapsedTime = time.Second
+	exp, mock := newTestExporter(t, cfg, pipeline.SignalLogs)
+	client.mockClient = mock
+	exp.client = client
+	return exp
+}
+
+func TestAppendIdempotentRetries(t *testing.T) {
+	offset := int64(len(existingData))
+	tests := []struct {
+		name          string
+		client        *positionClient
+		wantAttempts  int
+		wantForeign   int
+		wantOffsets   []int64
+		wantPermanent bool
+	}{
+		{
+			name:         "no failure",
+			client:       &positionClient{},
+			wantAttempts: 1,
+			wantOffsets:  []int64{offset},
+		},
+		{
+			name:         "failure before commit retried at the same offset",
+			client:       &positionClient{failBefore: map[int]bool{1: true}},
+			wantAttempts: 2,
+			wantOffsets:  []int64{offset},
+		},
+		{
+			name:         "committed attempt with matching offset",
+			client:       &positionClient{failAfter: map[int]bool{1: true}},
+			wantAttempts: 2,
+			wantOffsets:  []int64{offset},
+		},
+		{
+			name:         "other writer before the first attempt",
+			client:       &positionClient{foreignBefore: map[int]bool{1: true}},
+			wantAttempts: 2,
+			wantForeign:  1,
+			wantOffsets:  []int64{offset + int64(len(foreignData))},
+		},
+		{
+			name:          "committed attempt with moved offset",
+			client:        &positionClient{failAfter: map[int]bool{1: true}, foreignBefore: map[int]bool{2: true}},
+			wantAttempts:  2,
+			wantForeign:   1,
+			wantOffsets:   []int64{offset},
+			wantPermanent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := newIdempotentAppendExporter(t, tt.client)
+
+			err := exp.ConsumeLogs(context.Background(), newTestLogs(1))
+
+			assert.Equal(t, tt.wantAttempts, tt.client.attempts)
+			uploads := tt.client.recorded()
+			require.NotEmpty(t, uploads)
+			var offsets []int64
+			var chunk []byte
+			for _, upload := range uploads {
+				require.NotNil(t, upload.appendOpts.AppendPositionAccessConditions)
+				offsets = append(offsets, *upload.appendOpts.AppendPositionAccessConditions.AppendPosition)
+				chunk = upload.data
+			}
+			assert.Equal(t, tt.wantOffsets, offsets, "the chunk is appended once")
+			blob := tt.client.blobs[uploads[0].container+"/"+uploads[0].blob]
+			assert.True(t, bytes.HasPrefix(blob, existingData))
+			assert.Equal(t, 1, bytes.Count(blob, chunk))
+			assert.Equal(t, tt.wantForeign, bytes.Count(blob, foreignData))
+			if !tt.wantPermanent {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, errAppendPositionMoved)
+			assert.True(t, consumererror.IsPermanent(err), "a moved offset is not retried")
+		})
+	}
+}
+
+func TestAppendIdempotentRetriesDisabled(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AppendBlob.Enabled = true
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	assert.Nil(t, uploads[0].appendOpts.AppendPositionAccessConditions)
+}
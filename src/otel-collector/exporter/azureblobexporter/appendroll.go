@@ -0,0 +1,98 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"sync"
+	"time"
+)
+
+// maxAppendBlocks is the number of blocks Azure allows in an append blob.
+const maxAppendBlocks = 50000
+
+// appendTargetIdleTimeout is how long the append target of a name is kept without appends when no
+// roll_interval is set.
+const appendTargetIdleTimeout = time.Hour
+
+// appendTarget is the blob the chunks of a generated name are currently appended to.
+type appendTarget struct {
+	// serial is the ULID of the opening time, which replaces the unique suffix of the name
+	serial string
+	// blocks counts the successful appends, pending the appends still in progress
+	blocks   int
+	pending  int
+	opened   time.Time
+	lastUsed time.Time
+}
+
+// appendRoller rolls the append blob of every generated name to a new blob once it holds
+// append_blob.max_blocks blocks or was opened longer than append_blob.roll_interval ago. Names are
+// tracked without their unique suffix, and targets get a ULID of their opening time in its place, so
+// a restarted collector never appends to a blob whose block count it does not know.
+type appendRoller struct {
+	maxBlocks int
+	interval  time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	targets map[string]*appendTarget
+}
+
+// newAppendRoller returns the roller of cfg, or nil when neither max_blocks nor roll_interval is set.
+func newAppendRoller(cfg AppendBlob) *appendRoller {
+	if cfg.MaxBlocks == 0 && cfg.RollInterval == 0 {
+		return nil
+	}
+	return &appendRoller{
+		maxBlocks: cfg.MaxBlocks,
+		interval:  cfg.RollInterval,
+		now:       time.Now,
+		targets:   map[string]*appendTarget{},
+	}
+}
+
+// target returns the blob the next chunk of name, the generated name without its unique suffix, is
+// appended to. The append is pending until it is passed to done, and pending appends count towards
+// max_blocks, so concurrent appends cannot overshoot it.
+func (r *appendRoller) target(name string) *appendTarget {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.prune(now)
+
+	t, ok := r.targets[name]
+	if !ok || (r.maxBlocks > 0 && t.blocks+t.pending >= r.maxBlocks) || (r.interval > 0 && now.Sub(t.opened) >= r.interval) {
+		t = &appendTarget{serial: newULID(now), opened: now}
+		r.targets[name] = t
+	}
+	t.pending++
+	t.lastUsed = now
+	return t
+}
+
+// done ends an append to t, which only counts as a block when it succeeded.
+func (r *appendRoller) done(t *appendTarget, appended bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.pending--
+	if appended {
+		t.blocks++
+	}
+}
+
+// prune forgets the targets of names without recent or pending appends, such as the names of past
+// hours. r.mu must be held.
+func (r *appendRoller) prune(now time.Time) {
+	idle := appendTargetIdleTimeout
+	if r.interval > 0 {
+		// A target idle for longer than roll_interval would be rolled on its next append anyway
+		idle = r.interval
+	}
+	for name, t := range r.targets {
+		if t.pending == 0 && now.Sub(t.lastUsed) > idle {
+			delete(r.targets, name)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// newTestRoller returns a roller of cfg whose clock is *now.
+func newTestRoller(cfg AppendBlob, now *time.Time) *appendRoller {
+	r := newAppendRoller(cfg)
+	r.now = func() time.Time { return *now }
+	return r
+}
+
+func TestAppendRollerMaxBlocks(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := newTestRoller(AppendBlob{MaxBlocks: maxAppendBlocks}, &now)
+
+	serials := map[string]int{}
+	for range maxAppendBlocks + 1 {
+		target := r.target("traces.json")
+		r.done(target, true)
+		serials[target.serial]++
+	}
+	require.Len(t, serials, 2)
+	counts := []int{}
+	for _, n := range serials {
+		counts = append(counts, n)
+	}
+	assert.ElementsMatch(t, []int{maxAppendBlocks, 1}, counts)
+}
+
+func TestAppendRollerFailedAppends(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := newTestRoller(AppendBlob{MaxBlocks: 2}, &now)
+
+	first := r.target("traces.json")
+	r.done(first, false)
+	for range 2 {
+		target := r.target("traces.json")
+		assert.Equal(t, first.serial, target.serial, "failed appends do not count as blocks")
+		r.done(target, true)
+	}
+	assert.NotEqual(t, first.serial, r.target("traces.json").serial)
+}
+
+func TestAppendRollerPendingAppends(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := newTestRoller(AppendBlob{MaxBlocks: 2}, &now)
+
+	first := r.target("traces.json")
+	second := r.target("traces.json")
+	third := r.target("traces.json")
+	assert.Equal(t, first.serial, second.serial)
+	assert.NotEqual(t, first.serial, third.serial, "pending appends count towards max_blocks")
+}
+
+func TestAppendRollerInterval(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := newTestRoller(AppendBlob{RollInterval: time.Minute}, &now)
+
+	first := r.target("traces.json")
+	r.done(first, true)
+	now = now.Add(30 * time.Second)
+	second := r.target("traces.json")
+	r.done(second, true)
+	assert.Equal(t, first.serial, second.serial)
+
+	now = now.Add(30 * time.Second)
+	assert.NotEqual(t, first.serial, r.target("traces.json").serial)
+	assert.NotEqual(t, first.serial, r.target("logs.json").serial, "every name has its own target")
+}
+
+func TestAppendRollerPrune(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := newTestRoller(AppendBlob{MaxBlocks: 10}, &now)
+
+	pending := r.target("pending.json")
+	r.done(r.target("idle.json"), true)
+	now = now.Add(appendTargetIdleTimeout + time.Second)
+	r.target("other.json")
+
+	assert.NotContains(t, r.targets, "idle.json")
+	assert.Contains(t, r.targets, "pending.json", "targets with pending appends are kept")
+	r.done(pending, true)
+}
+
+func TestRollingAppendBlobs(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AppendBlob.Enabled = true
+	cfg.AppendBlob.MaxBlocks = 2
+	cfg.BlobNameFormat.LogsFormat = "logs.json"
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+	ctx := context.Background()
+
+	for range 3 {
+		require.NoError(t, exp.ConsumeLogs(ctx, newTestLogs(1)))
+	}
+
+	require.Len(t, client.blobs, 2, "the random serial does not split the chunks of a name")
+	for name, data := range client.blobs {
+		assert.Regexp(t, regexp.MustCompile(`^logs/logs\.json_[0-9A-HJKMNP-TV-Z]{26}$`), name)
+		assert.Contains(t, []int{1, 2}, strings.Count(string(data), "\n"))
+	}
+}
+
+func TestRollingAppendBlobsFailure(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AppendBlob.Enabled = true
+	cfg.AppendBlob.MaxBlocks = 2
+	cfg.BlobNameFormat.LogsFormat = "logs.json"
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+	ctx := context.Background()
+
+	client.fail = failContainer("logs")
+	require.Error(t, exp.ConsumeLogs(ctx, newTestLogs(1)))
+	client.fail = nil
+	for range 2 {
+		require.NoError(t, exp.ConsumeLogs(ctx, newTestLogs(1)))
+	}
+	assert.Len(t, client.blobs, 1, "a failed append does not count as a block")
+}
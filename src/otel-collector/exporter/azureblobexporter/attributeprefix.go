@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// prefixStripper removes configured prefixes from attribute keys, trying the longest prefix first.
+type prefixStripper struct {
+	prefixes []string
+	// conflicts counts the keys left as they are because their stripped key was taken
+	conflicts int
+}
+
+func newPrefixStripper(prefixes []string) *prefixStripper {
+	sorted := append([]string(nil), prefixes...)
+	slices.SortStableFunc(sorted, func(a, b string) int { return len(b) - len(a) })
+	return &prefixStripper{prefixes: sorted}
+}
+
+// strip renames the keys of attrs that start with a prefix. A key whose stripped form is already in
+// attrs, or was produced by stripping an earlier key, keeps its prefix, so no value is overwritten.
+func (s *prefixStripper) strip(attrs pcommon.Map) {
+	type rename struct{ from, to string }
+	var renames []rename
+	attrs.Range(func(k string, _ pcommon.Value) bool {
+		for _, prefix := range s.prefixes {
+			if stripped, ok := strings.CutPrefix(k, prefix); ok && stripped != "" {
+				renames = append(renames, rename{from: k, to: stripped})
+				break
+			}
+		}
+		return true
+	})
+
+	for _, r := range renames {
+		if _, taken := attrs.Get(r.to); taken {
+			s.conflicts++
+			continue
+		}
+		v, _ := attrs.Get(r.from)
+		v.CopyTo(attrs.PutEmpty(r.to))
+		attrs.Remove(r.from)
+	}
+}
+
+// stripAttributePrefixes returns a copy of telemetryData with the prefixes removed from the keys of
+// its resource, scope, record, span event, span link and data point attributes, and the number of
+// keys left unstripped because of a conflict.
+func stripAttributePrefixes(telemetryData any, prefixes []string) (any, int) {
+	s := newPrefixStripper(prefixes)
+
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		stripped := ptrace.NewTraces()
+		td.CopyTo(stripped)
+		rss := stripped.ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			s.strip(rss.At(i).Resource().Attributes())
+			sss := rss.At(i).ScopeSpans()
+			for j := 0; j < sss.Len(); j++ {
+				s.strip(sss.At(j).Scope().Attributes())
+				spans := sss.At(j).Spans()
+				for k := 0; k < spans.Len(); k++ {
+					span := spans.At(k)
+					s.strip(span.Attributes())
+					for l := 0; l < span.Events().Len(); l++ {
+						s.strip(span.Events().At(l).Attributes())
+					}
+					for l := 0; l < span.Links().Len(); l++ {
+						s.strip(span.Links().At(l).Attributes())
+					}
+				}
+			}
+		}
+		return stripped, s.conflicts
+	case plog.Logs:
+		stripped := plog.NewLogs()
+		td.CopyTo(stripped)
+		rls := stripped.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			s.strip(rls.At(i).Resource().Attributes())
+			sls := rls.At(i).ScopeLogs()
+			for j := 0; j < sls.Len(); j++ {
+				s.strip(sls.At(j).Scope().Attributes())
+				records := sls.At(j).LogRecords()
+				for k := 0; k < records.Len(); k++ {
+					s.strip(records.At(k).Attributes())
+				}
+			}
+		}
+		return stripped, s.conflicts
+	case pmetric.Metrics:
+		stripped := pmetric.NewMetrics()
+		td.CopyTo(stripped)
+		rms := stripped.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			s.strip(rms.At(i).Resource().Attributes())
+			sms := rms.At(i).ScopeMetrics()
+			for j := 0; j < sms.Len(); j++ {
+				s.strip(sms.At(j).Scope().Attributes())
+				metrics := sms.At(j).Metrics()
+				for k := 0; k < metrics.Len(); k++ {
+					forEachDataPointAttributes(metrics.At(k), s.strip)
+				}
+			}
+		}
+		return stripped, s.conflicts
+	default:
+		return telemetryData, 0
+	}
+}
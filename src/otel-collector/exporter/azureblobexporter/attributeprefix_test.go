@@ -0,0 +1,155 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// testPrefixes lists the shorter prefix first, so stripping has to order them by length.
+var testPrefixes = []string{"custom.", "custom.company."}
+
+// putAttrs puts the key and value pairs into attrs in order, which decides the key that wins a conflict.
+func putAttrs(attrs pcommon.Map, pairs [][2]string) {
+	for _, kv := range pairs {
+		attrs.PutStr(kv[0], kv[1])
+	}
+}
+
+func TestStripAttributePrefixes(t *testing.T) {
+	tests := []struct {
+		name          string
+		attrs         [][2]string
+		want          map[string]any
+		wantConflicts int
+	}{
+		{
+			name:  "longest prefix first",
+			attrs: [][2]string{{"custom.company.team", "payments"}, {"custom.region", "eu"}, {"service.name", "checkout"}},
+			want:  map[string]any{"team": "payments", "region": "eu", "service.name": "checkout"},
+		},
+		{
+			name:  "key equal to a prefix",
+			attrs: [][2]string{{"custom.", "kept"}},
+			want:  map[string]any{"custom.": "kept"},
+		},
+		{
+			name:          "stripped key exists",
+			attrs:         [][2]string{{"team", "platform"}, {"custom.company.team", "payments"}},
+			want:          map[string]any{"team": "platform", "custom.company.team": "payments"},
+			wantConflicts: 1,
+		},
+		{
+			name:          "two keys strip to the same key",
+			attrs:         [][2]string{{"custom.company.region", "eu"}, {"custom.region", "us"}},
+			want:          map[string]any{"region": "eu", "custom.region": "us"},
+			wantConflicts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := plog.NewLogs()
+			rl := ld.ResourceLogs().AppendEmpty()
+			putAttrs(rl.Resource().Attributes(), tt.attrs)
+			sl := rl.ScopeLogs().AppendEmpty()
+			putAttrs(sl.Scope().Attributes(), tt.attrs)
+			putAttrs(sl.LogRecords().AppendEmpty().Attributes(), tt.attrs)
+			input := pcommon.NewMap()
+			rl.Resource().Attributes().CopyTo(input)
+
+			stripped, conflicts := stripAttributePrefixes(ld, testPrefixes)
+
+			got := stripped.(plog.Logs).ResourceLogs().At(0)
+			assert.Equal(t, tt.want, got.Resource().Attributes().AsRaw())
+			assert.Equal(t, tt.want, got.ScopeLogs().At(0).Scope().Attributes().AsRaw())
+			assert.Equal(t, tt.want, got.ScopeLogs().At(0).LogRecords().At(0).Attributes().AsRaw())
+			assert.Equal(t, 3*tt.wantConflicts, conflicts, "conflicts are counted for every map")
+			assert.Equal(t, input.AsRaw(), rl.Resource().Attributes().AsRaw(), "the input is not modified")
+		})
+	}
+}
+
+func TestStripAttributePrefixesTraces(t *testing.T) {
+	td := ptrace.NewTraces()
+	span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.Attributes().PutStr("custom.company.order", "o-1")
+	span.Events().AppendEmpty().Attributes().PutStr("custom.retry", "true")
+	span.Links().AppendEmpty().Attributes().PutStr("custom.company.origin", "queue")
+
+	stripped, conflicts := stripAttributePrefixes(td, testPrefixes)
+
+	got := stripped.(ptrace.Traces).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, map[string]any{"order": "o-1"}, got.Attributes().AsRaw())
+	assert.Equal(t, map[string]any{"retry": "true"}, got.Events().At(0).Attributes().AsRaw())
+	assert.Equal(t, map[string]any{"origin": "queue"}, got.Links().At(0).Attributes().AsRaw())
+	assert.Zero(t, conflicts)
+}
+
+func TestStripAttributePrefixesMetrics(t *testing.T) {
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	metrics.AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().Attributes().PutStr("custom.queue", "orders")
+	metrics.AppendEmpty().SetEmptyHistogram().DataPoints().AppendEmpty().Attributes().PutStr("custom.company.route", "/pay")
+
+	stripped, _ := stripAttributePrefixes(md, testPrefixes)
+
+	got := stripped.(pmetric.Metrics).ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	assert.Equal(t, map[string]any{"queue": "orders"}, got.At(0).Gauge().DataPoints().At(0).Attributes().AsRaw())
+	assert.Equal(t, map[string]any{"route": "/pay"}, got.At(1).Histogram().DataPoints().At(0).Attributes().AsRaw())
+}
+
+func TestConsumeLogsStripAttributePrefixes(t *testing.T) {
+	tel := componenttest.NewTelemetry()
+	t.Cleanup(func() { require.NoError(t, tel.Shutdown(context.Background())) })
+	cfg := newTestConfig()
+	cfg.StripAttributePrefixes = testPrefixes
+	exp, client := newTestExporterWithSettings(t, cfg, pipeline.SignalLogs, tel.NewTelemetrySettings())
+	ld := newTestLogs(1)
+	attrs := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes()
+	putAttrs(attrs, [][2]string{{"custom.company.team", "payments"}, {"team", "platform"}, {"custom.region", "eu"}})
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), ld))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	got, err := (&plog.JSONUnmarshaler{}).UnmarshalLogs(uploads[0].data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"custom.company.team": "payments", "team": "platform", "region": "eu"},
+		got.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().AsRaw())
+	assert.Equal(t, int64(1), counterValue(t, tel, "otelcol_exporter_azureblob_attribute_prefix_conflicts"))
+}
+
+func TestStripAttributePrefixesValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		wantErr  string
+	}{
+		{name: "prefixes", prefixes: testPrefixes},
+		{name: "empty prefix", prefixes: []string{"custom.", ""}, wantErr: "strip_attribute_prefixes cannot contain an empty prefix"},
+		{name: "duplicate prefix", prefixes: []string{"custom.", "custom."}, wantErr: `strip_attribute_prefixes contains "custom." more than once`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.StripAttributePrefixes = tt.prefixes
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Avro schemas of the rows, with the field names and types of the parquet columns. Optional parquet
+// columns of pointer type are nullable, the others hold their zero value when unset, as in parquet.
+const (
+	avroTypedAttributesSchema = `{
+		"type": "record", "name": "TypedAttributes",
+		"fields": [
+			{"name": "int", "type": {"type": "map", "values": "long"}},
+			{"name": "double", "type": {"type": "map", "values": "double"}},
+			{"name": "bool", "type": {"type": "map", "values": "boolean"}}
+		]
+	}`
+
+	avroSpanSchemaJSON = `{
+		"type": "record", "name": "Span", "namespace": "opentelemetry.azureblob",
+		"fields": [
+			{"name": "trace_id", "type": "string"},
+			{"name": "span_id", "type": "string"},
+			{"name": "parent_span_id", "type": "string"},
+			{"name": "trace_state", "type": "string"},
+			{"name": "flags", "type": "long"},
+			{"name": "name", "type": "string"},
+			{"name": "kind", "type": "int"},
+			{"name": "start_time_unix_nano", "type": ["null", "long"], "default": null},
+			{"name": "end_time_unix_nano", "type": ["null", "long"], "default": null},
+			{"name": "status_code", "type": "int"},
+			{"name": "status_message", "type": "string"},
+			{"name": "resource_attributes", "type": {"type": "map", "values": "string"}},
+			{"name": "span_attributes", "type": {"type": "map", "values": "string"}},
+			{"name": "scope_name", "type": "string"},
+			{"name": "scope_version", "type": "string"},
+			{"name": "events", "type": {"type": "array", "items": {
+				"type": "record", "name": "SpanEvent",
+				"fields": [
+					{"name": "time_unix_nano", "type": ["null", "long"], "default": null},
+					{"name": "name", "type": "string"},
+					{"name": "attributes", "type": {"type": "map", "values": "string"}},
+					{"name": "dropped_attributes_count", "type": ["null", "long"], "default": null}
+				]
+			}}},
+			{"name": "links", "type": {"type": "array", "items": {
+				"type": "record", "name": "SpanLink",
+				"fields": [
+					{"name": "trace_id", "type": "string"},
+					{"name": "span_id", "type": "string"},
+					{"name": "trace_state", "type": "string"},
+					{"name": "attributes", "type": {"type": "map", "values": "string"}},
+					{"name": "dropped_attributes_count", "type": ["null", "long"], "default": null}
+				]
+			}}},
+			{"name": "resource_attributes_typed", "type": ["null", ` + avroTypedAttributesSchema + `], "default": null},
+			{"name": "span_attributes_typed", "type": ["null", "TypedAttributes"], "default": null},
+			{"name": "dropped_attributes_count", "type": ["null", "long"], "default": null},
+			{"name": "dropped_events_count", "type": ["null", "long"], "default": null},
+			{"name": "dropped_links_count", "type": ["null", "long"], "default": null}
+		]
+	}`
+
+	avroLogSchemaJSON = `{
+		"type": "record", "name": "Log", "namespace": "opentelemetry.azureblob",
+		"fields": [
+			{"name": "timestamp_unix_nano", "type": ["null", "long"], "default": null},
+			{"name": "observed_timestamp_unix_nano", "type": ["null", "long"], "default": null},
+			{"name": "severity_number", "type": "int"},
+			{"name": "severity_text", "type": "string"},
+			{"name": "body", "type": "string"},
+			{"name": "trace_id", "type": "string"},
+			{"name": "span_id", "type": "string"},
+			{"name": "flags", "type": "long"},
+			{"name": "resource_attributes", "type": {"type": "map", "values": "string"}},
+			{"name": "log_attributes", "type": {"type": "map", "values": "string"}},
+			{"name": "scope_name", "type": "string"},
+			{"name": "scope_version", "type": "string"},
+			{"name": "resource_attributes_typed", "type": ["null", ` + avroTypedAttributesSchema + `], "default": null},
+			{"name": "log_attributes_typed", "type": ["null", "TypedAttributes"], "default": null},
+			{"name": "dropped_attributes_count", "type": ["null", "long"], "default": null}
+		]
+	}`
+
+	avroMetricSchemaJSON = `{
+		"type": "record", "name": "Metric", "namespace": "opentelemetry.azureblob",
+		"fields": [
+			{"name": "name", "type": "string"},
+			{"name": "description", "type": "string"},
+			{"name": "unit", "type": "string"},
+			{"name": "type", "type": "string"},
+			{"name": "time_unix_nano", "type": ["null", "long"], "default": null},
+			{"name": "value_type", "type": "string"},
+			{"name": "int_value", "type": "long"},
+			{"name": "double_value", "type": "double"},
+			{"name": "resource_attributes", "type": {"type": "map", "values": "string"}},
+			{"name": "metric_attributes", "type": {"type": "map", "values": "string"}},
+			{"name": "scope_name", "type": "string"},
+			{"name": "scope_version", "type": "string"},
+			{"name": "original_name", "type": "string"},
+			{"name": "resource_attributes_typed", "type": ["null", ` + avroTypedAttributesSchema + `], "default": null},
+			{"name": "metric_attributes_typed", "type": ["null", "TypedAttributes"], "default": null},
+			{"name": "is_monotonic", "type": "boolean"},
+			{"name": "aggregation_temporality", "type": "string"},
+			{"name": "start_time_unix_nano", "type": "long"},
+			{"name": "count", "type": ["null", "long"], "default": null},
+			{"name": "quantile_values", "type": {"type": "array", "items": {
+				"type": "record", "name": "QuantileValue",
+				"fields": [
+					{"name": "quantile", "type": "double"},
+					{"name": "value", "type": "double"}
+				]
+			}}},
+			{"name": "exemplars", "type": "string"}
+		]
+	}`
+)
+
+// The schemas are parsed with their own caches, as each of them defines TypedAttributes.
+var (
+	avroSpanSchema   = mustParseAvroSchema(avroSpanSchemaJSON)
+	avroLogSchema    = mustParseAvroSchema(avroLogSchemaJSON)
+	avroMetricSchema = mustParseAvroSchema(avroMetricSchemaJSON)
+)
+
+func mustParseAvroSchema(schema string) avro.Schema {
+	s, err := avro.ParseWithCache(schema, "", &avro.SchemaCache{})
+	if err != nil {
+		panic(fmt.Sprintf("invalid avro schema: %v", err))
+	}
+	return s
+}
+
+// avroMetric is a metric row with the summary count as a long, as Avro has no unsigned long.
+type avroMetric struct {
+	ParquetMetric
+	Count *int64 `avro:"count"`
+}
+
+// avroMarshaller writes the rows of the parquet format as an Avro object container file, whose
+// header holds the schema, so readers need no schema registry to decode a blob.
+type avroMarshaller struct {
+	rows *parquetMarshaller
+}
+
+func newAvroMarshaller(config *Config) (*avroMarshaller, error) {
+	rows, err := newParquetMarshaller(config)
+	if err != nil {
+		return nil, err
+	}
+	return &avroMarshaller{rows: rows}, nil
+}
+
+func (a *avroMarshaller) MarshalTraces(td ptrace.Traces) ([]byte, error) {
+	return marshalToAvro(avroSpanSchema, a.rows.spanRows(td))
+}
+
+func (a *avroMarshaller) MarshalLogs(ld plog.Logs) ([]byte, error) {
+	return marshalToAvro(avroLogSchema, a.rows.logRows(ld))
+}
+
+func (a *avroMarshaller) MarshalMetrics(md pmetric.Metrics) ([]byte, error) {
+	rows := a.rows.metricRows(md)
+	metrics := make([]avroMetric, len(rows))
+	for i, row := range rows {
+		metrics[i].ParquetMetric = row
+		if row.Count != nil {
+			metrics[i].Count = ptr(int64(*row.Count))
+		}
+	}
+	return marshalToAvro(avroMetricSchema, metrics)
+}
+
+func (a *avroMarshaller) format() string {
+	return formatTypeAvro
+}
+
+// marshalToAvro writes rows to an object container file. The header also holds the schema version
+// of the parquet format, as both formats share their rows.
+func marshalToAvro[T any](schema avro.Schema, rows []T) ([]byte, error) {
+	if len(rows) == 0 {
+		return []byte{}, nil
+	}
+
+	buf := new(bytes.Buffer)
+	encoder, err := ocf.NewEncoderWithSchema(schema, buf,
+		ocf.WithMetadataKeyVal(parquetSchemaVersionKey, []byte(strconv.Itoa(parquetSchemaVersion))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create avro encoder: %w", err)
+	}
+	for i := range rows {
+		if err = encoder.Encode(&rows[i]); err != nil {
+			return nil, fmt.Errorf("failed to write avro data: %w", err)
+		}
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close avro encoder: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
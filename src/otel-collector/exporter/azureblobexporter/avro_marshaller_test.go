@@ -0,0 +1,116 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// readAvro decodes the records of the object container file data with the schema of its header.
+func readAvro[T any](t *testing.T, data []byte) ([]T, map[string][]byte) {
+	t.Helper()
+	decoder, err := ocf.NewDecoder(bytes.NewReader(data))
+	require.NoError(t, err)
+	var rows []T
+	for decoder.HasNext() {
+		var row T
+		require.NoError(t, decoder.Decode(&row))
+		rows = append(rows, row)
+	}
+	require.NoError(t, decoder.Error())
+	return rows, decoder.Metadata()
+}
+
+func newTestAvroMarshaller(t *testing.T) *avroMarshaller {
+	t.Helper()
+	m, err := newAvroMarshaller(newTestConfig())
+	require.NoError(t, err)
+	return m
+}
+
+func TestAvroTraces(t *testing.T) {
+	m := newTestAvroMarshaller(t)
+	td := newTestSpanWithEvents()
+	td.ResourceSpans().At(0).Resource().Attributes().PutStr("service.name", "checkout")
+
+	data, err := m.MarshalTraces(td)
+	require.NoError(t, err)
+
+	spans, metadata := readAvro[ParquetSpan](t, data)
+	assert.Equal(t, m.rows.spanRows(td), spans)
+	assert.Contains(t, string(metadata["avro.schema"]), `"name":"opentelemetry.azureblob.Span"`, "the header holds the schema")
+	assert.Equal(t, strconv.Itoa(parquetSchemaVersion), string(metadata[parquetSchemaVersionKey]))
+}
+
+func TestAvroLogs(t *testing.T) {
+	m := newTestAvroMarshaller(t)
+	ld := newTestLogs(1, 2, 3)
+
+	data, err := m.MarshalLogs(ld)
+	require.NoError(t, err)
+
+	logs, _ := readAvro[ParquetLog](t, data)
+	require.Len(t, logs, 3)
+	assert.Equal(t, m.rows.logRows(ld), logs)
+}
+
+func TestAvroMetrics(t *testing.T) {
+	m := newTestAvroMarshaller(t)
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	gauge := metrics.AppendEmpty()
+	gauge.SetName("queue.size")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(7)
+	summary := metrics.AppendEmpty()
+	summary.SetName("rpc.duration")
+	dp := summary.SetEmptySummary().DataPoints().AppendEmpty()
+	dp.SetCount(42)
+	dp.SetSum(12.5)
+	qv := dp.QuantileValues().AppendEmpty()
+	qv.SetQuantile(0.99)
+	qv.SetValue(1.7)
+
+	data, err := m.MarshalMetrics(md)
+	require.NoError(t, err)
+
+	rows, _ := readAvro[avroMetric](t, data)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "queue.size", rows[0].Name)
+	assert.Equal(t, int64(7), rows[0].IntValue)
+	assert.Nil(t, rows[0].Count)
+	assert.Equal(t, "rpc.duration", rows[1].Name)
+	assert.Equal(t, 12.5, rows[1].DoubleValue)
+	assert.Equal(t, ptr(int64(42)), rows[1].Count, "the summary count is a long")
+	assert.Equal(t, []ParquetQuantileValue{{Quantile: 0.99, Value: 1.7}}, rows[1].QuantileValues)
+}
+
+func TestAvroEmpty(t *testing.T) {
+	data, err := newTestAvroMarshaller(t).MarshalLogs(newTestLogs())
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestConsumeLogsAvro(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.FormatType = formatTypeAvro
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1, 2)))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	assert.True(t, strings.Contains(uploads[0].blob, ".avro"), uploads[0].blob)
+	logs, _ := readAvro[ParquetLog](t, uploads[0].data)
+	assert.Len(t, logs, 2)
+}
@@ -0,0 +1,203 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// batcher buffers the telemetry of many consume calls and writes it as one blob once the buffer
+// reaches the configured size, on every flush interval, and on shutdown.
+type batcher struct {
+	cfg    Batch
+	logger *zap.Logger
+	write  func(ctx context.Context, telemetryData any, r timestampRange) error
+
+	mu         sync.Mutex
+	data       any
+	size       int
+	timestamps timestampRange
+
+	stop chan struct{}
+	done chan struct{}
+
+	// compact only writes the buffer on shutdown, or once it reached its size. It then sets
+	// overflowed, and later telemetry is written as it is consumed.
+	compact    bool
+	overflowed bool
+}
+
+func newBatcher(cfg Batch, logger *zap.Logger, write func(context.Context, any, timestampRange) error) *batcher {
+	return &batcher{
+		cfg:    cfg,
+		logger: logger,
+		write:  write,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// newCompactor returns a batcher for compact_on_shutdown, which has no flush interval.
+func newCompactor(cfg CompactOnShutdown, logger *zap.Logger, write func(context.Context, any, timestampRange) error) *batcher {
+	b := newBatcher(Batch{Enabled: true, MaxBytes: cfg.MaxBytes}, logger, write)
+	b.compact = true
+	close(b.done)
+	return b
+}
+
+// start flushes the buffer every flush interval until shutdown.
+func (b *batcher) start() {
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(b.cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := b.flush(context.Background()); err != nil {
+					b.logger.Error("Failed to flush batch", zap.Error(err))
+				}
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
+
+// add copies telemetryData into the buffer, as the exporter does not mutate the data it
+// receives, and flushes the buffer when it reached the size threshold or, with flush_on_root_span,
+// when telemetryData holds a root span. r is the range of watermark timestamps of telemetryData,
+// the watermark advances once the buffer is written.
+func (b *batcher) add(ctx context.Context, telemetryData any, r timestampRange) error {
+	b.mu.Lock()
+	if b.overflowed {
+		b.mu.Unlock()
+		return b.write(ctx, telemetryData, r)
+	}
+	if b.data == nil {
+		b.data = newTelemetryLike(telemetryData)
+	}
+	appendTelemetry(b.data, telemetryData)
+	b.size += protoSize(telemetryData)
+	b.timestamps.merge(r)
+	if b.size < b.cfg.MaxBytes && !(b.cfg.FlushOnRootSpan && hasRootSpan(telemetryData)) {
+		b.mu.Unlock()
+		return nil
+	}
+	data, r := b.take()
+	if b.compact {
+		b.overflowed = true
+		b.logger.Warn("compact_on_shutdown buffer is full, writing it and exporting further telemetry as it is consumed",
+			zap.Int("max_bytes", b.cfg.MaxBytes))
+	}
+	b.mu.Unlock()
+
+	// The buffer holds the data of other callers as well, so retrying this call would not
+	// resend the rest of the failed blob.
+	if err := b.write(ctx, data, r); err != nil {
+		return consumererror.NewPermanent(fmt.Errorf("failed to flush batch: %w", err))
+	}
+	return nil
+}
+
+// flush writes the buffer if it holds any telemetry.
+func (b *batcher) flush(ctx context.Context) error {
+	b.mu.Lock()
+	data, r := b.take()
+	b.mu.Unlock()
+	if data == nil {
+		return nil
+	}
+	return b.write(ctx, data, r)
+}
+
+// take empties the buffer and returns its content. b.mu must be held.
+func (b *batcher) take() (any, timestampRange) {
+	data, r := b.data, b.timestamps
+	b.data, b.size, b.timestamps = nil, 0, timestampRange{}
+	return data, r
+}
+
+// shutdown stops the flush interval and writes the remaining buffer.
+func (b *batcher) shutdown(ctx context.Context) error {
+	close(b.stop)
+	<-b.done
+	return b.flush(ctx)
+}
+
+// hasRootSpan reports whether telemetryData is traces holding a span without parent.
+func hasRootSpan(telemetryData any) bool {
+	td, ok := telemetryData.(ptrace.Traces)
+	if !ok {
+		return false
+	}
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		sss := td.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if spans.At(k).ParentSpanID().IsEmpty() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func newTelemetryLike(telemetryData any) any {
+	switch telemetryData.(type) {
+	case ptrace.Traces:
+		return ptrace.NewTraces()
+	case plog.Logs:
+		return plog.NewLogs()
+	default:
+		return pmetric.NewMetrics()
+	}
+}
+
+// appendTelemetry copies the resources of src to the end of dst.
+func appendTelemetry(dst, src any) {
+	switch d := dst.(type) {
+	case ptrace.Traces:
+		rss := src.(ptrace.Traces).ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			rss.At(i).CopyTo(d.ResourceSpans().AppendEmpty())
+		}
+	case plog.Logs:
+		rls := src.(plog.Logs).ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			rls.At(i).CopyTo(d.ResourceLogs().AppendEmpty())
+		}
+	case pmetric.Metrics:
+		rms := src.(pmetric.Metrics).ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			rms.At(i).CopyTo(d.ResourceMetrics().AppendEmpty())
+		}
+	}
+}
+
+// protoSize returns the OTLP protobuf size of telemetryData, which approximates the size it adds
+// to a blob without marshalling it twice.
+func protoSize(telemetryData any) int {
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		return (&ptrace.ProtoMarshaler{}).TracesSize(td)
+	case plog.Logs:
+		return (&plog.ProtoMarshaler{}).LogsSize(td)
+	case pmetric.Metrics:
+		return (&pmetric.ProtoMarshaler{}).MetricsSize(td)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// newBatchConfig returns a configuration batching up to maxBytes for flushInterval.
+func newBatchConfig(maxBytes int, flushInterval time.Duration) *Config {
+	cfg := newTestConfig()
+	cfg.Batch = Batch{Enabled: true, MaxBytes: maxBytes, FlushInterval: flushInterval}
+	return cfg
+}
+
+// logRecordCounts returns the number of log records of every JSON blob uploaded to client.
+func logRecordCounts(t *testing.T, client *mockClient) []int {
+	t.Helper()
+	var counts []int
+	for _, upload := range client.recorded() {
+		ld, err := (&plog.JSONUnmarshaler{}).UnmarshalLogs(upload.data)
+		require.NoError(t, err)
+		counts = append(counts, ld.LogRecordCount())
+	}
+	return counts
+}
+
+// newUnstoppedExporter is newTestExporter leaving the shutdown, which writes the buffer, to the test.
+func newUnstoppedExporter(t *testing.T, cfg *Config, signal pipeline.Signal) (*azureBlobExporter, *mockClient) {
+	t.Helper()
+	require.NoError(t, cfg.Validate())
+	exp, err := newAzureBlobExporter(cfg, component.MustNewID("azureblob"), componenttest.NewNopTelemetrySettings(), signal)
+	require.NoError(t, err)
+	require.NoError(t, exp.start(context.Background(), componenttest.NewNopHost()))
+	client := &mockClient{}
+	exp.client = client
+	return exp, client
+}
+
+// recordCounts returns the number of records of every JSON blob of signal uploaded to client.
+func recordCounts(t *testing.T, client *mockClient, signal pipeline.Signal) []int {
+	t.Helper()
+	var counts []int
+	for _, upload := range client.recorded() {
+		var telemetryData any
+		var err error
+		switch signal {
+		case pipeline.SignalTraces:
+			telemetryData, err = (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(upload.data)
+		case pipeline.SignalLogs:
+			telemetryData, err = (&plog.JSONUnmarshaler{}).UnmarshalLogs(upload.data)
+		case pipeline.SignalMetrics:
+			telemetryData, err = (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics(upload.data)
+		}
+		require.NoError(t, err)
+		counts = append(counts, recordCount(telemetryData))
+	}
+	return counts
+}
+
+func TestBatchMergesConsumes(t *testing.T) {
+	exp, client := newUnstoppedExporter(t, newBatchConfig(1<<20, time.Hour), pipeline.SignalLogs)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(pcommon.Timestamp(i+1))))
+		}()
+	}
+	wg.Wait()
+	assert.Empty(t, client.recorded(), "small batches are buffered")
+
+	require.NoError(t, exp.shutdown(context.Background()))
+	assert.Equal(t, []int{10}, logRecordCounts(t, client), "the buffer is written as one blob on shutdown")
+}
+
+func TestBatchSizeFlush(t *testing.T) {
+	size := protoSize(newTestLogs(1))
+	exp, client := newTestExporter(t, newBatchConfig(3*size, time.Hour), pipeline.SignalLogs)
+
+	for i := range 2 {
+		require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(pcommon.Timestamp(i+1))))
+	}
+	assert.Empty(t, client.recorded())
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(3)))
+	assert.Equal(t, []int{3}, logRecordCounts(t, client), "reaching max_bytes writes the buffer")
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(4)))
+	assert.Len(t, client.recorded(), 1, "the buffer starts empty again")
+}
+
+func TestBatchIntervalFlush(t *testing.T) {
+	exp, client := newTestExporter(t, newBatchConfig(1<<20, 10*time.Millisecond), pipeline.SignalLogs)
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(2)))
+
+	require.Eventually(t, func() bool { return len(client.recorded()) == 1 }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{2}, logRecordCounts(t, client), "the flush interval writes the buffer")
+}
+
+func TestBatchFlushFailure(t *testing.T) {
+	size := protoSize(newTestLogs(1))
+	exp, client := newTestExporter(t, newBatchConfig(size, time.Hour), pipeline.SignalLogs)
+	client.fail = failContainer("logs")
+
+	err := exp.ConsumeLogs(context.Background(), newTestLogs(1))
+	require.ErrorIs(t, err, errTestUpload)
+	assert.ErrorContains(t, err, "failed to flush batch")
+}
+
+func TestCompactOnShutdown(t *testing.T) {
+	for _, signal := range []pipeline.Signal{pipeline.SignalTraces, pipeline.SignalLogs, pipeline.SignalMetrics} {
+		t.Run(signal.String(), func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.CompactOnShutdown.Enabled = true
+			exp, client := newUnstoppedExporter(t, cfg, signal)
+
+			for range 3 {
+				consumeSignal(t, exp, signal)
+			}
+			assert.Empty(t, client.recorded(), "nothing is written before shutdown")
+
+			require.NoError(t, exp.shutdown(context.Background()))
+			assert.Equal(t, []int{3}, recordCounts(t, client, signal), "shutdown writes a single consolidated blob")
+		})
+	}
+}
+
+func TestCompactOnShutdownFull(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CompactOnShutdown = CompactOnShutdown{Enabled: true, MaxBytes: 2 * protoSize(newTestLogs(1))}
+	exp, client := newUnstoppedExporter(t, cfg, pipeline.SignalLogs)
+
+	for i := range 2 {
+		require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(pcommon.Timestamp(i+1))))
+	}
+	assert.Equal(t, []int{2}, logRecordCounts(t, client), "a full buffer is written at once")
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(3)))
+	assert.Equal(t, []int{2, 1}, logRecordCounts(t, client), "later telemetry is written as it is consumed")
+
+	require.NoError(t, exp.shutdown(context.Background()))
+	assert.Len(t, client.recorded(), 2, "the buffer is empty on shutdown")
+}
+
+func TestCompactOnShutdownValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{
+			name:      "default max bytes",
+			configure: func(cfg *Config) { cfg.CompactOnShutdown.Enabled = true },
+		},
+		{
+			name: "with batch",
+			configure: func(cfg *Config) {
+				cfg.CompactOnShutdown.Enabled = true
+				cfg.Batch = Batch{Enabled: true, MaxBytes: 1024, FlushInterval: time.Second}
+			},
+			wantErr: "compact_on_shutdown cannot be combined with batch",
+		},
+		{
+			name:      "no max bytes",
+			configure: func(cfg *Config) { cfg.CompactOnShutdown = CompactOnShutdown{Enabled: true} },
+			wantErr:   "compact_on_shutdown.max_bytes must be positive when compaction is enabled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tt.configure(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
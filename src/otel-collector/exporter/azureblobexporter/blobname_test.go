@@ -0,0 +1,392 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestGenerateBlobName(t *testing.T) {
+	traces := newTestTraces(map[string]any{"tenant": "acme"})
+	tests := []struct {
+		name       string
+		configure  func(*Config)
+		formatType string
+		compressed bool
+		want       string
+	}{
+		{
+			name: "default format",
+			want: `^\d{4}/\d{2}/\d{2}/traces_\d{2}_\d{2}_\d{2}\.json_\d+$`,
+		},
+		{
+			name:      "serial before extension",
+			configure: func(cfg *Config) { cfg.BlobNameFormat.SerialNumBeforeExtension = true },
+			want:      `^\d{4}/\d{2}/\d{2}/traces_\d{2}_\d{2}_\d{2}_\d+\.json$`,
+		},
+		{
+			name:       "compressed",
+			compressed: true,
+			want:       `^\d{4}/\d{2}/\d{2}/traces_\d{2}_\d{2}_\d{2}\.json_\d+\.gz$`,
+		},
+		{
+			name:      "ulid",
+			configure: func(cfg *Config) { cfg.BlobNameFormat.UniqueSuffix = uniqueSuffixULID },
+			want:      `^\d{4}/\d{2}/\d{2}/traces_\d{2}_\d{2}_\d{2}\.json_[0-9A-HJKMNP-TV-Z]{26}$`,
+		},
+		{
+			name: "template",
+			configure: func(cfg *Config) {
+				cfg.BlobNameFormat.TemplateEnabled = true
+				cfg.BlobNameFormat.TracesFormat = `{{ resourceAttr "tenant" }}/traces.json`
+			},
+			want: `^acme/traces\.json_\d+$`,
+		},
+		{
+			name: "default container and formats",
+			configure: func(cfg *Config) {
+				cfg.DefaultContainer = "otel"
+				cfg.Container.Traces = ""
+				cfg.Formats = []string{formatTypeJSON, formatTypeNDJSON}
+			},
+			formatType: formatTypeNDJSON,
+			want:       `^ndjson/traces/\d{4}/\d{2}/\d{2}/traces_\d{2}_\d{2}_\d{2}\.ndjson_\d+$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			if tt.configure != nil {
+				tt.configure(cfg)
+			}
+			exp, _ := newTestExporter(t, cfg, pipeline.SignalTraces)
+			formatType := tt.formatType
+			if formatType == "" {
+				formatType = formatTypeJSON
+			}
+
+			name, err := exp.generateBlobName(pipeline.SignalTraces, traces, formatType, nil, tt.compressed)
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(tt.want), name.String())
+		})
+	}
+}
+
+func TestGenerateBlobNameUniqueULID(t *testing.T) {
+	const workers, perWorker = 50, 2000
+	cfg := newTestConfig()
+	cfg.BlobNameFormat.UniqueSuffix = uniqueSuffixULID
+	exp, _ := newTestExporter(t, cfg, pipeline.SignalTraces)
+	traces := newTestTraces(map[string]any{"tenant": "acme"})
+
+	names := make([][]string, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				name, err := exp.generateBlobName(pipeline.SignalTraces, traces, formatTypeJSON, nil, false)
+				if !assert.NoError(t, err) {
+					return
+				}
+				names[w] = append(names[w], name.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, workers*perWorker)
+	for _, workerNames := range names {
+		for _, name := range workerNames {
+			require.False(t, seen[name], "duplicate blob name %q", name)
+			seen[name] = true
+		}
+	}
+	assert.Len(t, seen, workers*perWorker)
+}
+
+func TestGenerateBlobNameConcurrentTemplate(t *testing.T) {
+	const workers, perWorker = 20, 200
+	cfg := newTestConfig()
+	cfg.BlobNameFormat.TemplateEnabled = true
+	cfg.BlobNameFormat.TracesFormat = `{{ resourceAttr "tenant" }}/traces.json`
+	exp, _ := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every worker renders its own batch, so the functions of one batch must not leak into another.
+			// Names are rendered as time layouts, hence tenants without digits.
+			tenant := fmt.Sprintf("tenant%c", 'a'+w)
+			traces := newTestTraces(map[string]any{"tenant": tenant})
+			for range perWorker {
+				name, err := exp.generateBlobName(pipeline.SignalTraces, traces, formatTypeJSON, nil, false)
+				if !assert.NoError(t, err) || !assert.True(t, strings.HasPrefix(name.String(), tenant+"/traces.json_"), name.String()) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGenerateBlobNameTruncation(t *testing.T) {
+	traces := newTestTraces(map[string]any{"tenant": strings.Repeat("t", 200)})
+	hash, err := contentHash(traces)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		configure  func(*Config)
+		compressed bool
+		wantSuffix string
+	}{
+		{
+			name:       "serial after extension",
+			wantSuffix: ".json_" + hash,
+		},
+		{
+			name:       "serial before extension",
+			configure:  func(cfg *Config) { cfg.BlobNameFormat.SerialNumBeforeExtension = true },
+			wantSuffix: "_" + hash + ".json",
+		},
+		{
+			name:       "compressed",
+			compressed: true,
+			wantSuffix: ".json_" + hash + ".gz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.BlobNameFormat.TemplateEnabled = true
+			cfg.BlobNameFormat.TracesFormat = `{{ resourceAttr "tenant" }}/traces.json`
+			cfg.BlobNameFormat.TracesMaxLength = 80
+			cfg.BlobNameFormat.DeterministicFromContent = true
+			if tt.configure != nil {
+				tt.configure(cfg)
+			}
+			exp, _ := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+			generated, err := exp.generateBlobName(pipeline.SignalTraces, traces, formatTypeJSON, nil, tt.compressed)
+			require.NoError(t, err)
+			name := generated.String()
+			assert.Len(t, name, 80)
+			assert.True(t, strings.HasPrefix(name, "ttt"), name)
+			assert.True(t, strings.HasSuffix(name, tt.wantSuffix), name)
+		})
+	}
+}
+
+func TestGenerateBlobNameMaxLengthTooShort(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.BlobNameFormat.TracesMaxLength = 10
+	cfg.BlobNameFormat.UniqueSuffix = uniqueSuffixULID
+	exp, _ := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	_, err := exp.generateBlobName(pipeline.SignalTraces, newTestTraces(map[string]any{}), formatTypeJSON, nil, false)
+	assert.ErrorContains(t, err, "blob name max length 10 leaves no room")
+}
+
+func TestTruncateBlobName(t *testing.T) {
+	assert.Equal(t, "abc", truncateBlobName("abcdef", 3))
+	assert.Equal(t, "a", truncateBlobName("aé", 2), "multi-byte characters are not split")
+	assert.Equal(t, "aé", truncateBlobName("aéb", 3))
+}
+
+func TestGenerateBlobNameTimezone(t *testing.T) {
+	// 02:30 UTC on January 1st is still December 31st in New York
+	logs := newTestLogs(pcommon.NewTimestampFromTime(time.Date(2024, time.January, 1, 2, 30, 0, 0, time.UTC)))
+	tests := []struct {
+		timezone string
+		want     string
+	}{
+		{timezone: "UTC", want: `^2024/01/01/logs_02_30_00\.json_\d+$`},
+		{timezone: "America/New_York", want: `^2023/12/31/logs_21_30_00\.json_\d+$`},
+		{timezone: "Asia/Tokyo", want: `^2024/01/01/logs_11_30_00\.json_\d+$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.BlobNameFormat.TimeSource = timeSourceTelemetry
+			cfg.BlobNameFormat.Timezone = tt.timezone
+			exp, _ := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+			name, err := exp.generateBlobName(pipeline.SignalLogs, logs, formatTypeJSON, nil, false)
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(tt.want), name.String())
+		})
+	}
+}
+
+func TestGenerateBlobNameTimezoneDefault(t *testing.T) {
+	assert.Equal(t, "UTC", newTestConfig().BlobNameFormat.Timezone)
+
+	cfg := newTestConfig()
+	cfg.BlobNameFormat.Timezone = "Mars/Olympus_Mons"
+	assert.ErrorContains(t, cfg.Validate(), `invalid blob_name_format.timezone "Mars/Olympus_Mons"`)
+}
+
+func TestGenerateBlobNameRowCountSchemaHash(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.FormatType = formatTypeParquet
+	cfg.BlobNameFormat.TemplateEnabled = true
+	cfg.BlobNameFormat.TracesFormat = "{{ rowCount }}/{{ schemaHash }}/traces.parquet"
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	for _, spans := range []int{5, 12} {
+		require.NoError(t, exp.ConsumeTraces(context.Background(), newTestSpans(spans)))
+	}
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 2)
+	var hashes []string
+	for i, rows := range []int{5, 12} {
+		file := openParquet(t, uploads[i].data)
+		require.Equal(t, int64(rows), file.NumRows())
+		parts := strings.Split(uploads[i].blob, "/")
+		require.Len(t, parts, 3, uploads[i].blob)
+		assert.Equal(t, strconv.Itoa(rows), parts[0], "the name holds the row count of the blob")
+		assert.Regexp(t, `^[0-9a-f]{8}$`, parts[1])
+		hashes = append(hashes, parts[1])
+	}
+	assert.Equal(t, hashes[0], hashes[1], "blobs of the same schema have the same hash")
+
+	logsCfg := newTestConfig()
+	logsCfg.FormatType = formatTypeParquet
+	logsCfg.BlobNameFormat.TemplateEnabled = true
+	logsCfg.BlobNameFormat.LogsFormat = "{{ schemaHash }}/logs.parquet"
+	logs, logsClient := newTestExporter(t, logsCfg, pipeline.SignalLogs)
+	require.NoError(t, logs.ConsumeLogs(context.Background(), newTestLogs(1)))
+	uploads = logsClient.recorded()
+	require.Len(t, uploads, 1)
+	assert.NotEqual(t, hashes[0], strings.Split(uploads[0].blob, "/")[0], "the log schema has another hash")
+}
+
+func TestGenerateBlobNameRowCountJSON(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.BlobNameFormat.TemplateEnabled = true
+	cfg.BlobNameFormat.TracesFormat = "{{ rowCount }}/{{ schemaHash }}traces.json"
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	require.NoError(t, exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{}, map[string]any{}, map[string]any{})))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	assert.Regexp(t, `^3/traces\.json_\d+$`, uploads[0].blob, "other formats count records and have no schema hash")
+}
+
+// TestGenerateBlobNameRecordIndices renders getSpan, getMetric and getLogRecord with indices in and out
+// of range. An index out of range gives the default name rather than a name with a blank segment.
+func TestGenerateBlobNameRecordIndices(t *testing.T) {
+	tests := []struct {
+		name        string
+		signal      pipeline.Signal
+		format      string
+		want        string
+		wantWarning string
+	}{
+		{
+			name:   "span",
+			signal: pipeline.SignalTraces,
+			format: "{{ (getSpan . 0 0 0).Name }}/traces.json",
+			want:   `^span/traces\.json_\d+$`,
+		},
+		{
+			name:        "span out of range",
+			signal:      pipeline.SignalTraces,
+			format:      "{{ (getSpan . 0 0 1).Name }}/traces.json",
+			want:        `^\d{4}/\d{2}/\d{2}/traces_\d{2}_\d{2}_\d{2}\.json_\d+$`,
+			wantWarning: "no span at index 0/0/1",
+		},
+		{
+			name:   "guarded span out of range",
+			signal: pipeline.SignalTraces,
+			format: "{{ if hasSpan . 1 0 0 }}{{ (getSpan . 1 0 0).Name }}{{ else }}unnamed{{ end }}/traces.json",
+			want:   `^unnamed/traces\.json_\d+$`,
+		},
+		{
+			name:   "metric",
+			signal: pipeline.SignalMetrics,
+			format: "{{ (getMetric . 0 0 0).Name }}/metrics.json",
+			want:   `^gauge/metrics\.json_\d+$`,
+		},
+		{
+			name:        "metric out of range",
+			signal:      pipeline.SignalMetrics,
+			format:      "{{ (getMetric . 0 1 0).Name }}/metrics.json",
+			want:        `^\d{4}/\d{2}/\d{2}/metrics_\d{2}_\d{2}_\d{2}\.json_\d+$`,
+			wantWarning: "no metric at index 0/1/0",
+		},
+		{
+			name:   "guarded metric out of range",
+			signal: pipeline.SignalMetrics,
+			format: "{{ if hasMetric . 0 0 -1 }}{{ (getMetric . 0 0 -1).Name }}{{ else }}unnamed{{ end }}/metrics.json",
+			want:   `^unnamed/metrics\.json_\d+$`,
+		},
+		{
+			name:   "log record",
+			signal: pipeline.SignalLogs,
+			format: "{{ (getLogRecord . 0 0 0).Body.Str }}/logs.json",
+			want:   `^log/logs\.json_\d+$`,
+		},
+		{
+			name:        "log record out of range",
+			signal:      pipeline.SignalLogs,
+			format:      "{{ (getLogRecord . 2 0 0).Body.Str }}/logs.json",
+			want:        `^\d{4}/\d{2}/\d{2}/logs_\d{2}_\d{2}_\d{2}\.json_\d+$`,
+			wantWarning: "no log record at index 2/0/0",
+		},
+		{
+			name:   "guarded log record out of range",
+			signal: pipeline.SignalLogs,
+			format: "{{ if hasLogRecord . 0 0 1 }}{{ (getLogRecord . 0 0 1).Body.Str }}{{ else }}unnamed{{ end }}/logs.json",
+			want:   `^unnamed/logs\.json_\d+$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.BlobNameFormat.TemplateEnabled = true
+			cfg.BlobNameFormat.TracesFormat = tt.format
+			cfg.BlobNameFormat.MetricsFormat = tt.format
+			cfg.BlobNameFormat.LogsFormat = tt.format
+			core, logs := observer.New(zap.WarnLevel)
+			set := componenttest.NewNopTelemetrySettings()
+			set.Logger = zap.New(core)
+			exp, client := newTestExporterWithSettings(t, cfg, tt.signal, set)
+
+			consumeSignal(t, exp, tt.signal)
+
+			uploads := client.recorded()
+			require.Len(t, uploads, 1)
+			assert.Regexp(t, regexp.MustCompile(tt.want), uploads[0].blob)
+			warnings := logs.FilterMessage("Failed to execute blob name template, using default blob name format").All()
+			if tt.wantWarning == "" {
+				assert.Empty(t, warnings)
+				return
+			}
+			require.Len(t, warnings, 1)
+			assert.Contains(t, warnings[0].ContextMap()["error"], tt.wantWarning)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxBlobTags is the number of index tags Azure allows on a blob.
+	maxBlobTags = 10
+	// maxBlobTagNameLength and maxBlobTagValueLength are the Azure limits of index tag names and values.
+	maxBlobTagNameLength  = 128
+	maxBlobTagValueLength = 256
+)
+
+var (
+	// blobTagInvalidChars matches the characters Azure does not allow in index tag names and values.
+	blobTagInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9 +\-./:=_]`)
+	// metadataNamePattern matches valid metadata names, which Azure requires to be C# identifiers.
+	metadataNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// metadataInvalidChars matches the characters that cannot be sent in a metadata header value.
+	metadataInvalidChars = regexp.MustCompile(`[^\x20-\x7e]`)
+)
+
+// blobProperties holds the metadata and index tags of an uploaded blob.
+type blobProperties struct {
+	metadata map[string]*string
+	tags     map[string]string
+}
+
+// blobPropertiesOf returns the blob_metadata and blob_tags of a blob of telemetryData, taken from
+// its first resource. Attributes the resource does not have are left out. Characters Azure does
+// not allow are stripped from the values, and tags with nothing left are skipped.
+func (e *azureBlobExporter) blobPropertiesOf(telemetryData any) blobProperties {
+	var props blobProperties
+	if len(e.config.BlobMetadata) == 0 && len(e.config.BlobTags) == 0 {
+		return props
+	}
+	attrs, ok := firstResourceAttributes(telemetryData)
+	if !ok {
+		return props
+	}
+
+	for key, name := range e.config.BlobMetadata {
+		attr, ok := attrs.Get(key)
+		if !ok {
+			continue
+		}
+		value := metadataInvalidChars.ReplaceAllString(attr.AsString(), "")
+		if props.metadata == nil {
+			props.metadata = make(map[string]*string, len(e.config.BlobMetadata))
+		}
+		props.metadata[name] = &value
+	}
+
+	for key, name := range e.config.BlobTags {
+		attr, ok := attrs.Get(key)
+		if !ok {
+			continue
+		}
+		value := sanitizeBlobTagValue(attr.AsString())
+		if value == "" && attr.AsString() != "" {
+			e.logger.Warn("Skipping blob index tag without any allowed characters",
+				zap.String("attribute", key),
+				zap.String("tag", name))
+			continue
+		}
+		if props.tags == nil {
+			props.tags = make(map[string]string, len(e.config.BlobTags))
+		}
+		props.tags[name] = value
+	}
+	return props
+}
+
+// sanitizeBlobTagValue strips the characters Azure does not allow in index tag values and
+// truncates the value to the maximum length.
+func sanitizeBlobTagValue(value string) string {
+	value = blobTagInvalidChars.ReplaceAllString(value, "")
+	if len(value) > maxBlobTagValueLength {
+		value = value[:maxBlobTagValueLength]
+	}
+	return strings.TrimSpace(value)
+}
+
+// metadataValues returns the blob metadata as plain strings.
+func (p blobProperties) metadataValues() map[string]string {
+	if len(p.metadata) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(p.metadata))
+	for k, v := range p.metadata {
+		if v != nil {
+			values[k] = *v
+		}
+	}
+	return values
+}
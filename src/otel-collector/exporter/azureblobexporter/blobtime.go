@@ -0,0 +1,109 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"time"
+	// The collector image ships without a time zone database
+	_ "time/tzdata"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// blobTime returns the time formatted into the blob name of telemetryData, in blob_name_format.timezone.
+// With the telemetry time source or deterministic names it is the earliest record timestamp, falling
+// back to the upload time when no record has a timestamp.
+func (e *azureBlobExporter) blobTime(telemetryData any) time.Time {
+	t := time.Now()
+	if e.config.BlobNameFormat.TimeSource == timeSourceTelemetry || e.config.BlobNameFormat.DeterministicFromContent {
+		if earliest := earliestTimestamp(telemetryData); earliest != 0 {
+			t = earliest.AsTime()
+		}
+	}
+	if e.blobLocation != nil {
+		t = t.In(e.blobLocation)
+	}
+	return t
+}
+
+// earliestTimestamp returns the earliest span start, log record timestamp or metric data point
+// timestamp of telemetryData, or 0 when no record has one. Log records without a timestamp count
+// with their observed timestamp.
+func earliestTimestamp(telemetryData any) pcommon.Timestamp {
+	var earliest pcommon.Timestamp
+	observe := func(ts pcommon.Timestamp) {
+		if ts != 0 && (earliest == 0 || ts < earliest) {
+			earliest = ts
+		}
+	}
+
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		rss := td.ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			sss := rss.At(i).ScopeSpans()
+			for j := 0; j < sss.Len(); j++ {
+				spans := sss.At(j).Spans()
+				for k := 0; k < spans.Len(); k++ {
+					observe(spans.At(k).StartTimestamp())
+				}
+			}
+		}
+	case plog.Logs:
+		rls := td.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			sls := rls.At(i).ScopeLogs()
+			for j := 0; j < sls.Len(); j++ {
+				records := sls.At(j).LogRecords()
+				for k := 0; k < records.Len(); k++ {
+					if ts := records.At(k).Timestamp(); ts != 0 {
+						observe(ts)
+					} else {
+						observe(records.At(k).ObservedTimestamp())
+					}
+				}
+			}
+		}
+	case pmetric.Metrics:
+		rms := td.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			sms := rms.At(i).ScopeMetrics()
+			for j := 0; j < sms.Len(); j++ {
+				metrics := sms.At(j).Metrics()
+				for k := 0; k < metrics.Len(); k++ {
+					observeDataPointTimestamps(metrics.At(k), observe)
+				}
+			}
+		}
+	}
+	return earliest
+}
+
+func observeDataPointTimestamps(m pmetric.Metric, observe func(pcommon.Timestamp)) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := 0; i < m.Gauge().DataPoints().Len(); i++ {
+			observe(m.Gauge().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeSum:
+		for i := 0; i < m.Sum().DataPoints().Len(); i++ {
+			observe(m.Sum().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeHistogram:
+		for i := 0; i < m.Histogram().DataPoints().Len(); i++ {
+			observe(m.Histogram().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := 0; i < m.ExponentialHistogram().DataPoints().Len(); i++ {
+			observe(m.ExponentialHistogram().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeSummary:
+		for i := 0; i < m.Summary().DataPoints().Len(); i++ {
+			observe(m.Summary().DataPoints().At(i).Timestamp())
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+func TestClientOptionsAzblobOptions(t *testing.T) {
+	options := ClientOptions{
+		Retry:            ClientRetry{MaxRetries: -1, TryTimeout: time.Minute, RetryDelay: time.Second, MaxRetryDelay: 10 * time.Second},
+		DisableTelemetry: true,
+		ProxyURL:         "http://proxy.corp:3128",
+	}
+
+	opts, err := options.azblobOptions()
+	require.NoError(t, err)
+	assert.Equal(t, policy.RetryOptions{MaxRetries: -1, TryTimeout: time.Minute, RetryDelay: time.Second, MaxRetryDelay: 10 * time.Second}, opts.Retry)
+	assert.True(t, opts.Telemetry.Disabled)
+	httpClient, ok := opts.Transport.(*http.Client)
+	require.True(t, ok)
+	proxy, err := httpClient.Transport.(*http.Transport).Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "test.blob.core.windows.net"}})
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy.corp:3128", proxy.String())
+
+	opts, err = ClientOptions{}.azblobOptions()
+	require.NoError(t, err)
+	assert.Nil(t, opts.Transport, "the SDK transport is kept without proxy")
+	assert.False(t, opts.Telemetry.Disabled)
+}
+
+func TestClientOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ClientOptions
+		wantErr string
+	}{
+		{name: "disabled retries", options: ClientOptions{Retry: ClientRetry{MaxRetries: -1}}},
+		{name: "invalid max retries", options: ClientOptions{Retry: ClientRetry{MaxRetries: -2}}, wantErr: "client_options.retry.max_retries must be -1"},
+		{name: "negative delay", options: ClientOptions{Retry: ClientRetry{RetryDelay: -time.Second}}, wantErr: "client_options.retry durations cannot be negative"},
+		{name: "relative proxy", options: ClientOptions{ProxyURL: "proxy.corp:3128"}, wantErr: `client_options.proxy_url "proxy.corp:3128" must be an absolute URL`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ClientOptions = tt.options
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNewClientWithClientOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		auth Authentication
+	}{
+		{name: "service principal", auth: Authentication{Type: ServicePrincipal, TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}},
+		{name: "default credentials", auth: Authentication{Type: DefaultCredentials}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Auth = tt.auth
+			cfg.URL = "https://test.blob.core.windows.net/"
+			cfg.ClientOptions = ClientOptions{Retry: ClientRetry{MaxRetries: -1}, DisableTelemetry: true, ProxyURL: "http://proxy.corp:3128"}
+			require.NoError(t, cfg.Validate())
+			exp, err := newAzureBlobExporter(cfg, component.MustNewID("azureblob"), componenttest.NewNopTelemetrySettings(), pipeline.SignalLogs)
+			require.NoError(t, err)
+
+			client, cred, err := exp.newClient(context.Background())
+			require.NoError(t, err)
+			assert.NotNil(t, cred)
+			assert.Equal(t, "https://test.blob.core.windows.net/", client.URL())
+		})
+	}
+}
+
+// TestClientOptionsProxy sends an upload through a proxy that fails every request, checking that the
+// options reach the client: the request goes through the proxy, once, without SDK telemetry.
+func TestClientOptionsProxy(t *testing.T) {
+	var mu sync.Mutex
+	var userAgents []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents = append(userAgents, r.UserAgent())
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(proxy.Close)
+
+	cfg := newTestConfig()
+	cfg.Auth = Authentication{Type: SharedAccessSignature, SASToken: "sv=2022-11-02&sig=test"}
+	cfg.URL = "http://test.blob.core.windows.net/"
+	cfg.ClientOptions = ClientOptions{Retry: ClientRetry{MaxRetries: -1}, DisableTelemetry: true, ProxyURL: proxy.URL}
+	require.NoError(t, cfg.Validate())
+	exp, err := newAzureBlobExporter(cfg, component.MustNewID("azureblob"), componenttest.NewNopTelemetrySettings(), pipeline.SignalLogs)
+	require.NoError(t, err)
+	client, _, err := exp.newClient(context.Background())
+	require.NoError(t, err)
+
+	_, err = client.UploadStream(context.Background(), "logs", "blob.json", strings.NewReader("{}"), nil)
+	require.Error(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, userAgents, 1, "max_retries -1 disables the retries of the SDK")
+	assert.NotContains(t, userAgents[0], "azsdk-go", "disable_telemetry leaves the SDK out of the User-Agent")
+}
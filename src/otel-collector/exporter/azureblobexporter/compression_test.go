@@ -0,0 +1,149 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// gunzip decompresses every member of a gzip stream.
+func gunzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	out, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return out
+}
+
+func TestConsumeTracesGzip(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		level       int
+		unmarshaler ptrace.Unmarshaler
+	}{
+		{name: "json", format: formatTypeJSON, level: gzip.DefaultCompression, unmarshaler: &ptrace.JSONUnmarshaler{}},
+		{name: "json best compression", format: formatTypeJSON, level: gzip.BestCompression, unmarshaler: &ptrace.JSONUnmarshaler{}},
+		{name: "proto best speed", format: formatTypeProto, level: gzip.BestSpeed, unmarshaler: &ptrace.ProtoUnmarshaler{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.FormatType = tt.format
+			cfg.Compression = compressionGzip
+			cfg.CompressionLevel = tt.level
+			exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+			td := newTestTraces(map[string]any{"tenant": "acme"}, map[string]any{"tenant": "globex"})
+
+			require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+
+			uploads := client.recorded()
+			require.Len(t, uploads, 1)
+			assert.True(t, strings.HasSuffix(uploads[0].blob, ".gz"), uploads[0].blob)
+			written, err := tt.unmarshaler.UnmarshalTraces(gunzip(t, uploads[0].data))
+			require.NoError(t, err)
+			assert.Equal(t, td.SpanCount(), written.SpanCount())
+			assert.Equal(t, spanNames(td), spanNames(written))
+		})
+	}
+}
+
+func TestConsumeLogsGzipAppendBlob(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		separator string
+	}{
+		{name: "ndjson", format: formatTypeNDJSON},
+		{name: "json with separator", format: formatTypeJSON, separator: "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.FormatType = tt.format
+			cfg.Compression = compressionGzip
+			cfg.AppendBlob.Enabled = true
+			cfg.AppendBlob.Separator = tt.separator
+			cfg.BlobNameFormat.LogsFormat = "logs"
+			cfg.BlobNameFormat.SerialNumRange = 1
+			exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+			require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+			require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(2)))
+
+			require.Len(t, client.blobs, 1, "both chunks are appended to one blob")
+			for _, data := range client.blobs {
+				assert.Equal(t, 2, bytes.Count(data, []byte{0x1f, 0x8b, 0x08}), "every chunk is its own gzip member")
+				lines := nonEmptyLines(t, gunzip(t, data))
+				require.Len(t, lines, 2, "a multi-member reader decompresses both chunks")
+				if tt.format == formatTypeJSON {
+					for _, line := range lines {
+						_, err := (&plog.JSONUnmarshaler{}).UnmarshalLogs([]byte(line))
+						assert.NoError(t, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompressMinBytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		minBytes       int
+		wantCompressed bool
+	}{
+		{name: "below threshold", minBytes: 1 << 20},
+		{name: "above threshold", minBytes: 1, wantCompressed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Compression = compressionGzip
+			cfg.CompressMinBytes = tt.minBytes
+			exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+			require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+
+			uploads := client.recorded()
+			require.Len(t, uploads, 1)
+			assert.Equal(t, tt.wantCompressed, strings.HasSuffix(uploads[0].blob, ".gz"), uploads[0].blob)
+			data := uploads[0].data
+			if tt.wantCompressed {
+				data = gunzip(t, data)
+			}
+			_, err := (&plog.JSONUnmarshaler{}).UnmarshalLogs(data)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// nonEmptyLines returns the non-empty lines of data.
+func nonEmptyLines(t *testing.T, data []byte) []string {
+	t.Helper()
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
@@ -0,0 +1,91 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// inFlightClient is a mockClient whose uploads take a while, recording the most uploads in flight at once.
+type inFlightClient struct {
+	*mockClient
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *inFlightClient) UploadStream(ctx context.Context, container, blob string, body io.Reader, o *azblob.UploadStreamOptions) (azblob.UploadStreamResponse, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		peak := c.maxInFlight.Load()
+		if n <= peak || c.maxInFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return c.mockClient.UploadStream(ctx, container, blob, body, o)
+}
+
+func TestMaxConcurrentUploads(t *testing.T) {
+	for _, limit := range []int{1, 3} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.MaxConcurrentUploads = limit
+			exp, mock := newTestExporter(t, cfg, pipeline.SignalLogs)
+			client := &inFlightClient{mockClient: mock}
+			exp.client = client
+
+			var wg sync.WaitGroup
+			for i := range 30 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					assert.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(pcommon.Timestamp(i+1))))
+				}()
+			}
+			wg.Wait()
+
+			assert.Len(t, mock.recorded(), 30)
+			assert.LessOrEqual(t, client.maxInFlight.Load(), int32(limit))
+			assert.Positive(t, client.maxInFlight.Load())
+		})
+	}
+}
+
+func TestMaxConcurrentUploadsContextDone(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxConcurrentUploads = 1
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+	release, err := exp.acquireUploadSlot(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = exp.ConsumeLogs(ctx, newTestLogs(1))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "waiting for an upload slot")
+	assert.Empty(t, client.recorded())
+}
+
+func TestMaxConcurrentUploadsValidate(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MaxConcurrentUploads = -1
+	assert.ErrorContains(t, cfg.Validate(), "max_concurrent_uploads cannot be negative")
+
+	cfg.MaxConcurrentUploads = 0
+	assert.NoError(t, cfg.Validate(), "0 does not limit uploads")
+}
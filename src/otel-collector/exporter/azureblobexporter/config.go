@@ -4,18 +4,56 @@
 package azureblobexporter
 
 import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.opentelemetry.io/collector/pipeline"
 )
 
+// accessTiers maps the access_tier values to the blob access tiers.
+var accessTiers = map[string]blob.AccessTier{
+	"hot":     blob.AccessTierHot,
+	"cool":    blob.AccessTierCool,
+	"cold":    blob.AccessTierCold,
+	"archive": blob.AccessTierArchive,
+}
+
 type TelemetryConfig struct {
 	Logs    string `mapstructure:"logs"`
 	Metrics string `mapstructure:"metrics"`
 	Traces  string `mapstructure:"traces"`
 }
 
+// forSignal returns the value configured for signal, empty for unknown signals.
+func (t TelemetryConfig) forSignal(signal pipeline.Signal) string {
+	switch signal {
+	case pipeline.SignalMetrics:
+		return t.Metrics
+	case pipeline.SignalLogs:
+		return t.Logs
+	case pipeline.SignalTraces:
+		return t.Traces
+	default:
+		return ""
+	}
+}
+
 type Encodings struct {
 	Logs    *component.ID `mapstructure:"logs"`
 	Metrics *component.ID `mapstructure:"metrics"`
@@ -30,15 +68,431 @@ type BlobNameFormat struct {
 	SerialNumBeforeExtension bool              `mapstructure:"serial_num_before_extension"`
 	TemplateEnabled          bool              `mapstructure:"template_enabled"`
 	Params                   map[string]string `mapstructure:"params"`
+
+	// DeterministicFromContent replaces the random serial with a hash of the batch content, and formats the
+	// earliest record timestamp like time_source telemetry, so re-exporting a batch overwrites its blob.
+	DeterministicFromContent bool `mapstructure:"deterministic_from_content"`
+
+	// TimeSource is the time formatted into blob names: now, the upload time, or telemetry, the earliest
+	// record timestamp of the batch, so late or replayed data lands in the date path of its events.
+	TimeSource string `mapstructure:"time_source"`
+
+	// Timezone is the IANA time zone, e.g. UTC or America/New_York, blob name times are formatted in, so
+	// collectors in different regions write into the same date paths.
+	Timezone string `mapstructure:"timezone"`
+
+	// UniqueSuffix is appended to blob names to tell apart the blobs of the same second: serial, a random
+	// number below serial_num_range, or uuid and ulid, globally unique and sortable by creation time.
+	UniqueSuffix string `mapstructure:"unique_suffix"`
+
+	// MetricsMaxLength, LogsMaxLength and TracesMaxLength cap the length of generated blob names per signal.
+	// Longer names are truncated while keeping the file extension. Zero means no limit.
+	MetricsMaxLength int `mapstructure:"metrics_max_length"`
+	LogsMaxLength    int `mapstructure:"logs_max_length"`
+	TracesMaxLength  int `mapstructure:"traces_max_length"`
 }
 
 type AppendBlob struct {
 	Enabled   bool   `mapstructure:"enabled"`
 	Separator string `mapstructure:"separator"`
+	// IdempotentRetries appends every chunk at the blob size read before its first attempt, so a retry
+	// cannot append a chunk that an earlier attempt already committed a second time.
+	IdempotentRetries bool `mapstructure:"idempotent_retries"`
+	// MaxBlocks rolls to a new append blob once the current one holds this many blocks, staying below the
+	// 50,000 block limit of Azure. Zero does not roll by block count.
+	MaxBlocks int `mapstructure:"max_blocks"`
+	// RollInterval rolls to a new append blob once the current one is this old. Zero does not roll by age.
+	RollInterval time.Duration `mapstructure:"roll_interval"`
+}
+
+// SizeRouting splits batches between this exporter and a sibling azureeventhubs exporter by marshalled size.
+type SizeRouting struct {
+	// ThresholdBytes is the marshalled batch size from which batches are exported to blob storage. Smaller batches
+	// are skipped so an azureeventhubs exporter configured with the same threshold sends them instead. Zero disables routing.
+	ThresholdBytes int `mapstructure:"threshold_bytes"`
+}
+
+// accepts reports whether a payload of the given size is routed to blob storage.
+func (r SizeRouting) accepts(size int) bool {
+	return r.ThresholdBytes <= 0 || size >= r.ThresholdBytes
+}
+
+// ContainerCheck verifies that a container exists when it is first used, rather than on start.
+type ContainerCheck struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Create creates a missing container instead of failing the upload.
+	Create bool `mapstructure:"create"`
+	// TTL is how long a container that was found is trusted to exist before it is checked again.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// RawOTLP configures writing every batch as raw OTLP protobuf in addition to the configured format.
+type RawOTLP struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Container receives the raw OTLP blobs. Defaults to the container of the signal.
+	Container string `mapstructure:"container"`
+	// Prefix is prepended to the blob name of the raw OTLP blobs.
+	Prefix string `mapstructure:"prefix"`
+}
+
+// MetricNameNormalization configures rewriting metric names to a character set downstream systems accept.
+type MetricNameNormalization struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Characters are the characters replaced in metric names. Defaults to dots and slashes.
+	Characters string `mapstructure:"characters"`
+	// Replacement replaces each of the characters. Defaults to an underscore.
+	Replacement string `mapstructure:"replacement"`
+}
+
+// replacer returns the replacer applying the normalization, or nil when it is disabled.
+func (n MetricNameNormalization) replacer() *strings.Replacer {
+	if !n.Enabled {
+		return nil
+	}
+	oldnew := make([]string, 0, 2*len(n.Characters))
+	for _, c := range n.Characters {
+		oldnew = append(oldnew, string(c), n.Replacement)
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// ContainerPolicy overrides the upload policy of the global configuration for one container.
+type ContainerPolicy struct {
+	// RetryOnFailure replaces retry_on_failure for uploads to the container. Unset fields keep the values of
+	// retry_on_failure.
+	RetryOnFailure configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+	// OverflowContainer replaces overflow_container for blobs whose upload to the container failed.
+	OverflowContainer string `mapstructure:"overflow_container"`
+}
+
+// StartupRetry configures retrying client creation when the exporter starts.
+type StartupRetry struct {
+	// MaxAttempts is the number of attempts before start fails. Values below 1 mean a single attempt.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Interval is the wait between attempts.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// CSVConfig configures the csv format.
+type CSVConfig struct {
+	// Delimiter is the single character separating fields, e.g. ";" for spreadsheets using a comma as
+	// decimal separator.
+	Delimiter string `mapstructure:"delimiter"`
+}
+
+// ParquetConfig configures the parquet format.
+type ParquetConfig struct {
+	// MapValueEncoding is the encoding of the value column of attribute maps: plain, dictionary,
+	// delta_length_byte_array or delta_byte_array. Empty keeps the writer default.
+	MapValueEncoding string `mapstructure:"map_value_encoding"`
+
+	// Compression is the codec of the parquet column chunks: none, snappy, gzip, brotli or zstd.
+	Compression string `mapstructure:"compression"`
+
+	// RowGroupSize is the maximum number of rows per row group. Smaller row groups let query engines
+	// skip more data using the row group statistics. Zero keeps the writer default.
+	RowGroupSize int64 `mapstructure:"row_group_size"`
+
+	// WriteStatistics writes the min/max statistics of every column chunk and page. Disabling it saves
+	// the CPU spent computing them, but query engines can no longer skip row groups or pages by value.
+	WriteStatistics bool `mapstructure:"write_statistics"`
+
+	// TypedAttributes adds *_attributes_typed columns holding int, double and bool attributes under
+	// their original type. The string attribute maps are written either way.
+	TypedAttributes bool `mapstructure:"typed_attributes"`
+
+	// ExpectedSchemaVersion is the parquet schema version readers were built for. The exporter refuses to
+	// start when its schema version differs, unless AllowSchemaChange is set. Zero skips the check.
+	ExpectedSchemaVersion int `mapstructure:"expected_schema_version"`
+
+	// AllowSchemaChange starts the exporter with a warning when the schema version differs from
+	// ExpectedSchemaVersion.
+	AllowSchemaChange bool `mapstructure:"allow_schema_change"`
+}
+
+// Watermark configures skipping records that were already exported, for idempotent reprocessing.
+type Watermark struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Storage is the ID of the storage extension that persists the watermark, e.g. file_storage.
+	Storage component.ID `mapstructure:"storage"`
+	// AllowedLateness still exports records up to this far behind the watermark, so data that
+	// arrives out of order is not lost.
+	AllowedLateness time.Duration `mapstructure:"allowed_lateness"`
+}
+
+// ContainerIndex configures an append-only index of the blobs written to each container.
+type ContainerIndex struct {
+	Enabled bool `mapstructure:"enabled"`
+	// BlobName is the name of the index blob in every container.
+	BlobName string `mapstructure:"blob_name"`
+}
+
+// PartitionByAttribute writes the resources of a batch to one blob per value of a resource attribute.
+type PartitionByAttribute struct {
+	// Key is the resource attribute to partition on, e.g. tenant.id. Empty disables partitioning.
+	Key string `mapstructure:"key"`
+	// Default is the partition of resources without the attribute.
+	Default string `mapstructure:"default"`
+}
+
+// PartitionByService writes the resources of a batch to one blob per service.namespace and
+// service.name pair.
+type PartitionByService struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DefaultNamespace is the namespace of resources without service.namespace.
+	DefaultNamespace string `mapstructure:"default_namespace"`
+	// DefaultName is the name of resources without service.name.
+	DefaultName string `mapstructure:"default_name"`
+}
+
+// Batch configures buffering consumed telemetry in memory and writing it as one blob.
+type Batch struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxBytes flushes the buffer once the OTLP protobuf size of the buffered telemetry reaches it.
+	MaxBytes int `mapstructure:"max_bytes"`
+	// FlushInterval flushes the buffer at least this often.
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	// FlushOnRootSpan flushes the buffer as soon as it receives a span without parent, which usually
+	// completes its trace, for low latency archival of finished traces.
+	FlushOnRootSpan bool `mapstructure:"flush_on_root_span"`
+}
+
+// CPK configures a customer-provided key that encrypts the blobs the exporter writes.
+type CPK struct {
+	// Key is the base64 encoded AES-256 key. Empty disables customer-provided keys.
+	Key string `mapstructure:"key"`
+	// KeySHA256 is the base64 encoded SHA-256 hash of the key. It is computed from the key when empty.
+	KeySHA256 string `mapstructure:"key_sha256"`
+}
+
+// info returns the key in the form the blob operations take it, or nil without a key.
+func (c CPK) info() (*blob.CPKInfo, error) {
+	if c.Key == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("cpk.key must be base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("cpk.key must be a 256-bit key of 32 bytes, got %d bytes", len(key))
+	}
+	sum := sha256.Sum256(key)
+	hash := base64.StdEncoding.EncodeToString(sum[:])
+	if c.KeySHA256 != "" && c.KeySHA256 != hash {
+		return nil, errors.New("cpk.key_sha256 does not match the SHA-256 hash of cpk.key")
+	}
+	algorithm := blob.EncryptionAlgorithmTypeAES256
+	return &blob.CPKInfo{EncryptionKey: &c.Key, EncryptionKeySHA256: &hash, EncryptionAlgorithm: &algorithm}, nil
+}
+
+// formatOverride returns the format_<signal> override of signal, empty without one.
+func (c *Config) formatOverride(signal pipeline.Signal) string {
+	return TelemetryConfig{Logs: c.FormatLogs, Metrics: c.FormatMetrics, Traces: c.FormatTraces}.forSignal(signal)
+}
+
+// formatFor returns the format the blobs of signal are written in when formats is not set.
+func (c *Config) formatFor(signal pipeline.Signal) string {
+	if format := c.formatOverride(signal); format != "" {
+		return format
+	}
+	return c.FormatType
+}
+
+// usesFormat reports whether format is written for any signal, either listed in formats or selected
+// by format or a per-signal override.
+func (c *Config) usesFormat(format string) bool {
+	if slices.Contains(c.Formats, format) {
+		return true
+	}
+	for _, signal := range []pipeline.Signal{pipeline.SignalLogs, pipeline.SignalMetrics, pipeline.SignalTraces} {
+		if c.formatFor(signal) == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ADLSGen2 configures writing through the Data Lake Storage Gen2 API.
+type ADLSGen2 struct {
+	// Enabled creates the directories of every blob name and writes the blob as a file in the last of
+	// them, so directory ACLs and renames apply. The account must have a hierarchical namespace.
+	Enabled bool `mapstructure:"enabled"`
+	// Endpoint is the Data Lake (dfs) endpoint of the account. Empty uses url, whose .blob. host is
+	// replaced with the .dfs. host.
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// validate checks the settings of the adls_gen2 mode against the options the Data Lake API cannot
+// apply to the files it creates.
+func (a ADLSGen2) validate(c *Config) error {
+	if !a.Enabled {
+		return nil
+	}
+	if a.Endpoint != "" {
+		if c.Auth.Type == ConnectionString {
+			return errors.New("adls_gen2.endpoint cannot be set when auth type is connection_string, the connection string determines the endpoint")
+		}
+		if u, err := url.Parse(a.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("adls_gen2.endpoint %q must be an absolute URL", a.Endpoint)
+		}
+	}
+	if c.EncryptionScope != "" {
+		return errors.New("encryption_scope cannot be used with adls_gen2, Data Lake files are created without an encryption scope")
+	}
+	if c.AccessTier != "" {
+		return errors.New("access_tier cannot be used with adls_gen2, Data Lake files are created in the default access tier")
+	}
+	if len(c.BlobTags) > 0 {
+		return errors.New("blob_tags cannot be used with adls_gen2, Data Lake files are created without index tags")
+	}
+	return nil
+}
+
+// ClientOptions configures the HTTP pipeline of the Azure SDK client that talks to Blob Storage.
+type ClientOptions struct {
+	// Retry configures the retries of the SDK itself, which happen within every upload attempt.
+	Retry ClientRetry `mapstructure:"retry"`
+	// DisableTelemetry leaves out the SDK name and version from the User-Agent header.
+	DisableTelemetry bool `mapstructure:"disable_telemetry"`
+	// ProxyURL routes requests to Blob Storage through an HTTP proxy, e.g. http://proxy.corp:3128.
+	// Empty uses the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+	ProxyURL string `mapstructure:"proxy_url"`
+}
+
+// ClientRetry configures the retry policy of the Azure SDK. Zero values keep the SDK defaults.
+type ClientRetry struct {
+	// MaxRetries is the number of retries of a request. -1 disables SDK retries, leaving them to
+	// retry_on_failure.
+	MaxRetries int32 `mapstructure:"max_retries"`
+	// TryTimeout bounds every single try of a request.
+	TryTimeout time.Duration `mapstructure:"try_timeout"`
+	// RetryDelay is the initial delay between tries, which grows exponentially up to MaxRetryDelay.
+	RetryDelay    time.Duration `mapstructure:"retry_delay"`
+	MaxRetryDelay time.Duration `mapstructure:"max_retry_delay"`
+}
+
+// azblobOptions returns the options of the Blob Storage clients.
+func (c ClientOptions) azblobOptions() (*azblob.ClientOptions, error) {
+	opts := &azblob.ClientOptions{}
+	opts.Retry = policy.RetryOptions{
+		MaxRetries:    c.Retry.MaxRetries,
+		TryTimeout:    c.Retry.TryTimeout,
+		RetryDelay:    c.Retry.RetryDelay,
+		MaxRetryDelay: c.Retry.MaxRetryDelay,
+	}
+	opts.Telemetry.Disabled = c.DisableTelemetry
+	if c.ProxyURL != "" {
+		proxy, err := url.Parse(c.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("client_options.proxy_url %q must be an absolute URL", c.ProxyURL)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxy)
+		opts.Transport = &http.Client{Transport: transport}
+	}
+	return opts, nil
+}
+
+// encryptionScope renders the encryption_scope template for signal.
+func (c *Config) encryptionScope(signal pipeline.Signal) (string, error) {
+	if c.EncryptionScope == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("encryption_scope").Option("missingkey=error").Parse(c.EncryptionScope)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse encryption_scope: %w", err)
+	}
+	var buf strings.Builder
+	if err = tmpl.Execute(&buf, struct{ Signal string }{Signal: signal.String()}); err != nil {
+		return "", fmt.Errorf("failed to execute encryption_scope: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// DeadLetter configures writing the payloads of failed uploads to a local directory.
+type DeadLetter struct {
+	// Directory receives every payload along with a sidecar JSON file naming its container and blob.
+	// Empty disables the dead letter.
+	Directory string `mapstructure:"directory"`
+	// MaxBytes bounds the size of the directory. The oldest payloads are removed to make room.
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// Metrics, Logs and Traces replace Directory as the destination of the failed uploads of a signal,
+	// e.g. to keep logs locally but drop metrics.
+	Metrics DeadLetterDestination `mapstructure:"metrics"`
+	Logs    DeadLetterDestination `mapstructure:"logs"`
+	Traces  DeadLetterDestination `mapstructure:"traces"`
+}
+
+// DeadLetterDestination is where the failed uploads of a signal go. At most one of its fields is set.
+type DeadLetterDestination struct {
+	// Container receives the payloads as blobs of the storage account, each with a sidecar JSON blob.
+	Container string `mapstructure:"container"`
+	// Path is the local directory receiving the payloads, bounded by max_bytes like directory.
+	Path string `mapstructure:"path"`
+	// Drop discards the payloads, so the export succeeds without retrying them.
+	Drop bool `mapstructure:"drop"`
+}
+
+// isSet reports whether the destination overrides directory.
+func (d DeadLetterDestination) isSet() bool {
+	return d.Container != "" || d.Path != "" || d.Drop
+}
+
+// forSignal returns the destination of the failed uploads of signal: its own destination when set,
+// directory otherwise.
+func (d DeadLetter) forSignal(signal pipeline.Signal) DeadLetterDestination {
+	var dest DeadLetterDestination
+	switch signal {
+	case pipeline.SignalMetrics:
+		dest = d.Metrics
+	case pipeline.SignalLogs:
+		dest = d.Logs
+	case pipeline.SignalTraces:
+		dest = d.Traces
+	}
+	if dest.isSet() {
+		return dest
+	}
+	return DeadLetterDestination{Path: d.Directory}
+}
+
+// validate checks the destinations of the dead letter.
+func (d DeadLetter) validate() error {
+	localPath := d.Directory != ""
+	for _, signal := range []pipeline.Signal{pipeline.SignalLogs, pipeline.SignalMetrics, pipeline.SignalTraces} {
+		dest := d.forSignal(signal)
+		set := 0
+		for _, field := range []bool{dest.Container != "", dest.Path != "", dest.Drop} {
+			if field {
+				set++
+			}
+		}
+		if set > 1 {
+			return fmt.Errorf("dead_letter.%s must set only one of container, path and drop", signal)
+		}
+		if dest.Container != "" && !validContainerName(dest.Container) {
+			return fmt.Errorf("dead_letter.%s.container %q is not a valid container name", signal, dest.Container)
+		}
+		localPath = localPath || dest.Path != ""
+	}
+	if localPath && d.MaxBytes <= 0 {
+		return errors.New("dead_letter.max_bytes must be positive when a dead letter directory is set")
+	}
+	return nil
+}
+
+// CompactOnShutdown configures buffering all consumed telemetry in memory and writing it as a
+// single blob per signal on shutdown, for short-lived jobs.
+type CompactOnShutdown struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxBytes bounds the buffer by the OTLP protobuf size of the buffered telemetry. Once it is
+	// reached, the buffer is written and later telemetry is written as it is consumed.
+	MaxBytes int `mapstructure:"max_bytes"`
 }
 
 type Authentication struct {
-	// Type is the authentication type. supported values are connection_string, service_principal, system_managed_identity, user_managed_identity, workload_identity, and default_credentials
+	// Type is the authentication type. supported values are connection_string, service_principal, system_managed_identity, user_managed_identity, workload_identity, shared_access_signature, and default_credentials
 	Type AuthType `mapstructure:"type"`
 
 	// TenantID is the tenand id for the AAD App. It's only needed when type is service_principal or workload_identity.
@@ -46,6 +500,11 @@ type Authentication struct {
 
 	// ClientID is the AAD Application client id. It's needed when type is service_principal, user_managed_identity or workload_identity
 	ClientID string `mapstructure:"client_id"`
+
+	// ResourceID is the Azure resource ID of the user-assigned managed identity, e.g.
+	// /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.ManagedIdentity/userAssignedIdentities/<name>.
+	// It can replace client_id when type is user_managed_identity.
+	ResourceID string `mapstructure:"resource_id"`
 	// ClientSecret only needed when auth type is service_principal
 
 	ClientSecret string `mapstructure:"client_secret"`
@@ -55,8 +514,66 @@ type Authentication struct {
 
 	// FederatedTokenFile is the path to the file containing the federated token. It's needed when type is workload_identity.
 	FederatedTokenFile string `mapstructure:"federated_token_file"`
+
+	// SASToken is the shared access signature appended to the URL. It's needed when type is shared_access_signature
+	// and the URL does not already carry a signature.
+	SASToken string `mapstructure:"sas_token"`
+
+	// ReportCredentialSource requests a token on start and reports which source of the default credential chain,
+	// e.g. managed_identity or azure_cli, provided it. Only used when type is default_credentials.
+	ReportCredentialSource bool `mapstructure:"report_credential_source"`
+}
+
+// ignoredFields returns the names of the fields that are set but not used by the auth type.
+func (a Authentication) ignoredFields() []string {
+	fields := []struct {
+		name   string
+		set    bool
+		usedBy []AuthType
+	}{
+		{"tenant_id", a.TenantID != "", []AuthType{ServicePrincipal, WorkloadIdentity}},
+		{"client_id", a.ClientID != "", []AuthType{ServicePrincipal, UserManagedIdentity, WorkloadIdentity}},
+		{"resource_id", a.ResourceID != "", []AuthType{UserManagedIdentity}},
+		{"client_secret", a.ClientSecret != "", []AuthType{ServicePrincipal}},
+		{"connection_string", a.ConnectionString != "", []AuthType{ConnectionString}},
+		{"federated_token_file", a.FederatedTokenFile != "", []AuthType{WorkloadIdentity}},
+		{"sas_token", a.SASToken != "", []AuthType{SharedAccessSignature}},
+	}
+	var ignored []string
+	for _, f := range fields {
+		if f.set && !slices.Contains(f.usedBy, a.Type) {
+			ignored = append(ignored, f.name)
+		}
+	}
+	return ignored
+}
+
+// hasSASSignature reports whether rawURL carries a shared access signature.
+func hasSASSignature(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Query().Get("sig") != ""
+}
+
+// sasURL returns rawURL with the SAS token appended to its query.
+func sasURL(rawURL, token string) (string, error) {
+	token = strings.TrimPrefix(token, "?")
+	if token == "" {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.RawQuery != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += token
+	return u.String(), nil
 }
 
+// maxBlobNameLength is the longest blob name accepted by Azure Storage.
+const maxBlobNameLength = 1024
+
 type AuthType string
 
 const (
@@ -65,31 +582,259 @@ const (
 	UserManagedIdentity   AuthType = "user_managed_identity"
 	ServicePrincipal      AuthType = "service_principal"
 	WorkloadIdentity      AuthType = "workload_identity"
+	SharedAccessSignature AuthType = "shared_access_signature"
 	DefaultCredentials    AuthType = "default_credentials"
 )
 
 // Config contains the main configuration options for the azure storage blob exporter
 type Config struct {
 	// URL is the endpoint to the azure storage account. This is only required until there is an azure auth extension in the future.
+	// It must be empty for connection_string auth, where the connection string determines the endpoint.
 	URL string `mapstructure:"url"`
 
-	// A container organizes a set of blobs, similar to a directory in a file system.
+	// A container organizes a set of blobs, similar to a directory in a file system. A container containing
+	// "{{" is a blob name template rendered for every batch, e.g. "traces-{{ resourceAttr \"tenant\" }}".
 	Container TelemetryConfig `mapstructure:"container"`
 	Auth      Authentication  `mapstructure:"auth"`
 
+	// StrictAuthValidation fails validation when auth fields are set that the auth type does not use,
+	// instead of logging a warning on start.
+	StrictAuthValidation bool `mapstructure:"strict_auth_validation"`
+
+	// DefaultContainer receives the signals whose container is not set, with blob names prefixed by the
+	// signal name, e.g. traces/2006/01/02/traces_15_04_05.json.
+	DefaultContainer string `mapstructure:"default_container"`
+
+	// TemplatedContainerFallback writes the batches whose container template fails, or renders an empty or
+	// invalid name, to default_container. When disabled, their upload fails instead.
+	TemplatedContainerFallback bool `mapstructure:"templated_container_fallback"`
+
+	// ContainerIndex appends a line with the name, time and size of every upload to an index blob in its
+	// container.
+	ContainerIndex ContainerIndex `mapstructure:"container_index"`
+
+	// OverflowContainer receives the blobs whose upload to the primary container failed because it is
+	// missing, disabled or over its limits.
+	OverflowContainer string `mapstructure:"overflow_container"`
+
+	// CPK encrypts every blob with a customer-provided key instead of a Microsoft-managed key.
+	CPK CPK `mapstructure:"cpk"`
+
+	// EncryptionScope is the server-side encryption scope of every blob. It is a Go template executed
+	// with the signal, e.g. "otel-{{ .Signal }}", so signals can use different scopes. Empty uses the
+	// default scope of the container.
+	EncryptionScope string `mapstructure:"encryption_scope"`
+
+	// ClientOptions configures the Azure SDK client: its own retries, its telemetry and a proxy.
+	ClientOptions ClientOptions `mapstructure:"client_options"`
+
+	// ADLSGen2 writes block blobs as files of an account with a hierarchical namespace.
+	ADLSGen2 ADLSGen2 `mapstructure:"adls_gen2"`
+
+	// DeadLetter writes blobs whose upload finally failed to the local filesystem for later replay.
+	DeadLetter DeadLetter `mapstructure:"dead_letter"`
+
+	// ContainerPolicies overrides the retry and overflow settings per container name, e.g. to retry uploads to an
+	// audit container forever while traces give up early.
+	ContainerPolicies map[string]ContainerPolicy `mapstructure:"container_policies"`
+
+	// CreateContainerIfNotExists creates the configured containers on start, so uploads to a missing container do not fail.
+	CreateContainerIfNotExists bool `mapstructure:"create_container_if_not_exists"`
+
+	// ContainerCheck checks that a container exists, and optionally creates it, on its first upload.
+	ContainerCheck ContainerCheck `mapstructure:"container_check"`
+
+	// StartupRetry retries credential and client creation in start, e.g. while a managed identity endpoint is not yet available.
+	StartupRetry StartupRetry `mapstructure:"startup_retry"`
+
 	// BlobNameFormat is the format of the blob name. It controls the uploaded blob name, e.g. "2006/01/02/metrics_15_04_05.json"
 	BlobNameFormat BlobNameFormat `mapstructure:"blob_name_format"`
 
-	// FormatType is the format of encoded telemetry data. Supported values are json, proto, and parquet.
+	// FormatType is the format of encoded telemetry data. Supported values are json, ndjson, proto, parquet, csv, and avro.
+	// otlp_json and otlp_proto are aliases of json and proto.
 	FormatType string `mapstructure:"format"`
 
+	// FormatLogs, FormatMetrics and FormatTraces override format for their signal, e.g. parquet traces
+	// next to ndjson logs. Blobs of an overridden signal get the extension of their format.
+	FormatLogs    string `mapstructure:"format_logs"`
+	FormatMetrics string `mapstructure:"format_metrics"`
+	FormatTraces  string `mapstructure:"format_traces"`
+
+	// Formats writes every batch once per listed format, each under a prefix named after the format.
+	// When set, it replaces format, and the first format is the primary one.
+	Formats []string `mapstructure:"formats"`
+
+	// Compression is the compression applied to uploaded blobs. Supported values are none and gzip.
+	// gzip compresses the output of any format and appends .gz to the blob name.
+	Compression string `mapstructure:"compression"`
+
+	// CompressionLevel is the gzip level, from -2 (Huffman only) and -1 (default) to 9 (best compression).
+	CompressionLevel int `mapstructure:"compression_level"`
+
+	// CompressMinBytes is the payload size from which gzip compression is applied. Smaller payloads
+	// are uploaded uncompressed and without the .gz extension. 0 compresses every payload.
+	CompressMinBytes int `mapstructure:"compress_min_bytes"`
+
+	// Parquet configures the parquet format.
+	Parquet ParquetConfig `mapstructure:"parquet"`
+
+	// CSV configures the csv format.
+	CSV CSVConfig `mapstructure:"csv"`
+
+	// AccessTier is the access tier of uploaded blobs: hot, cool, cold or archive. Empty uses the
+	// account default. Only block blobs can be tiered, so it cannot be combined with append blobs.
+	AccessTier string `mapstructure:"access_tier"`
+
+	// OverwritePolicy is what a block blob upload does when the blob already exists: overwrite it, or
+	// fail or skip the upload, enforced by the service with an If-None-Match: * condition. Appends to
+	// append blobs are not affected.
+	OverwritePolicy string `mapstructure:"overwrite_policy"`
+
+	// BlobMetadata maps resource attribute keys to the names of metadata set on every blob, taken from
+	// the first resource of the blob.
+	BlobMetadata map[string]string `mapstructure:"blob_metadata"`
+
+	// BlobTags maps resource attribute keys to the names of index tags set on every blob, taken from the
+	// first resource of the blob. Index tags make blobs searchable by tag without listing the container.
+	BlobTags map[string]string `mapstructure:"blob_tags"`
+
 	// AppendBlob configures append blob behavior
 	AppendBlob AppendBlob `mapstructure:"append_blob"`
 
+	// PreserveOrder writes one batch at a time, finishing its retries before the next, and numbers every
+	// record with an azureblob.sequence attribute that increases in write order.
+	PreserveOrder bool `mapstructure:"preserve_order"`
+
+	// AppendUnsupportedFallback uploads every chunk as its own block blob once the account rejects
+	// append blobs, instead of failing every export.
+	AppendUnsupportedFallback bool `mapstructure:"append_unsupported_fallback"`
+
+	// AggregateMetrics replaces the data points of every metric with one summary data point per resource
+	// and scope holding their count, sum, minimum and maximum. This is lossy: data point attributes,
+	// individual values and histogram buckets are not exported.
+	AggregateMetrics bool `mapstructure:"aggregate_metrics"`
+
+	// TracesWithErrorsOnly only exports traces that contain at least one span with an error status.
+	TracesWithErrorsOnly bool `mapstructure:"traces_with_errors_only"`
+
+	// IDValidation checks the trace and span IDs of spans and log records. "drop" removes records with
+	// invalid IDs, "flag" keeps them with an azureblob.invalid_id attribute. Empty disables the check.
+	IDValidation string `mapstructure:"id_validation"`
+
+	// DedupSpans drops spans with a duplicate (trace_id, span_id) within a batch, keeping the first occurrence.
+	DedupSpans bool `mapstructure:"dedup_spans"`
+
+	// IncludeDroppedCounts adds the OTLP dropped attributes, events and links counts to the parquet output of spans, span
+	// events and links, and logs.
+	IncludeDroppedCounts bool `mapstructure:"include_dropped_counts"`
+
+	// MetricNameNormalization replaces unsafe characters in metric names in the parquet, ndjson and csv output,
+	// keeping the original name in original_name.
+	MetricNameNormalization MetricNameNormalization `mapstructure:"metric_name_normalization"`
+
+	// StripAttributePrefixes removes these prefixes from attribute keys in the exported blobs, longest
+	// prefix first. A key keeps its prefix when the stripped key already exists on the same record.
+	StripAttributePrefixes []string `mapstructure:"strip_attribute_prefixes"`
+
+	// AlsoWriteRawOTLP writes the raw OTLP protobuf of every batch to a sibling container or prefix for lossless replay.
+	AlsoWriteRawOTLP RawOTLP `mapstructure:"also_write_raw_otlp"`
+
+	// SizeRouting only exports batches at or above a size threshold, leaving smaller ones to the Event Hubs exporter.
+	SizeRouting SizeRouting `mapstructure:"size_routing"`
+
+	// NullMissingTimestamps stores unset timestamps as null in parquet output instead of 0, which readers interpret as 1970-01-01.
+	NullMissingTimestamps bool `mapstructure:"null_missing_timestamps"`
+
+	// PartitionFromBodyPath is a dotted path into JSON or map log bodies, e.g. "tenant.id". The value of the
+	// first log record that has it is available to blob name templates through the partition function.
+	PartitionFromBodyPath string `mapstructure:"partition_from_body_path"`
+
+	// PartitionByAttribute splits every batch by the value of a resource attribute and writes one blob
+	// per value, available to the blob name templates as attributePartition.
+	PartitionByAttribute PartitionByAttribute `mapstructure:"partition_by_attribute"`
+
+	// PartitionByService splits every batch by service.namespace and service.name and writes one blob
+	// per service, available to the blob name templates as servicePartition.
+	PartitionByService PartitionByService `mapstructure:"partition_by_service"`
+
+	// WriteStartupConfig writes the effective config, with secrets redacted, to the _startup/ prefix of
+	// the signal's container when the exporter starts.
+	WriteStartupConfig bool `mapstructure:"write_startup_config"`
+
+	// Batch buffers the telemetry of many consume calls into one blob.
+	Batch Batch `mapstructure:"batch"`
+
+	// CompactOnShutdown buffers all telemetry until shutdown and writes it as one blob.
+	CompactOnShutdown CompactOnShutdown `mapstructure:"compact_on_shutdown"`
+
+	// Watermark skips records at or below the highest timestamp already exported for the signal.
+	Watermark Watermark `mapstructure:"watermark"`
+
 	// Encoding extension to apply for logs/metrics/traces. If present, overrides the marshaler configuration option and format.
 	Encodings Encodings `mapstructure:"encodings"`
 
+	// BackOffConfig retries failed uploads with exponential backoff. Non-retryable errors, e.g. 403, fail at once.
 	configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+
+	// QueueSettings buffers exports in a sending queue, which survives restarts when it is backed by a
+	// storage extension.
+	QueueSettings exporterhelper.QueueBatchConfig `mapstructure:"sending_queue"`
+
+	// Timeout bounds every upload attempt, so a hung network call fails and is retried instead of
+	// blocking the pipeline. Zero disables the timeout.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// MaxConcurrentUploads caps the uploads in flight at once. Exports beyond it wait for a free slot,
+	// which applies backpressure to the pipeline. Zero does not limit uploads.
+	MaxConcurrentUploads int `mapstructure:"max_concurrent_uploads"`
+
+	// ShutdownRetryTimeout bounds how long uploads keep retrying once the exporter shuts down, overriding
+	// retry_on_failure.max_elapsed_time. Zero keeps retrying as during normal operation.
+	ShutdownRetryTimeout time.Duration `mapstructure:"shutdown_retry_timeout"`
+}
+
+// Unmarshal decodes the configuration, starting every container policy from the global
+// retry_on_failure so that a policy only has to set the fields it changes. The default signal
+// containers only apply when default_container is not set, otherwise it receives the signals whose
+// container is not configured.
+func (c *Config) Unmarshal(conf *confmap.Conf) error {
+	if err := conf.Unmarshal(c); err != nil {
+		return err
+	}
+	if c.DefaultContainer != "" {
+		for signal, container := range map[pipeline.Signal]*string{
+			pipeline.SignalMetrics: &c.Container.Metrics,
+			pipeline.SignalLogs:    &c.Container.Logs,
+			pipeline.SignalTraces:  &c.Container.Traces,
+		} {
+			if !conf.IsSet("container" + confmap.KeyDelimiter + signal.String()) {
+				*container = ""
+			}
+		}
+	}
+	// Aliases are resolved here, so the rest of the exporter only sees the formats they select
+	for _, format := range []*string{&c.FormatType, &c.FormatLogs, &c.FormatMetrics, &c.FormatTraces} {
+		if resolved, ok := formatAliases[*format]; ok {
+			*format = resolved
+		}
+	}
+	for i, format := range c.Formats {
+		if resolved, ok := formatAliases[format]; ok {
+			c.Formats[i] = resolved
+		}
+	}
+	for name := range c.ContainerPolicies {
+		sub, err := conf.Sub("container_policies" + confmap.KeyDelimiter + name)
+		if err != nil {
+			return err
+		}
+		policy := ContainerPolicy{RetryOnFailure: c.BackOffConfig}
+		if err = sub.Unmarshal(&policy); err != nil {
+			return fmt.Errorf("failed to decode container_policies.%s: %w", name, err)
+		}
+		c.ContainerPolicies[name] = policy
+	}
+	return nil
 }
 
 func (c *Config) Validate() error {
@@ -102,26 +847,341 @@ func (c *Config) Validate() error {
 		if c.Auth.ConnectionString == "" {
 			return errors.New("connection_string cannot be empty when auth type is connection_string")
 		}
+		if c.URL != "" {
+			return errors.New("url cannot be set when auth type is connection_string, the connection string determines the endpoint")
+		}
 	case ServicePrincipal:
 		if c.Auth.TenantID == "" || c.Auth.ClientID == "" || c.Auth.ClientSecret == "" {
 			return errors.New("tenant_id, client_id and client_secret cannot be empty when auth type is service-principal")
 		}
 	case UserManagedIdentity:
-		if c.Auth.ClientID == "" {
-			return errors.New("client_id cannot be empty when auth type is user_managed_identity")
+		if c.Auth.ClientID == "" && c.Auth.ResourceID == "" {
+			return errors.New("client_id or resource_id must be set when auth type is user_managed_identity")
+		}
+		if c.Auth.ClientID != "" && c.Auth.ResourceID != "" {
+			return errors.New("client_id and resource_id cannot both be set when auth type is user_managed_identity")
 		}
 	case WorkloadIdentity:
 		if c.Auth.TenantID == "" || c.Auth.ClientID == "" || c.Auth.FederatedTokenFile == "" {
 			return errors.New("tenant_id, client_id and federated_token_file cannot be empty when auth type is workload_identity")
 		}
+	case SharedAccessSignature:
+		if c.Auth.SASToken == "" && !hasSASSignature(c.URL) {
+			return errors.New("sas_token cannot be empty when auth type is shared_access_signature and the url carries no signature")
+		}
 	case DefaultCredentials:
 		// No additional fields required for default credentials
 		// DefaultAzureCredential will automatically detect credentials from environment
 	}
 
-	if c.FormatType != "json" && c.FormatType != "proto" && c.FormatType != "parquet" {
+	if ignored := c.Auth.ignoredFields(); len(ignored) > 0 && c.StrictAuthValidation {
+		return fmt.Errorf("%s cannot be set when auth type is %s", strings.Join(ignored, ", "), c.Auth.Type)
+	}
+
+	if c.Auth.ReportCredentialSource && c.Auth.Type != DefaultCredentials {
+		return errors.New("report_credential_source can only be used when auth type is default_credentials")
+	}
+
+	for _, maxLength := range []int{c.BlobNameFormat.MetricsMaxLength, c.BlobNameFormat.LogsMaxLength, c.BlobNameFormat.TracesMaxLength} {
+		if maxLength < 0 || maxLength > maxBlobNameLength {
+			return fmt.Errorf("blob name max length must be between 0 and %d", maxBlobNameLength)
+		}
+	}
+
+	if c.AlsoWriteRawOTLP.Enabled && c.AlsoWriteRawOTLP.Container == "" && c.AlsoWriteRawOTLP.Prefix == "" {
+		return errors.New("also_write_raw_otlp requires a container or prefix so raw blobs do not overwrite the typed ones")
+	}
+
+	if c.ContainerIndex.Enabled && c.ContainerIndex.BlobName == "" {
+		return errors.New("container_index.blob_name cannot be empty when the container index is enabled")
+	}
+
+	if c.Container.Metrics == "" && c.Container.Logs == "" && c.Container.Traces == "" && c.DefaultContainer == "" {
+		return errors.New("no container configured, set container.logs, container.metrics, container.traces or default_container")
+	}
+
+	for _, signal := range []pipeline.Signal{pipeline.SignalLogs, pipeline.SignalMetrics, pipeline.SignalTraces} {
+		name := c.Container.forSignal(signal)
+		if !isContainerTemplate(name) {
+			continue
+		}
+		if _, err := parseBlobNameTemplate("container", name); err != nil {
+			return fmt.Errorf("invalid container.%s template: %w", signal, err)
+		}
+		if c.TemplatedContainerFallback && c.DefaultContainer == "" {
+			return fmt.Errorf("container.%s is a template and requires default_container as the fallback for invalid names, or templated_container_fallback: false", signal)
+		}
+		if c.WriteStartupConfig && c.DefaultContainer == "" {
+			return fmt.Errorf("container.%s is a template and requires default_container to receive the startup config", signal)
+		}
+	}
+	if isContainerTemplate(c.DefaultContainer) {
+		return errors.New("default_container cannot be a template, it is the fallback of templated containers")
+	}
+
+	if c.OverflowContainer != "" && slices.Contains([]string{c.Container.Metrics, c.Container.Logs, c.Container.Traces, c.DefaultContainer}, c.OverflowContainer) {
+		return fmt.Errorf("overflow_container %q must differ from the signal containers", c.OverflowContainer)
+	}
+
+	for name, policy := range c.ContainerPolicies {
+		if policy.OverflowContainer == name {
+			return fmt.Errorf("container_policies.%s.overflow_container must differ from the container", name)
+		}
+		if err := policy.RetryOnFailure.Validate(); err != nil {
+			return fmt.Errorf("container_policies.%s.retry_on_failure: %w", name, err)
+		}
+	}
+
+	if c.StartupRetry.MaxAttempts > 1 && c.StartupRetry.Interval <= 0 {
+		return errors.New("startup_retry.interval must be positive when max_attempts is greater than 1")
+	}
+
+	if c.PartitionFromBodyPath != "" && slices.Contains(strings.Split(c.PartitionFromBodyPath, "."), "") {
+		return fmt.Errorf("invalid partition_from_body_path %q: path segments cannot be empty", c.PartitionFromBodyPath)
+	}
+
+	if c.PartitionByAttribute.Key != "" && c.PartitionByAttribute.Default == "" {
+		return errors.New("partition_by_attribute.default cannot be empty when a key is set")
+	}
+
+	if c.PartitionByService.Enabled && (c.PartitionByService.DefaultNamespace == "" || c.PartitionByService.DefaultName == "") {
+		return errors.New("partition_by_service.default_namespace and default_name cannot be empty")
+	}
+
+	if c.SizeRouting.ThresholdBytes < 0 {
+		return errors.New("size_routing.threshold_bytes cannot be negative")
+	}
+
+	if c.IDValidation != "" && c.IDValidation != idValidationDrop && c.IDValidation != idValidationFlag {
+		return fmt.Errorf("unknown id_validation mode %q, must be %q or %q", c.IDValidation, idValidationDrop, idValidationFlag)
+	}
+
+	if _, err := parquetEncoding(c.Parquet.MapValueEncoding); err != nil {
+		return fmt.Errorf("invalid parquet.map_value_encoding: %w", err)
+	}
+
+	if _, err := parquetCompression(c.Parquet.Compression); err != nil {
+		return fmt.Errorf("invalid parquet.compression: %w", err)
+	}
+
+	if c.Parquet.ExpectedSchemaVersion < 0 {
+		return errors.New("parquet.expected_schema_version cannot be negative")
+	}
+
+	if c.Parquet.RowGroupSize < 0 {
+		return errors.New("parquet.row_group_size cannot be negative")
+	}
+
+	if c.MetricNameNormalization.Enabled {
+		if c.MetricNameNormalization.Characters == "" {
+			return errors.New("metric_name_normalization.characters cannot be empty when normalization is enabled")
+		}
+		if strings.ContainsAny(c.MetricNameNormalization.Replacement, c.MetricNameNormalization.Characters) {
+			return errors.New("metric_name_normalization.replacement cannot contain the replaced characters")
+		}
+	}
+
+	for i, prefix := range c.StripAttributePrefixes {
+		if prefix == "" {
+			return errors.New("strip_attribute_prefixes cannot contain an empty prefix")
+		}
+		if slices.Contains(c.StripAttributePrefixes[:i], prefix) {
+			return fmt.Errorf("strip_attribute_prefixes contains %q more than once", prefix)
+		}
+	}
+
+	if c.Batch.Enabled && (c.Batch.MaxBytes <= 0 || c.Batch.FlushInterval <= 0) {
+		return errors.New("batch.max_bytes and batch.flush_interval must be positive when batching is enabled")
+	}
+
+	if c.CompactOnShutdown.Enabled {
+		if c.Batch.Enabled {
+			return errors.New("compact_on_shutdown cannot be combined with batch")
+		}
+		if c.CompactOnShutdown.MaxBytes <= 0 {
+			return errors.New("compact_on_shutdown.max_bytes must be positive when compaction is enabled")
+		}
+	}
+
+	if c.Watermark.Enabled && c.Watermark.Storage == (component.ID{}) {
+		return errors.New("watermark requires a storage extension")
+	}
+
+	if c.Watermark.AllowedLateness < 0 {
+		return errors.New("watermark.allowed_lateness cannot be negative")
+	}
+
+	if c.ContainerCheck.Enabled && c.ContainerCheck.TTL <= 0 {
+		return errors.New("container_check.ttl must be positive when the container check is enabled")
+	}
+
+	if c.ClientOptions.Retry.MaxRetries < -1 {
+		return errors.New("client_options.retry.max_retries must be -1, to disable retries, or more")
+	}
+	if c.ClientOptions.Retry.TryTimeout < 0 || c.ClientOptions.Retry.RetryDelay < 0 || c.ClientOptions.Retry.MaxRetryDelay < 0 {
+		return errors.New("client_options.retry durations cannot be negative")
+	}
+	if _, err := c.ClientOptions.azblobOptions(); err != nil {
+		return err
+	}
+
+	if _, err := c.CPK.info(); err != nil {
+		return err
+	}
+	if c.CPK.KeySHA256 != "" && c.CPK.Key == "" {
+		return errors.New("cpk.key_sha256 requires cpk.key")
+	}
+	for _, signal := range []pipeline.Signal{pipeline.SignalTraces, pipeline.SignalLogs, pipeline.SignalMetrics} {
+		if _, err := c.encryptionScope(signal); err != nil {
+			return err
+		}
+	}
+	if c.EncryptionScope != "" && c.CPK.Key != "" {
+		return errors.New("encryption_scope cannot be combined with cpk, a blob is encrypted with either")
+	}
+
+	if err := c.ADLSGen2.validate(c); err != nil {
+		return err
+	}
+
+	if err := c.DeadLetter.validate(); err != nil {
+		return err
+	}
+
+	if c.Timeout < 0 {
+		return errors.New("timeout cannot be negative")
+	}
+
+	if c.MaxConcurrentUploads < 0 {
+		return errors.New("max_concurrent_uploads cannot be negative")
+	}
+
+	if c.ShutdownRetryTimeout < 0 {
+		return errors.New("shutdown_retry_timeout cannot be negative")
+	}
+
+	switch c.Compression {
+	case compressionNone, compressionGzip:
+	default:
+		return fmt.Errorf("unknown compression %q, must be %q or %q", c.Compression, compressionNone, compressionGzip)
+	}
+
+	if c.CompressionLevel < gzip.HuffmanOnly || c.CompressionLevel > gzip.BestCompression {
+		return fmt.Errorf("compression_level must be between %d and %d", gzip.HuffmanOnly, gzip.BestCompression)
+	}
+
+	if c.CompressMinBytes < 0 {
+		return errors.New("compress_min_bytes cannot be negative")
+	}
+
+	if c.CompressMinBytes > 0 && c.AppendBlob.Enabled {
+		return errors.New("compress_min_bytes cannot be used with append_blob, chunks of one blob must all be compressed or not")
+	}
+
+	if _, ok := accessTiers[c.AccessTier]; !ok && c.AccessTier != "" {
+		return fmt.Errorf("unknown access_tier %q, must be hot, cool, cold or archive", c.AccessTier)
+	}
+
+	if c.AccessTier != "" && c.AppendBlob.Enabled {
+		return errors.New("access_tier cannot be used with append_blob, append blobs do not support access tiers")
+	}
+
+	for key, name := range c.BlobMetadata {
+		if !metadataNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid blob_metadata name %q for attribute %q, must be a valid C# identifier", name, key)
+		}
+	}
+
+	if len(c.BlobTags) > maxBlobTags {
+		return fmt.Errorf("blob_tags has %d tags, Azure allows at most %d per blob", len(c.BlobTags), maxBlobTags)
+	}
+	for key, name := range c.BlobTags {
+		if name == "" || len(name) > maxBlobTagNameLength || blobTagInvalidChars.MatchString(name) {
+			return fmt.Errorf("invalid blob_tags name %q for attribute %q, must be 1 to %d letters, digits or any of ' +-./:=_'", name, key, maxBlobTagNameLength)
+		}
+	}
+
+	if (len(c.BlobMetadata) > 0 || len(c.BlobTags) > 0) && c.AppendBlob.Enabled {
+		return errors.New("blob_metadata and blob_tags cannot be used with append_blob, they are only set on block blob uploads")
+	}
+
+	if c.AppendBlob.MaxBlocks < 0 || c.AppendBlob.MaxBlocks > maxAppendBlocks {
+		return fmt.Errorf("append_blob.max_blocks must be between 0 and %d", maxAppendBlocks)
+	}
+	if c.AppendBlob.RollInterval < 0 {
+		return errors.New("append_blob.roll_interval cannot be negative")
+	}
+
+	if c.BlobNameFormat.DeterministicFromContent && c.AppendBlob.Enabled {
+		return errors.New("blob_name_format.deterministic_from_content cannot be used with append_blob, re-exported batches would be appended again")
+	}
+
+	switch c.OverwritePolicy {
+	case "", overwritePolicyOverwrite, overwritePolicyFail, overwritePolicySkip:
+	default:
+		return fmt.Errorf("unknown overwrite_policy %q, must be %q, %q or %q",
+			c.OverwritePolicy, overwritePolicyOverwrite, overwritePolicyFail, overwritePolicySkip)
+	}
+
+	switch c.BlobNameFormat.UniqueSuffix {
+	case "", uniqueSuffixSerial:
+	case uniqueSuffixUUID, uniqueSuffixULID:
+		if c.BlobNameFormat.DeterministicFromContent {
+			return fmt.Errorf("blob_name_format.unique_suffix %q cannot be used with deterministic_from_content, which replaces the suffix with a content hash", c.BlobNameFormat.UniqueSuffix)
+		}
+	default:
+		return fmt.Errorf("unknown blob_name_format.unique_suffix %q, must be %q, %q or %q",
+			c.BlobNameFormat.UniqueSuffix, uniqueSuffixSerial, uniqueSuffixUUID, uniqueSuffixULID)
+	}
+
+	if _, err := time.LoadLocation(c.BlobNameFormat.Timezone); err != nil {
+		return fmt.Errorf("invalid blob_name_format.timezone %q: %w", c.BlobNameFormat.Timezone, err)
+	}
+
+	switch c.BlobNameFormat.TimeSource {
+	case "", timeSourceNow, timeSourceTelemetry:
+	default:
+		return fmt.Errorf("unknown blob_name_format.time_source %q, must be %q or %q", c.BlobNameFormat.TimeSource, timeSourceNow, timeSourceTelemetry)
+	}
+
+	if c.FormatType != "json" && c.FormatType != "proto" && c.FormatType != "parquet" && c.FormatType != "ndjson" && c.FormatType != "csv" && c.FormatType != "avro" {
 		return errors.New("unknown format type: " + c.FormatType)
 	}
 
+	if _, err := csvDelimiter(c.CSV.Delimiter); err != nil {
+		return err
+	}
+
+	for _, signal := range []pipeline.Signal{pipeline.SignalLogs, pipeline.SignalMetrics, pipeline.SignalTraces} {
+		format := c.formatOverride(signal)
+		if format == "" {
+			continue
+		}
+		if _, ok := formatExtensions[format]; !ok {
+			return fmt.Errorf("unknown format type in format_%s: %q", signal, format)
+		}
+		if len(c.Formats) > 0 {
+			return fmt.Errorf("format_%s cannot be combined with formats", signal)
+		}
+	}
+
+	if c.usesFormat(formatTypeCSV) && c.AppendBlob.Enabled {
+		return errors.New("csv format cannot be used with append_blob, every chunk would repeat the header row")
+	}
+	if c.usesFormat(formatTypeAvro) && c.AppendBlob.Enabled {
+		return errors.New("avro format cannot be used with append_blob, every chunk would repeat the container file header")
+	}
+
+	seenFormats := make(map[string]struct{}, len(c.Formats))
+	for _, format := range c.Formats {
+		if _, ok := formatExtensions[format]; !ok {
+			return fmt.Errorf("unknown format type in formats: %q", format)
+		}
+		if _, ok := seenFormats[format]; ok {
+			return fmt.Errorf("format %q is listed more than once in formats", format)
+		}
+		seenFormats[format] = struct{}{}
+	}
+
 	return nil
 }
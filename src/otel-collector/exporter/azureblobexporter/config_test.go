@@ -0,0 +1,227 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestUnmarshalContainers(t *testing.T) {
+	tests := []struct {
+		name          string
+		conf          map[string]any
+		wantContainer TelemetryConfig
+		wantDefault   string
+	}{
+		{
+			name:          "defaults",
+			conf:          map[string]any{},
+			wantContainer: TelemetryConfig{Metrics: "metrics", Logs: "logs", Traces: "traces"},
+		},
+		{
+			name:          "explicit containers",
+			conf:          map[string]any{"container": map[string]any{"logs": "app-logs"}},
+			wantContainer: TelemetryConfig{Metrics: "metrics", Logs: "app-logs", Traces: "traces"},
+		},
+		{
+			name:          "default container",
+			conf:          map[string]any{"default_container": "otel"},
+			wantContainer: TelemetryConfig{},
+			wantDefault:   "otel",
+		},
+		{
+			name:          "default container with explicit containers",
+			conf:          map[string]any{"default_container": "otel", "container": map[string]any{"logs": "logs"}},
+			wantContainer: TelemetryConfig{Logs: "logs"},
+			wantDefault:   "otel",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			require.NoError(t, cfg.Unmarshal(confmap.NewFromStringMap(tt.conf)))
+			assert.Equal(t, tt.wantContainer, cfg.Container)
+			assert.Equal(t, tt.wantDefault, cfg.DefaultContainer)
+		})
+	}
+}
+
+func TestValidateConnectionStringURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{
+			name:      "connection string without url",
+			configure: func(*Config) {},
+		},
+		{
+			name:      "connection string with url",
+			configure: func(cfg *Config) { cfg.URL = "https://test.blob.core.windows.net/" },
+			wantErr:   "url cannot be set when auth type is connection_string",
+		},
+		{
+			name:      "empty connection string",
+			configure: func(cfg *Config) { cfg.Auth.ConnectionString = "" },
+			wantErr:   "connection_string cannot be empty when auth type is connection_string",
+		},
+		{
+			name:      "url required for other auth types",
+			configure: func(cfg *Config) { cfg.Auth = Authentication{Type: SystemManagedIdentity} },
+			wantErr:   "url cannot be empty when auth type is not connection_string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Auth.ConnectionString = testConnectionString
+			tt.configure(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUserManagedIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    Authentication
+		wantErr string
+	}{
+		{
+			name: "client id",
+			auth: Authentication{Type: UserManagedIdentity, ClientID: "00000000-0000-0000-0000-000000000000"},
+		},
+		{
+			name: "resource id",
+			auth: Authentication{Type: UserManagedIdentity, ResourceID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/collector"},
+		},
+		{
+			name:    "neither",
+			auth:    Authentication{Type: UserManagedIdentity},
+			wantErr: "client_id or resource_id must be set when auth type is user_managed_identity",
+		},
+		{
+			name:    "both",
+			auth:    Authentication{Type: UserManagedIdentity, ClientID: "client", ResourceID: "resource"},
+			wantErr: "client_id and resource_id cannot both be set when auth type is user_managed_identity",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Auth = tt.auth
+			cfg.URL = "https://test.blob.core.windows.net/"
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			exp, err := newAzureBlobExporter(cfg, component.MustNewID("azureblob"), componenttest.NewNopTelemetrySettings(), pipeline.SignalLogs)
+			require.NoError(t, err)
+			client, cred, err := exp.newClient(context.Background())
+			require.NoError(t, err)
+			assert.NotNil(t, cred)
+			assert.Equal(t, "https://test.blob.core.windows.net/", client.URL())
+		})
+	}
+}
+
+func TestIgnoredAuthFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		auth        Authentication
+		wantIgnored []string
+	}{
+		{
+			name:        "connection string with identity",
+			auth:        Authentication{Type: ConnectionString, ConnectionString: testConnectionString, TenantID: "tenant", ClientID: "client"},
+			wantIgnored: []string{"tenant_id", "client_id"},
+		},
+		{
+			name:        "service principal with secrets of other types",
+			auth:        Authentication{Type: ServicePrincipal, TenantID: "tenant", ClientID: "client", ClientSecret: "secret", ConnectionString: testConnectionString, SASToken: "sig=test"},
+			wantIgnored: []string{"connection_string", "sas_token"},
+		},
+		{
+			name:        "system managed identity with client id",
+			auth:        Authentication{Type: SystemManagedIdentity, ClientID: "client"},
+			wantIgnored: []string{"client_id"},
+		},
+		{
+			name:        "user managed identity with client secret",
+			auth:        Authentication{Type: UserManagedIdentity, ClientID: "client", ClientSecret: "secret"},
+			wantIgnored: []string{"client_secret"},
+		},
+		{
+			name:        "workload identity with resource id",
+			auth:        Authentication{Type: WorkloadIdentity, TenantID: "tenant", ClientID: "client", FederatedTokenFile: "/var/run/token", ResourceID: "resource"},
+			wantIgnored: []string{"resource_id"},
+		},
+		{
+			name:        "sas with client secret",
+			auth:        Authentication{Type: SharedAccessSignature, SASToken: "sig=test", ClientSecret: "secret"},
+			wantIgnored: []string{"client_secret"},
+		},
+		{
+			name:        "default credentials with federated token file",
+			auth:        Authentication{Type: DefaultCredentials, FederatedTokenFile: "/var/run/token"},
+			wantIgnored: []string{"federated_token_file"},
+		},
+		{
+			name: "only used fields",
+			auth: Authentication{Type: ServicePrincipal, TenantID: "tenant", ClientID: "client", ClientSecret: "secret"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Auth = tt.auth
+			if tt.auth.Type != ConnectionString {
+				cfg.URL = "https://test.blob.core.windows.net/"
+			}
+			assert.Equal(t, tt.wantIgnored, cfg.Auth.ignoredFields())
+
+			// The ignored fields are reported on start without strict_auth_validation
+			core, logs := observer.New(zap.WarnLevel)
+			set := componenttest.NewNopTelemetrySettings()
+			set.Logger = zap.New(core)
+			newTestExporterWithSettings(t, cfg, pipeline.SignalLogs, set)
+			warnings := logs.FilterMessage("Authentication fields are not used by the auth type and are ignored").All()
+			if len(tt.wantIgnored) == 0 {
+				assert.Empty(t, warnings)
+			} else {
+				require.Len(t, warnings, 1)
+				assert.Contains(t, warnings[0].Context, zap.Strings("fields", tt.wantIgnored))
+			}
+
+			cfg.StrictAuthValidation = true
+			err := cfg.Validate()
+			if len(tt.wantIgnored) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, fmt.Sprintf("%s cannot be set when auth type is %s", strings.Join(tt.wantIgnored, ", "), tt.auth.Type))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+// errContainerNotFound is returned for uploads to a container that the container check did not find.
+var errContainerNotFound = errors.New("container does not exist")
+
+// containerCache remembers the containers that were found to exist, for the container_check TTL.
+type containerCache struct {
+	cfg ContainerCheck
+	now func() time.Time
+
+	mu      sync.Mutex
+	checked map[string]time.Time
+}
+
+// newContainerCache returns the cache for cfg, or nil when the container check is disabled.
+func newContainerCache(cfg ContainerCheck) *containerCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &containerCache{
+		cfg:     cfg,
+		now:     time.Now,
+		checked: make(map[string]time.Time),
+	}
+}
+
+// exists reports whether containerName was found within the TTL.
+func (c *containerCache) exists(containerName string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checkedAt, ok := c.checked[containerName]
+	return ok && c.now().Sub(checkedAt) < c.cfg.TTL
+}
+
+func (c *containerCache) add(containerName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked[containerName] = c.now()
+}
+
+// checkContainer verifies that containerName exists before its first upload and again once the
+// TTL expired, creating it when container_check.create is set. Only found containers are cached,
+// so a missing container is checked on every upload until it is created. A failed check, e.g. when
+// the identity may not read container properties, is logged and leaves it to the upload to fail.
+func (e *azureBlobExporter) checkContainer(ctx context.Context, containerName string) error {
+	if e.containers == nil || e.containers.exists(containerName) {
+		return nil
+	}
+
+	exists, err := e.client.ContainerExists(ctx, containerName)
+	if err != nil {
+		e.logger.Warn("Failed to check container existence", zap.String("container", containerName), zap.Error(err))
+		return nil
+	}
+	if !exists {
+		if !e.config.ContainerCheck.Create {
+			return consumererror.NewPermanent(fmt.Errorf("%w: %q", errContainerNotFound, containerName))
+		}
+		if err = e.client.CreateContainer(ctx, containerName); err != nil {
+			return fmt.Errorf("failed to create container %q: %w", containerName, err)
+		}
+		e.logger.Info("Created missing container", zap.String("container", containerName))
+	}
+	e.containers.add(containerName)
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+// isContainerTemplate reports whether a configured container name is a template rendered per batch.
+func isContainerTemplate(name string) bool {
+	return strings.Contains(name, "{{")
+}
+
+// validContainerName reports whether name follows the Azure container naming rules: 3 to 63
+// lowercase letters, digits and hyphens, starting and ending with a letter or digit, without
+// consecutive hyphens.
+func validContainerName(name string) bool {
+	if len(name) < 3 || len(name) > 63 || name[0] == '-' || name[len(name)-1] == '-' || strings.Contains(name, "--") {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// renderContainer renders the container template of the exporter for a batch. A template that fails
+// or renders an empty or invalid container name falls back to default_container when
+// templated_container_fallback is enabled, and fails the upload otherwise.
+func (e *azureBlobExporter) renderContainer(ctx context.Context, telemetryData any) (string, error) {
+	name, err := executeBlobNameTemplate(e.containerTemplate, telemetryData, e.config.PartitionFromBodyPath, e.config.PartitionByAttribute, e.config.PartitionByService)
+	if err == nil {
+		err = checkRenderedContainer(name)
+	}
+	if err == nil {
+		return name, nil
+	}
+	if !e.config.TemplatedContainerFallback {
+		// Rendering the same batch again gives the same name, so retrying cannot help
+		return "", consumererror.NewPermanent(fmt.Errorf("failed to resolve container template: %w", err))
+	}
+	e.telemetry.templatedContainerFallbacks.Add(ctx, 1)
+	e.logger.Warn("Failed to resolve container template, using default_container",
+		zap.String("default_container", e.config.DefaultContainer),
+		zap.Error(err))
+	return e.config.DefaultContainer, nil
+}
+
+// checkRenderedContainer checks a rendered container name. text/template renders a missing
+// attribute as "<no value>", which is reported as an empty render.
+func checkRenderedContainer(name string) error {
+	if name == "" || strings.Contains(name, "<no value>") {
+		return fmt.Errorf("rendered container name %q is empty where an attribute is missing", name)
+	}
+	if !validContainerName(name) {
+		return fmt.Errorf("invalid container name %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+const testTemplatedContainer = `traces-{{ resourceAttr "tenant" }}`
+
+func TestTemplatedContainer(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Container.Traces = testTemplatedContainer
+	cfg.DefaultContainer = "traces-shared"
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	require.NoError(t, exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{"tenant": "acme"})))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	assert.Equal(t, "traces-acme", uploads[0].container)
+}
+
+func TestTemplatedContainerFallback(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]any
+	}{
+		{name: "missing attribute", attrs: map[string]any{}},
+		{name: "invalid name", attrs: map[string]any{"tenant": "Acme_Corp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tel := componenttest.NewTelemetry()
+			t.Cleanup(func() { require.NoError(t, tel.Shutdown(context.Background())) })
+			cfg := newTestConfig()
+			cfg.Container.Traces = testTemplatedContainer
+			cfg.DefaultContainer = "traces-shared"
+			exp, client := newTestExporterWithSettings(t, cfg, pipeline.SignalTraces, tel.NewTelemetrySettings())
+
+			require.NoError(t, exp.ConsumeTraces(context.Background(), newTestTraces(tt.attrs)))
+
+			uploads := client.recorded()
+			require.Len(t, uploads, 1)
+			assert.Equal(t, "traces-shared", uploads[0].container)
+			assert.Equal(t, int64(1), counterValue(t, tel, "otelcol_exporter_azureblob_templated_container_fallbacks"))
+		})
+	}
+}
+
+func TestTemplatedContainerWithoutFallback(t *testing.T) {
+	tel := componenttest.NewTelemetry()
+	t.Cleanup(func() { require.NoError(t, tel.Shutdown(context.Background())) })
+	cfg := newTestConfig()
+	cfg.Container.Traces = testTemplatedContainer
+	cfg.TemplatedContainerFallback = false
+	exp, client := newTestExporterWithSettings(t, cfg, pipeline.SignalTraces, tel.NewTelemetrySettings())
+
+	err := exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{}))
+
+	require.Error(t, err)
+	assert.True(t, consumererror.IsPermanent(err))
+	assert.ErrorContains(t, err, "is empty where an attribute is missing")
+	assert.Empty(t, client.recorded())
+	assert.Zero(t, counterValue(t, tel, "otelcol_exporter_azureblob_templated_container_fallbacks"))
+}
+
+func TestTemplatedContainerValidate(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Container.Traces = testTemplatedContainer
+	assert.ErrorContains(t, cfg.Validate(), "requires default_container as the fallback")
+
+	cfg.TemplatedContainerFallback = false
+	assert.NoError(t, cfg.Validate())
+
+	cfg.WriteStartupConfig = true
+	assert.ErrorContains(t, cfg.Validate(), "requires default_container to receive the startup config")
+}
+
+func TestCheckRenderedContainer(t *testing.T) {
+	assert.NoError(t, checkRenderedContainer("traces-acme"))
+	assert.ErrorContains(t, checkRenderedContainer(""), "is empty")
+	assert.ErrorContains(t, checkRenderedContainer("traces-<no value>"), "is empty")
+	assert.ErrorContains(t, checkRenderedContainer("traces--acme"), "invalid container name")
+	assert.ErrorContains(t, checkRenderedContainer("ab"), "invalid container name")
+}
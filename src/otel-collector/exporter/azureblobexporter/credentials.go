@@ -0,0 +1,140 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+const (
+	// storageScope is the token scope of Azure Storage.
+	storageScope = "https://storage.azure.com/.default"
+
+	// imdsEndpoint is the token endpoint of the instance metadata service used by managed identities on VMs and AKS.
+	imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// imdsProbeTimeout bounds the wait for IMDS, which does not respond outside Azure.
+	imdsProbeTimeout = time.Second
+)
+
+// credentialSource is one credential of the default chain, named after its source.
+type credentialSource struct {
+	name string
+	cred azcore.TokenCredential
+}
+
+// credentialChain tries its sources in order, like DefaultAzureCredential, and remembers the
+// source that succeeded so it can be reported and used for all later tokens.
+type credentialChain struct {
+	sources []credentialSource
+
+	mu   sync.Mutex
+	used *credentialSource
+}
+
+// newDefaultCredentialChain returns the sources of DefaultAzureCredential: environment variables,
+// workload identity, managed identity, the Azure CLI and the Azure Developer CLI. Sources whose
+// configuration is missing from the environment are left out.
+func newDefaultCredentialChain() (*credentialChain, error) {
+	var sources []credentialSource
+	if cred, err := azidentity.NewEnvironmentCredential(nil); err == nil {
+		sources = append(sources, credentialSource{name: "environment", cred: cred})
+	}
+	if cred, err := azidentity.NewWorkloadIdentityCredential(nil); err == nil {
+		sources = append(sources, credentialSource{name: "workload_identity", cred: cred})
+	}
+
+	miOptions := &azidentity.ManagedIdentityCredentialOptions{}
+	if clientID, ok := os.LookupEnv("AZURE_CLIENT_ID"); ok {
+		miOptions.ID = azidentity.ClientID(clientID)
+	}
+	if cred, err := azidentity.NewManagedIdentityCredential(miOptions); err == nil {
+		sources = append(sources, credentialSource{name: "managed_identity", cred: &imdsProbeCredential{cred: cred}})
+	}
+	if cred, err := azidentity.NewAzureCLICredential(nil); err == nil {
+		sources = append(sources, credentialSource{name: "azure_cli", cred: cred})
+	}
+	if cred, err := azidentity.NewAzureDeveloperCLICredential(nil); err == nil {
+		sources = append(sources, credentialSource{name: "azure_developer_cli", cred: cred})
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("no credential source is available")
+	}
+	return &credentialChain{sources: sources}, nil
+}
+
+// GetToken returns a token of the source that succeeded before, or of the first source that
+// succeeds. A source that rejects the credentials ends the chain, as in DefaultAzureCredential,
+// rather than silently falling back to another identity.
+func (c *credentialChain) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.used != nil {
+		return c.used.cred.GetToken(ctx, opts)
+	}
+
+	var errs []error
+	for i := range c.sources {
+		source := &c.sources[i]
+		token, err := source.cred.GetToken(ctx, opts)
+		if err == nil {
+			c.used = source
+			return token, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", source.name, err))
+
+		var authErr *azidentity.AuthenticationFailedError
+		if errors.As(err, &authErr) {
+			break
+		}
+	}
+	return azcore.AccessToken{}, errors.Join(errs...)
+}
+
+// source returns the name of the source that succeeded, or an empty string before any succeeded.
+func (c *credentialChain) source() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.used == nil {
+		return ""
+	}
+	return c.used.name
+}
+
+// imdsProbeCredential checks that IMDS responds before the first token request of a managed
+// identity, as DefaultAzureCredential does, so that the chain moves on quickly outside Azure
+// instead of waiting for the token request to time out.
+type imdsProbeCredential struct {
+	cred   azcore.TokenCredential
+	probed bool
+}
+
+func (c *imdsProbeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	// App Service, Functions and Cloud Shell provide their own endpoint instead of IMDS
+	if !c.probed && os.Getenv("IDENTITY_ENDPOINT") == "" && os.Getenv("MSI_ENDPOINT") == "" {
+		probeCtx, cancel := context.WithTimeout(ctx, imdsProbeTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, imdsEndpoint, nil)
+		if err != nil {
+			return azcore.AccessToken{}, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return azcore.AccessToken{}, fmt.Errorf("managed identity endpoint is not available: %w", err)
+		}
+		resp.Body.Close()
+		c.probed = true
+	}
+	return c.cred.GetToken(ctx, opts)
+}
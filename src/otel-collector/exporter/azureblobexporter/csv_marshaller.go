@@ -0,0 +1,262 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// CSV header rows. Column names follow the parquet columns, and attribute maps are JSON encoded.
+var (
+	csvSpanHeader = []string{
+		"trace_id", "span_id", "parent_span_id", "name", "kind", "start_time_unix_nano", "end_time_unix_nano",
+		"status_code", "status_message", "span_attributes", "resource_attributes", "scope_name", "scope_version",
+	}
+	csvLogHeader = []string{
+		"time_unix_nano", "observed_time_unix_nano", "severity_number", "severity_text", "body", "trace_id",
+		"span_id", "flags", "log_attributes", "resource_attributes", "scope_name", "scope_version",
+	}
+	csvMetricHeader = []string{
+		"name", "original_name", "description", "unit", "type", "start_time_unix_nano", "time_unix_nano",
+		"value", "count", "sum", "min", "max", "bucket_counts", "explicit_bounds", "quantiles",
+		"attributes", "resource_attributes", "scope_name", "scope_version",
+	}
+)
+
+// csvMarshaller writes a header row and one row for every span, log record and metric data point.
+// Fields are quoted per RFC 4180 when they contain the delimiter, quotes or line breaks.
+type csvMarshaller struct {
+	delimiter rune
+	// metricNameReplacer normalizes metric names, nil when metric_name_normalization is disabled
+	metricNameReplacer *strings.Replacer
+}
+
+func newCSVMarshaller(config *Config) (*csvMarshaller, error) {
+	delimiter, err := csvDelimiter(config.CSV.Delimiter)
+	if err != nil {
+		return nil, err
+	}
+	return &csvMarshaller{
+		delimiter:          delimiter,
+		metricNameReplacer: config.MetricNameNormalization.replacer(),
+	}, nil
+}
+
+// csvDelimiter returns the configured delimiter, a comma when empty.
+func csvDelimiter(delimiter string) (rune, error) {
+	if delimiter == "" {
+		return ',', nil
+	}
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid csv.delimiter %q, must be a single character other than a quote or line break", delimiter)
+	}
+	return r, nil
+}
+
+// csvTable collects the rows of a blob, keeping the first error of encoding a JSON column.
+type csvTable struct {
+	rows [][]string
+	err  error
+}
+
+// json encodes v for a JSON column, e.g. an attribute map.
+func (t *csvTable) json(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		if t.err == nil {
+			t.err = err
+		}
+		return ""
+	}
+	return string(b)
+}
+
+func (c *csvMarshaller) MarshalTraces(td ptrace.Traces) ([]byte, error) {
+	var table csvTable
+
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		resource := table.json(rs.Resource().Attributes().AsRaw())
+
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				parentSpanID := ""
+				if !span.ParentSpanID().IsEmpty() {
+					parentSpanID = span.ParentSpanID().String()
+				}
+				table.rows = append(table.rows, []string{
+					span.TraceID().String(),
+					span.SpanID().String(),
+					parentSpanID,
+					span.Name(),
+					strconv.Itoa(int(span.Kind())),
+					nanos(span.StartTimestamp()),
+					nanos(span.EndTimestamp()),
+					strconv.Itoa(int(span.Status().Code())),
+					span.Status().Message(),
+					table.json(span.Attributes().AsRaw()),
+					resource,
+					ss.Scope().Name(),
+					ss.Scope().Version(),
+				})
+			}
+		}
+	}
+
+	return c.write(csvSpanHeader, &table)
+}
+
+func (c *csvMarshaller) MarshalLogs(ld plog.Logs) ([]byte, error) {
+	var table csvTable
+
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		resource := table.json(rl.Resource().Attributes().AsRaw())
+
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				logRecord := sl.LogRecords().At(k)
+				traceID, spanID := "", ""
+				if !logRecord.TraceID().IsEmpty() {
+					traceID = logRecord.TraceID().String()
+				}
+				if !logRecord.SpanID().IsEmpty() {
+					spanID = logRecord.SpanID().String()
+				}
+				table.rows = append(table.rows, []string{
+					nanos(logRecord.Timestamp()),
+					nanos(logRecord.ObservedTimestamp()),
+					strconv.Itoa(int(logRecord.SeverityNumber())),
+					logRecord.SeverityText(),
+					logRecord.Body().AsString(),
+					traceID,
+					spanID,
+					strconv.FormatUint(uint64(logRecord.Flags()), 10),
+					table.json(logRecord.Attributes().AsRaw()),
+					resource,
+					sl.Scope().Name(),
+					sl.Scope().Version(),
+				})
+			}
+		}
+	}
+
+	return c.write(csvLogHeader, &table)
+}
+
+func (c *csvMarshaller) MarshalMetrics(md pmetric.Metrics) ([]byte, error) {
+	var table csvTable
+
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		resource := table.json(rm.Resource().Attributes().AsRaw())
+
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				// The data points are flattened the same way as ndjson records
+				for _, dp := range ndjsonDataPoints(sm.Metrics().At(k)) {
+					if c.metricNameReplacer != nil {
+						dp.OriginalName = dp.Name
+						dp.Name = c.metricNameReplacer.Replace(dp.Name)
+					}
+					var bucketCounts, explicitBounds, quantiles string
+					if len(dp.BucketCounts) > 0 {
+						bucketCounts = table.json(dp.BucketCounts)
+					}
+					if len(dp.ExplicitBounds) > 0 {
+						explicitBounds = table.json(dp.ExplicitBounds)
+					}
+					if len(dp.Quantiles) > 0 {
+						quantiles = table.json(dp.Quantiles)
+					}
+					table.rows = append(table.rows, []string{
+						dp.Name,
+						dp.OriginalName,
+						dp.Description,
+						dp.Unit,
+						dp.Type,
+						dp.StartTimeUnixNano,
+						dp.TimeUnixNano,
+						csvNumber(dp.Value),
+						csvNumber(dp.Count),
+						csvNumber(dp.Sum),
+						csvNumber(dp.Min),
+						csvNumber(dp.Max),
+						bucketCounts,
+						explicitBounds,
+						quantiles,
+						table.json(dp.Attributes),
+						resource,
+						sm.Scope().Name(),
+						sm.Scope().Version(),
+					})
+				}
+			}
+		}
+	}
+
+	return c.write(csvMetricHeader, &table)
+}
+
+func (*csvMarshaller) format() string {
+	return formatTypeCSV
+}
+
+// write encodes the header row and the rows of table. Batches without rows produce an empty blob.
+func (c *csvMarshaller) write(header []string, table *csvTable) ([]byte, error) {
+	if table.err != nil {
+		return nil, fmt.Errorf("failed to encode csv column: %w", table.err)
+	}
+	if len(table.rows) == 0 {
+		return []byte{}, nil
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = c.delimiter
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(table.rows); err != nil {
+		return nil, fmt.Errorf("failed to write csv data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// csvNumber formats an int64, *uint64 or *float64 value, or returns an empty field for nil.
+func csvNumber(v any) string {
+	switch n := v.(type) {
+	case int64:
+		return strconv.FormatInt(n, 10)
+	case float64:
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	case *uint64:
+		if n != nil {
+			return strconv.FormatUint(*n, 10)
+		}
+	case *float64:
+		if n != nil {
+			return strconv.FormatFloat(*n, 'g', -1, 64)
+		}
+	}
+	return ""
+}
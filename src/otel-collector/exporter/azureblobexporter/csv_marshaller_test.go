@@ -0,0 +1,199 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// newTestCSVMarshaller returns a csv marshaller with the given delimiter, a comma when empty.
+func newTestCSVMarshaller(t *testing.T, delimiter string) *csvMarshaller {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.FormatType = formatTypeCSV
+	cfg.CSV.Delimiter = delimiter
+	require.NoError(t, cfg.Validate())
+	m, err := newCSVMarshaller(cfg)
+	require.NoError(t, err)
+	return m
+}
+
+// readCSV parses data written with delimiter, returning the header row and the data rows.
+func readCSV(t *testing.T, data []byte, delimiter rune) ([]string, [][]string) {
+	t.Helper()
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter
+	records, err := r.ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records, "the blob has a header row")
+	return records[0], records[1:]
+}
+
+// column returns the field of row in the column named name of header.
+func column(t *testing.T, header, row []string, name string) string {
+	t.Helper()
+	for i, h := range header {
+		if h == name {
+			return row[i]
+		}
+	}
+	require.Failf(t, "missing column", "no column %s in %v", name, header)
+	return ""
+}
+
+func TestCSVMarshallerHeader(t *testing.T) {
+	m := newTestCSVMarshaller(t, "")
+
+	traces, err := m.MarshalTraces(newTestSpans(1))
+	require.NoError(t, err)
+	header, _ := readCSV(t, traces, ',')
+	assert.Equal(t, csvSpanHeader, header)
+
+	logs, err := m.MarshalLogs(newTestLogs(1))
+	require.NoError(t, err)
+	header, _ = readCSV(t, logs, ',')
+	assert.Equal(t, csvLogHeader, header)
+
+	metrics, err := m.MarshalMetrics(newTestMetrics(1))
+	require.NoError(t, err)
+	header, _ = readCSV(t, metrics, ',')
+	assert.Equal(t, csvMetricHeader, header)
+}
+
+func TestCSVMarshallerRowCounts(t *testing.T) {
+	m := newTestCSVMarshaller(t, "")
+
+	traces, err := m.MarshalTraces(newTestSpans(7))
+	require.NoError(t, err)
+	_, rows := readCSV(t, traces, ',')
+	assert.Len(t, rows, 7, "a row per span")
+
+	logs, err := m.MarshalLogs(newTestLogs(1, 2, 3))
+	require.NoError(t, err)
+	_, rows = readCSV(t, logs, ',')
+	assert.Len(t, rows, 3, "a row per log record")
+
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	gauge := metrics.AppendEmpty()
+	gauge.SetName("queue.depth")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(3)
+	gauge.Gauge().DataPoints().AppendEmpty().SetIntValue(5)
+	histogram := metrics.AppendEmpty()
+	histogram.SetName("latency")
+	dp := histogram.SetEmptyHistogram().DataPoints().AppendEmpty()
+	dp.SetCount(4)
+	dp.BucketCounts().FromRaw([]uint64{1, 3})
+	dp.ExplicitBounds().FromRaw([]float64{0.5})
+	data, err := m.MarshalMetrics(md)
+	require.NoError(t, err)
+	header, rows := readCSV(t, data, ',')
+	require.Len(t, rows, 3, "a row per data point")
+	assert.Equal(t, "5", column(t, header, rows[1], "value"))
+	assert.Equal(t, "4", column(t, header, rows[2], "count"))
+	assert.Equal(t, "[1,3]", column(t, header, rows[2], "bucket_counts"))
+	assert.Equal(t, "[0.5]", column(t, header, rows[2], "explicit_bounds"))
+}
+
+func TestCSVMarshallerEmpty(t *testing.T) {
+	m := newTestCSVMarshaller(t, "")
+
+	data, err := m.MarshalLogs(plog.NewLogs())
+	require.NoError(t, err)
+	assert.Empty(t, data, "a batch without rows has no header row either")
+}
+
+func TestCSVMarshallerEscaping(t *testing.T) {
+	values := []string{
+		"plain",
+		"with, comma",
+		"with; semicolon",
+		"with \"quotes\"",
+		"multi\nline",
+		"carriage\r\nreturn",
+	}
+	for _, delimiter := range []string{"", ";", "\t", "|"} {
+		t.Run(strconv.Quote(delimiter), func(t *testing.T) {
+			m := newTestCSVMarshaller(t, delimiter)
+			comma := ','
+			if delimiter != "" {
+				comma = []rune(delimiter)[0]
+			}
+			ld := plog.NewLogs()
+			rl := ld.ResourceLogs().AppendEmpty()
+			rl.Resource().Attributes().PutStr("service.name", "check, out")
+			records := rl.ScopeLogs().AppendEmpty().LogRecords()
+			for _, value := range values {
+				record := records.AppendEmpty()
+				record.Body().SetStr(value)
+				record.Attributes().PutStr("value", value)
+			}
+
+			data, err := m.MarshalLogs(ld)
+			require.NoError(t, err)
+			header, rows := readCSV(t, data, comma)
+			require.Len(t, rows, len(values))
+			for i, value := range values {
+				row := rows[i]
+				require.Len(t, row, len(csvLogHeader))
+				// encoding/csv reads \r\n inside quoted fields back as \n
+				assert.Equal(t, strings.ReplaceAll(value, "\r\n", "\n"), column(t, header, row, "body"))
+				assert.JSONEq(t, `{"service.name":"check, out"}`, column(t, header, row, "resource_attributes"))
+			}
+			assert.Contains(t, string(data), `"with ""quotes"""`, "quotes are doubled inside quoted fields")
+		})
+	}
+}
+
+func TestCSVDelimiterValidate(t *testing.T) {
+	tests := []struct {
+		delimiter string
+		wantErr   bool
+	}{
+		{delimiter: ""},
+		{delimiter: ";"},
+		{delimiter: "\t"},
+		{delimiter: ",,", wantErr: true},
+		{delimiter: `"`, wantErr: true},
+		{delimiter: "\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Quote(tt.delimiter), func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.FormatType = formatTypeCSV
+			cfg.CSV.Delimiter = tt.delimiter
+			err := cfg.Validate()
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, "invalid csv.delimiter")
+		})
+	}
+}
+
+func TestCSVBlob(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.FormatType = formatTypeCSV
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1, 2)))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	assert.Regexp(t, `logs_\d{2}_\d{2}_\d{2}\.csv_\d+$`, uploads[0].blob, "the blob gets the csv extension")
+	_, rows := readCSV(t, uploads[0].data, ',')
+	assert.Len(t, rows, 2)
+}
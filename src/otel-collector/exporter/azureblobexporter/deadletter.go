@@ -0,0 +1,147 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// deadLetterSidecarSuffix is appended to the file name of a payload for its metadata file.
+const deadLetterSidecarSuffix = ".json"
+
+// deadLetterEntry is the sidecar metadata of a dead lettered payload, holding what is needed to
+// replay the upload.
+type deadLetterEntry struct {
+	Container  string            `json:"container"`
+	Blob       string            `json:"blob"`
+	Signal     string            `json:"signal"`
+	Format     string            `json:"format"`
+	Compressed bool              `json:"compressed"`
+	AppendBlob bool              `json:"append_blob"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Size       int               `json:"size"`
+	FailedAt   time.Time         `json:"failed_at"`
+	Error      string            `json:"error"`
+}
+
+// deadLetter writes payloads whose upload finally failed to a local directory, removing the oldest
+// payloads once the directory would grow beyond max_bytes.
+type deadLetter struct {
+	directory string
+	maxBytes  int64
+	signal    pipeline.Signal
+
+	mu   sync.Mutex
+	next uint64
+}
+
+func newDeadLetter(directory string, maxBytes int64, signal pipeline.Signal) (*deadLetter, error) {
+	if err := os.MkdirAll(directory, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+	return &deadLetter{directory: directory, maxBytes: maxBytes, signal: signal}, nil
+}
+
+// write stores data and its sidecar. The payload is written first, so a sidecar only exists for a
+// complete payload.
+func (d *deadLetter) write(data []byte, entry deadLetterEntry) (string, error) {
+	sidecar, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode dead letter metadata: %w", err)
+	}
+	size := int64(len(data) + len(sidecar))
+	if size > d.maxBytes {
+		return "", fmt.Errorf("payload of %d bytes exceeds dead_letter.max_bytes", size)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err = d.makeRoom(size); err != nil {
+		return "", err
+	}
+
+	// Names sort by the time they were written, which makeRoom relies on
+	name := fmt.Sprintf("%s-%s-%06d", entry.FailedAt.UTC().Format("20060102T150405.000000000Z"), d.signal, d.next)
+	d.next++
+	path := filepath.Join(d.directory, name)
+	if err = writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("failed to write dead letter payload: %w", err)
+	}
+	if err = writeFileAtomic(path+deadLetterSidecarSuffix, sidecar); err != nil {
+		return "", errors.Join(fmt.Errorf("failed to write dead letter metadata: %w", err), os.Remove(path))
+	}
+	return path, nil
+}
+
+// makeRoom removes the oldest payloads and their sidecars until size more bytes fit into the
+// directory. d.mu must be held. Files removed concurrently by the exporters of other signals sharing
+// the directory are skipped.
+func (d *deadLetter) makeRoom(size int64) error {
+	entries, err := os.ReadDir(d.directory)
+	if err != nil {
+		return fmt.Errorf("failed to read dead letter directory: %w", err)
+	}
+
+	var total int64
+	sizes := map[string]int64{}
+	var payloads []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dead letter directory: %w", err)
+		}
+		total += info.Size()
+		payload := strings.TrimSuffix(entry.Name(), deadLetterSidecarSuffix)
+		if _, ok := sizes[payload]; !ok {
+			payloads = append(payloads, payload)
+		}
+		sizes[payload] += info.Size()
+	}
+
+	slices.Sort(payloads)
+	for _, payload := range payloads {
+		if total+size <= d.maxBytes {
+			break
+		}
+		for _, name := range []string{payload + deadLetterSidecarSuffix, payload} {
+			if err := os.Remove(filepath.Join(d.directory, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to rotate dead letter directory: %w", err)
+			}
+		}
+		total -= sizes[payload]
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file that is renamed to path, so readers never see a
+// partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+var errTestUpload = errors.New("upload failed")
+
+// failContainer makes every upload to container fail.
+func failContainer(container string) func(string, string) error {
+	return func(c, _ string) error {
+		if c == container {
+			return errTestUpload
+		}
+		return nil
+	}
+}
+
+func TestDeadLetterDirectory(t *testing.T) {
+	dir := t.TempDir()
+	cfg := newTestConfig()
+	cfg.DeadLetter.Directory = dir
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+	client.fail = failContainer("traces")
+
+	require.NoError(t, exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{"service.name": "svc"})))
+
+	payload, entry := readDeadLetter(t, dir)
+	assert.Contains(t, string(payload), `"svc"`)
+	assert.Equal(t, "traces", entry.Container)
+	assert.Equal(t, "traces", entry.Signal)
+	assert.Equal(t, formatTypeJSON, entry.Format)
+	assert.Contains(t, entry.Error, errTestUpload.Error())
+}
+
+func TestDeadLetterPerSignalDestinations(t *testing.T) {
+	dir := t.TempDir()
+	logsDir := filepath.Join(t.TempDir(), "logs")
+	cfg := newTestConfig()
+	cfg.DeadLetter.Directory = dir
+	cfg.DeadLetter.Logs = DeadLetterDestination{Path: logsDir}
+	cfg.DeadLetter.Metrics = DeadLetterDestination{Drop: true}
+	cfg.DeadLetter.Traces = DeadLetterDestination{Container: "dead-traces"}
+
+	t.Run("logs to their path", func(t *testing.T) {
+		exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+		client.fail = failContainer("logs")
+
+		require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+
+		_, entry := readDeadLetter(t, logsDir)
+		assert.Equal(t, "logs", entry.Signal)
+		assert.Empty(t, readDir(t, dir), "directory is replaced by the logs path")
+	})
+
+	t.Run("metrics dropped", func(t *testing.T) {
+		exp, client := newTestExporter(t, cfg, pipeline.SignalMetrics)
+		client.fail = failContainer("metrics")
+
+		require.NoError(t, exp.ConsumeMetrics(context.Background(), newTestMetrics()))
+
+		assert.Empty(t, client.recorded())
+		assert.Empty(t, readDir(t, dir))
+	})
+
+	t.Run("traces to their container", func(t *testing.T) {
+		exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+		client.fail = failContainer("traces")
+
+		require.NoError(t, exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{"service.name": "svc"})))
+
+		uploads := client.recorded()
+		require.Len(t, uploads, 2)
+		assert.Equal(t, "dead-traces", uploads[0].container)
+		assert.Contains(t, string(uploads[0].data), `"svc"`)
+		assert.Equal(t, "dead-traces", uploads[1].container)
+		assert.Equal(t, uploads[0].blob+deadLetterSidecarSuffix, uploads[1].blob)
+		var entry deadLetterEntry
+		require.NoError(t, json.Unmarshal(uploads[1].data, &entry))
+		assert.Equal(t, "traces", entry.Container)
+		assert.Equal(t, uploads[0].blob, entry.Blob)
+		assert.Empty(t, readDir(t, dir))
+	})
+}
+
+func TestDeadLetterContainerFailure(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.DeadLetter.Traces = DeadLetterDestination{Container: "dead-traces"}
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+	client.fail = func(string, string) error { return errTestUpload }
+
+	err := exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{}))
+	assert.ErrorIs(t, err, errTestUpload)
+}
+
+func TestDeadLetterWithoutDestination(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.DeadLetter.Metrics = DeadLetterDestination{Drop: true}
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+	client.fail = failContainer("logs")
+
+	assert.ErrorIs(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)), errTestUpload)
+}
+
+func TestDeadLetterValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		deadLetter DeadLetter
+		wantErr    string
+	}{
+		{
+			name:       "disabled",
+			deadLetter: DeadLetter{},
+		},
+		{
+			name:       "per signal",
+			deadLetter: DeadLetter{MaxBytes: 1, Logs: DeadLetterDestination{Path: "/tmp/logs"}, Metrics: DeadLetterDestination{Drop: true}, Traces: DeadLetterDestination{Container: "dead-traces"}},
+		},
+		{
+			name:       "several destinations",
+			deadLetter: DeadLetter{Logs: DeadLetterDestination{Container: "dead-logs", Drop: true}},
+			wantErr:    "dead_letter.logs must set only one of container, path and drop",
+		},
+		{
+			name:       "invalid container",
+			deadLetter: DeadLetter{Traces: DeadLetterDestination{Container: "Dead_Traces"}},
+			wantErr:    `dead_letter.traces.container "Dead_Traces" is not a valid container name`,
+		},
+		{
+			name:       "path without max_bytes",
+			deadLetter: DeadLetter{Metrics: DeadLetterDestination{Path: "/tmp/metrics"}},
+			wantErr:    "dead_letter.max_bytes must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.deadLetter.validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// readDeadLetter returns the only payload of a dead letter directory and its sidecar.
+func readDeadLetter(t *testing.T, dir string) ([]byte, deadLetterEntry) {
+	t.Helper()
+	names := readDir(t, dir)
+	require.Len(t, names, 2)
+	payload, err := os.ReadFile(filepath.Join(dir, names[0]))
+	require.NoError(t, err)
+	sidecar, err := os.ReadFile(filepath.Join(dir, names[0]+deadLetterSidecarSuffix))
+	require.NoError(t, err)
+	var entry deadLetterEntry
+	require.NoError(t, json.Unmarshal(sidecar, &entry))
+	return payload, entry
+}
+
+// readDir returns the sorted file names of dir, nil when it does not exist.
+func readDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
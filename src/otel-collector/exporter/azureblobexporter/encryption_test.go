@@ -0,0 +1,188 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// consumeSignal exports a batch of signal to exp.
+func consumeSignal(t *testing.T, exp *azureBlobExporter, signal pipeline.Signal) {
+	t.Helper()
+	switch signal {
+	case pipeline.SignalTraces:
+		require.NoError(t, exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{"tenant": "acme"})))
+	case pipeline.SignalLogs:
+		require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+	case pipeline.SignalMetrics:
+		require.NoError(t, exp.ConsumeMetrics(context.Background(), newTestMetrics(1)))
+	}
+}
+
+// uploadEncryption returns the customer-provided key and encryption scope of upload, whether it is a
+// block blob upload or an append.
+func uploadEncryption(upload mockUpload) (*blob.CPKInfo, *blob.CPKScopeInfo) {
+	if upload.appendOpts != nil {
+		return upload.appendOpts.CPKInfo, upload.appendOpts.CPKScopeInfo
+	}
+	return upload.opts.CPKInfo, upload.opts.CPKScopeInfo
+}
+
+func TestEncryptionScope(t *testing.T) {
+	for _, appendBlob := range []bool{false, true} {
+		for _, signal := range []pipeline.Signal{pipeline.SignalTraces, pipeline.SignalLogs, pipeline.SignalMetrics} {
+			name := signal.String()
+			if appendBlob {
+				name += "/append"
+			}
+			t.Run(name, func(t *testing.T) {
+				cfg := newTestConfig()
+				cfg.EncryptionScope = "{{ .Signal }}-scope"
+				cfg.AppendBlob.Enabled = appendBlob
+				exp, client := newTestExporter(t, cfg, signal)
+
+				consumeSignal(t, exp, signal)
+
+				uploads := client.recorded()
+				require.Len(t, uploads, 1)
+				assert.Equal(t, appendBlob, uploads[0].appendOpts != nil)
+				cpk, scope := uploadEncryption(uploads[0])
+				assert.Nil(t, cpk)
+				require.NotNil(t, scope)
+				assert.Equal(t, signal.String()+"-scope", *scope.EncryptionScope)
+			})
+		}
+	}
+}
+
+func TestEncryptionScopeUnset(t *testing.T) {
+	exp, client := newTestExporter(t, newTestConfig(), pipeline.SignalLogs)
+
+	consumeSignal(t, exp, pipeline.SignalLogs)
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	_, scope := uploadEncryption(uploads[0])
+	assert.Nil(t, scope)
+}
+
+func TestEncryptionScopeValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{
+			name:      "static scope",
+			configure: func(cfg *Config) { cfg.EncryptionScope = "confidential" },
+		},
+		{
+			name:      "invalid template",
+			configure: func(cfg *Config) { cfg.EncryptionScope = "{{ .Signal" },
+			wantErr:   "failed to parse encryption_scope",
+		},
+		{
+			name:      "unknown field",
+			configure: func(cfg *Config) { cfg.EncryptionScope = "{{ .Tenant }}" },
+			wantErr:   "failed to execute encryption_scope",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tt.configure(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// testCPKKey is a customer-provided key of the required 32 bytes.
+var testCPKKey = bytes.Repeat([]byte{7}, 32)
+
+func TestCPK(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(testCPKKey)
+	sum := sha256.Sum256(testCPKKey)
+	hash := base64.StdEncoding.EncodeToString(sum[:])
+
+	for _, appendBlob := range []bool{false, true} {
+		t.Run(fmt.Sprintf("append %t", appendBlob), func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.CPK = CPK{Key: key}
+			cfg.AppendBlob.Enabled = appendBlob
+			exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+			consumeSignal(t, exp, pipeline.SignalLogs)
+
+			uploads := client.recorded()
+			require.Len(t, uploads, 1)
+			assert.Equal(t, appendBlob, uploads[0].appendOpts != nil)
+			cpk, scope := uploadEncryption(uploads[0])
+			assert.Nil(t, scope)
+			require.NotNil(t, cpk)
+			assert.Equal(t, key, *cpk.EncryptionKey)
+			assert.Equal(t, hash, *cpk.EncryptionKeySHA256, "the hash is computed from the key")
+			assert.Equal(t, blob.EncryptionAlgorithmTypeAES256, *cpk.EncryptionAlgorithm)
+		})
+	}
+}
+
+func TestCPKValidate(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(testCPKKey)
+	sum := sha256.Sum256(testCPKKey)
+	tests := []struct {
+		name    string
+		cpk     CPK
+		scope   string
+		wantErr string
+	}{
+		{name: "key", cpk: CPK{Key: key}},
+		{name: "key and hash", cpk: CPK{Key: key, KeySHA256: base64.StdEncoding.EncodeToString(sum[:])}},
+		{
+			name:    "short key",
+			cpk:     CPK{Key: base64.StdEncoding.EncodeToString(testCPKKey[:16])},
+			wantErr: "cpk.key must be a 256-bit key of 32 bytes, got 16 bytes",
+		},
+		{name: "not base64", cpk: CPK{Key: "not base64!"}, wantErr: "cpk.key must be base64 encoded"},
+		{
+			name:    "wrong hash",
+			cpk:     CPK{Key: key, KeySHA256: base64.StdEncoding.EncodeToString(make([]byte, 32))},
+			wantErr: "cpk.key_sha256 does not match the SHA-256 hash of cpk.key",
+		},
+		{name: "hash without key", cpk: CPK{KeySHA256: "hash"}, wantErr: "cpk.key_sha256 requires cpk.key"},
+		{
+			name:    "with encryption scope",
+			cpk:     CPK{Key: key},
+			scope:   "confidential",
+			wantErr: "encryption_scope cannot be combined with cpk",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.CPK = tt.cpk
+			cfg.EncryptionScope = tt.scope
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
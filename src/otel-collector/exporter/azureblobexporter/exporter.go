@@ -5,18 +5,34 @@ package azureblobexporter
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand/v2"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azdatalake/datalakeerror"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/pdata/pcommon"
@@ -24,16 +40,55 @@ import (
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 )
 
 type azureBlobExporter struct {
 	config           *Config
+	id               component.ID
 	logger           *zap.Logger
+	telemetry        *exporterTelemetry
 	client           azblobClient
 	signal           pipeline.Signal
-	marshaller       marshaller
+	marshallers      []marshaller // one per format, the primary format first
+	rawMarshaller    marshaller
 	blobNameTemplate *blobNameTemplate
+	watermark        *watermark
+	deadLetter       *deadLetter // the local dead letter directory of the signal, nil without one
+	batcher          *batcher
+	containers       *containerCache
+	appendRoller     *appendRoller
+	failedWrites     *failedWrites
+
+	// orderMu serializes writes when preserve_order is enabled, and guards nextSequence.
+	orderMu      sync.Mutex
+	nextSequence int64
+
+	// blobLocation is the time zone of blob name times.
+	blobLocation *time.Location
+
+	// deadLetterDest is where the failed uploads of the signal go.
+	deadLetterDest DeadLetterDestination
+
+	// containerTemplate renders the container of every batch, nil when the container of the signal is static.
+	containerTemplate *template.Template
+
+	// cpk is the customer-provided key of all blob writes, nil without one.
+	cpk *blob.CPKInfo
+	// cpkScope is the encryption scope of all blob writes, nil without one.
+	cpkScope *blob.CPKScopeInfo
+
+	// uploadSlots holds a token for every upload in flight when max_concurrent_uploads is set.
+	uploadSlots chan struct{}
+
+	// appendUnsupported is set once the account rejected append blobs and uploads fell back to block blobs.
+	appendUnsupported atomic.Bool
+
+	// retriesAborted is closed shutdown_retry_timeout after shutdown began, and stops pending retries.
+	retriesAborted chan struct{}
+	abortRetries   sync.Once
 }
 
 type blobNameTemplate struct {
@@ -51,30 +106,30 @@ func getAttrStandalone(attrs pcommon.Map, key string) any {
 
 var tempFuncs = template.FuncMap{
 	"getResourceSpanAttr": func(traces ptrace.Traces, rmIndex int, key string) any {
-		if traces.ResourceSpans().Len() > 0 {
+		if inRange(rmIndex, traces.ResourceSpans().Len()) {
 			rs := traces.ResourceSpans().At(rmIndex)
 			return getAttrStandalone(rs.Resource().Attributes(), key)
 		}
 		return nil
 	},
 	"getResourceMetricAttr": func(metrics pmetric.Metrics, rmIndex int, key string) any {
-		if metrics.ResourceMetrics().Len() > 0 {
+		if inRange(rmIndex, metrics.ResourceMetrics().Len()) {
 			rm := metrics.ResourceMetrics().At(rmIndex)
 			return getAttrStandalone(rm.Resource().Attributes(), key)
 		}
 		return nil
 	},
 	"getResourceLogAttr": func(logs plog.Logs, rlIndex int, key string) any {
-		if logs.ResourceLogs().Len() > 0 {
+		if inRange(rlIndex, logs.ResourceLogs().Len()) {
 			rl := logs.ResourceLogs().At(rlIndex)
 			return getAttrStandalone(rl.Resource().Attributes(), key)
 		}
 		return nil
 	},
 	"getScopeSpanAttr": func(traces ptrace.Traces, rmIndex, ilsIndex int, key string) any {
-		if traces.ResourceSpans().Len() > 0 {
+		if inRange(rmIndex, traces.ResourceSpans().Len()) {
 			rs := traces.ResourceSpans().At(rmIndex)
-			if rs.ScopeSpans().Len() > 0 {
+			if inRange(ilsIndex, rs.ScopeSpans().Len()) {
 				ils := rs.ScopeSpans().At(ilsIndex)
 				return getAttrStandalone(ils.Scope().Attributes(), key)
 			}
@@ -82,9 +137,9 @@ var tempFuncs = template.FuncMap{
 		return nil
 	},
 	"getScopeMetricAttr": func(metrics pmetric.Metrics, rmIndex, ilmIndex int, key string) any {
-		if metrics.ResourceMetrics().Len() > 0 {
+		if inRange(rmIndex, metrics.ResourceMetrics().Len()) {
 			rm := metrics.ResourceMetrics().At(rmIndex)
-			if rm.ScopeMetrics().Len() > 0 {
+			if inRange(ilmIndex, rm.ScopeMetrics().Len()) {
 				ilm := rm.ScopeMetrics().At(ilmIndex)
 				return getAttrStandalone(ilm.Scope().Attributes(), key)
 			}
@@ -92,61 +147,224 @@ var tempFuncs = template.FuncMap{
 		return nil
 	},
 	"getScopeLogAttr": func(logs plog.Logs, rlIndex, ilsIndex int, key string) any {
-		if logs.ResourceLogs().Len() > 0 {
+		if inRange(rlIndex, logs.ResourceLogs().Len()) {
 			rl := logs.ResourceLogs().At(rlIndex)
-			if rl.ScopeLogs().Len() > 0 {
+			if inRange(ilsIndex, rl.ScopeLogs().Len()) {
 				ils := rl.ScopeLogs().At(ilsIndex)
 				return getAttrStandalone(ils.Scope().Attributes(), key)
 			}
 		}
 		return nil
 	},
-	"getMetric": func(metrics pmetric.Metrics, rmIndex, ilmIndex, metricIndex int) any {
-		if metrics.ResourceMetrics().Len() > 0 {
-			rm := metrics.ResourceMetrics().At(rmIndex)
-			if rm.ScopeMetrics().Len() > 0 {
-				ilm := rm.ScopeMetrics().At(ilmIndex)
-				if ilm.Metrics().Len() > 0 {
-					return ilm.Metrics().At(metricIndex)
-				}
-			}
+	// getMetric, getSpan and getLogRecord fail the template for out of range indices, so the default
+	// blob name is used instead of a name with blanks. hasMetric, hasSpan and hasLogRecord report
+	// whether the indices are in range, to guard them with if.
+	"getMetric": func(metrics pmetric.Metrics, rmIndex, ilmIndex, metricIndex int) (any, error) {
+		if metric, ok := metricAt(metrics, rmIndex, ilmIndex, metricIndex); ok {
+			return metric, nil
 		}
-		return pmetric.Metric{}
+		return nil, fmt.Errorf("no metric at index %d/%d/%d", rmIndex, ilmIndex, metricIndex)
 	},
-	"getSpan": func(traces ptrace.Traces, rmIndex, ilsIndex, spanIndex int) any {
-		if traces.ResourceSpans().Len() > 0 {
-			rs := traces.ResourceSpans().At(rmIndex)
-			if rs.ScopeSpans().Len() > 0 {
-				ils := rs.ScopeSpans().At(ilsIndex)
-				if ils.Spans().Len() > 0 {
-					return ils.Spans().At(spanIndex)
-				}
-			}
+	"hasMetric": func(metrics pmetric.Metrics, rmIndex, ilmIndex, metricIndex int) bool {
+		_, ok := metricAt(metrics, rmIndex, ilmIndex, metricIndex)
+		return ok
+	},
+	"getSpan": func(traces ptrace.Traces, rmIndex, ilsIndex, spanIndex int) (any, error) {
+		if span, ok := spanAt(traces, rmIndex, ilsIndex, spanIndex); ok {
+			return span, nil
 		}
-		return ptrace.Span{}
+		return nil, fmt.Errorf("no span at index %d/%d/%d", rmIndex, ilsIndex, spanIndex)
 	},
-	"getLogRecord": func(logs plog.Logs, rlIndex, ilsIndex, logIndex int) any {
-		if logs.ResourceLogs().Len() > 0 {
-			rl := logs.ResourceLogs().At(rlIndex)
-			if rl.ScopeLogs().Len() > 0 {
-				ils := rl.ScopeLogs().At(ilsIndex)
-				if ils.LogRecords().Len() > 0 {
-					return ils.LogRecords().At(logIndex)
-				}
-			}
+	"hasSpan": func(traces ptrace.Traces, rmIndex, ilsIndex, spanIndex int) bool {
+		_, ok := spanAt(traces, rmIndex, ilsIndex, spanIndex)
+		return ok
+	},
+	"getLogRecord": func(logs plog.Logs, rlIndex, ilsIndex, logIndex int) (any, error) {
+		if logRecord, ok := logRecordAt(logs, rlIndex, ilsIndex, logIndex); ok {
+			return logRecord, nil
 		}
-		return plog.LogRecord{}
+		return nil, fmt.Errorf("no log record at index %d/%d/%d", rlIndex, ilsIndex, logIndex)
+	},
+	"hasLogRecord": func(logs plog.Logs, rlIndex, ilsIndex, logIndex int) bool {
+		_, ok := logRecordAt(logs, rlIndex, ilsIndex, logIndex)
+		return ok
 	},
 }
 
+// defaultBlobNameFormat returns the default name format of the blobs of signal, which names the blobs
+// whose name template fails rather than the unrendered template.
+func defaultBlobNameFormat(signal pipeline.Signal) string {
+	defaults := createDefaultConfig().(*Config).BlobNameFormat
+	switch signal {
+	case pipeline.SignalMetrics:
+		return defaults.MetricsFormat
+	case pipeline.SignalLogs:
+		return defaults.LogsFormat
+	default:
+		return defaults.TracesFormat
+	}
+}
+
+// inRange reports whether index is a valid index of a slice of length n.
+func inRange(index, n int) bool {
+	return index >= 0 && index < n
+}
+
+// metricAt returns the metric at the given indices, and false when any index is out of range.
+func metricAt(metrics pmetric.Metrics, rmIndex, ilmIndex, metricIndex int) (pmetric.Metric, bool) {
+	if !inRange(rmIndex, metrics.ResourceMetrics().Len()) {
+		return pmetric.Metric{}, false
+	}
+	rm := metrics.ResourceMetrics().At(rmIndex)
+	if !inRange(ilmIndex, rm.ScopeMetrics().Len()) {
+		return pmetric.Metric{}, false
+	}
+	ilm := rm.ScopeMetrics().At(ilmIndex)
+	if !inRange(metricIndex, ilm.Metrics().Len()) {
+		return pmetric.Metric{}, false
+	}
+	return ilm.Metrics().At(metricIndex), true
+}
+
+// spanAt returns the span at the given indices, and false when any index is out of range.
+func spanAt(traces ptrace.Traces, rsIndex, ilsIndex, spanIndex int) (ptrace.Span, bool) {
+	if !inRange(rsIndex, traces.ResourceSpans().Len()) {
+		return ptrace.Span{}, false
+	}
+	rs := traces.ResourceSpans().At(rsIndex)
+	if !inRange(ilsIndex, rs.ScopeSpans().Len()) {
+		return ptrace.Span{}, false
+	}
+	ils := rs.ScopeSpans().At(ilsIndex)
+	if !inRange(spanIndex, ils.Spans().Len()) {
+		return ptrace.Span{}, false
+	}
+	return ils.Spans().At(spanIndex), true
+}
+
+// logRecordAt returns the log record at the given indices, and false when any index is out of range.
+func logRecordAt(logs plog.Logs, rlIndex, ilsIndex, logIndex int) (plog.LogRecord, bool) {
+	if !inRange(rlIndex, logs.ResourceLogs().Len()) {
+		return plog.LogRecord{}, false
+	}
+	rl := logs.ResourceLogs().At(rlIndex)
+	if !inRange(ilsIndex, rl.ScopeLogs().Len()) {
+		return plog.LogRecord{}, false
+	}
+	ils := rl.ScopeLogs().At(ilsIndex)
+	if !inRange(logIndex, ils.LogRecords().Len()) {
+		return plog.LogRecord{}, false
+	}
+	return ils.LogRecords().At(logIndex), true
+}
+
+// Markers rendered by the rowCount and schemaHash template functions. Their values are only known
+// for the marshalled blob and contain digits, which the time layout applied to the rendered name would
+// replace. The markers are private use characters that are not part of any layout, and are substituted
+// once the name is formatted.
+const (
+	rowCountMarker   = "\uE000"
+	schemaHashMarker = "\uE001"
+)
+
+// batchFuncs returns the template functions bound to the telemetry of a single batch. They are
+// registered with a nil batch when the templates are parsed and rebound on a clone of the parsed
+// template for every execution, so concurrent consume calls never share per-batch state.
+func batchFuncs(telemetryData any, partitionPath string, byAttribute PartitionByAttribute, byService PartitionByService) template.FuncMap {
+	return template.FuncMap{
+		"partition": func() string {
+			value, _ := bodyPartition(telemetryData, partitionPath)
+			return value
+		},
+		"attributePartition": func() string {
+			return attributePartition(telemetryData, byAttribute)
+		},
+		"servicePartition": func() string {
+			return batchServicePartition(telemetryData, byService)
+		},
+		"resourceAttr": func(key string) any {
+			if attrs, ok := firstResourceAttributes(telemetryData); ok {
+				return getAttrStandalone(attrs, key)
+			}
+			return nil
+		},
+		"recordCount": func() int {
+			return recordCount(telemetryData)
+		},
+		"rowCount": func() string {
+			return rowCountMarker
+		},
+		"schemaHash": func() string {
+			return schemaHashMarker
+		},
+	}
+}
+
+// firstResourceAttributes returns the attributes of the first resource in the batch.
+func firstResourceAttributes(telemetryData any) (pcommon.Map, bool) {
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		if td.ResourceSpans().Len() > 0 {
+			return td.ResourceSpans().At(0).Resource().Attributes(), true
+		}
+	case plog.Logs:
+		if td.ResourceLogs().Len() > 0 {
+			return td.ResourceLogs().At(0).Resource().Attributes(), true
+		}
+	case pmetric.Metrics:
+		if td.ResourceMetrics().Len() > 0 {
+			return td.ResourceMetrics().At(0).Resource().Attributes(), true
+		}
+	}
+	return pcommon.Map{}, false
+}
+
+// recordCount returns the number of spans, log records or metric data points in the batch.
+func recordCount(telemetryData any) int {
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		return td.SpanCount()
+	case plog.Logs:
+		return td.LogRecordCount()
+	case pmetric.Metrics:
+		return td.DataPointCount()
+	}
+	return 0
+}
+
+func parseBlobNameTemplate(name, format string) (*template.Template, error) {
+	return template.New(name).Funcs(tempFuncs).Funcs(batchFuncs(nil, "", PartitionByAttribute{}, PartitionByService{})).Parse(format)
+}
+
+// executeBlobNameTemplate renders tmpl for a single batch. The parsed template is cloned so the
+// per-batch functions can be bound without racing with other executions.
+func executeBlobNameTemplate(tmpl *template.Template, telemetryData any, partitionPath string, byAttribute PartitionByAttribute, byService PartitionByService) (string, error) {
+	batchTmpl, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	batchTmpl.Funcs(batchFuncs(telemetryData, partitionPath, byAttribute, byService))
+
+	var buf bytes.Buffer
+	if err := batchTmpl.Execute(&buf, telemetryData); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 type azblobClient interface {
 	UploadStream(ctx context.Context, containerName, blobName string, body io.Reader, o *azblob.UploadStreamOptions) (azblob.UploadStreamResponse, error)
 	URL() string
 	AppendBlock(ctx context.Context, containerName, blobName string, data []byte, o *appendblob.AppendBlockOptions) error
+	AppendBlobSize(ctx context.Context, containerName, blobName string) (int64, error)
+	CreateContainer(ctx context.Context, containerName string) error
+	ContainerExists(ctx context.Context, containerName string) (bool, error)
 }
 
 type azblobClientImpl struct {
 	client *azblob.Client
+	// cpk is the customer-provided key that reading the properties of an encrypted blob requires.
+	cpk *blob.CPKInfo
 }
 
 func (c *azblobClientImpl) UploadStream(ctx context.Context, containerName, blobName string, body io.Reader, o *azblob.UploadStreamOptions) (azblob.UploadStreamResponse, error) {
@@ -166,22 +384,90 @@ func (rsc *readSeekCloser) Close() error {
 	return nil
 }
 
+// AppendBlock appends data to the append blob, creating the blob on first use.
 func (c *azblobClientImpl) AppendBlock(ctx context.Context, containerName, blobName string, data []byte, o *appendblob.AppendBlockOptions) error {
 	appendBlobClient := c.client.ServiceClient().NewContainerClient(containerName).NewAppendBlobClient(blobName)
 
 	// Wrap bytes.Reader to implement io.ReadSeekCloser
-	reader := &readSeekCloser{Reader: bytes.NewReader(data)}
-	_, err := appendBlobClient.AppendBlock(ctx, reader, o)
+	_, err := appendBlobClient.AppendBlock(ctx, &readSeekCloser{Reader: bytes.NewReader(data)}, o)
+	if !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return err
+	}
+
+	// The If-None-Match condition keeps a concurrent writer that created the blob in the meantime
+	// from having it truncated.
+	anyETag := azcore.ETagAny
+	createOptions := &appendblob.CreateOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &anyETag},
+		},
+	}
+	if o != nil {
+		// The blob is created with the key or scope its blocks are appended with
+		createOptions.CPKInfo = o.CPKInfo
+		createOptions.CPKScopeInfo = o.CPKScopeInfo
+	}
+	_, err = appendBlobClient.Create(ctx, createOptions)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobAlreadyExists) {
+		return err
+	}
+	_, err = appendBlobClient.AppendBlock(ctx, &readSeekCloser{Reader: bytes.NewReader(data)}, o)
+	return err
+}
+
+// AppendBlobSize returns the size of the append blob, or 0 when it does not exist yet.
+func (c *azblobClientImpl) AppendBlobSize(ctx context.Context, containerName, blobName string) (int64, error) {
+	props, err := c.client.ServiceClient().NewContainerClient(containerName).NewAppendBlobClient(blobName).GetProperties(ctx, &blob.GetPropertiesOptions{CPKInfo: c.cpk})
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if props.ContentLength == nil {
+		return 0, nil
+	}
+	return *props.ContentLength, nil
+}
+
+// CreateContainer creates the container, treating a container that already exists as success.
+func (c *azblobClientImpl) CreateContainer(ctx context.Context, containerName string) error {
+	_, err := c.client.CreateContainer(ctx, containerName, nil)
+	if bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return nil
+	}
 	return err
 }
 
-func newAzureBlobExporter(config *Config, logger *zap.Logger, signal pipeline.Signal) *azureBlobExporter {
+// ContainerExists reports whether the container exists.
+func (c *azblobClientImpl) ContainerExists(ctx context.Context, containerName string) (bool, error) {
+	_, err := c.client.ServiceClient().NewContainerClient(containerName).GetProperties(ctx, nil)
+	if bloberror.HasCode(err, bloberror.ContainerNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func newAzureBlobExporter(config *Config, id component.ID, set component.TelemetrySettings, signal pipeline.Signal) (*azureBlobExporter, error) {
+	telemetry, err := newExporterTelemetry(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter telemetry: %w", err)
+	}
+
 	return &azureBlobExporter{
 		config:           config,
-		logger:           logger,
+		id:               id,
+		logger:           set.Logger,
+		telemetry:        telemetry,
 		signal:           signal,
 		blobNameTemplate: &blobNameTemplate{},
-	}
+		nextSequence:     1,
+		retriesAborted:   make(chan struct{}),
+		containers:       newContainerCache(config.ContainerCheck),
+		uploadSlots:      newUploadSlots(config.MaxConcurrentUploads),
+		appendRoller:     newAppendRoller(config.AppendBlob),
+		failedWrites:     newFailedWrites(),
+	}, nil
 }
 
 func randomInRange(low, hi int) int {
@@ -195,76 +481,278 @@ func newMarshaller(config *Config, host component.Host) (marshaller, error) {
 	case formatTypeProto:
 		return newProtoMarshaller(), nil
 	case formatTypeParquet:
-		return newParquetMarshaller(), nil
+		return newParquetMarshaller(config)
+	case formatTypeNDJSON:
+		return newNDJSONMarshaller(config.MetricNameNormalization.replacer()), nil
+	case formatTypeCSV:
+		return newCSVMarshaller(config)
+	case formatTypeAvro:
+		return newAvroMarshaller(config)
 	default:
 		return nil, fmt.Errorf("unsupported format type: %s", config.FormatType)
 	}
 }
 
-func (e *azureBlobExporter) start(_ context.Context, host component.Host) error {
+func (e *azureBlobExporter) start(ctx context.Context, host component.Host) error {
 	var err error
 
-	// create marshaller
-	e.marshaller, err = newMarshaller(e.config, host)
+	if name := e.config.Container.forSignal(e.signal); isContainerTemplate(name) {
+		if e.containerTemplate, err = parseBlobNameTemplate("container", name); err != nil {
+			return fmt.Errorf("failed to parse container template: %w", err)
+		}
+	} else if _, err = e.containerName(e.signal); err != nil {
+		// Fail on start rather than on every upload when the signal has no container
+		return err
+	}
+
+	if e.blobLocation, err = time.LoadLocation(e.config.BlobNameFormat.Timezone); err != nil {
+		return fmt.Errorf("invalid blob_name_format.timezone: %w", err)
+	}
+
+	// create a marshaller per format
+	formats := e.config.Formats
+	if len(formats) == 0 {
+		formats = []string{e.config.formatFor(e.signal)}
+	}
+	e.marshallers = make([]marshaller, len(formats))
+	for i, format := range formats {
+		formatConfig := *e.config
+		formatConfig.FormatType = format
+		if e.marshallers[i], err = newMarshaller(&formatConfig, host); err != nil {
+			return err
+		}
+	}
+	if e.config.AlsoWriteRawOTLP.Enabled {
+		e.rawMarshaller = newProtoMarshaller()
+	}
+
+	if slices.Contains(formats, formatTypeParquet) {
+		if err = e.checkParquetSchemaVersion(); err != nil {
+			return err
+		}
+	}
+
+	e.deadLetterDest = e.config.DeadLetter.forSignal(e.signal)
+	if e.deadLetterDest.Path != "" {
+		if e.deadLetter, err = newDeadLetter(e.deadLetterDest.Path, e.config.DeadLetter.MaxBytes, e.signal); err != nil {
+			return err
+		}
+	}
+
+	if e.config.Watermark.Enabled {
+		e.watermark, err = newWatermark(ctx, host, e.config.Watermark, e.id, e.signal)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ignored := e.config.Auth.ignoredFields(); len(ignored) > 0 {
+		e.logger.Warn("Authentication fields are not used by the auth type and are ignored",
+			zap.String("type", string(e.config.Auth.Type)),
+			zap.Strings("fields", ignored))
+	}
+
+	if e.cpk, err = e.config.CPK.info(); err != nil {
+		return err
+	}
+	scope, err := e.config.encryptionScope(e.signal)
 	if err != nil {
 		return err
 	}
+	if scope != "" {
+		e.cpkScope = &blob.CPKScopeInfo{EncryptionScope: &scope}
+	}
+
+	// create client based on auth type, retrying transient failures such as
+	// a managed identity endpoint that is not yet reachable during startup
+	azblobClient := &azblobClientImpl{cpk: e.cpk}
+	var cred azcore.TokenCredential
+	attempts := max(e.config.StartupRetry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		azblobClient.client, cred, err = e.newClient(ctx)
+		if err == nil {
+			break
+		}
+		if attempt >= attempts {
+			return err
+		}
+		e.logger.Warn("Failed to create Azure Blob client, retrying",
+			zap.Int("attempt", attempt),
+			zap.Int("max_attempts", attempts),
+			zap.Duration("interval", e.config.StartupRetry.Interval),
+			zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.config.StartupRetry.Interval):
+		}
+	}
 
-	// create client based on auth type
-	authType := e.config.Auth.Type
-	azblobClient := &azblobClientImpl{}
 	e.client = azblobClient
+	if e.config.ADLSGen2.Enabled {
+		datalake, err := e.newDatalakeClient(cred)
+		if err != nil {
+			return err
+		}
+		e.client = newADLSClient(azblobClient, datalake)
+	}
+
+	// Initialize blob name templates if template parsing is enabled
+	if e.config.BlobNameFormat.TemplateEnabled {
+		e.blobNameTemplate.metrics, err = parseBlobNameTemplate("metrics", e.config.BlobNameFormat.MetricsFormat)
+		if err != nil {
+			return fmt.Errorf("failed to parse metrics blob name template: %w", err)
+		}
+
+		e.blobNameTemplate.logs, err = parseBlobNameTemplate("logs", e.config.BlobNameFormat.LogsFormat)
+		if err != nil {
+			return fmt.Errorf("failed to parse logs blob name template: %w", err)
+		}
+
+		e.blobNameTemplate.traces, err = parseBlobNameTemplate("traces", e.config.BlobNameFormat.TracesFormat)
+		if err != nil {
+			return fmt.Errorf("failed to parse traces blob name template: %w", err)
+		}
+	}
+
+	if e.config.CreateContainerIfNotExists {
+		if err = e.createContainers(ctx); err != nil {
+			return err
+		}
+	}
+
+	if e.config.Batch.Enabled {
+		e.batcher = newBatcher(e.config.Batch, e.logger, e.writeBlob)
+		e.batcher.start()
+	} else if e.config.CompactOnShutdown.Enabled {
+		e.batcher = newCompactor(e.config.CompactOnShutdown, e.logger, e.writeBlob)
+	}
+
+	if e.config.WriteStartupConfig {
+		containerName, err := e.containerName(e.signal)
+		if err != nil {
+			return err
+		}
+		if err = e.writeStartupConfig(ctx, containerName); err != nil {
+			e.logger.Warn("Failed to write startup config", zap.String("container", containerName), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// shutdown writes the buffered batch and closes the watermark storage. Uploads still retrying
+// shutdown_retry_timeout after shutdown began give up.
+func (e *azureBlobExporter) shutdown(ctx context.Context) error {
+	if e.config.ShutdownRetryTimeout > 0 {
+		// The timer outlives shutdown, as consume calls may still be retrying after it returned.
+		time.AfterFunc(e.config.ShutdownRetryTimeout, func() {
+			e.abortRetries.Do(func() { close(e.retriesAborted) })
+		})
+	}
+
+	var errs error
+	if e.batcher != nil {
+		if err := e.batcher.shutdown(ctx); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("failed to flush batch: %w", err))
+		}
+	}
+	if e.watermark != nil {
+		errs = errors.Join(errs, e.watermark.close(ctx))
+	}
+	return errs
+}
+
+// checkParquetSchemaVersion refuses to start when the parquet schema version differs from the
+// version readers expect, unless the change was allowed.
+func (e *azureBlobExporter) checkParquetSchemaVersion() error {
+	expected := e.config.Parquet.ExpectedSchemaVersion
+	if expected == 0 || expected == parquetSchemaVersion {
+		return nil
+	}
+	if !e.config.Parquet.AllowSchemaChange {
+		return fmt.Errorf("parquet schema version %d differs from parquet.expected_schema_version %d, set parquet.allow_schema_change once readers support it",
+			parquetSchemaVersion, expected)
+	}
+	e.logger.Warn("Parquet schema version differs from the expected version",
+		zap.Int("schema_version", parquetSchemaVersion),
+		zap.Int("expected_schema_version", expected))
+	return nil
+}
+
+// newClient creates the Azure Blob client for the configured authentication type, and returns the
+// token credential it uses, which is nil for connection strings and shared access signatures.
+func (e *azureBlobExporter) newClient(ctx context.Context) (*azblob.Client, azcore.TokenCredential, error) {
+	var client *azblob.Client
+	var cred azcore.TokenCredential
+	clientOptions, err := e.config.ClientOptions.azblobOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	authType := e.config.Auth.Type
 	switch authType {
 	case ConnectionString:
-		azblobClient.client, err = azblob.NewClientFromConnectionString(e.config.Auth.ConnectionString, nil)
+		client, err = azblob.NewClientFromConnectionString(e.config.Auth.ConnectionString, clientOptions)
 		if err != nil {
-			return fmt.Errorf("failed to create client from connection string: %w", err)
+			return nil, nil, fmt.Errorf("failed to create client from connection string: %w", err)
 		}
 	case ServicePrincipal:
-		cred, err := azidentity.NewClientSecretCredential(
+		cred, err = azidentity.NewClientSecretCredential(
 			e.config.Auth.TenantID,
 			e.config.Auth.ClientID,
 			e.config.Auth.ClientSecret,
 			nil)
 		if err != nil {
-			return fmt.Errorf("failed to create service principal credential: %w", err)
+			return nil, nil, fmt.Errorf("failed to create service principal credential: %w", err)
 		}
-		azblobClient.client, err = azblob.NewClient(e.config.URL, cred, nil)
+		client, err = azblob.NewClient(e.config.URL, cred, clientOptions)
 		if err != nil {
-			return fmt.Errorf("failed to create client with service principal: %w", err)
+			return nil, nil, fmt.Errorf("failed to create client with service principal: %w", err)
 		}
 	case SystemManagedIdentity:
-		cred, err := azidentity.NewManagedIdentityCredential(nil)
+		cred, err = azidentity.NewManagedIdentityCredential(nil)
 		if err != nil {
-			return fmt.Errorf("failed to create system managed identity credential: %w", err)
+			return nil, nil, fmt.Errorf("failed to create system managed identity credential: %w", err)
 		}
-		azblobClient.client, err = azblob.NewClient(e.config.URL, cred, nil)
+		client, err = azblob.NewClient(e.config.URL, cred, clientOptions)
 		if err != nil {
-			return fmt.Errorf("failed to create client with system managed identity: %w", err)
+			return nil, nil, fmt.Errorf("failed to create client with system managed identity: %w", err)
 		}
 	case UserManagedIdentity:
-		cred, err := azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
-			ID: azidentity.ClientID(e.config.Auth.ClientID),
-		})
+		var id azidentity.ManagedIDKind = azidentity.ClientID(e.config.Auth.ClientID)
+		if e.config.Auth.ResourceID != "" {
+			id = azidentity.ResourceID(e.config.Auth.ResourceID)
+		}
+		cred, err = azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{ID: id})
 		if err != nil {
-			return fmt.Errorf("failed to create user managed identity credential: %w", err)
+			return nil, nil, fmt.Errorf("failed to create user managed identity credential: %w", err)
 		}
-		azblobClient.client, err = azblob.NewClient(e.config.URL, cred, nil)
+		client, err = azblob.NewClient(e.config.URL, cred, clientOptions)
 		if err != nil {
-			return fmt.Errorf("failed to create client with user managed identity: %w", err)
+			return nil, nil, fmt.Errorf("failed to create client with user managed identity: %w", err)
 		}
 	case WorkloadIdentity:
-		cred, err := azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+		cred, err = azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
 			ClientID:      e.config.Auth.ClientID,
 			TenantID:      e.config.Auth.TenantID,
 			TokenFilePath: e.config.Auth.FederatedTokenFile,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to create workload identity credential: %w", err)
+			return nil, nil, fmt.Errorf("failed to create workload identity credential: %w", err)
+		}
+		client, err = azblob.NewClient(e.config.URL, cred, clientOptions)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create client with workload identity: %w", err)
 		}
-		azblobClient.client, err = azblob.NewClient(e.config.URL, cred, nil)
+	case SharedAccessSignature:
+		serviceURL, err := sasURL(e.config.URL, e.config.Auth.SASToken)
 		if err != nil {
-			return fmt.Errorf("failed to create client with workload identity: %w", err)
+			return nil, nil, fmt.Errorf("failed to build shared access signature url: %w", err)
+		}
+		client, err = azblob.NewClientWithNoCredential(serviceURL, clientOptions)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create client with shared access signature: %w", err)
 		}
 	case DefaultCredentials:
 		// Use DefaultAzureCredential for automatic credential discovery
@@ -275,105 +763,226 @@ func (e *azureBlobExporter) start(_ context.Context, host component.Host) error
 		// 4. Azure CLI
 		// 5. Azure PowerShell
 		e.logger.Info("Using DefaultAzureCredential for authentication")
-		cred, err := azidentity.NewDefaultAzureCredential(nil)
-		if err != nil {
-			e.logger.Error("Failed to create DefaultAzureCredential", zap.Error(err))
-			return fmt.Errorf("failed to create default Azure credential: %w", err)
+		if e.config.Auth.ReportCredentialSource {
+			cred, err = e.probeDefaultCredentials(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			cred, err = azidentity.NewDefaultAzureCredential(nil)
+			if err != nil {
+				e.logger.Error("Failed to create DefaultAzureCredential", zap.Error(err))
+				return nil, nil, fmt.Errorf("failed to create default Azure credential: %w", err)
+			}
+			e.logger.Info("DefaultAzureCredential created successfully")
 		}
-		e.logger.Info("DefaultAzureCredential created successfully")
 
-		azblobClient.client, err = azblob.NewClient(e.config.URL, cred, nil)
+		client, err = azblob.NewClient(e.config.URL, cred, clientOptions)
 		if err != nil {
 			e.logger.Error("Failed to create Azure Blob client", zap.Error(err), zap.String("url", e.config.URL))
-			return fmt.Errorf("failed to create client with default credentials: %w", err)
+			return nil, nil, fmt.Errorf("failed to create client with default credentials: %w", err)
 		}
 		e.logger.Info("Azure Blob client created successfully", zap.String("url", e.config.URL))
 	default:
-		return fmt.Errorf("unsupported authentication type: %s", authType)
+		return nil, nil, fmt.Errorf("unsupported authentication type: %s", authType)
 	}
 
-	e.client = azblobClient
-
-	// Initialize blob name templates if template parsing is enabled
-	if e.config.BlobNameFormat.TemplateEnabled {
-		e.blobNameTemplate.metrics, err = template.New("metrics").Funcs(tempFuncs).Parse(e.config.BlobNameFormat.MetricsFormat)
-		if err != nil {
-			return fmt.Errorf("failed to parse metrics blob name template: %w", err)
-		}
-
-		e.blobNameTemplate.logs, err = template.New("logs").Funcs(tempFuncs).Parse(e.config.BlobNameFormat.LogsFormat)
-		if err != nil {
-			return fmt.Errorf("failed to parse logs blob name template: %w", err)
-		}
+	return client, cred, nil
+}
 
-		e.blobNameTemplate.traces, err = template.New("traces").Funcs(tempFuncs).Parse(e.config.BlobNameFormat.TracesFormat)
-		if err != nil {
-			return fmt.Errorf("failed to parse traces blob name template: %w", err)
-		}
+// probeDefaultCredentials requests a token from the default credential chain, so that a broken
+// chain fails on start, and reports the source that provided it.
+func (e *azureBlobExporter) probeDefaultCredentials(ctx context.Context) (azcore.TokenCredential, error) {
+	chain, err := newDefaultCredentialChain()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create default Azure credential: %w", err)
 	}
-
-	return nil
+	if _, err = chain.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{storageScope}}); err != nil {
+		return nil, fmt.Errorf("default Azure credential failed to get a token: %w", err)
+	}
+	e.logger.Info("Default Azure credential authenticated", zap.String("source", chain.source()))
+	e.telemetry.credentialSource.Record(ctx, 1, metric.WithAttributes(attribute.String("source", chain.source())))
+	return chain, nil
 }
 
-func (e *azureBlobExporter) generateBlobName(signal pipeline.Signal, telemetryData any) (string, error) {
+// generateBlobName returns a unique name for the blob of telemetryData encoded as formatType into
+// data, which is the payload before compression.
+func (e *azureBlobExporter) generateBlobName(signal pipeline.Signal, telemetryData any, formatType string, data []byte, compressed bool) (generatedName, error) {
 	var format string
-	var blobName string
+	var maxLength int
 
-	now := time.Now()
+	now := e.blobTime(telemetryData)
 
+	var tmpl *template.Template
 	switch signal {
 	case pipeline.SignalMetrics:
 		format = e.config.BlobNameFormat.MetricsFormat
-		if e.config.BlobNameFormat.TemplateEnabled && e.blobNameTemplate.metrics != nil {
-			var buf bytes.Buffer
-			err := e.blobNameTemplate.metrics.Execute(&buf, telemetryData)
-			if err != nil {
-				e.logger.Warn("Failed to execute blob name template, using default blob name format", zap.Error(err))
-			} else {
-				blobName = buf.String()
-				format = blobName
-			}
-		}
+		maxLength = e.config.BlobNameFormat.MetricsMaxLength
+		tmpl = e.blobNameTemplate.metrics
 	case pipeline.SignalLogs:
 		format = e.config.BlobNameFormat.LogsFormat
-		if e.config.BlobNameFormat.TemplateEnabled && e.blobNameTemplate.logs != nil {
-			var buf bytes.Buffer
-			err := e.blobNameTemplate.logs.Execute(&buf, telemetryData)
-			if err != nil {
-				e.logger.Warn("Failed to execute blob name template, using default blob name format", zap.Error(err))
-			} else {
-				blobName = buf.String()
-				format = blobName
-			}
-		}
+		maxLength = e.config.BlobNameFormat.LogsMaxLength
+		tmpl = e.blobNameTemplate.logs
 	case pipeline.SignalTraces:
 		format = e.config.BlobNameFormat.TracesFormat
-		if e.config.BlobNameFormat.TemplateEnabled && e.blobNameTemplate.traces != nil {
-			var buf bytes.Buffer
-			err := e.blobNameTemplate.traces.Execute(&buf, telemetryData)
-			if err != nil {
-				e.logger.Warn("Failed to execute blob name template, using default blob name format", zap.Error(err))
-			} else {
-				blobName = buf.String()
-				format = blobName
+		maxLength = e.config.BlobNameFormat.TracesMaxLength
+		tmpl = e.blobNameTemplate.traces
+	default:
+		return generatedName{}, fmt.Errorf("unsupported signal type: %v", signal)
+	}
+
+	if e.config.BlobNameFormat.TemplateEnabled && tmpl != nil {
+		name, err := executeBlobNameTemplate(tmpl, telemetryData, e.config.PartitionFromBodyPath, e.config.PartitionByAttribute, e.config.PartitionByService)
+		if err != nil {
+			e.logger.Warn("Failed to execute blob name template, using default blob name format", zap.Error(err))
+			format = defaultBlobNameFormat(signal)
+		} else {
+			format = name
+		}
+	}
+
+	if len(e.config.Formats) > 0 || e.config.formatOverride(signal) != "" {
+		// The blobs of every format, and of signals with their own format, get the extension of their format
+		ext := filepath.Ext(format)
+		for _, formatExt := range formatExtensions {
+			if ext == formatExt {
+				format = strings.TrimSuffix(format, ext) + formatExtensions[formatType]
+				break
 			}
 		}
+	} else if (formatType == formatTypeNDJSON || formatType == formatTypeCSV || formatType == formatTypeAvro) && strings.HasSuffix(format, ".json") {
+		// NDJSON, CSV and Avro blobs keep the default name formats but get their own extension
+		format = strings.TrimSuffix(format, ".json") + formatExtensions[formatType]
+	}
+
+	var serial string
+	switch e.config.BlobNameFormat.UniqueSuffix {
+	case uniqueSuffixUUID:
+		// Version 7 UUIDs start with the Unix milliseconds, so they sort by creation time as well
+		id, err := uuid.NewV7()
+		if err != nil {
+			return generatedName{}, fmt.Errorf("failed to generate blob name suffix: %w", err)
+		}
+		serial = id.String()
+	case uniqueSuffixULID:
+		serial = newULID(time.Now())
 	default:
-		return "", fmt.Errorf("unsupported signal type: %v", signal)
+		serial = strconv.Itoa(randomInRange(0, int(e.config.BlobNameFormat.SerialNumRange)))
+	}
+	if e.rollsAppendBlobs() {
+		// Rolled append blobs get the ULID of their target instead, which has the same length
+		serial = newULID(time.Now())
+	}
+	if e.config.BlobNameFormat.DeterministicFromContent {
+		hash, err := contentHash(telemetryData)
+		if err != nil {
+			return generatedName{}, fmt.Errorf("failed to hash blob content: %w", err)
+		}
+		serial = hash
+	}
+
+	// Only the rendered part of the name is truncated, the prefixes, serial and extensions stay intact
+	ext := filepath.Ext(format)
+	stem, err := resolveMarkers(now.Format(strings.TrimSuffix(format, ext)), telemetryData, formatType, data)
+	if err != nil {
+		return generatedName{}, fmt.Errorf("failed to describe blob content: %w", err)
 	}
 
+	var beforeSerial, afterSerial string
 	if e.config.BlobNameFormat.SerialNumBeforeExtension {
-		// Append a random number and do so before the file extension if there is one
-		ext := filepath.Ext(format)
-		formatWithoutExt := strings.TrimSuffix(format, ext)
-		randInt := randomInRange(0, int(e.config.BlobNameFormat.SerialNumRange))
-		blobName = fmt.Sprintf("%s_%d%s", now.Format(formatWithoutExt), randInt, ext)
+		// Append the serial and do so before the file extension if there is one
+		afterSerial = ext
 	} else {
-		// Appends the random number after any potential file extension to minimize performance impact when high throughput
-		blobName = fmt.Sprintf("%s_%d", now.Format(format), randomInRange(0, int(e.config.BlobNameFormat.SerialNumRange)))
+		// Appends the serial after any potential file extension to minimize performance impact when high throughput
+		beforeSerial = ext
+	}
+	if compressed {
+		afterSerial += ".gz"
+	}
+
+	var prefix string
+	if e.config.Container.forSignal(signal) == "" {
+		// Signals sharing default_container are kept apart by their prefix
+		prefix = signal.String() + "/"
+	}
+	if len(e.config.Formats) > 0 {
+		// Each format is written under its own prefix, so the blobs of a batch do not collide
+		prefix = formatType + "/" + prefix
+	}
+
+	name := generatedName{head: prefix + stem + beforeSerial, serial: serial, tail: afterSerial}
+	if maxLength > 0 && len(name.String()) > maxLength {
+		room := len(stem) - (len(name.String()) - maxLength)
+		if room <= 0 {
+			return generatedName{}, fmt.Errorf("blob name max length %d leaves no room for the name before %q", maxLength, beforeSerial+"_"+serial+afterSerial)
+		}
+		full := name.String()
+		name.head = prefix + truncateBlobName(stem, room) + beforeSerial
+		e.logger.Debug("Truncated blob name exceeding max length",
+			zap.String("blob", full),
+			zap.String("truncated", name.String()),
+			zap.Int("max_length", maxLength))
+	}
+	return name, nil
+}
+
+// generatedName is a blob name split around its unique suffix, so append blobs can be rolled by the
+// name without it.
+type generatedName struct {
+	head, serial, tail string
+}
+
+func (n generatedName) String() string {
+	return n.head + "_" + n.serial + n.tail
+}
+
+// withSerial returns the name with serial as its unique suffix.
+func (n generatedName) withSerial(serial string) string {
+	return n.head + "_" + serial + n.tail
+}
+
+// withoutSerial returns the name without its unique suffix, which names every blob rolled from it.
+func (n generatedName) withoutSerial() string {
+	return n.head + n.tail
+}
+
+// resolveMarkers substitutes the values of the rowCount and schemaHash template functions in name.
+// rowCount is the number of rows of a parquet blob, read back from data, and the record count of
+// other formats. schemaHash is a digest of the parquet schema, and empty for other formats.
+func resolveMarkers(name string, telemetryData any, formatType string, data []byte) (string, error) {
+	if !strings.ContainsAny(name, rowCountMarker+schemaHashMarker) {
+		return name, nil
+	}
+	rows, schemaHash := strconv.Itoa(recordCount(telemetryData)), ""
+	if formatType == formatTypeParquet {
+		n, hash, err := parquetFileInfo(data)
+		if err != nil {
+			return "", err
+		}
+		rows, schemaHash = strconv.FormatInt(n, 10), hash
+	}
+	return strings.NewReplacer(rowCountMarker, rows, schemaHashMarker, schemaHash).Replace(name), nil
+}
+
+// contentHash returns a hex digest of the OTLP protobuf encoding of telemetryData, which is the same
+// for the same telemetry in every format.
+func contentHash(telemetryData any) (string, error) {
+	data, err := marshalTelemetry(newProtoMarshaller(), telemetryData)
+	if err != nil {
+		return "", err
 	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]), nil
+}
 
-	return blobName, nil
+// truncateBlobName shortens the rendered part of a blob name to at most maxLength bytes.
+func truncateBlobName(name string, maxLength int) string {
+	cut := maxLength
+	// Avoid splitting a multi-byte character
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut]
 }
 
 func (*azureBlobExporter) Capabilities() consumer.Capabilities {
@@ -381,67 +990,420 @@ func (*azureBlobExporter) Capabilities() consumer.Capabilities {
 }
 
 func (e *azureBlobExporter) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
-	// Marshal the metrics data
-	data, err := e.marshaller.MarshalMetrics(md)
-	if err != nil {
-		return fmt.Errorf("failed to marshal metrics: %w", err)
+	var r timestampRange
+	if e.watermark != nil {
+		var skipped int
+		md, r, skipped = filterMetricsAfter(md, e.watermark.threshold())
+		if skipped > 0 {
+			e.logger.Debug("Skipped data points at or below the watermark", zap.Int("data_points", skipped))
+			if md.DataPointCount() == 0 {
+				return nil
+			}
+		}
 	}
 
-	return e.consumeData(ctx, md, data, pipeline.SignalMetrics)
+	if e.config.AggregateMetrics {
+		md = aggregateMetrics(md)
+	}
+
+	return e.export(ctx, md, r)
 }
 
 func (e *azureBlobExporter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
-	// Marshal the logs data
-	data, err := e.marshaller.MarshalLogs(ld)
-	if err != nil {
-		return fmt.Errorf("failed to marshal logs: %w", err)
+	if e.config.IDValidation != "" {
+		var invalid int
+		ld, invalid = validateLogIDs(ld, e.config.IDValidation)
+		if invalid > 0 {
+			e.telemetry.invalidIDs.Add(ctx, int64(invalid))
+			e.logger.Debug("Found log records with invalid IDs", zap.Int("records", invalid), zap.String("mode", e.config.IDValidation))
+			if ld.LogRecordCount() == 0 {
+				return nil
+			}
+		}
+	}
+
+	var r timestampRange
+	if e.watermark != nil {
+		var skipped int
+		ld, r, skipped = filterLogsAfter(ld, e.watermark.threshold())
+		if skipped > 0 {
+			e.logger.Debug("Skipped log records at or below the watermark", zap.Int("records", skipped))
+			if ld.LogRecordCount() == 0 {
+				return nil
+			}
+		}
 	}
 
-	return e.consumeData(ctx, ld, data, pipeline.SignalLogs)
+	return e.export(ctx, ld, r)
 }
 
 func (e *azureBlobExporter) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
-	// Marshal the traces data
-	data, err := e.marshaller.MarshalTraces(td)
-	if err != nil {
-		return fmt.Errorf("failed to marshal traces: %w", err)
+	if e.config.IDValidation != "" {
+		var invalid int
+		td, invalid = validateSpanIDs(td, e.config.IDValidation)
+		if invalid > 0 {
+			e.telemetry.invalidIDs.Add(ctx, int64(invalid))
+			e.logger.Debug("Found spans with invalid IDs", zap.Int("spans", invalid), zap.String("mode", e.config.IDValidation))
+			if td.SpanCount() == 0 {
+				return nil
+			}
+		}
+	}
+
+	if e.config.DedupSpans {
+		var duplicates int
+		td, duplicates = dedupSpans(td)
+		if duplicates > 0 {
+			e.telemetry.deduplicatedSpans.Add(ctx, int64(duplicates))
+			e.logger.Debug("Dropped duplicate spans from batch", zap.Int("duplicates", duplicates))
+		}
+	}
+
+	if e.config.TracesWithErrorsOnly {
+		td = filterTracesWithErrors(td)
+		if td.SpanCount() == 0 {
+			e.logger.Debug("No traces with errors in batch, skipping export")
+			return nil
+		}
+	}
+
+	var r timestampRange
+	if e.watermark != nil {
+		var skipped int
+		td, r, skipped = filterTracesAfter(td, e.watermark.threshold())
+		if skipped > 0 {
+			e.logger.Debug("Skipped spans at or below the watermark", zap.Int("spans", skipped))
+			if td.SpanCount() == 0 {
+				return nil
+			}
+		}
+	}
+
+	return e.export(ctx, td, r)
+}
+
+// export writes telemetryData as a blob, or buffers it when batching or compact_on_shutdown is enabled. r is the
+// range of record timestamps of telemetryData for the watermark.
+func (e *azureBlobExporter) export(ctx context.Context, telemetryData any, r timestampRange) error {
+	if e.batcher != nil {
+		return e.batcher.add(ctx, telemetryData, r)
+	}
+	return e.writeBlob(ctx, telemetryData, r)
+}
+
+// writeBlob uploads telemetryData and advances the watermark to the highest timestamp of r. While
+// it is written, the watermark stays below the lowest timestamp of r.
+func (e *azureBlobExporter) writeBlob(ctx context.Context, telemetryData any, r timestampRange) error {
+	if e.watermark == nil {
+		return e.uploadPartitions(ctx, telemetryData)
+	}
+	e.watermark.begin(r)
+	err := e.uploadPartitions(ctx, telemetryData)
+	if endErr := e.watermark.end(ctx, r, err == nil); endErr != nil {
+		// The batch is already stored, and failing the export would upload it again
+		e.logger.Warn("Failed to persist watermark", zap.Error(endErr))
+	}
+	return err
+}
+
+// uploadPartitions marshals and uploads telemetryData, one blob per partition when partition_by_attribute
+// or partition_by_service is set. When the write fails, the blobs already stored and the sequence
+// numbers are remembered, and a retry of the same telemetryData only uploads the other blobs, with the
+// same numbers.
+func (e *azureBlobExporter) uploadPartitions(ctx context.Context, telemetryData any) (err error) {
+	written := map[writtenPart]bool{}
+	var sequence int64
+	if retried := e.failedWrites.take(telemetryData); retried != nil {
+		written, sequence = retried.written, retried.sequence
+	}
+	defer func(batch any) {
+		if err != nil && (len(written) > 0 || sequence > 0) {
+			e.failedWrites.put(batch, &failedWrite{written: written, sequence: sequence})
+		}
+	}(telemetryData)
+
+	if e.config.PreserveOrder {
+		// Holding the lock through the upload and its retries keeps the records of later writes
+		// behind this one, in blob order as well as in sequence numbers.
+		e.orderMu.Lock()
+		defer e.orderMu.Unlock()
+		if sequence == 0 {
+			sequence = e.nextSequence
+			telemetryData, e.nextSequence = withSequence(telemetryData, sequence)
+		} else {
+			// A retried batch keeps the numbers of its first write
+			telemetryData, _ = withSequence(telemetryData, sequence)
+		}
 	}
 
-	return e.consumeData(ctx, td, data, pipeline.SignalTraces)
+	partitions := []any{telemetryData}
+	if e.config.PartitionByAttribute.Key != "" || e.config.PartitionByService.Enabled {
+		partitions = splitByResource(telemetryData, e.resourcePartitionKey)
+	}
+
+	for p, partition := range partitions {
+		// Blob names, properties and the raw OTLP copy are taken from the unstripped partition
+		marshalled := partition
+		if len(e.config.StripAttributePrefixes) > 0 {
+			var conflicts int
+			if marshalled, conflicts = stripAttributePrefixes(partition, e.config.StripAttributePrefixes); conflicts > 0 {
+				e.telemetry.prefixConflicts.Add(ctx, int64(conflicts))
+				e.logger.Debug("Kept attribute prefixes whose stripped key already exists", zap.Int("conflicts", conflicts))
+			}
+		}
+
+		for i, m := range e.marshallers {
+			part := writtenPart{partition: p, format: m.format()}
+			if written[part] {
+				continue
+			}
+
+			data, err := marshalTelemetry(m, marshalled)
+			if err != nil {
+				return fmt.Errorf("failed to marshal %s as %s: %w", e.signal, m.format(), err)
+			}
+
+			// The size of the primary format decides for all formats
+			if i == 0 && !e.config.SizeRouting.accepts(len(data)) {
+				e.logger.Debug("Batch below size routing threshold, leaving it to the Event Hubs exporter",
+					zap.Int("size", len(data)),
+					zap.Int("threshold_bytes", e.config.SizeRouting.ThresholdBytes))
+				break
+			}
+
+			if err = e.consumeData(ctx, m, partition, data, e.signal, i == 0); err != nil {
+				return err
+			}
+			written[part] = true
+		}
+	}
+	return nil
 }
 
-func (e *azureBlobExporter) consumeData(ctx context.Context, telemetryData any, data []byte, signal pipeline.Signal) error {
+// consumeData uploads data, telemetryData marshalled by m. The raw OTLP copy is only written next
+// to the blob of the primary format.
+func (e *azureBlobExporter) consumeData(ctx context.Context, m marshaller, telemetryData any, data []byte, signal pipeline.Signal, primary bool) error {
+	compress := e.compresses(len(data))
+
 	// Generate a unique blob name
-	blobName, err := e.generateBlobName(signal, telemetryData)
+	name, err := e.generateBlobName(signal, telemetryData, m.format(), data, compress)
 	if err != nil {
 		return fmt.Errorf("failed to generate blobname: %w", err)
 	}
+	blobName := name.String()
 
 	var containerName string
-	switch signal {
-	case pipeline.SignalMetrics:
-		containerName = e.config.Container.Metrics
-	case pipeline.SignalLogs:
-		containerName = e.config.Container.Logs
-	case pipeline.SignalTraces:
-		containerName = e.config.Container.Traces
+	if e.containerTemplate != nil {
+		containerName, err = e.renderContainer(ctx, telemetryData)
+	} else {
+		containerName, err = e.containerName(signal)
+	}
+	if err != nil {
+		return err
+	}
+
+	var target *appendTarget
+	if e.rollsAppendBlobs() {
+		target = e.appendRoller.target(name.withoutSerial())
+		blobName = name.withSerial(target.serial)
+	}
+
+	if e.config.AppendBlob.Enabled && e.config.AppendBlob.Separator != "" && m.format() != formatTypeNDJSON {
+		// Add separator if configured. NDJSON chunks already end with a newline.
+		data = append(data, []byte(e.config.AppendBlob.Separator)...)
+	}
+
+	if compress {
+		// Every chunk becomes a complete gzip member. Appended members concatenate into a valid
+		// multi-member gzip stream, and the append separator is compressed along with its chunk.
+		if data, err = gzipCompress(data, e.config.CompressionLevel); err != nil {
+			return fmt.Errorf("failed to compress data: %w", err)
+		}
+	}
+
+	// The slot is held until the raw copy is written, so overflow and raw uploads count against the limit
+	release, err := e.acquireUploadSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	props := e.blobPropertiesOf(telemetryData)
+	err = e.upload(ctx, containerName, blobName, data, props)
+	if target != nil {
+		e.appendRoller.done(target, err == nil)
+	}
+	if err != nil {
+		overflowContainer := e.overflowContainer(containerName)
+		if overflowContainer != "" && isOverflowError(err) {
+			e.logger.Warn("Upload to primary container failed, writing to overflow container",
+				zap.String("container", containerName),
+				zap.String("overflow_container", overflowContainer),
+				zap.Error(err))
+			if overflowErr := e.upload(ctx, overflowContainer, blobName, data, props); overflowErr != nil {
+				err = errors.Join(err, overflowErr)
+			} else {
+				e.telemetry.overflowUploads.Add(ctx, 1)
+				containerName, err = overflowContainer, nil
+			}
+		}
+		if err != nil {
+			return e.writeDeadLetter(ctx, data, deadLetterEntry{
+				Container:  containerName,
+				Blob:       blobName,
+				Signal:     signal.String(),
+				Format:     m.format(),
+				Compressed: compress,
+				AppendBlob: e.config.AppendBlob.Enabled && !e.appendUnsupported.Load(),
+				Metadata:   props.metadataValues(),
+				Tags:       props.tags,
+				Size:       len(data),
+				FailedAt:   time.Now(),
+				Error:      err.Error(),
+			}, fmt.Errorf("failed to upload data: %w", err))
+		}
+	}
+
+	if e.rawMarshaller != nil && primary {
+		if err = e.uploadRawOTLP(ctx, telemetryData, containerName, blobName, props, compress); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollsAppendBlobs reports whether chunks are appended to the rolled append blobs of their name.
+func (e *azureBlobExporter) rollsAppendBlobs() bool {
+	return e.config.AppendBlob.Enabled && e.appendRoller != nil && !e.appendUnsupported.Load()
+}
+
+// newUploadSlots returns the semaphore bounding concurrent uploads to limit, or nil without a limit.
+func newUploadSlots(limit int) chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+	return make(chan struct{}, limit)
+}
+
+// acquireUploadSlot blocks until fewer than max_concurrent_uploads uploads are in flight, and returns
+// the function releasing the slot. It fails when ctx is done before a slot frees up.
+func (e *azureBlobExporter) acquireUploadSlot(ctx context.Context) (func(), error) {
+	if e.uploadSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case e.uploadSlots <- struct{}{}:
+		return func() { <-e.uploadSlots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for an upload slot: %w", ctx.Err())
+	}
+}
+
+// writeDeadLetter hands the payload of a failed upload to the dead letter destination of the signal:
+// a container, a local directory, or nowhere when it is dropped. The upload error is returned as is
+// without a destination, or when the payload could not be written. Once the payload is handled, the
+// export succeeds, as retrying it would store it twice.
+func (e *azureBlobExporter) writeDeadLetter(ctx context.Context, data []byte, entry deadLetterEntry, uploadErr error) error {
+	var destination, location string
+	switch {
+	case e.deadLetterDest.Drop:
+		destination = "drop"
+	case e.deadLetterDest.Container != "":
+		destination, location = "container", e.deadLetterDest.Container
+		if err := e.writeDeadLetterBlob(ctx, data, entry); err != nil {
+			return errors.Join(uploadErr, err)
+		}
+	case e.deadLetter != nil:
+		path, err := e.deadLetter.write(data, entry)
+		if err != nil {
+			return errors.Join(uploadErr, err)
+		}
+		destination, location = "path", path
 	default:
-		return fmt.Errorf("unsupported signal type: %v", signal)
+		return uploadErr
 	}
+	e.telemetry.deadLetters.Add(ctx, 1, metric.WithAttributes(attribute.String("destination", destination)))
+	e.logger.Warn("Upload failed, handed payload to the dead letter destination",
+		zap.String("container", entry.Container),
+		zap.String("blob", entry.Blob),
+		zap.String("destination", destination),
+		zap.String("location", location),
+		zap.Error(uploadErr))
+	return nil
+}
 
-	if e.config.AppendBlob.Enabled {
-		// Add separator if configured
-		if e.config.AppendBlob.Separator != "" {
-			data = append(data, []byte(e.config.AppendBlob.Separator)...)
+// writeDeadLetterBlob uploads the payload of a failed upload to the dead letter container under its
+// intended blob name, followed by its sidecar, so a sidecar only exists for a complete payload.
+// Payloads are written as block blobs, whether or not the failed upload was an append.
+func (e *azureBlobExporter) writeDeadLetterBlob(ctx context.Context, data []byte, entry deadLetterEntry) error {
+	sidecar, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter metadata: %w", err)
+	}
+	containerName := e.deadLetterDest.Container
+	if err = e.checkContainer(ctx, containerName); err != nil {
+		return err
+	}
+	for _, b := range []struct {
+		name string
+		data []byte
+	}{{entry.Blob, data}, {entry.Blob + deadLetterSidecarSuffix, sidecar}} {
+		err = e.retryUpload(ctx, containerName, func(ctx context.Context) error {
+			opts := &azblob.UploadStreamOptions{CPKInfo: e.cpk, CPKScopeInfo: e.cpkScope}
+			_, err := e.client.UploadStream(ctx, containerName, b.name, bytes.NewReader(b.data), opts)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write dead letter blob to container %q: %w", containerName, err)
 		}
-		err = e.client.AppendBlock(ctx, containerName, blobName, data, nil)
-	} else {
-		blobContentReader := bytes.NewReader(data)
-		_, err = e.client.UploadStream(ctx, containerName, blobName, blobContentReader, nil)
+	}
+	return nil
+}
+
+// upload writes data to the blob, appending to it when append blobs are enabled. props are set on
+// block blob uploads.
+func (e *azureBlobExporter) upload(ctx context.Context, containerName, blobName string, data []byte, props blobProperties) error {
+	if err := e.checkContainer(ctx, containerName); err != nil {
+		return err
 	}
 
+	var position appendPosition
+	err := e.retryUpload(ctx, containerName, func(ctx context.Context) error {
+		if e.config.AppendBlob.Enabled && !e.appendUnsupported.Load() {
+			err := e.appendBlock(ctx, containerName, blobName, data, &position)
+			if err == nil || !e.config.AppendUnsupportedFallback || !isAppendUnsupportedError(err) {
+				return err
+			}
+			if e.appendUnsupported.CompareAndSwap(false, true) {
+				e.logger.Warn("Storage account does not support append blobs, falling back to block blob uploads",
+					zap.String("account", e.client.URL()),
+					zap.Error(err))
+			}
+		}
+		opts := &azblob.UploadStreamOptions{Metadata: props.metadata, Tags: props.tags, CPKInfo: e.cpk, CPKScopeInfo: e.cpkScope}
+		if tier, ok := accessTiers[e.config.AccessTier]; ok {
+			opts.AccessTier = &tier
+		}
+		if e.config.OverwritePolicy == overwritePolicyFail || e.config.OverwritePolicy == overwritePolicySkip {
+			etag := azcore.ETagAny
+			opts.AccessConditions = &blob.AccessConditions{
+				ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &etag},
+			}
+		}
+		blobContentReader := bytes.NewReader(data)
+		_, err := e.client.UploadStream(ctx, containerName, blobName, blobContentReader, opts)
+		return err
+	})
+	if err != nil && e.config.OverwritePolicy == overwritePolicySkip && isBlobExistsError(err) {
+		// The blob may also have been written by an earlier attempt that reported a failure
+		e.logger.Debug("Blob already exists, skipping upload",
+			zap.String("container", containerName),
+			zap.String("blob", blobName))
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to upload data: %w", err)
+		return err
 	}
 
 	e.logger.Debug("Successfully exported data to Azure Blob Storage",
@@ -450,9 +1412,153 @@ func (e *azureBlobExporter) consumeData(ctx context.Context, telemetryData any,
 		zap.String("blob", blobName),
 		zap.Int("size", len(data)))
 
+	if e.config.ContainerIndex.Enabled {
+		e.appendIndexEntry(ctx, containerName, blobName, len(data))
+	}
+
+	return nil
+}
+
+// createContainers creates the configured containers that do not exist yet.
+func (e *azureBlobExporter) createContainers(ctx context.Context) error {
+	containers := []string{e.config.Container.Metrics, e.config.Container.Logs, e.config.Container.Traces, e.config.DefaultContainer}
+	if e.config.AlsoWriteRawOTLP.Enabled {
+		containers = append(containers, e.config.AlsoWriteRawOTLP.Container)
+	}
+	containers = append(containers, e.config.OverflowContainer)
+	for _, signal := range []pipeline.Signal{pipeline.SignalLogs, pipeline.SignalMetrics, pipeline.SignalTraces} {
+		containers = append(containers, e.config.DeadLetter.forSignal(signal).Container)
+	}
+	for _, policy := range e.config.ContainerPolicies {
+		containers = append(containers, policy.OverflowContainer)
+	}
+
+	created := make(map[string]struct{}, len(containers))
+	for _, containerName := range containers {
+		// The containers of templates are only known per batch, container_check can create them
+		if _, ok := created[containerName]; ok || containerName == "" || isContainerTemplate(containerName) {
+			continue
+		}
+		if err := e.client.CreateContainer(ctx, containerName); err != nil {
+			return fmt.Errorf("failed to create container %q: %w", containerName, err)
+		}
+		created[containerName] = struct{}{}
+	}
 	return nil
 }
 
+// overflowContainer returns the overflow container of containerName: the one of its
+// container_policies entry, if set, and overflow_container otherwise.
+func (e *azureBlobExporter) overflowContainer(containerName string) string {
+	if policy, ok := e.config.ContainerPolicies[containerName]; ok && policy.OverflowContainer != "" {
+		return policy.OverflowContainer
+	}
+	return e.config.OverflowContainer
+}
+
+// isBlobExistsError reports whether an upload with overwrite_policy fail or skip found the blob
+// already there. The service answers the If-None-Match: * condition with 409 BlobAlreadyExists, or
+// with 412 ConditionNotMet. In adls_gen2 mode, the Data Lake API answers with 409 PathAlreadyExists.
+func isBlobExistsError(err error) bool {
+	return bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet, bloberror.Code(datalakeerror.PathAlreadyExists))
+}
+
+// isOverflowError reports whether a failed upload should be written to the overflow container:
+// the primary container is missing or disabled, the account stays throttled after retrying, or an
+// append blob reached its block limit.
+func isOverflowError(err error) bool {
+	return errors.Is(err, errContainerNotFound) || bloberror.HasCode(err,
+		bloberror.ContainerNotFound,
+		bloberror.Code(datalakeerror.FileSystemNotFound),
+		bloberror.ContainerBeingDeleted,
+		bloberror.ContainerDisabled,
+		bloberror.ServerBusy,
+		bloberror.BlockCountExceedsLimit)
+}
+
+// isAppendUnsupportedError reports whether an append failed because the account does not support
+// append blobs, e.g. premium block blob accounts.
+func isAppendUnsupportedError(err error) bool {
+	return bloberror.HasCode(err,
+		bloberror.InvalidBlobType,
+		bloberror.Code("FeatureNotYetSupportedForHierarchicalNamespaceAccounts"))
+}
+
+// containerName returns the container configured for signal, or default_container when it has none
+// or its container is a template.
+func (e *azureBlobExporter) containerName(signal pipeline.Signal) (string, error) {
+	switch signal {
+	case pipeline.SignalMetrics, pipeline.SignalLogs, pipeline.SignalTraces:
+	default:
+		return "", fmt.Errorf("unsupported signal type: %v", signal)
+	}
+	if name := e.config.Container.forSignal(signal); name != "" && !isContainerTemplate(name) {
+		return name, nil
+	}
+	if e.config.DefaultContainer == "" {
+		return "", fmt.Errorf("no container configured for %s, set container.%s or default_container", signal, signal)
+	}
+	return e.config.DefaultContainer, nil
+}
+
+// uploadRawOTLP writes the batch as raw OTLP protobuf next to the typed blob for lossless replay.
+// Appended protobuf chunks concatenate into a single valid OTLP request, so no separator is added.
+// The copy is compressed when the typed blob is, so that it matches the .gz extension of blobName.
+func (e *azureBlobExporter) uploadRawOTLP(ctx context.Context, telemetryData any, containerName, blobName string, props blobProperties, compressed bool) error {
+	raw, err := marshalTelemetry(e.rawMarshaller, telemetryData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal raw OTLP data: %w", err)
+	}
+	if compressed {
+		if raw, err = gzipCompress(raw, e.config.CompressionLevel); err != nil {
+			return fmt.Errorf("failed to compress raw OTLP data: %w", err)
+		}
+	}
+
+	if e.config.AlsoWriteRawOTLP.Container != "" {
+		containerName = e.config.AlsoWriteRawOTLP.Container
+	}
+	if err = e.upload(ctx, containerName, e.config.AlsoWriteRawOTLP.Prefix+blobName, raw, props); err != nil {
+		return fmt.Errorf("failed to upload raw OTLP data: %w", err)
+	}
+	return nil
+}
+
+// compresses reports whether a payload of size bytes is gzip compressed.
+func (e *azureBlobExporter) compresses(size int) bool {
+	return e.config.Compression == compressionGzip && size >= e.config.CompressMinBytes
+}
+
+// marshalTelemetry marshals traces, logs or metrics with m.
+func marshalTelemetry(m marshaller, telemetryData any) ([]byte, error) {
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		return m.MarshalTraces(td)
+	case plog.Logs:
+		return m.MarshalLogs(td)
+	case pmetric.Metrics:
+		return m.MarshalMetrics(td)
+	default:
+		return nil, fmt.Errorf("unsupported telemetry data type: %T", telemetryData)
+	}
+}
+
+// gzipCompress compresses data into a single gzip member.
+func gzipCompress(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 type readSeekCloserWrapper struct {
 	*bytes.Reader
 }
@@ -0,0 +1,197 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// testConnectionString is a well-formed connection string, creating a client from it does not connect.
+const testConnectionString = "DefaultEndpointsProtocol=https;AccountName=test;AccountKey=dGVzdA==;EndpointSuffix=core.windows.net"
+
+// mockUpload is a block blob upload or an append received by mockClient.
+type mockUpload struct {
+	container  string
+	blob       string
+	data       []byte
+	opts       *azblob.UploadStreamOptions
+	appendOpts *appendblob.AppendBlockOptions
+}
+
+// mockClient records the uploads and appends of an exporter. fail decides, for every upload and append,
+// whether it fails and with which error.
+type mockClient struct {
+	mu      sync.Mutex
+	uploads []mockUpload
+	blobs   map[string][]byte
+	created []string
+	fail    func(container, blob string) error
+}
+
+func (m *mockClient) failure(container, blob string) error {
+	if m.fail == nil {
+		return nil
+	}
+	return m.fail(container, blob)
+}
+
+func (m *mockClient) UploadStream(_ context.Context, container, blob string, body io.Reader, o *azblob.UploadStreamOptions) (azblob.UploadStreamResponse, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return azblob.UploadStreamResponse{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err = m.failure(container, blob); err != nil {
+		return azblob.UploadStreamResponse{}, err
+	}
+	m.uploads = append(m.uploads, mockUpload{container: container, blob: blob, data: data, opts: o})
+	return azblob.UploadStreamResponse{}, nil
+}
+
+func (m *mockClient) URL() string {
+	return "https://test.blob.core.windows.net/"
+}
+
+func (m *mockClient) AppendBlock(_ context.Context, container, blob string, data []byte, o *appendblob.AppendBlockOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.failure(container, blob); err != nil {
+		return err
+	}
+	if m.blobs == nil {
+		m.blobs = map[string][]byte{}
+	}
+	m.blobs[container+"/"+blob] = append(m.blobs[container+"/"+blob], data...)
+	m.uploads = append(m.uploads, mockUpload{container: container, blob: blob, data: append([]byte(nil), data...), appendOpts: o})
+	return nil
+}
+
+func (m *mockClient) AppendBlobSize(_ context.Context, container, blob string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.blobs[container+"/"+blob])), nil
+}
+
+func (m *mockClient) CreateContainer(_ context.Context, container string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.created = append(m.created, container)
+	return nil
+}
+
+func (m *mockClient) ContainerExists(context.Context, string) (bool, error) {
+	return true, nil
+}
+
+// recorded returns a copy of the uploads received so far.
+func (m *mockClient) recorded() []mockUpload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mockUpload(nil), m.uploads...)
+}
+
+// newTestConfig returns the default configuration with a container per signal and connection string
+// authentication, without retries.
+func newTestConfig() *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Auth.ConnectionString = testConnectionString
+	cfg.Container = TelemetryConfig{Metrics: "metrics", Logs: "logs", Traces: "traces"}
+	cfg.BackOffConfig.Enabled = false
+	return cfg
+}
+
+// newTestExporter validates cfg and starts an exporter of signal that uploads to a mock client.
+func newTestExporter(t *testing.T, cfg *Config, signal pipeline.Signal) (*azureBlobExporter, *mockClient) {
+	t.Helper()
+	return newTestExporterWithSettings(t, cfg, signal, componenttest.NewNopTelemetrySettings())
+}
+
+// newTestExporterWithSettings is newTestExporter recording the telemetry of the exporter to set.
+func newTestExporterWithSettings(t *testing.T, cfg *Config, signal pipeline.Signal, set component.TelemetrySettings) (*azureBlobExporter, *mockClient) {
+	t.Helper()
+	require.NoError(t, cfg.Validate())
+	exp, err := newAzureBlobExporter(cfg, component.MustNewID("azureblob"), set, signal)
+	require.NoError(t, err)
+	require.NoError(t, exp.start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, exp.shutdown(context.Background())) })
+	client := &mockClient{}
+	exp.client = client
+	return exp, client
+}
+
+// newTestTraces returns traces with a span per resource, each resource carrying the given attributes.
+func newTestTraces(resources ...map[string]any) ptrace.Traces {
+	td := ptrace.NewTraces()
+	for i, attrs := range resources {
+		rs := td.ResourceSpans().AppendEmpty()
+		_ = rs.Resource().Attributes().FromRaw(attrs)
+		span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		span.SetName("span")
+		span.SetTraceID(pcommon.TraceID([16]byte{1, byte(i + 1)}))
+		span.SetSpanID(pcommon.SpanID([8]byte{1, byte(i + 1)}))
+	}
+	return td
+}
+
+// newTestLogs returns logs with a log record per timestamp, in a single resource.
+func newTestLogs(timestamps ...pcommon.Timestamp) plog.Logs {
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for _, ts := range timestamps {
+		record := records.AppendEmpty()
+		record.SetTimestamp(ts)
+		record.Body().SetStr("log")
+	}
+	return ld
+}
+
+// newTestMetrics returns metrics with a gauge data point per timestamp, in a single resource.
+func newTestMetrics(timestamps ...pcommon.Timestamp) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	metric := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("gauge")
+	points := metric.SetEmptyGauge().DataPoints()
+	if len(timestamps) == 0 {
+		timestamps = []pcommon.Timestamp{1}
+	}
+	for _, ts := range timestamps {
+		point := points.AppendEmpty()
+		point.SetTimestamp(ts)
+		point.SetIntValue(1)
+	}
+	return md
+}
+
+// counterValue returns the sum of the data points of an int64 counter recorded to tel, 0 when it was
+// never incremented.
+func counterValue(t *testing.T, tel *componenttest.Telemetry, name string) int64 {
+	t.Helper()
+	m, err := tel.GetMetric(name)
+	if err != nil {
+		return 0
+	}
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	require.True(t, ok, "%s is not an int64 counter", name)
+	var total int64
+	for _, point := range sum.DataPoints {
+		total += point.Value
+	}
+	return total
+}
@@ -4,7 +4,9 @@
 package azureblobexporter
 
 import (
+	"compress/gzip"
 	"context"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configretry"
@@ -20,8 +22,49 @@ const (
 	formatTypeJSON    = "json"
 	formatTypeProto   = "proto"
 	formatTypeParquet = "parquet"
+	formatTypeNDJSON  = "ndjson"
+	formatTypeCSV     = "csv"
+	formatTypeAvro    = "avro"
+
+	// aliases naming the formats that write canonical OTLP
+	formatTypeOTLPJSON  = "otlp_json"
+	formatTypeOTLPProto = "otlp_proto"
+
+	// the compression applied to uploaded blobs
+	compressionNone = "none"
+	compressionGzip = "gzip"
+
+	// the time formatted into blob names
+	timeSourceNow       = "now"
+	timeSourceTelemetry = "telemetry"
+
+	// the unique suffix of blob names
+	uniqueSuffixSerial = "serial"
+	uniqueSuffixUUID   = "uuid"
+	uniqueSuffixULID   = "ulid"
+
+	// what an upload does when its block blob already exists
+	overwritePolicyOverwrite = "overwrite"
+	overwritePolicyFail      = "fail"
+	overwritePolicySkip      = "skip"
 )
 
+// formatExtensions maps every format to the extension of its blobs when several formats are written.
+var formatExtensions = map[string]string{
+	formatTypeJSON:    ".json",
+	formatTypeNDJSON:  ".ndjson",
+	formatTypeProto:   ".pb",
+	formatTypeParquet: ".parquet",
+	formatTypeCSV:     ".csv",
+	formatTypeAvro:    ".avro",
+}
+
+// formatAliases maps every alias to the format it selects.
+var formatAliases = map[string]string{
+	formatTypeOTLPJSON:  formatTypeJSON,
+	formatTypeOTLPProto: formatTypeProto,
+}
+
 // NewFactory creates a factory for Azure Blob exporter.
 func NewFactory() exporter.Factory {
 	return exporter.NewFactory(
@@ -38,6 +81,10 @@ func createDefaultConfig() component.Config {
 		Auth: Authentication{
 			Type: ConnectionString,
 		},
+		StartupRetry: StartupRetry{
+			MaxAttempts: 1,
+			Interval:    5 * time.Second,
+		},
 		Container: TelemetryConfig{
 			Metrics: "metrics",
 			Logs:    "logs",
@@ -50,29 +97,87 @@ func createDefaultConfig() component.Config {
 			SerialNumRange:  10000,
 			Params:          map[string]string{},
 			TemplateEnabled: false,
+			TimeSource:      timeSourceNow,
+			Timezone:        "UTC",
+			UniqueSuffix:    uniqueSuffixSerial,
+		},
+		FormatType:       formatTypeJSON,
+		OverwritePolicy:  overwritePolicyOverwrite,
+		Compression:      compressionNone,
+		CompressionLevel: gzip.DefaultCompression,
+		Parquet: ParquetConfig{
+			Compression:     "snappy",
+			WriteStatistics: true,
+		},
+		CSV: CSVConfig{
+			Delimiter: ",",
 		},
-		FormatType: formatTypeJSON,
 		AppendBlob: AppendBlob{
 			Enabled:   false,
 			Separator: "\n",
 		},
+		AlsoWriteRawOTLP: RawOTLP{
+			Prefix: "raw/",
+		},
+		ContainerIndex: ContainerIndex{
+			BlobName: "_index.ndjson",
+		},
+		ContainerCheck: ContainerCheck{
+			TTL: 10 * time.Minute,
+		},
+		TemplatedContainerFallback: true,
+		PartitionByAttribute: PartitionByAttribute{
+			Default: "unknown",
+		},
+		PartitionByService: PartitionByService{
+			DefaultNamespace: "default",
+			DefaultName:      "unknown_service",
+		},
+		Batch: Batch{
+			MaxBytes:      8 * 1024 * 1024,
+			FlushInterval: 30 * time.Second,
+		},
+		DeadLetter: DeadLetter{
+			MaxBytes: 1024 * 1024 * 1024,
+		},
+		CompactOnShutdown: CompactOnShutdown{
+			MaxBytes: 64 * 1024 * 1024,
+		},
+		MetricNameNormalization: MetricNameNormalization{
+			Characters:  "./",
+			Replacement: "_",
+		},
 		Encodings:     Encodings{},
 		BackOffConfig: configretry.NewDefaultBackOffConfig(),
+		Timeout:       30 * time.Second,
+		QueueSettings: newDefaultQueueConfig(),
 	}
 }
 
+// newDefaultQueueConfig returns the exporterhelper queue defaults with the queue disabled, so exports
+// stay synchronous unless sending_queue is enabled.
+func newDefaultQueueConfig() exporterhelper.QueueBatchConfig {
+	queue := exporterhelper.NewDefaultQueueConfig()
+	queue.Enabled = false
+	return queue
+}
+
 func createLogsExporter(ctx context.Context,
 	params exporter.Settings,
 	config component.Config,
 ) (exporter.Logs, error) {
 	cfg := config.(*Config)
-	azBlobExporter := newAzureBlobExporter(cfg, params.Logger, pipeline.SignalLogs)
+	azBlobExporter, err := newAzureBlobExporter(cfg, params.ID, params.TelemetrySettings, pipeline.SignalLogs)
+	if err != nil {
+		return nil, err
+	}
 
 	return exporterhelper.NewLogs(ctx, params,
 		config,
 		azBlobExporter.ConsumeLogs,
 		exporterhelper.WithStart(azBlobExporter.start),
-		exporterhelper.WithRetry(cfg.BackOffConfig))
+		exporterhelper.WithShutdown(azBlobExporter.shutdown),
+		exporterhelper.WithQueue(cfg.QueueSettings))
 }
 
 func createMetricsExporter(ctx context.Context,
@@ -80,13 +185,17 @@ func createMetricsExporter(ctx context.Context,
 	config component.Config,
 ) (exporter.Metrics, error) {
 	cfg := config.(*Config)
-	azBlobExporter := newAzureBlobExporter(cfg, params.Logger, pipeline.SignalMetrics)
+	azBlobExporter, err := newAzureBlobExporter(cfg, params.ID, params.TelemetrySettings, pipeline.SignalMetrics)
+	if err != nil {
+		return nil, err
+	}
 
 	return exporterhelper.NewMetrics(ctx, params,
 		config,
 		azBlobExporter.ConsumeMetrics,
 		exporterhelper.WithStart(azBlobExporter.start),
-		exporterhelper.WithRetry(cfg.BackOffConfig))
+		exporterhelper.WithShutdown(azBlobExporter.shutdown),
+		exporterhelper.WithQueue(cfg.QueueSettings))
 }
 
 func createTracesExporter(ctx context.Context,
@@ -94,12 +203,16 @@ func createTracesExporter(ctx context.Context,
 	config component.Config,
 ) (exporter.Traces, error) {
 	cfg := config.(*Config)
-	azBlobExporter := newAzureBlobExporter(cfg, params.Logger, pipeline.SignalTraces)
+	azBlobExporter, err := newAzureBlobExporter(cfg, params.ID, params.TelemetrySettings, pipeline.SignalTraces)
+	if err != nil {
+		return nil, err
+	}
 
 	return exporterhelper.NewTraces(ctx,
 		params,
 		config,
 		azBlobExporter.ConsumeTraces,
 		exporterhelper.WithStart(azBlobExporter.start),
-		exporterhelper.WithRetry(cfg.BackOffConfig))
+		exporterhelper.WithShutdown(azBlobExporter.shutdown),
+		exporterhelper.WithQueue(cfg.QueueSettings))
 }
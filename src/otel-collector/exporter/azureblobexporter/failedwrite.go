@@ -0,0 +1,106 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"sync"
+	"time"
+)
+
+// maxFailedWrites is the number of failed batches remembered for their retry.
+const maxFailedWrites = 1000
+
+// failedWriteTimeout is how long a failed batch is remembered for its retry.
+const failedWriteTimeout = time.Hour
+
+// writtenPart is a blob of a batch: the index of its partition and its format.
+type writtenPart struct {
+	partition int
+	format    string
+}
+
+// failedWrite is the state of a batch whose write failed, reused when the batch is retried.
+type failedWrite struct {
+	// written holds the parts that were stored before the write failed
+	written map[writtenPart]bool
+	// sequence is the first preserve_order sequence number of the batch, 0 when it was not numbered
+	sequence int64
+	failed   time.Time
+}
+
+// failedWrites remembers the batches whose write failed, by a hash of their content, so a retry of
+// the same batch by the sending queue or a client only writes the parts that are still missing, and
+// keeps the sequence numbers of its first write.
+type failedWrites struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	writes map[string]*failedWrite
+}
+
+func newFailedWrites() *failedWrites {
+	return &failedWrites{
+		now:    time.Now,
+		writes: map[string]*failedWrite{},
+	}
+}
+
+// take returns and forgets the failed write of telemetryData, nil when it did not fail before.
+// The batch is only hashed when a failed write is remembered.
+func (f *failedWrites) take(telemetryData any) *failedWrite {
+	f.mu.Lock()
+	empty := len(f.writes) == 0
+	f.mu.Unlock()
+	if empty {
+		return nil
+	}
+
+	hash, err := contentHash(telemetryData)
+	if err != nil {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w, ok := f.writes[hash]
+	if !ok || f.now().Sub(w.failed) > failedWriteTimeout {
+		return nil
+	}
+	delete(f.writes, hash)
+	return w
+}
+
+// put remembers w as the failed write of telemetryData. When maxFailedWrites batches are
+// remembered, the oldest is forgotten.
+func (f *failedWrites) put(telemetryData any, w *failedWrite) {
+	hash, err := contentHash(telemetryData)
+	if err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	w.failed = now
+	f.prune(now)
+	if _, ok := f.writes[hash]; !ok && len(f.writes) >= maxFailedWrites {
+		var oldest string
+		for h, fw := range f.writes {
+			if oldest == "" || fw.failed.Before(f.writes[oldest].failed) {
+				oldest = h
+			}
+		}
+		delete(f.writes, oldest)
+	}
+	f.writes[hash] = w
+}
+
+// prune forgets the failed writes that were not retried in time. f.mu must be held.
+func (f *failedWrites) prune(now time.Time) {
+	for hash, w := range f.writes {
+		if now.Sub(w.failed) > failedWriteTimeout {
+			delete(f.writes, hash)
+		}
+	}
+}
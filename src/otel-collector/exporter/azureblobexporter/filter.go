@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// filterTracesWithErrors returns a copy of td that only contains the spans of traces with at least
+// one error span. Spans are grouped by trace ID across all resources and scopes of the batch, so a
+// trace is kept as a whole even when its error span lives under a different resource.
+func filterTracesWithErrors(td ptrace.Traces) ptrace.Traces {
+	errorTraces := make(map[pcommon.TraceID]struct{})
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if spans.At(k).Status().Code() == ptrace.StatusCodeError {
+					errorTraces[spans.At(k).TraceID()] = struct{}{}
+				}
+			}
+		}
+	}
+
+	filtered := ptrace.NewTraces()
+	if len(errorTraces) == 0 {
+		return filtered
+	}
+
+	td.CopyTo(filtered)
+	removeSpans(filtered, func(span ptrace.Span) bool {
+		_, ok := errorTraces[span.TraceID()]
+		return !ok
+	})
+	return filtered
+}
+
+// dedupSpans drops spans whose (trace ID, span ID) pair already appeared earlier in the batch,
+// keeping the first occurrence. td is only copied when it contains duplicates.
+func dedupSpans(td ptrace.Traces) (ptrace.Traces, int) {
+	type spanKey struct {
+		traceID pcommon.TraceID
+		spanID  pcommon.SpanID
+	}
+
+	isDuplicate := func() func(ptrace.Span) bool {
+		seen := make(map[spanKey]struct{})
+		return func(span ptrace.Span) bool {
+			key := spanKey{traceID: span.TraceID(), spanID: span.SpanID()}
+			if _, ok := seen[key]; ok {
+				return true
+			}
+			seen[key] = struct{}{}
+			return false
+		}
+	}
+
+	duplicates := 0
+	check := isDuplicate()
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if check(spans.At(k)) {
+					duplicates++
+				}
+			}
+		}
+	}
+	if duplicates == 0 {
+		return td, 0
+	}
+
+	deduped := ptrace.NewTraces()
+	td.CopyTo(deduped)
+	removeSpans(deduped, isDuplicate())
+	return deduped, duplicates
+}
+
+// removeSpans removes the spans matching remove from td, dropping scopes and resources left empty.
+func removeSpans(td ptrace.Traces, remove func(ptrace.Span) bool) {
+	td.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(remove)
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// newDuplicateTraces returns traces with two resources: the first holds spans "a" and "b", the second
+// a copy of "a" named "a again" and a span "c" of another trace.
+func newDuplicateTraces() ptrace.Traces {
+	td := ptrace.NewTraces()
+	addSpan := func(spans ptrace.SpanSlice, name string, traceID pcommon.TraceID, spanID pcommon.SpanID) {
+		span := spans.AppendEmpty()
+		span.SetName(name)
+		span.SetTraceID(traceID)
+		span.SetSpanID(spanID)
+	}
+	first := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	addSpan(first, "a", pcommon.TraceID{1}, pcommon.SpanID{1})
+	addSpan(first, "b", pcommon.TraceID{1}, pcommon.SpanID{2})
+	second := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	addSpan(second, "a again", pcommon.TraceID{1}, pcommon.SpanID{1})
+	addSpan(second, "c", pcommon.TraceID{2}, pcommon.SpanID{1})
+	return td
+}
+
+func TestDedupSpans(t *testing.T) {
+	td := newDuplicateTraces()
+
+	deduped, duplicates := dedupSpans(td)
+	assert.Equal(t, 1, duplicates)
+	assert.Equal(t, []string{"a", "b", "c"}, spanNames(deduped))
+	assert.Equal(t, 4, td.SpanCount(), "the batch is not modified")
+
+	again, duplicates := dedupSpans(deduped)
+	assert.Zero(t, duplicates)
+	assert.Equal(t, deduped, again)
+}
+
+func TestDedupSpansDropsEmptyResources(t *testing.T) {
+	td := ptrace.NewTraces()
+	for range 2 {
+		span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		span.SetTraceID(pcommon.TraceID{1})
+		span.SetSpanID(pcommon.SpanID{1})
+	}
+
+	deduped, duplicates := dedupSpans(td)
+	assert.Equal(t, 1, duplicates)
+	assert.Equal(t, 1, deduped.ResourceSpans().Len())
+}
+
+func TestConsumeTracesDedup(t *testing.T) {
+	tel := componenttest.NewTelemetry()
+	t.Cleanup(func() { require.NoError(t, tel.Shutdown(context.Background())) })
+	cfg := newTestConfig()
+	cfg.DedupSpans = true
+	exp, client := newTestExporterWithSettings(t, cfg, pipeline.SignalTraces, tel.NewTelemetrySettings())
+
+	require.NoError(t, exp.ConsumeTraces(context.Background(), newDuplicateTraces()))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 1)
+	written, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(uploads[0].data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, spanNames(written))
+	assert.Equal(t, int64(1), counterValue(t, tel, "otelcol_exporter_azureblob_deduplicated_spans"))
+}
@@ -0,0 +1,122 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// blobFormat returns the extension of the blob name before its serial.
+func blobFormat(blob string) string {
+	return strings.Split(path.Ext(blob), "_")[0]
+}
+
+func TestFormatOverride(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.FormatTraces = formatTypeParquet
+	cfg.FormatLogs = formatTypeNDJSON
+
+	traces, tracesClient := newTestExporter(t, cfg, pipeline.SignalTraces)
+	require.NoError(t, traces.ConsumeTraces(context.Background(), newTestTraces(map[string]any{"tenant": "acme"})))
+	logs, logsClient := newTestExporter(t, cfg, pipeline.SignalLogs)
+	require.NoError(t, logs.ConsumeLogs(context.Background(), newTestLogs(1, 2)))
+	metrics, metricsClient := newTestExporter(t, cfg, pipeline.SignalMetrics)
+	require.NoError(t, metrics.ConsumeMetrics(context.Background(), newTestMetrics(1)))
+
+	uploads := tracesClient.recorded()
+	require.Len(t, uploads, 1)
+	assert.Equal(t, ".parquet", blobFormat(uploads[0].blob))
+	assert.Len(t, readParquet[ParquetSpan](t, uploads[0].data), 1)
+
+	uploads = logsClient.recorded()
+	require.Len(t, uploads, 1)
+	assert.Equal(t, ".ndjson", blobFormat(uploads[0].blob))
+	assert.Len(t, nonEmptyLines(t, uploads[0].data), 2)
+
+	uploads = metricsClient.recorded()
+	require.Len(t, uploads, 1)
+	assert.Equal(t, ".json", blobFormat(uploads[0].blob), "signals without override use format")
+	_, err := (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics(uploads[0].data)
+	assert.NoError(t, err)
+}
+
+func TestFormatOverrideValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{
+			name:      "override",
+			configure: func(cfg *Config) { cfg.FormatLogs = formatTypeCSV },
+		},
+		{
+			name:      "unknown format",
+			configure: func(cfg *Config) { cfg.FormatMetrics = "xml" },
+			wantErr:   `unknown format type in format_metrics: "xml"`,
+		},
+		{
+			name: "with formats",
+			configure: func(cfg *Config) {
+				cfg.FormatTraces = formatTypeParquet
+				cfg.Formats = []string{formatTypeJSON, formatTypeNDJSON}
+			},
+			wantErr: "format_traces cannot be combined with formats",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tt.configure(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOTLPFormats(t *testing.T) {
+	tests := []struct {
+		format      string
+		unmarshaler ptrace.Unmarshaler
+	}{
+		{format: formatTypeOTLPJSON, unmarshaler: &ptrace.JSONUnmarshaler{}},
+		{format: formatTypeOTLPProto, unmarshaler: &ptrace.ProtoUnmarshaler{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			cfg := newTestConfig()
+			require.NoError(t, cfg.Unmarshal(confmap.NewFromStringMap(map[string]any{"format": tt.format})))
+			exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+			td := newTestTraces(map[string]any{"tenant": "acme"}, map[string]any{"tenant": "globex"})
+
+			require.NoError(t, exp.ConsumeTraces(context.Background(), td))
+
+			uploads := client.recorded()
+			require.Len(t, uploads, 1)
+			got, err := tt.unmarshaler.UnmarshalTraces(uploads[0].data)
+			require.NoError(t, err, "the blob is an OTLP ExportTraceServiceRequest")
+			require.Equal(t, td.SpanCount(), got.SpanCount())
+			for i := range td.ResourceSpans().Len() {
+				want := td.ResourceSpans().At(i).ScopeSpans().At(0).Spans().At(0)
+				span := got.ResourceSpans().At(i).ScopeSpans().At(0).Spans().At(0)
+				assert.Equal(t, want.TraceID(), span.TraceID())
+				assert.Equal(t, want.SpanID(), span.SpanID())
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	// idValidationDrop drops records with invalid trace or span IDs.
+	idValidationDrop = "drop"
+	// idValidationFlag keeps records with invalid IDs and marks them with invalidIDAttribute.
+	idValidationFlag = "flag"
+
+	invalidIDAttribute = "azureblob.invalid_id"
+)
+
+// pdata stores trace and span IDs as fixed 16 and 8 byte arrays, so IDs that are too short have
+// already been zero-padded by the time they reach the exporter. What remains detectable is an
+// all-zero ID: a span needs both IDs, and a log record that references a span must also carry the
+// trace it belongs to.
+func invalidSpanIDs(span ptrace.Span) bool {
+	return span.TraceID().IsEmpty() || span.SpanID().IsEmpty()
+}
+
+func invalidLogIDs(lr plog.LogRecord) bool {
+	return lr.TraceID().IsEmpty() && !lr.SpanID().IsEmpty()
+}
+
+// validateSpanIDs drops or flags the spans of td with invalid IDs according to mode and returns
+// the number of invalid spans. td is only copied when it contains invalid spans.
+func validateSpanIDs(td ptrace.Traces, mode string) (ptrace.Traces, int) {
+	invalid := 0
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if invalidSpanIDs(spans.At(k)) {
+					invalid++
+				}
+			}
+		}
+	}
+	if invalid == 0 {
+		return td, 0
+	}
+
+	validated := ptrace.NewTraces()
+	td.CopyTo(validated)
+	switch mode {
+	case idValidationDrop:
+		removeSpans(validated, invalidSpanIDs)
+	case idValidationFlag:
+		rss = validated.ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			sss := rss.At(i).ScopeSpans()
+			for j := 0; j < sss.Len(); j++ {
+				spans := sss.At(j).Spans()
+				for k := 0; k < spans.Len(); k++ {
+					if invalidSpanIDs(spans.At(k)) {
+						spans.At(k).Attributes().PutBool(invalidIDAttribute, true)
+					}
+				}
+			}
+		}
+	}
+	return validated, invalid
+}
+
+// validateLogIDs drops or flags the log records of ld with invalid IDs according to mode and
+// returns the number of invalid records. ld is only copied when it contains invalid records.
+func validateLogIDs(ld plog.Logs, mode string) (plog.Logs, int) {
+	invalid := 0
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		sls := rls.At(i).ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			records := sls.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				if invalidLogIDs(records.At(k)) {
+					invalid++
+				}
+			}
+		}
+	}
+	if invalid == 0 {
+		return ld, 0
+	}
+
+	validated := plog.NewLogs()
+	ld.CopyTo(validated)
+	switch mode {
+	case idValidationDrop:
+		validated.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+			rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+				sl.LogRecords().RemoveIf(invalidLogIDs)
+				return sl.LogRecords().Len() == 0
+			})
+			return rl.ScopeLogs().Len() == 0
+		})
+	case idValidationFlag:
+		rls = validated.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			sls := rls.At(i).ScopeLogs()
+			for j := 0; j < sls.Len(); j++ {
+				records := sls.At(j).LogRecords()
+				for k := 0; k < records.Len(); k++ {
+					if invalidLogIDs(records.At(k)) {
+						records.At(k).Attributes().PutBool(invalidIDAttribute, true)
+					}
+				}
+			}
+		}
+	}
+	return validated, invalid
+}
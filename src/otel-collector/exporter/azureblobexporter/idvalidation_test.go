@@ -0,0 +1,155 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+var (
+	testTraceID = pcommon.TraceID([16]byte{1, 2, 3})
+	testSpanID  = pcommon.SpanID([8]byte{1, 2, 3})
+)
+
+// newIDTraces returns traces with a span named after each entry of ids, carrying its trace and span ID.
+func newIDTraces(ids map[string][2]bool) ptrace.Traces {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for name, set := range ids {
+		span := spans.AppendEmpty()
+		span.SetName(name)
+		if set[0] {
+			span.SetTraceID(testTraceID)
+		}
+		if set[1] {
+			span.SetSpanID(testSpanID)
+		}
+	}
+	return td
+}
+
+func TestValidateSpanIDs(t *testing.T) {
+	td := newIDTraces(map[string][2]bool{
+		"valid":      {true, true},
+		"no trace":   {false, true},
+		"no span":    {true, false},
+		"no ids":     {false, false},
+		"also valid": {true, true},
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		validated, invalid := validateSpanIDs(td, idValidationDrop)
+		assert.Equal(t, 3, invalid)
+		assert.ElementsMatch(t, []string{"valid", "also valid"}, spanNames(validated))
+		assert.Equal(t, 5, td.SpanCount(), "the batch is not modified")
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		validated, invalid := validateSpanIDs(td, idValidationFlag)
+		assert.Equal(t, 3, invalid)
+		spans := validated.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+		require.Equal(t, 5, spans.Len())
+		for i := 0; i < spans.Len(); i++ {
+			_, flagged := spans.At(i).Attributes().Get(invalidIDAttribute)
+			assert.Equal(t, invalidSpanIDs(spans.At(i)), flagged, spans.At(i).Name())
+		}
+	})
+
+	t.Run("all valid", func(t *testing.T) {
+		valid := newIDTraces(map[string][2]bool{"valid": {true, true}})
+		validated, invalid := validateSpanIDs(valid, idValidationDrop)
+		assert.Zero(t, invalid)
+		assert.Equal(t, valid, validated)
+	})
+}
+
+func TestValidateLogIDs(t *testing.T) {
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Body().SetStr("without ids")
+	withIDs := records.AppendEmpty()
+	withIDs.Body().SetStr("with ids")
+	withIDs.SetTraceID(testTraceID)
+	withIDs.SetSpanID(testSpanID)
+	onlyTrace := records.AppendEmpty()
+	onlyTrace.Body().SetStr("only trace")
+	onlyTrace.SetTraceID(testTraceID)
+	onlySpan := records.AppendEmpty()
+	onlySpan.Body().SetStr("only span")
+	onlySpan.SetSpanID(testSpanID)
+
+	t.Run("drop", func(t *testing.T) {
+		validated, invalid := validateLogIDs(ld, idValidationDrop)
+		assert.Equal(t, 1, invalid)
+		assert.Equal(t, []string{"without ids", "with ids", "only trace"}, logBodies(validated))
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		validated, invalid := validateLogIDs(ld, idValidationFlag)
+		assert.Equal(t, 1, invalid)
+		flagged := validated.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(3)
+		v, ok := flagged.Attributes().Get(invalidIDAttribute)
+		require.True(t, ok)
+		assert.True(t, v.Bool())
+	})
+}
+
+func TestConsumeTracesWithInvalidIDs(t *testing.T) {
+	tel := componenttest.NewTelemetry()
+	t.Cleanup(func() { require.NoError(t, tel.Shutdown(context.Background())) })
+	cfg := newTestConfig()
+	cfg.IDValidation = idValidationDrop
+	exp, client := newTestExporterWithSettings(t, cfg, pipeline.SignalTraces, tel.NewTelemetrySettings())
+
+	require.NoError(t, exp.ConsumeTraces(context.Background(), newIDTraces(map[string][2]bool{"no ids": {false, false}})))
+	assert.Empty(t, client.recorded(), "a batch without valid spans is not written")
+	assert.Equal(t, int64(1), counterValue(t, tel, "otelcol_exporter_azureblob_invalid_ids"))
+}
+
+func TestIDValidationConfig(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.IDValidation = "fix"
+	assert.ErrorContains(t, cfg.Validate(), `unknown id_validation mode "fix"`)
+}
+
+// spanNames returns the names of the spans of td.
+func spanNames(td ptrace.Traces) []string {
+	var names []string
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				names = append(names, spans.At(k).Name())
+			}
+		}
+	}
+	return names
+}
+
+// logBodies returns the string bodies of the log records of ld.
+func logBodies(ld plog.Logs) []string {
+	var bodies []string
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		sls := rls.At(i).ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			records := sls.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				bodies = append(bodies, records.At(k).Body().Str())
+			}
+		}
+	}
+	return bodies
+}
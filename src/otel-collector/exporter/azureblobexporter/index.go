@@ -0,0 +1,44 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"go.uber.org/zap"
+)
+
+// indexEntry is a line of the container index.
+type indexEntry struct {
+	Blob      string    `json:"blob"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int       `json:"size"`
+}
+
+// appendIndexEntry appends an entry for an upload of size bytes to blobName to the index blob of
+// the container. Appends are atomic, so collectors sharing a container can write the same index
+// without coordinating. A failure is only logged: the blob itself is stored, and failing the export
+// would upload it again.
+func (e *azureBlobExporter) appendIndexEntry(ctx context.Context, containerName, blobName string, size int) {
+	line, err := json.Marshal(indexEntry{Blob: blobName, Timestamp: time.Now().UTC(), Size: size})
+	if err != nil {
+		e.logger.Warn("Failed to encode container index entry", zap.Error(err))
+		return
+	}
+	line = append(line, '\n')
+
+	err = e.retryUpload(ctx, containerName, func(ctx context.Context) error {
+		return e.client.AppendBlock(ctx, containerName, e.config.ContainerIndex.BlobName, line, &appendblob.AppendBlockOptions{CPKInfo: e.cpk, CPKScopeInfo: e.cpkScope})
+	})
+	if err != nil {
+		e.logger.Warn("Failed to append to container index",
+			zap.String("container", containerName),
+			zap.String("index", e.config.ContainerIndex.BlobName),
+			zap.String("blob", blobName),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// NDJSON record structs for OpenTelemetry data. Field names follow OTLP JSON, and attributes keep
+// their value types.
+
+type ndjsonScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type ndjsonStatus struct {
+	Code    int32  `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type ndjsonSpanEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+}
+
+type ndjsonSpanLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	TraceState string         `json:"traceState,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type ndjsonSpan struct {
+	TraceID           string            `json:"traceId"`
+	SpanID            string            `json:"spanId"`
+	ParentSpanID      string            `json:"parentSpanId,omitempty"`
+	TraceState        string            `json:"traceState,omitempty"`
+	Flags             uint32            `json:"flags,omitempty"`
+	Name              string            `json:"name"`
+	Kind              int32             `json:"kind"`
+	StartTimeUnixNano string            `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string            `json:"endTimeUnixNano"`
+	Status            ndjsonStatus      `json:"status"`
+	Attributes        map[string]any    `json:"attributes,omitempty"`
+	Events            []ndjsonSpanEvent `json:"events,omitempty"`
+	Links             []ndjsonSpanLink  `json:"links,omitempty"`
+	Resource          map[string]any    `json:"resource,omitempty"`
+	Scope             ndjsonScope       `json:"scope"`
+}
+
+type ndjsonLog struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int32          `json:"severityNumber,omitempty"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 any            `json:"body,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+	Flags                uint32         `json:"flags,omitempty"`
+	Attributes           map[string]any `json:"attributes,omitempty"`
+	Resource             map[string]any `json:"resource,omitempty"`
+	Scope                ndjsonScope    `json:"scope"`
+}
+
+type ndjsonDataPoint struct {
+	Name              string             `json:"name"`
+	OriginalName      string             `json:"originalName,omitempty"`
+	Description       string             `json:"description,omitempty"`
+	Unit              string             `json:"unit,omitempty"`
+	Type              string             `json:"type"`
+	StartTimeUnixNano string             `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string             `json:"timeUnixNano"`
+	Value             any                `json:"value,omitempty"`
+	Count             *uint64            `json:"count,omitempty"`
+	Sum               *float64           `json:"sum,omitempty"`
+	Min               *float64           `json:"min,omitempty"`
+	Max               *float64           `json:"max,omitempty"`
+	BucketCounts      []uint64           `json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64          `json:"explicitBounds,omitempty"`
+	Quantiles         map[string]float64 `json:"quantiles,omitempty"`
+	Attributes        map[string]any     `json:"attributes,omitempty"`
+	Resource          map[string]any     `json:"resource,omitempty"`
+	Scope             ndjsonScope        `json:"scope"`
+}
+
+// ndjsonMarshaller writes one JSON object per line for every span, log record and metric data point.
+type ndjsonMarshaller struct {
+	// metricNameReplacer normalizes metric names, nil when metric_name_normalization is disabled
+	metricNameReplacer *strings.Replacer
+}
+
+func newNDJSONMarshaller(metricNameReplacer *strings.Replacer) *ndjsonMarshaller {
+	return &ndjsonMarshaller{metricNameReplacer: metricNameReplacer}
+}
+
+func (*ndjsonMarshaller) MarshalTraces(td ptrace.Traces) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		resource := rs.Resource().Attributes().AsRaw()
+
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			scope := ndjsonScope{Name: ss.Scope().Name(), Version: ss.Scope().Version()}
+
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				parentSpanID := ""
+				if !span.ParentSpanID().IsEmpty() {
+					parentSpanID = span.ParentSpanID().String()
+				}
+				err := enc.Encode(ndjsonSpan{
+					TraceID:           span.TraceID().String(),
+					SpanID:            span.SpanID().String(),
+					ParentSpanID:      parentSpanID,
+					TraceState:        span.TraceState().AsRaw(),
+					Flags:             span.Flags(),
+					Name:              span.Name(),
+					Kind:              int32(span.Kind()),
+					StartTimeUnixNano: nanos(span.StartTimestamp()),
+					EndTimeUnixNano:   nanos(span.EndTimestamp()),
+					Status:            ndjsonStatus{Code: int32(span.Status().Code()), Message: span.Status().Message()},
+					Attributes:        span.Attributes().AsRaw(),
+					Events:            ndjsonSpanEvents(span.Events()),
+					Links:             ndjsonSpanLinks(span.Links()),
+					Resource:          resource,
+					Scope:             scope,
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (*ndjsonMarshaller) MarshalLogs(ld plog.Logs) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		resource := rl.Resource().Attributes().AsRaw()
+
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			scope := ndjsonScope{Name: sl.Scope().Name(), Version: sl.Scope().Version()}
+
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				logRecord := sl.LogRecords().At(k)
+				record := ndjsonLog{
+					TimeUnixNano:         nanos(logRecord.Timestamp()),
+					ObservedTimeUnixNano: nanos(logRecord.ObservedTimestamp()),
+					SeverityNumber:       int32(logRecord.SeverityNumber()),
+					SeverityText:         logRecord.SeverityText(),
+					Body:                 logRecord.Body().AsRaw(),
+					Flags:                uint32(logRecord.Flags()),
+					Attributes:           logRecord.Attributes().AsRaw(),
+					Resource:             resource,
+					Scope:                scope,
+				}
+				if !logRecord.TraceID().IsEmpty() {
+					record.TraceID = logRecord.TraceID().String()
+				}
+				if !logRecord.SpanID().IsEmpty() {
+					record.SpanID = logRecord.SpanID().String()
+				}
+				if err := enc.Encode(record); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (n *ndjsonMarshaller) MarshalMetrics(md pmetric.Metrics) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		resource := rm.Resource().Attributes().AsRaw()
+
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			scope := ndjsonScope{Name: sm.Scope().Name(), Version: sm.Scope().Version()}
+
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				for _, dp := range ndjsonDataPoints(sm.Metrics().At(k)) {
+					dp.Resource = resource
+					dp.Scope = scope
+					if n.metricNameReplacer != nil {
+						dp.OriginalName = dp.Name
+						dp.Name = n.metricNameReplacer.Replace(dp.Name)
+					}
+					if err := enc.Encode(dp); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (*ndjsonMarshaller) format() string {
+	return formatTypeNDJSON
+}
+
+func ndjsonSpanEvents(events ptrace.SpanEventSlice) []ndjsonSpanEvent {
+	var records []ndjsonSpanEvent
+	for i := 0; i < events.Len(); i++ {
+		event := events.At(i)
+		records = append(records, ndjsonSpanEvent{
+			TimeUnixNano: nanos(event.Timestamp()),
+			Name:         event.Name(),
+			Attributes:   event.Attributes().AsRaw(),
+		})
+	}
+	return records
+}
+
+func ndjsonSpanLinks(links ptrace.SpanLinkSlice) []ndjsonSpanLink {
+	var records []ndjsonSpanLink
+	for i := 0; i < links.Len(); i++ {
+		link := links.At(i)
+		records = append(records, ndjsonSpanLink{
+			TraceID:    link.TraceID().String(),
+			SpanID:     link.SpanID().String(),
+			TraceState: link.TraceState().AsRaw(),
+			Attributes: link.Attributes().AsRaw(),
+		})
+	}
+	return records
+}
+
+// ndjsonDataPoints returns a record for every data point of metric.
+func ndjsonDataPoints(metric pmetric.Metric) []ndjsonDataPoint {
+	newDataPoint := func(attrs pcommon.Map, start, ts pcommon.Timestamp) ndjsonDataPoint {
+		dp := ndjsonDataPoint{
+			Name:         metric.Name(),
+			Description:  metric.Description(),
+			Unit:         metric.Unit(),
+			Type:         strings.ToLower(metric.Type().String()),
+			TimeUnixNano: nanos(ts),
+			Attributes:   attrs.AsRaw(),
+		}
+		if start != 0 {
+			dp.StartTimeUnixNano = nanos(start)
+		}
+		return dp
+	}
+	numberValue := func(dp pmetric.NumberDataPoint) any {
+		if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+			return dp.IntValue()
+		}
+		return dp.DoubleValue()
+	}
+
+	var records []ndjsonDataPoint
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			record := newDataPoint(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+			record.Value = numberValue(dp)
+			records = append(records, record)
+		}
+	case pmetric.MetricTypeSum:
+		dps := metric.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			record := newDataPoint(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+			record.Value = numberValue(dp)
+			records = append(records, record)
+		}
+	case pmetric.MetricTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			record := newDataPoint(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+			record.Count = ptr(dp.Count())
+			if dp.HasSum() {
+				record.Sum = ptr(dp.Sum())
+			}
+			if dp.HasMin() {
+				record.Min = ptr(dp.Min())
+			}
+			if dp.HasMax() {
+				record.Max = ptr(dp.Max())
+			}
+			record.BucketCounts = dp.BucketCounts().AsRaw()
+			record.ExplicitBounds = dp.ExplicitBounds().AsRaw()
+			records = append(records, record)
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			record := newDataPoint(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+			record.Count = ptr(dp.Count())
+			if dp.HasSum() {
+				record.Sum = ptr(dp.Sum())
+			}
+			if dp.HasMin() {
+				record.Min = ptr(dp.Min())
+			}
+			if dp.HasMax() {
+				record.Max = ptr(dp.Max())
+			}
+			records = append(records, record)
+		}
+	case pmetric.MetricTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			dp := dps.At(i)
+			record := newDataPoint(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+			record.Count = ptr(dp.Count())
+			record.Sum = ptr(dp.Sum())
+			if dp.QuantileValues().Len() > 0 {
+				record.Quantiles = make(map[string]float64, dp.QuantileValues().Len())
+				for q := 0; q < dp.QuantileValues().Len(); q++ {
+					qv := dp.QuantileValues().At(q)
+					record.Quantiles[strconv.FormatFloat(qv.Quantile(), 'g', -1, 64)] = qv.Value()
+				}
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// nanos formats ts as a string, as OTLP JSON does for 64-bit integers.
+func nanos(ts pcommon.Timestamp) string {
+	return strconv.FormatUint(uint64(ts), 10)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
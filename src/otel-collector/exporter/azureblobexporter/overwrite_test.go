@@ -0,0 +1,86 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+func TestOverwritePolicyAccessConditions(t *testing.T) {
+	tests := []struct {
+		policy        string
+		wantCondition bool
+	}{
+		{policy: overwritePolicyOverwrite},
+		{policy: overwritePolicyFail, wantCondition: true},
+		{policy: overwritePolicySkip, wantCondition: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.OverwritePolicy = tt.policy
+			exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+
+			require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+
+			uploads := client.recorded()
+			require.Len(t, uploads, 1)
+			if !tt.wantCondition {
+				assert.Nil(t, uploads[0].opts.AccessConditions)
+				return
+			}
+			require.NotNil(t, uploads[0].opts.AccessConditions)
+			assert.Equal(t, azcore.ETagAny, *uploads[0].opts.AccessConditions.ModifiedAccessConditions.IfNoneMatch)
+		})
+	}
+}
+
+func TestOverwritePolicyBlobExists(t *testing.T) {
+	alreadyExists := &azcore.ResponseError{StatusCode: http.StatusConflict, ErrorCode: string(bloberror.BlobAlreadyExists)}
+	conditionNotMet := &azcore.ResponseError{StatusCode: http.StatusPreconditionFailed, ErrorCode: string(bloberror.ConditionNotMet)}
+	serverError := &azcore.ResponseError{StatusCode: http.StatusInternalServerError, ErrorCode: string(bloberror.InternalError)}
+	tests := []struct {
+		name    string
+		policy  string
+		err     error
+		wantErr error
+	}{
+		{name: "skip conflict", policy: overwritePolicySkip, err: alreadyExists},
+		{name: "skip precondition failed", policy: overwritePolicySkip, err: conditionNotMet},
+		{name: "skip other error", policy: overwritePolicySkip, err: serverError, wantErr: serverError},
+		{name: "fail conflict", policy: overwritePolicyFail, err: alreadyExists, wantErr: alreadyExists},
+		{name: "fail precondition failed", policy: overwritePolicyFail, err: conditionNotMet, wantErr: conditionNotMet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.OverwritePolicy = tt.policy
+			exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+			client.fail = func(string, string) error { return tt.err }
+
+			err := exp.ConsumeLogs(context.Background(), newTestLogs(1))
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOverwritePolicyValidate(t *testing.T) {
+	cfg := newTestConfig()
+	assert.Equal(t, overwritePolicyOverwrite, cfg.OverwritePolicy)
+
+	cfg.OverwritePolicy = "replace"
+	assert.ErrorContains(t, cfg.Validate(), `unknown overwrite_policy "replace", must be "overwrite", "fail" or "skip"`)
+}
@@ -5,82 +5,208 @@ package azureblobexporter
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/encoding"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
-// Parquet schema structs for OpenTelemetry data
+// Parquet schema structs for OpenTelemetry data. The avro tags name the fields of the avro format,
+// which writes the same rows.
 
 // ParquetSpan represents a trace span in Parquet format
 type ParquetSpan struct {
-	TraceID            string            `parquet:"trace_id"`
-	SpanID             string            `parquet:"span_id"`
-	ParentSpanID       string            `parquet:"parent_span_id,optional"`
-	Name               string            `parquet:"name"`
-	Kind               int32             `parquet:"kind"`
-	StartTimeUnixNano  int64             `parquet:"start_time_unix_nano"`
-	EndTimeUnixNano    int64             `parquet:"end_time_unix_nano"`
-	StatusCode         int32             `parquet:"status_code"`
-	StatusMessage      string            `parquet:"status_message,optional"`
-	ResourceAttributes map[string]string `parquet:"resource_attributes,optional"`
-	SpanAttributes     map[string]string `parquet:"span_attributes,optional"`
-	ScopeName          string            `parquet:"scope_name,optional"`
-	ScopeVersion       string            `parquet:"scope_version,optional"`
+	TraceID            string             `parquet:"trace_id" avro:"trace_id"`
+	SpanID             string             `parquet:"span_id" avro:"span_id"`
+	ParentSpanID       string             `parquet:"parent_span_id,optional" avro:"parent_span_id"`
+	TraceState         string             `parquet:"trace_state,optional" avro:"trace_state"`
+	Flags              uint32             `parquet:"flags,optional" avro:"flags"`
+	Name               string             `parquet:"name" avro:"name"`
+	Kind               int32              `parquet:"kind" avro:"kind"`
+	StartTimeUnixNano  *int64             `parquet:"start_time_unix_nano,optional" avro:"start_time_unix_nano"`
+	EndTimeUnixNano    *int64             `parquet:"end_time_unix_nano,optional" avro:"end_time_unix_nano"`
+	StatusCode         int32              `parquet:"status_code" avro:"status_code"`
+	StatusMessage      string             `parquet:"status_message,optional" avro:"status_message"`
+	ResourceAttributes map[string]string  `parquet:"resource_attributes,optional" avro:"resource_attributes"`
+	SpanAttributes     map[string]string  `parquet:"span_attributes,optional" avro:"span_attributes"`
+	ScopeName          string             `parquet:"scope_name,optional" avro:"scope_name"`
+	ScopeVersion       string             `parquet:"scope_version,optional" avro:"scope_version"`
+	Events             []ParquetSpanEvent `parquet:"events,list,optional" avro:"events"`
+	Links              []ParquetSpanLink  `parquet:"links,list,optional" avro:"links"`
+	// Typed attributes are only populated when parquet.typed_attributes is enabled
+	ResourceAttributesTyped *ParquetTypedAttributes `parquet:"resource_attributes_typed,optional" avro:"resource_attributes_typed"`
+	SpanAttributesTyped     *ParquetTypedAttributes `parquet:"span_attributes_typed,optional" avro:"span_attributes_typed"`
+	// Dropped counts are only populated when include_dropped_counts is enabled
+	DroppedAttributesCount *uint32 `parquet:"dropped_attributes_count,optional" avro:"dropped_attributes_count"`
+	DroppedEventsCount     *uint32 `parquet:"dropped_events_count,optional" avro:"dropped_events_count"`
+	DroppedLinksCount      *uint32 `parquet:"dropped_links_count,optional" avro:"dropped_links_count"`
+}
+
+// ParquetSpanEvent represents a span event in Parquet format
+type ParquetSpanEvent struct {
+	TimeUnixNano *int64            `parquet:"time_unix_nano,optional" avro:"time_unix_nano"`
+	Name         string            `parquet:"name" avro:"name"`
+	Attributes   map[string]string `parquet:"attributes,optional" avro:"attributes"`
+	// DroppedAttributesCount is only populated when include_dropped_counts is enabled
+	DroppedAttributesCount *uint32 `parquet:"dropped_attributes_count,optional" avro:"dropped_attributes_count"`
+}
+
+// ParquetSpanLink represents a link from a span to another span in Parquet format
+type ParquetSpanLink struct {
+	TraceID    string            `parquet:"trace_id" avro:"trace_id"`
+	SpanID     string            `parquet:"span_id" avro:"span_id"`
+	TraceState string            `parquet:"trace_state,optional" avro:"trace_state"`
+	Attributes map[string]string `parquet:"attributes,optional" avro:"attributes"`
+	// DroppedAttributesCount is only populated when include_dropped_counts is enabled
+	DroppedAttributesCount *uint32 `parquet:"dropped_attributes_count,optional" avro:"dropped_attributes_count"`
 }
 
 // ParquetLog represents a log record in Parquet format
 type ParquetLog struct {
-	Timestamp          int64             `parquet:"timestamp_unix_nano"`
-	ObservedTimestamp  int64             `parquet:"observed_timestamp_unix_nano"`
-	SeverityNumber     int32             `parquet:"severity_number"`
-	SeverityText       string            `parquet:"severity_text,optional"`
-	Body               string            `parquet:"body"`
-	TraceID            string            `parquet:"trace_id,optional"`
-	SpanID             string            `parquet:"span_id,optional"`
-	Flags              uint32            `parquet:"flags"`
-	ResourceAttributes map[string]string `parquet:"resource_attributes,optional"`
-	LogAttributes      map[string]string `parquet:"log_attributes,optional"`
-	ScopeName          string            `parquet:"scope_name,optional"`
-	ScopeVersion       string            `parquet:"scope_version,optional"`
+	Timestamp          *int64            `parquet:"timestamp_unix_nano,optional" avro:"timestamp_unix_nano"`
+	ObservedTimestamp  *int64            `parquet:"observed_timestamp_unix_nano,optional" avro:"observed_timestamp_unix_nano"`
+	SeverityNumber     int32             `parquet:"severity_number" avro:"severity_number"`
+	SeverityText       string            `parquet:"severity_text,optional" avro:"severity_text"`
+	Body               string            `parquet:"body" avro:"body"`
+	TraceID            string            `parquet:"trace_id,optional" avro:"trace_id"`
+	SpanID             string            `parquet:"span_id,optional" avro:"span_id"`
+	Flags              uint32            `parquet:"flags" avro:"flags"`
+	ResourceAttributes map[string]string `parquet:"resource_attributes,optional" avro:"resource_attributes"`
+	LogAttributes      map[string]string `parquet:"log_attributes,optional" avro:"log_attributes"`
+	ScopeName          string            `parquet:"scope_name,optional" avro:"scope_name"`
+	ScopeVersion       string            `parquet:"scope_version,optional" avro:"scope_version"`
+	// Typed attributes are only populated when parquet.typed_attributes is enabled
+	ResourceAttributesTyped *ParquetTypedAttributes `parquet:"resource_attributes_typed,optional" avro:"resource_attributes_typed"`
+	LogAttributesTyped      *ParquetTypedAttributes `parquet:"log_attributes_typed,optional" avro:"log_attributes_typed"`
+	// DroppedAttributesCount is only populated when include_dropped_counts is enabled
+	DroppedAttributesCount *uint32 `parquet:"dropped_attributes_count,optional" avro:"dropped_attributes_count"`
 }
 
 // ParquetMetric represents a metric data point in Parquet format
 type ParquetMetric struct {
-	Name               string            `parquet:"name"`
-	Description        string            `parquet:"description,optional"`
-	Unit               string            `parquet:"unit,optional"`
-	Type               string            `parquet:"type"` // gauge, sum, histogram, etc.
-	TimeUnixNano       int64             `parquet:"time_unix_nano"`
-	ValueType          string            `parquet:"value_type"` // int, double
-	IntValue           int64             `parquet:"int_value,optional"`
-	DoubleValue        float64           `parquet:"double_value,optional"`
-	ResourceAttributes map[string]string `parquet:"resource_attributes,optional"`
-	MetricAttributes   map[string]string `parquet:"metric_attributes,optional"`
-	ScopeName          string            `parquet:"scope_name,optional"`
-	ScopeVersion       string            `parquet:"scope_version,optional"`
+	Name               string            `parquet:"name" avro:"name"`
+	Description        string            `parquet:"description,optional" avro:"description"`
+	Unit               string            `parquet:"unit,optional" avro:"unit"`
+	Type               string            `parquet:"type" avro:"type"` // gauge, sum, histogram, etc.
+	TimeUnixNano       *int64            `parquet:"time_unix_nano,optional" avro:"time_unix_nano"`
+	ValueType          string            `parquet:"value_type" avro:"value_type"` // int, double
+	IntValue           int64             `parquet:"int_value,optional" avro:"int_value"`
+	DoubleValue        float64           `parquet:"double_value,optional" avro:"double_value"`
+	ResourceAttributes map[string]string `parquet:"resource_attributes,optional" avro:"resource_attributes"`
+	MetricAttributes   map[string]string `parquet:"metric_attributes,optional" avro:"metric_attributes"`
+	ScopeName          string            `parquet:"scope_name,optional" avro:"scope_name"`
+	ScopeVersion       string            `parquet:"scope_version,optional" avro:"scope_version"`
+	// OriginalName is the name before metric_name_normalization, only populated when it is enabled
+	OriginalName string `parquet:"original_name,optional" avro:"original_name"`
+	// Typed attributes are only populated when parquet.typed_attributes is enabled
+	ResourceAttributesTyped *ParquetTypedAttributes `parquet:"resource_attributes_typed,optional" avro:"resource_attributes_typed"`
+	MetricAttributesTyped   *ParquetTypedAttributes `parquet:"metric_attributes_typed,optional" avro:"metric_attributes_typed"`
 	// For Sum metrics
-	IsMonotonic            bool   `parquet:"is_monotonic,optional"`
-	AggregationTemporality string `parquet:"aggregation_temporality,optional"`
-	StartTimeUnixNano      int64  `parquet:"start_time_unix_nano,optional"`
+	IsMonotonic            bool   `parquet:"is_monotonic,optional" avro:"is_monotonic"`
+	AggregationTemporality string `parquet:"aggregation_temporality,optional" avro:"aggregation_temporality"`
+	StartTimeUnixNano      int64  `parquet:"start_time_unix_nano,optional" avro:"start_time_unix_nano"`
+	// For Summary metrics, whose sum is the double value. Avro has no unsigned long, so the avro
+	// format writes the count of avroMetric instead.
+	Count          *uint64                `parquet:"count,optional" avro:"-"`
+	QuantileValues []ParquetQuantileValue `parquet:"quantile_values,list,optional" avro:"quantile_values"`
+	// Exemplars is the JSON encoded list of the exemplars of gauge, sum, histogram and exponential
+	// histogram data points
+	Exemplars string `parquet:"exemplars,optional" avro:"exemplars"`
 }
 
-type parquetMarshaller struct{}
+// ParquetQuantileValue represents a quantile of a summary data point in Parquet format
+type ParquetQuantileValue struct {
+	Quantile float64 `parquet:"quantile" avro:"quantile"`
+	Value    float64 `parquet:"value" avro:"value"`
+}
 
-func newParquetMarshaller() *parquetMarshaller {
-	return &parquetMarshaller{}
+// parquetExemplar is an exemplar of a metric data point, as encoded into the exemplars column.
+type parquetExemplar struct {
+	// Value is an int64 or a float64. Non-finite doubles are the strings "NaN", "Infinity" and
+	// "-Infinity", as in OTLP JSON.
+	Value             any    `json:"value"`
+	TimestampUnixNano int64  `json:"timestamp_unix_nano"`
+	TraceID           string `json:"trace_id,omitempty"`
+	SpanID            string `json:"span_id,omitempty"`
+}
+
+// ParquetTypedAttributes holds the int, double and bool attributes under their original type, so
+// they can be filtered on without parsing the string map. Other attributes, such as slices and
+// maps, are only in the string map, as JSON.
+type ParquetTypedAttributes struct {
+	Int    map[string]int64   `parquet:"int,optional" avro:"int"`
+	Double map[string]float64 `parquet:"double,optional" avro:"double"`
+	Bool   map[string]bool    `parquet:"bool,optional" avro:"bool"`
+}
+
+type parquetMarshaller struct {
+	includeDroppedCounts  bool
+	nullMissingTimestamps bool
+	typedAttributes       bool
+	mapValueEncoding      encoding.Encoding
+	writeStatistics       bool
+	writerOptions         []parquet.WriterOption
+	metricNameReplacer    *strings.Replacer
+}
+
+func newParquetMarshaller(config *Config) (*parquetMarshaller, error) {
+	mapValueEncoding, err := parquetEncoding(config.Parquet.MapValueEncoding)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := parquetCompression(config.Parquet.Compression)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetMarshaller{
+		includeDroppedCounts:  config.IncludeDroppedCounts,
+		nullMissingTimestamps: config.NullMissingTimestamps,
+		typedAttributes:       config.Parquet.TypedAttributes,
+		mapValueEncoding:      mapValueEncoding,
+		writeStatistics:       config.Parquet.WriteStatistics,
+		metricNameReplacer:    config.MetricNameNormalization.replacer(),
+		writerOptions: []parquet.WriterOption{
+			parquet.Compression(compression),
+			parquet.MaxRowsPerRowGroup(config.Parquet.RowGroupSize),
+			parquet.KeyValueMetadata(parquetSchemaVersionKey, strconv.Itoa(parquetSchemaVersion)),
+		},
+	}, nil
 }
 
 func (p *parquetMarshaller) MarshalTraces(td ptrace.Traces) ([]byte, error) {
+	return marshalToParquet(p.spanRows(td), p.mapValueEncoding, p.writeStatistics, p.writerOptions...)
+}
+
+func (p *parquetMarshaller) MarshalLogs(ld plog.Logs) ([]byte, error) {
+	return marshalToParquet(p.logRows(ld), p.mapValueEncoding, p.writeStatistics, p.writerOptions...)
+}
+
+func (p *parquetMarshaller) MarshalMetrics(md pmetric.Metrics) ([]byte, error) {
+	return marshalToParquet(p.metricRows(md), p.mapValueEncoding, p.writeStatistics, p.writerOptions...)
+}
+
+func (p *parquetMarshaller) format() string {
+	return formatTypeParquet
+}
+
+// spanRows returns a row for every span of td. The rows are shared with the avro format.
+func (p *parquetMarshaller) spanRows(td ptrace.Traces) []ParquetSpan {
 	var spans []ParquetSpan
 
 	for i := 0; i < td.ResourceSpans().Len(); i++ {
 		rs := td.ResourceSpans().At(i)
 		resourceAttrs := attributesToMap(rs.Resource().Attributes())
+		resourceTyped := p.typedAttributesOf(rs.Resource().Attributes())
 
 		for j := 0; j < rs.ScopeSpans().Len(); j++ {
 			ss := rs.ScopeSpans().At(j)
@@ -96,34 +222,47 @@ func (p *parquetMarshaller) MarshalTraces(td ptrace.Traces) ([]byte, error) {
 				}
 
 				parquetSpan := ParquetSpan{
-					TraceID:            span.TraceID().String(),
-					SpanID:             span.SpanID().String(),
-					ParentSpanID:       parentSpanID,
-					Name:               span.Name(),
-					Kind:               int32(span.Kind()),
-					StartTimeUnixNano:  int64(span.StartTimestamp()),
-					EndTimeUnixNano:    int64(span.EndTimestamp()),
-					StatusCode:         int32(span.Status().Code()),
-					StatusMessage:      span.Status().Message(),
-					ResourceAttributes: resourceAttrs,
-					SpanAttributes:     attributesToMap(span.Attributes()),
-					ScopeName:          scopeName,
-					ScopeVersion:       scopeVersion,
+					TraceID:                 span.TraceID().String(),
+					SpanID:                  span.SpanID().String(),
+					ParentSpanID:            parentSpanID,
+					TraceState:              span.TraceState().AsRaw(),
+					Flags:                   span.Flags(),
+					Name:                    span.Name(),
+					Kind:                    int32(span.Kind()),
+					StartTimeUnixNano:       p.timestamp(span.StartTimestamp()),
+					EndTimeUnixNano:         p.timestamp(span.EndTimestamp()),
+					StatusCode:              int32(span.Status().Code()),
+					StatusMessage:           span.Status().Message(),
+					ResourceAttributes:      resourceAttrs,
+					SpanAttributes:          attributesToMap(span.Attributes()),
+					ScopeName:               scopeName,
+					ScopeVersion:            scopeVersion,
+					Events:                  p.spanEvents(span.Events()),
+					Links:                   p.spanLinks(span.Links()),
+					ResourceAttributesTyped: resourceTyped,
+					SpanAttributesTyped:     p.typedAttributesOf(span.Attributes()),
+				}
+				if p.includeDroppedCounts {
+					parquetSpan.DroppedAttributesCount = uint32Ptr(span.DroppedAttributesCount())
+					parquetSpan.DroppedEventsCount = uint32Ptr(span.DroppedEventsCount())
+					parquetSpan.DroppedLinksCount = uint32Ptr(span.DroppedLinksCount())
 				}
 				spans = append(spans, parquetSpan)
 			}
 		}
 	}
 
-	return marshalToParquet(spans)
+	return spans
 }
 
-func (p *parquetMarshaller) MarshalLogs(ld plog.Logs) ([]byte, error) {
+// logRows returns a row for every log record of ld.
+func (p *parquetMarshaller) logRows(ld plog.Logs) []ParquetLog {
 	var logs []ParquetLog
 
 	for i := 0; i < ld.ResourceLogs().Len(); i++ {
 		rl := ld.ResourceLogs().At(i)
 		resourceAttrs := attributesToMap(rl.Resource().Attributes())
+		resourceTyped := p.typedAttributesOf(rl.Resource().Attributes())
 
 		for j := 0; j < rl.ScopeLogs().Len(); j++ {
 			sl := rl.ScopeLogs().At(j)
@@ -144,33 +283,41 @@ func (p *parquetMarshaller) MarshalLogs(ld plog.Logs) ([]byte, error) {
 				}
 
 				parquetLog := ParquetLog{
-					Timestamp:          int64(logRecord.Timestamp()),
-					ObservedTimestamp:  int64(logRecord.ObservedTimestamp()),
-					SeverityNumber:     int32(logRecord.SeverityNumber()),
-					SeverityText:       logRecord.SeverityText(),
-					Body:               logRecord.Body().AsString(),
-					TraceID:            traceID,
-					SpanID:             spanID,
-					Flags:              uint32(logRecord.Flags()),
-					ResourceAttributes: resourceAttrs,
-					LogAttributes:      attributesToMap(logRecord.Attributes()),
-					ScopeName:          scopeName,
-					ScopeVersion:       scopeVersion,
+					Timestamp:               p.timestamp(logRecord.Timestamp()),
+					ObservedTimestamp:       p.timestamp(logRecord.ObservedTimestamp()),
+					SeverityNumber:          int32(logRecord.SeverityNumber()),
+					SeverityText:            logRecord.SeverityText(),
+					Body:                    logRecord.Body().AsString(),
+					TraceID:                 traceID,
+					SpanID:                  spanID,
+					Flags:                   uint32(logRecord.Flags()),
+					ResourceAttributes:      resourceAttrs,
+					LogAttributes:           attributesToMap(logRecord.Attributes()),
+					ScopeName:               scopeName,
+					ScopeVersion:            scopeVersion,
+					ResourceAttributesTyped: resourceTyped,
+					LogAttributesTyped:      p.typedAttributesOf(logRecord.Attributes()),
+				}
+				if p.includeDroppedCounts {
+					parquetLog.DroppedAttributesCount = uint32Ptr(logRecord.DroppedAttributesCount())
 				}
 				logs = append(logs, parquetLog)
 			}
 		}
 	}
 
-	return marshalToParquet(logs)
+	return logs
 }
 
-func (p *parquetMarshaller) MarshalMetrics(md pmetric.Metrics) ([]byte, error) {
+// metricRows returns a row for every data point of md.
+func (p *parquetMarshaller) metricRows(md pmetric.Metrics) []ParquetMetric {
 	var metrics []ParquetMetric
 
 	for i := 0; i < md.ResourceMetrics().Len(); i++ {
 		rm := md.ResourceMetrics().At(i)
 		resourceAttrs := attributesToMap(rm.Resource().Attributes())
+		resourceTyped := p.typedAttributesOf(rm.Resource().Attributes())
+		first := len(metrics)
 
 		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
 			sm := rm.ScopeMetrics().At(j)
@@ -183,28 +330,70 @@ func (p *parquetMarshaller) MarshalMetrics(md pmetric.Metrics) ([]byte, error) {
 				// Handle different metric types
 				switch metric.Type() {
 				case pmetric.MetricTypeGauge:
-					metrics = append(metrics, extractGaugeMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
+					metrics = append(metrics, p.extractGaugeMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
 				case pmetric.MetricTypeSum:
-					metrics = append(metrics, extractSumMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
+					metrics = append(metrics, p.extractSumMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
 				case pmetric.MetricTypeHistogram:
-					metrics = append(metrics, extractHistogramMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
+					metrics = append(metrics, p.extractHistogramMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
 				case pmetric.MetricTypeSummary:
-					metrics = append(metrics, extractSummaryMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
+					metrics = append(metrics, p.extractSummaryMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
 				case pmetric.MetricTypeExponentialHistogram:
-					metrics = append(metrics, extractExponentialHistogramMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
+					metrics = append(metrics, p.extractExponentialHistogramMetrics(metric, resourceAttrs, scopeName, scopeVersion)...)
 				}
 			}
 		}
+		for k := first; k < len(metrics); k++ {
+			metrics[k].ResourceAttributesTyped = resourceTyped
+			if p.metricNameReplacer != nil {
+				metrics[k].OriginalName = metrics[k].Name
+				metrics[k].Name = p.metricNameReplacer.Replace(metrics[k].Name)
+			}
+		}
 	}
 
-	return marshalToParquet(metrics)
+	return metrics
 }
 
-func (p *parquetMarshaller) format() string {
-	return formatTypeParquet
+// Helper functions
+
+func (p *parquetMarshaller) spanEvents(events ptrace.SpanEventSlice) []ParquetSpanEvent {
+	if events.Len() == 0 {
+		return nil
+	}
+	result := make([]ParquetSpanEvent, events.Len())
+	for i := 0; i < events.Len(); i++ {
+		event := events.At(i)
+		result[i] = ParquetSpanEvent{
+			TimeUnixNano: p.timestamp(event.Timestamp()),
+			Name:         event.Name(),
+			Attributes:   attributesToMap(event.Attributes()),
+		}
+		if p.includeDroppedCounts {
+			result[i].DroppedAttributesCount = uint32Ptr(event.DroppedAttributesCount())
+		}
+	}
+	return result
 }
 
-// Helper functions
+func (p *parquetMarshaller) spanLinks(links ptrace.SpanLinkSlice) []ParquetSpanLink {
+	if links.Len() == 0 {
+		return nil
+	}
+	result := make([]ParquetSpanLink, links.Len())
+	for i := 0; i < links.Len(); i++ {
+		link := links.At(i)
+		result[i] = ParquetSpanLink{
+			TraceID:    link.TraceID().String(),
+			SpanID:     link.SpanID().String(),
+			TraceState: link.TraceState().AsRaw(),
+			Attributes: attributesToMap(link.Attributes()),
+		}
+		if p.includeDroppedCounts {
+			result[i].DroppedAttributesCount = uint32Ptr(link.DroppedAttributesCount())
+		}
+	}
+	return result
+}
 
 func attributesToMap(attrs pcommon.Map) map[string]string {
 	result := make(map[string]string, attrs.Len())
@@ -215,22 +404,108 @@ func attributesToMap(attrs pcommon.Map) map[string]string {
 	return result
 }
 
-func extractGaugeMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
+// typedAttributesOf returns the int, double and bool attributes of attrs, or nil when
+// typed_attributes is disabled.
+func (p *parquetMarshaller) typedAttributesOf(attrs pcommon.Map) *ParquetTypedAttributes {
+	if !p.typedAttributes {
+		return nil
+	}
+	typed := &ParquetTypedAttributes{}
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		switch v.Type() {
+		case pcommon.ValueTypeInt:
+			if typed.Int == nil {
+				typed.Int = map[string]int64{}
+			}
+			typed.Int[k] = v.Int()
+		case pcommon.ValueTypeDouble:
+			if typed.Double == nil {
+				typed.Double = map[string]float64{}
+			}
+			typed.Double[k] = v.Double()
+		case pcommon.ValueTypeBool:
+			if typed.Bool == nil {
+				typed.Bool = map[string]bool{}
+			}
+			typed.Bool[k] = v.Bool()
+		}
+		return true
+	})
+	return typed
+}
+
+// timestamp converts ts for a timestamp column. Unset timestamps are stored as null instead of the
+// Unix epoch when null_missing_timestamps is enabled.
+func (p *parquetMarshaller) timestamp(ts pcommon.Timestamp) *int64 {
+	if ts == 0 && p.nullMissingTimestamps {
+		return nil
+	}
+	v := int64(ts)
+	return &v
+}
+
+// exemplarsJSON returns the JSON encoded exemplars, or an empty string when there are none.
+func exemplarsJSON(exemplars pmetric.ExemplarSlice) string {
+	if exemplars.Len() == 0 {
+		return ""
+	}
+	result := make([]parquetExemplar, exemplars.Len())
+	for i := 0; i < exemplars.Len(); i++ {
+		exemplar := exemplars.At(i)
+		result[i].TimestampUnixNano = int64(exemplar.Timestamp())
+		switch exemplar.ValueType() {
+		case pmetric.ExemplarValueTypeInt:
+			result[i].Value = exemplar.IntValue()
+		case pmetric.ExemplarValueTypeDouble:
+			result[i].Value = jsonFloat(exemplar.DoubleValue())
+		}
+		if !exemplar.TraceID().IsEmpty() {
+			result[i].TraceID = exemplar.TraceID().String()
+		}
+		if !exemplar.SpanID().IsEmpty() {
+			result[i].SpanID = exemplar.SpanID().String()
+		}
+	}
+	// Marshalling cannot fail, as non-finite values are strings
+	data, _ := json.Marshal(result)
+	return string(data)
+}
+
+// jsonFloat returns v, or its OTLP JSON string when it is not finite, which JSON numbers cannot hold.
+func jsonFloat(v float64) any {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	}
+	return v
+}
+
+func uint32Ptr(v uint32) *uint32 {
+	return &v
+}
+
+func (p *parquetMarshaller) extractGaugeMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
 	var metrics []ParquetMetric
 	gauge := metric.Gauge()
 
 	for i := 0; i < gauge.DataPoints().Len(); i++ {
 		dp := gauge.DataPoints().At(i)
 		pm := ParquetMetric{
-			Name:               metric.Name(),
-			Description:        metric.Description(),
-			Unit:               metric.Unit(),
-			Type:               "gauge",
-			TimeUnixNano:       int64(dp.Timestamp()),
-			ResourceAttributes: resourceAttrs,
-			MetricAttributes:   attributesToMap(dp.Attributes()),
-			ScopeName:          scopeName,
-			ScopeVersion:       scopeVersion,
+			Name:                  metric.Name(),
+			Description:           metric.Description(),
+			Unit:                  metric.Unit(),
+			Type:                  "gauge",
+			TimeUnixNano:          p.timestamp(dp.Timestamp()),
+			ResourceAttributes:    resourceAttrs,
+			MetricAttributes:      attributesToMap(dp.Attributes()),
+			MetricAttributesTyped: p.typedAttributesOf(dp.Attributes()),
+			ScopeName:             scopeName,
+			ScopeVersion:          scopeVersion,
+			Exemplars:             exemplarsJSON(dp.Exemplars()),
 		}
 
 		switch dp.ValueType() {
@@ -248,7 +523,7 @@ func extractGaugeMetrics(metric pmetric.Metric, resourceAttrs map[string]string,
 	return metrics
 }
 
-func extractSumMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
+func (p *parquetMarshaller) extractSumMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
 	var metrics []ParquetMetric
 	sum := metric.Sum()
 
@@ -267,12 +542,14 @@ func extractSumMetrics(metric pmetric.Metric, resourceAttrs map[string]string, s
 			Description:            metric.Description(),
 			Unit:                   metric.Unit(),
 			Type:                   "sum",
-			TimeUnixNano:           int64(dp.Timestamp()),
+			TimeUnixNano:           p.timestamp(dp.Timestamp()),
 			StartTimeUnixNano:      int64(dp.StartTimestamp()),
 			ResourceAttributes:     resourceAttrs,
 			MetricAttributes:       attributesToMap(dp.Attributes()),
+			MetricAttributesTyped:  p.typedAttributesOf(dp.Attributes()),
 			ScopeName:              scopeName,
 			ScopeVersion:           scopeVersion,
+			Exemplars:              exemplarsJSON(dp.Exemplars()),
 			IsMonotonic:            sum.IsMonotonic(),
 			AggregationTemporality: aggregationTemporality,
 		}
@@ -292,7 +569,7 @@ func extractSumMetrics(metric pmetric.Metric, resourceAttrs map[string]string, s
 	return metrics
 }
 
-func extractHistogramMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
+func (p *parquetMarshaller) extractHistogramMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
 	var metrics []ParquetMetric
 	histogram := metric.Histogram()
 
@@ -313,14 +590,16 @@ func extractHistogramMetrics(metric pmetric.Metric, resourceAttrs map[string]str
 			Description:            metric.Description(),
 			Unit:                   metric.Unit(),
 			Type:                   "histogram",
-			TimeUnixNano:           int64(dp.Timestamp()),
+			TimeUnixNano:           p.timestamp(dp.Timestamp()),
 			StartTimeUnixNano:      int64(dp.StartTimestamp()),
 			ValueType:              "double",
 			DoubleValue:            dp.Sum(),
 			ResourceAttributes:     resourceAttrs,
 			MetricAttributes:       attributesToMap(dp.Attributes()),
+			MetricAttributesTyped:  p.typedAttributesOf(dp.Attributes()),
 			ScopeName:              scopeName,
 			ScopeVersion:           scopeVersion,
+			Exemplars:              exemplarsJSON(dp.Exemplars()),
 			AggregationTemporality: aggregationTemporality,
 		}
 
@@ -330,7 +609,7 @@ func extractHistogramMetrics(metric pmetric.Metric, resourceAttrs map[string]str
 	return metrics
 }
 
-func extractSummaryMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
+func (p *parquetMarshaller) extractSummaryMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
 	var metrics []ParquetMetric
 	summary := metric.Summary()
 
@@ -339,18 +618,24 @@ func extractSummaryMetrics(metric pmetric.Metric, resourceAttrs map[string]strin
 
 		// Store summary sum as the primary value
 		pm := ParquetMetric{
-			Name:               metric.Name(),
-			Description:        metric.Description(),
-			Unit:               metric.Unit(),
-			Type:               "summary",
-			TimeUnixNano:       int64(dp.Timestamp()),
-			StartTimeUnixNano:  int64(dp.StartTimestamp()),
-			ValueType:          "double",
-			DoubleValue:        dp.Sum(),
-			ResourceAttributes: resourceAttrs,
-			MetricAttributes:   attributesToMap(dp.Attributes()),
-			ScopeName:          scopeName,
-			ScopeVersion:       scopeVersion,
+			Name:                  metric.Name(),
+			Description:           metric.Description(),
+			Unit:                  metric.Unit(),
+			Type:                  "summary",
+			TimeUnixNano:          p.timestamp(dp.Timestamp()),
+			StartTimeUnixNano:     int64(dp.StartTimestamp()),
+			ValueType:             "double",
+			DoubleValue:           dp.Sum(),
+			ResourceAttributes:    resourceAttrs,
+			MetricAttributes:      attributesToMap(dp.Attributes()),
+			MetricAttributesTyped: p.typedAttributesOf(dp.Attributes()),
+			ScopeName:             scopeName,
+			ScopeVersion:          scopeVersion,
+			Count:                 ptr(dp.Count()),
+		}
+		for q := 0; q < dp.QuantileValues().Len(); q++ {
+			qv := dp.QuantileValues().At(q)
+			pm.QuantileValues = append(pm.QuantileValues, ParquetQuantileValue{Quantile: qv.Quantile(), Value: qv.Value()})
 		}
 
 		metrics = append(metrics, pm)
@@ -359,7 +644,7 @@ func extractSummaryMetrics(metric pmetric.Metric, resourceAttrs map[string]strin
 	return metrics
 }
 
-func extractExponentialHistogramMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
+func (p *parquetMarshaller) extractExponentialHistogramMetrics(metric pmetric.Metric, resourceAttrs map[string]string, scopeName, scopeVersion string) []ParquetMetric {
 	var metrics []ParquetMetric
 	expHistogram := metric.ExponentialHistogram()
 
@@ -379,14 +664,16 @@ func extractExponentialHistogramMetrics(metric pmetric.Metric, resourceAttrs map
 			Description:            metric.Description(),
 			Unit:                   metric.Unit(),
 			Type:                   "exponential_histogram",
-			TimeUnixNano:           int64(dp.Timestamp()),
+			TimeUnixNano:           p.timestamp(dp.Timestamp()),
 			StartTimeUnixNano:      int64(dp.StartTimestamp()),
 			ValueType:              "double",
 			DoubleValue:            dp.Sum(),
 			ResourceAttributes:     resourceAttrs,
 			MetricAttributes:       attributesToMap(dp.Attributes()),
+			MetricAttributesTyped:  p.typedAttributesOf(dp.Attributes()),
 			ScopeName:              scopeName,
 			ScopeVersion:           scopeVersion,
+			Exemplars:              exemplarsJSON(dp.Exemplars()),
 			AggregationTemporality: aggregationTemporality,
 		}
 
@@ -396,14 +683,22 @@ func extractExponentialHistogramMetrics(metric pmetric.Metric, resourceAttrs map
 	return metrics
 }
 
-func marshalToParquet[T any](rows []T) ([]byte, error) {
+func marshalToParquet[T any](rows []T, mapValueEncoding encoding.Encoding, writeStatistics bool, options ...parquet.WriterOption) ([]byte, error) {
 	if len(rows) == 0 {
 		return []byte{}, nil
 	}
 
+	schema := parquetSchemaOf[T](mapValueEncoding)
+	options = append([]parquet.WriterOption{schema}, options...)
+	if !writeStatistics {
+		// The min/max bounds are what costs CPU; null counts are still recorded
+		for _, path := range schema.Columns() {
+			options = append(options, parquet.SkipPageBounds(path...))
+		}
+	}
+
 	buf := new(bytes.Buffer)
-	// Create writer with Snappy compression
-	writer := parquet.NewGenericWriter[T](buf, parquet.Compression(&parquet.Snappy))
+	writer := parquet.NewGenericWriter[T](buf, options...)
 
 	_, err := writer.Write(rows)
 	if err != nil {
@@ -417,3 +712,14 @@ func marshalToParquet[T any](rows []T) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+// parquetFileInfo reads the row count and a short hex digest of the schema from the footer of a
+// marshalled parquet file.
+func parquetFileInfo(data []byte) (int64, string, error) {
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read parquet footer: %w", err)
+	}
+	sum := sha256.Sum256([]byte(file.Schema().String()))
+	return file.NumRows(), hex.EncodeToString(sum[:4]), nil
+}
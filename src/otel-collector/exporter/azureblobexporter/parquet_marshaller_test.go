@@ -0,0 +1,344 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// newTestParquetMarshaller returns the parquet marshaller of the test configuration changed by configure.
+func newTestParquetMarshaller(t *testing.T, configure func(*ParquetConfig)) *parquetMarshaller {
+	t.Helper()
+	cfg := newTestConfig()
+	if configure != nil {
+		configure(&cfg.Parquet)
+	}
+	require.NoError(t, cfg.Validate())
+	m, err := newParquetMarshaller(cfg)
+	require.NoError(t, err)
+	return m
+}
+
+// openParquet opens the parquet file data to inspect its footer.
+func openParquet(t *testing.T, data []byte) *parquet.File {
+	t.Helper()
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return file
+}
+
+// readParquet reads the rows of the parquet file data.
+func readParquet[T any](t *testing.T, data []byte) []T {
+	t.Helper()
+	rows, err := parquet.Read[T](bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	return rows
+}
+
+func TestParquetCompression(t *testing.T) {
+	td := newTestTraces(map[string]any{"tenant": "acme"}, map[string]any{"tenant": "globex"})
+	want := readParquet[ParquetSpan](t, mustMarshalTraces(t, newTestParquetMarshaller(t, nil), td))
+	require.Len(t, want, 2)
+
+	for name, codec := range parquetCompressions {
+		t.Run(name, func(t *testing.T) {
+			data := mustMarshalTraces(t, newTestParquetMarshaller(t, func(cfg *ParquetConfig) { cfg.Compression = name }), td)
+
+			for _, rowGroup := range openParquet(t, data).Metadata().RowGroups {
+				for _, column := range rowGroup.Columns {
+					assert.Equal(t, codec.CompressionCodec(), column.MetaData.Codec, column.MetaData.PathInSchema)
+				}
+			}
+			assert.Equal(t, want, readParquet[ParquetSpan](t, data), "every codec decodes to the same rows")
+		})
+	}
+}
+
+func TestParquetCompressionDefault(t *testing.T) {
+	m := newTestParquetMarshaller(t, func(cfg *ParquetConfig) { cfg.Compression = "" })
+	data := mustMarshalTraces(t, m, newTestTraces(map[string]any{}))
+
+	column := openParquet(t, data).Metadata().RowGroups[0].Columns[0]
+	assert.Equal(t, parquet.Snappy.CompressionCodec(), column.MetaData.Codec, "snappy stays the default codec")
+}
+
+// mustMarshalTraces marshals td with m.
+func mustMarshalTraces(t *testing.T, m *parquetMarshaller, td ptrace.Traces) []byte {
+	t.Helper()
+	data, err := m.MarshalTraces(td)
+	require.NoError(t, err)
+	return data
+}
+
+// newTestSpans returns traces with n spans in a single resource.
+func newTestSpans(n int) ptrace.Traces {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i := range n {
+		span := spans.AppendEmpty()
+		span.SetName("span")
+		span.SetTraceID(pcommon.TraceID([16]byte{1, byte(i >> 8), byte(i)}))
+		span.SetSpanID(pcommon.SpanID([8]byte{1, byte(i >> 8), byte(i)}))
+	}
+	return td
+}
+
+func TestParquetRowGroupSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		rowGroupSize  int64
+		spans         int
+		wantRowGroups []int64
+	}{
+		{name: "writer default", spans: 1000, wantRowGroups: []int64{1000}},
+		{name: "even split", rowGroupSize: 100, spans: 1000, wantRowGroups: []int64{100, 100, 100, 100, 100, 100, 100, 100, 100, 100}},
+		{name: "last row group smaller", rowGroupSize: 400, spans: 1000, wantRowGroups: []int64{400, 400, 200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestParquetMarshaller(t, func(cfg *ParquetConfig) { cfg.RowGroupSize = tt.rowGroupSize })
+			data := mustMarshalTraces(t, m, newTestSpans(tt.spans))
+
+			file := openParquet(t, data)
+			var rowGroups []int64
+			for _, rowGroup := range file.Metadata().RowGroups {
+				rowGroups = append(rowGroups, rowGroup.NumRows)
+			}
+			assert.Equal(t, tt.wantRowGroups, rowGroups)
+			assert.Len(t, readParquet[ParquetSpan](t, data), tt.spans)
+		})
+	}
+}
+
+func TestParquetRowGroupSizeValidate(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Parquet.RowGroupSize = -1
+	assert.EqualError(t, cfg.Validate(), "parquet.row_group_size cannot be negative")
+}
+
+func TestParquetWriteStatistics(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(strconv.FormatBool(enabled), func(t *testing.T) {
+			m := newTestParquetMarshaller(t, func(cfg *ParquetConfig) { cfg.WriteStatistics = enabled })
+			data := mustMarshalTraces(t, m, newTestSpans(10))
+
+			checked := 0
+			for _, rowGroup := range openParquet(t, data).Metadata().RowGroups {
+				for _, column := range rowGroup.Columns {
+					stats := column.MetaData.Statistics
+					path := strings.Join(column.MetaData.PathInSchema, ".")
+					if path != "trace_id" && path != "span_id" && path != "name" {
+						continue
+					}
+					checked++
+					if enabled {
+						assert.NotEmpty(t, stats.MinValue, path)
+						assert.NotEmpty(t, stats.MaxValue, path)
+					} else {
+						assert.Empty(t, stats.MinValue, path)
+						assert.Empty(t, stats.MaxValue, path)
+					}
+				}
+			}
+			assert.Equal(t, 3, checked)
+			assert.Len(t, readParquet[ParquetSpan](t, data), 10)
+		})
+	}
+}
+
+// newTestSpanWithEvents returns traces with a span holding two events and a link.
+func newTestSpanWithEvents() ptrace.Traces {
+	td := newTestSpans(1)
+	span := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	for i, name := range []string{"retry", "exception"} {
+		event := span.Events().AppendEmpty()
+		event.SetName(name)
+		event.SetTimestamp(pcommon.Timestamp(1000 + i))
+		event.Attributes().PutInt("attempt", int64(i+1))
+	}
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(pcommon.TraceID([16]byte{2, 1}))
+	link.SetSpanID(pcommon.SpanID([8]byte{2, 1}))
+	link.TraceState().FromRaw("vendor=value")
+	link.Attributes().PutStr("relation", "follows")
+	return td
+}
+
+func TestParquetSpanEventsAndLinks(t *testing.T) {
+	data := mustMarshalTraces(t, newTestParquetMarshaller(t, nil), newTestSpanWithEvents())
+
+	spans := readParquet[ParquetSpan](t, data)
+	require.Len(t, spans, 1)
+	assert.Equal(t, []ParquetSpanEvent{
+		{TimeUnixNano: ptr(int64(1000)), Name: "retry", Attributes: map[string]string{"attempt": "1"}},
+		{TimeUnixNano: ptr(int64(1001)), Name: "exception", Attributes: map[string]string{"attempt": "2"}},
+	}, spans[0].Events)
+	assert.Equal(t, []ParquetSpanLink{{
+		TraceID:    "02010000000000000000000000000000",
+		SpanID:     "0201000000000000",
+		TraceState: "vendor=value",
+		Attributes: map[string]string{"relation": "follows"},
+	}}, spans[0].Links)
+}
+
+func TestJSONSpanEventsAndLinks(t *testing.T) {
+	td := newTestSpanWithEvents()
+	data, err := newJSONMarshaller().MarshalTraces(td)
+	require.NoError(t, err)
+
+	written, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(data)
+	require.NoError(t, err)
+	want := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	got := written.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, want.Events().Len(), got.Events().Len())
+	for i := range want.Events().Len() {
+		assert.Equal(t, want.Events().At(i).Name(), got.Events().At(i).Name())
+		assert.Equal(t, want.Events().At(i).Timestamp(), got.Events().At(i).Timestamp())
+		assert.Equal(t, want.Events().At(i).Attributes().AsRaw(), got.Events().At(i).Attributes().AsRaw())
+	}
+	require.Equal(t, 1, got.Links().Len())
+	assert.Equal(t, want.Links().At(0).TraceID(), got.Links().At(0).TraceID())
+	assert.Equal(t, want.Links().At(0).SpanID(), got.Links().At(0).SpanID())
+	assert.Equal(t, want.Links().At(0).Attributes().AsRaw(), got.Links().At(0).Attributes().AsRaw())
+}
+
+func TestSpanTraceStateAndFlags(t *testing.T) {
+	td := newTestSpans(1)
+	span := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	span.TraceState().FromRaw("congo=t61rcWkgMzE,rojo=00f067aa0ba902b7")
+	span.SetFlags(1) // sampled
+
+	t.Run("parquet", func(t *testing.T) {
+		spans := readParquet[ParquetSpan](t, mustMarshalTraces(t, newTestParquetMarshaller(t, nil), td))
+		require.Len(t, spans, 1)
+		assert.Equal(t, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", spans[0].TraceState)
+		assert.Equal(t, uint32(1), spans[0].Flags)
+	})
+	t.Run("json", func(t *testing.T) {
+		data, err := newJSONMarshaller().MarshalTraces(td)
+		require.NoError(t, err)
+		written, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(data)
+		require.NoError(t, err)
+		got := written.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+		assert.Equal(t, "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7", got.TraceState().AsRaw())
+		assert.Equal(t, uint32(1), got.Flags())
+	})
+}
+
+func TestParquetExemplars(t *testing.T) {
+	tests := []struct {
+		name       string
+		dataPoints func(pmetric.Metric) pmetric.NumberDataPointSlice
+	}{
+		{name: "gauge", dataPoints: func(m pmetric.Metric) pmetric.NumberDataPointSlice { return m.SetEmptyGauge().DataPoints() }},
+		{name: "sum", dataPoints: func(m pmetric.Metric) pmetric.NumberDataPointSlice { return m.SetEmptySum().DataPoints() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := pmetric.NewMetrics()
+			metric := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+			metric.SetName("http.server.duration")
+			dp := tt.dataPoints(metric).AppendEmpty()
+			dp.SetDoubleValue(0.25)
+			first := dp.Exemplars().AppendEmpty()
+			first.SetDoubleValue(0.9)
+			first.SetTimestamp(1000)
+			first.SetTraceID(pcommon.TraceID([16]byte{1, 2, 3}))
+			first.SetSpanID(pcommon.SpanID([8]byte{4, 5, 6}))
+			second := dp.Exemplars().AppendEmpty()
+			second.SetIntValue(7)
+			second.SetTimestamp(2000)
+
+			data, err := newTestParquetMarshaller(t, nil).MarshalMetrics(md)
+			require.NoError(t, err)
+
+			metrics := readParquet[ParquetMetric](t, data)
+			require.Len(t, metrics, 1)
+			var exemplars []parquetExemplar
+			require.NoError(t, json.Unmarshal([]byte(metrics[0].Exemplars), &exemplars))
+			assert.Equal(t, []parquetExemplar{
+				{Value: 0.9, TimestampUnixNano: 1000, TraceID: "01020300000000000000000000000000", SpanID: "0405060000000000"},
+				{Value: float64(7), TimestampUnixNano: 2000},
+			}, exemplars)
+		})
+	}
+}
+
+func TestParquetSummaryQuantiles(t *testing.T) {
+	md := pmetric.NewMetrics()
+	metric := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("rpc.duration")
+	dp := metric.SetEmptySummary().DataPoints().AppendEmpty()
+	dp.SetCount(42)
+	dp.SetSum(12.5)
+	for _, q := range []ParquetQuantileValue{{Quantile: 0.5, Value: 0.1}, {Quantile: 0.95, Value: 0.8}, {Quantile: 0.99, Value: 1.7}} {
+		qv := dp.QuantileValues().AppendEmpty()
+		qv.SetQuantile(q.Quantile)
+		qv.SetValue(q.Value)
+	}
+
+	data, err := newTestParquetMarshaller(t, nil).MarshalMetrics(md)
+	require.NoError(t, err)
+
+	metrics := readParquet[ParquetMetric](t, data)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "summary", metrics[0].Type)
+	assert.Equal(t, 12.5, metrics[0].DoubleValue)
+	require.NotNil(t, metrics[0].Count)
+	assert.Equal(t, uint64(42), *metrics[0].Count)
+	assert.Equal(t, []ParquetQuantileValue{{Quantile: 0.5, Value: 0.1}, {Quantile: 0.95, Value: 0.8}, {Quantile: 0.99, Value: 1.7}}, metrics[0].QuantileValues)
+}
+
+func TestParquetDroppedCounts(t *testing.T) {
+	td := newTestSpanWithEvents()
+	span := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	span.SetDroppedAttributesCount(3)
+	span.SetDroppedEventsCount(4)
+	span.SetDroppedLinksCount(5)
+	span.Events().At(0).SetDroppedAttributesCount(6)
+	ld := newTestLogs(1)
+	ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).SetDroppedAttributesCount(7)
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(strconv.FormatBool(enabled), func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.IncludeDroppedCounts = enabled
+			m, err := newParquetMarshaller(cfg)
+			require.NoError(t, err)
+
+			spans := readParquet[ParquetSpan](t, mustMarshalTraces(t, m, td))
+			require.Len(t, spans, 1)
+			data, err := m.MarshalLogs(ld)
+			require.NoError(t, err)
+			logs := readParquet[ParquetLog](t, data)
+			require.Len(t, logs, 1)
+
+			if !enabled {
+				assert.Nil(t, spans[0].DroppedAttributesCount)
+				assert.Nil(t, spans[0].DroppedEventsCount)
+				assert.Nil(t, spans[0].DroppedLinksCount)
+				assert.Nil(t, spans[0].Events[0].DroppedAttributesCount)
+				assert.Nil(t, logs[0].DroppedAttributesCount)
+				return
+			}
+			assert.Equal(t, ptr(uint32(3)), spans[0].DroppedAttributesCount)
+			assert.Equal(t, ptr(uint32(4)), spans[0].DroppedEventsCount)
+			assert.Equal(t, ptr(uint32(5)), spans[0].DroppedLinksCount)
+			assert.Equal(t, ptr(uint32(6)), spans[0].Events[0].DroppedAttributesCount)
+			assert.Equal(t, ptr(uint32(0)), spans[0].Links[0].DroppedAttributesCount)
+			assert.Equal(t, ptr(uint32(7)), logs[0].DroppedAttributesCount)
+		})
+	}
+}
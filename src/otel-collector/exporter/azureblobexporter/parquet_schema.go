@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/encoding"
+)
+
+const (
+	// parquetSchemaVersion is the version of the parquet row schemas. It is only incremented by changes
+	// that break readers, such as removed, renamed or retyped columns. New optional columns keep it.
+	parquetSchemaVersion = 1
+	// parquetSchemaVersionKey is the footer metadata key holding the schema version of a parquet blob.
+	parquetSchemaVersionKey = "otel.schema_version"
+)
+
+// parquetEncodings maps the supported map_value_encoding values to parquet encodings.
+var parquetEncodings = map[string]encoding.Encoding{
+	"plain":                   &parquet.Plain,
+	"dictionary":              &parquet.RLEDictionary,
+	"delta_length_byte_array": &parquet.DeltaLengthByteArray,
+	"delta_byte_array":        &parquet.DeltaByteArray,
+}
+
+// parquetEncoding returns the parquet encoding named name, or nil for an empty name.
+func parquetEncoding(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return nil, nil
+	}
+	enc, ok := parquetEncodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parquet encoding %q", name)
+	}
+	return enc, nil
+}
+
+// parquetCompressions maps the supported parquet.compression values to parquet codecs.
+var parquetCompressions = map[string]compress.Codec{
+	"none":   &parquet.Uncompressed,
+	"snappy": &parquet.Snappy,
+	"gzip":   &parquet.Gzip,
+	"brotli": &parquet.Brotli,
+	"zstd":   &parquet.Zstd,
+}
+
+// parquetCompression returns the parquet codec named name. An empty name selects snappy, which
+// was the only codec before the codec became configurable.
+func parquetCompression(name string) (compress.Codec, error) {
+	if name == "" {
+		return &parquet.Snappy, nil
+	}
+	codec, ok := parquetCompressions[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parquet compression %q", name)
+	}
+	return codec, nil
+}
+
+// parquetSchemaOf returns the schema of the row type T. When mapValueEncoding is set, it is applied
+// to the value column of every string map field, e.g. to dictionary encode repetitive attribute
+// values.
+func parquetSchemaOf[T any](mapValueEncoding encoding.Encoding) *parquet.Schema {
+	schema := parquet.SchemaOf(new(T))
+	if mapValueEncoding == nil {
+		return schema
+	}
+	return parquet.NewSchema(schema.Name(), withMapValueEncoding(schema, mapValueEncoding, false))
+}
+
+// withMapValueEncoding rebuilds the group node with enc applied to the string value leaf of its
+// maps. The byte array encodings cannot encode the numeric values of typed attribute maps.
+// Fields keep their names and Go accessors, so rows of the original struct type can be written.
+func withMapValueEncoding(node parquet.Node, enc encoding.Encoding, inMap bool) parquet.Node {
+	if node.Leaf() {
+		return node
+	}
+	lt := node.Type().LogicalType()
+	isMap := lt != nil && lt.Map != nil
+
+	fields := node.Fields()
+	encoded := make([]parquet.Field, len(fields))
+	for i, field := range fields {
+		var n parquet.Node
+		if inMap && field.Leaf() && field.Name() == "value" && field.Type().Kind() == parquet.ByteArray {
+			n = parquet.Encoded(field, enc)
+		} else {
+			// The key/value pairs of a map live in its repeated key_value child group.
+			n = withMapValueEncoding(field, enc, isMap)
+		}
+		encoded[i] = schemaField{Node: n, field: field}
+	}
+	return groupNode{Node: node, fields: encoded}
+}
+
+// groupNode replaces the fields of a parquet group node.
+type groupNode struct {
+	parquet.Node
+	fields []parquet.Field
+}
+
+func (n groupNode) Fields() []parquet.Field { return n.fields }
+
+// schemaField pairs a rewritten node with the name and Go accessor of the field it replaces.
+type schemaField struct {
+	parquet.Node
+	field parquet.Field
+}
+
+func (f schemaField) Name() string { return f.field.Name() }
+
+func (f schemaField) Value(base reflect.Value) reflect.Value { return f.field.Value(base) }
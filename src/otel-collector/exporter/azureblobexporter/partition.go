@@ -0,0 +1,196 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// bodyPartition returns the value found at the dotted path in the body of the first log record
+// that has one. Map bodies are walked directly and string bodies are parsed as JSON; records with
+// other bodies, or bodies that are not valid JSON, are skipped.
+func bodyPartition(telemetryData any, path string) (string, bool) {
+	logs, ok := telemetryData.(plog.Logs)
+	if !ok || path == "" {
+		return "", false
+	}
+
+	keys := strings.Split(path, ".")
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		scopeLogs := logs.ResourceLogs().At(i).ScopeLogs()
+		for j := 0; j < scopeLogs.Len(); j++ {
+			records := scopeLogs.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				if value, ok := bodyValue(records.At(k).Body(), keys); ok {
+					return value, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func bodyValue(body pcommon.Value, keys []string) (string, bool) {
+	switch body.Type() {
+	case pcommon.ValueTypeMap:
+		value := body
+		for _, key := range keys {
+			if value.Type() != pcommon.ValueTypeMap {
+				return "", false
+			}
+			var ok bool
+			if value, ok = value.Map().Get(key); !ok {
+				return "", false
+			}
+		}
+		return value.AsString(), true
+	case pcommon.ValueTypeStr:
+		var value any
+		if err := json.Unmarshal([]byte(body.Str()), &value); err != nil {
+			return "", false
+		}
+		for _, key := range keys {
+			fields, ok := value.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			if value, ok = fields[key]; !ok {
+				return "", false
+			}
+		}
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case map[string]any, []any:
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return "", false
+			}
+			return string(raw), true
+		default:
+			return fmt.Sprint(v), true
+		}
+	}
+	return "", false
+}
+
+// attributePartition returns the partition_by_attribute value of a batch split by
+// splitByResourceAttribute, taken from its first resource.
+func attributePartition(telemetryData any, byAttribute PartitionByAttribute) string {
+	attrs, ok := firstResourceAttributes(telemetryData)
+	if !ok {
+		return byAttribute.Default
+	}
+	return resourcePartition(attrs, byAttribute)
+}
+
+func resourcePartition(attrs pcommon.Map, byAttribute PartitionByAttribute) string {
+	if value, ok := attrs.Get(byAttribute.Key); ok && value.AsString() != "" {
+		return value.AsString()
+	}
+	return byAttribute.Default
+}
+
+// batchServicePartition returns the partition_by_service path of a batch split by splitByResource,
+// taken from its first resource.
+func batchServicePartition(telemetryData any, byService PartitionByService) string {
+	attrs, ok := firstResourceAttributes(telemetryData)
+	if !ok {
+		attrs = pcommon.NewMap()
+	}
+	return servicePartition(attrs, byService)
+}
+
+// servicePartition returns the service.namespace and service.name of a resource as a two segment
+// path, e.g. "shop/checkout", using the defaults for missing or empty attributes.
+func servicePartition(attrs pcommon.Map, byService PartitionByService) string {
+	namespace, name := byService.DefaultNamespace, byService.DefaultName
+	if value, ok := attrs.Get("service.namespace"); ok && value.AsString() != "" {
+		namespace = value.AsString()
+	}
+	if value, ok := attrs.Get("service.name"); ok && value.AsString() != "" {
+		name = value.AsString()
+	}
+	return pathSegment(namespace) + "/" + pathSegment(name)
+}
+
+// pathSegment makes value safe as a single blob path segment: slashes, backslashes and control
+// characters become underscores, and so do the "." and ".." segments.
+func pathSegment(value string) string {
+	if value == "." || value == ".." {
+		return strings.Repeat("_", len(value))
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// resourcePartitionKey returns the partition of a resource, combining its partition_by_attribute
+// value and its partition_by_service path for those that are enabled.
+func (e *azureBlobExporter) resourcePartitionKey(attrs pcommon.Map) string {
+	var key string
+	if e.config.PartitionByAttribute.Key != "" {
+		key = resourcePartition(attrs, e.config.PartitionByAttribute)
+	}
+	if e.config.PartitionByService.Enabled {
+		key += "\x00" + servicePartition(attrs, e.config.PartitionByService)
+	}
+	return key
+}
+
+// splitByResource groups the resources of telemetryData by their partition key, in order of first
+// appearance. telemetryData is returned as is when all of its resources share one partition.
+func splitByResource(telemetryData any, partitionKey func(pcommon.Map) string) []any {
+	var partitions []any
+	index := map[string]int{}
+	// partitionOf returns the index of the partition of attrs, adding a partition created by
+	// newPartition when it is the first resource with that key.
+	partitionOf := func(attrs pcommon.Map, newPartition func() any) int {
+		value := partitionKey(attrs)
+		i, ok := index[value]
+		if !ok {
+			i = len(partitions)
+			index[value] = i
+			partitions = append(partitions, newPartition())
+		}
+		return i
+	}
+
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		rss := td.ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			p := partitionOf(rss.At(i).Resource().Attributes(), func() any { return ptrace.NewTraces() })
+			rss.At(i).CopyTo(partitions[p].(ptrace.Traces).ResourceSpans().AppendEmpty())
+		}
+	case plog.Logs:
+		rls := td.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			p := partitionOf(rls.At(i).Resource().Attributes(), func() any { return plog.NewLogs() })
+			rls.At(i).CopyTo(partitions[p].(plog.Logs).ResourceLogs().AppendEmpty())
+		}
+	case pmetric.Metrics:
+		rms := td.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			p := partitionOf(rms.At(i).Resource().Attributes(), func() any { return pmetric.NewMetrics() })
+			rms.At(i).CopyTo(partitions[p].(pmetric.Metrics).ResourceMetrics().AppendEmpty())
+		}
+	}
+
+	if len(partitions) <= 1 {
+		return []any{telemetryData}
+	}
+	return partitions
+}
@@ -0,0 +1,186 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// newPartitionedConfig returns a configuration writing every tenant to its own path, as JSON and NDJSON.
+func newPartitionedConfig() *Config {
+	cfg := newTestConfig()
+	cfg.PartitionByAttribute.Key = "tenant"
+	cfg.Formats = []string{formatTypeJSON, formatTypeNDJSON}
+	cfg.BlobNameFormat.TemplateEnabled = true
+	cfg.BlobNameFormat.TracesFormat = "{{ attributePartition }}/traces.json"
+	return cfg
+}
+
+// uploadedBlobs returns the names of uploads, without their unique suffix.
+func uploadedBlobs(uploads []mockUpload) []string {
+	var names []string
+	for _, u := range uploads {
+		names = append(names, u.blob[:strings.LastIndex(u.blob, "_")])
+	}
+	return names
+}
+
+func TestPartitionByAttribute(t *testing.T) {
+	cfg := newPartitionedConfig()
+	cfg.Formats = nil
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	traces := newTestTraces(
+		map[string]any{"tenant": "acme", "host": "a"},
+		map[string]any{"tenant": "globex"},
+		map[string]any{"host": "c"},
+		map[string]any{"tenant": "acme", "host": "b"},
+		map[string]any{"tenant": ""},
+	)
+	require.NoError(t, exp.ConsumeTraces(context.Background(), traces))
+
+	uploads := client.recorded()
+	assert.Equal(t, []string{"acme/traces.json", "globex/traces.json", "unknown/traces.json"}, uploadedBlobs(uploads))
+	assert.Equal(t, []int{2, 1, 2}, []int{resourceCount(t, uploads[0]), resourceCount(t, uploads[1]), resourceCount(t, uploads[2])})
+}
+
+func TestPartitionByAttributeSinglePartition(t *testing.T) {
+	traces := newTestTraces(map[string]any{"tenant": "acme"}, map[string]any{"tenant": "acme"})
+	partitions := splitByResource(traces, func(attrs pcommon.Map) string {
+		return resourcePartition(attrs, PartitionByAttribute{Key: "tenant", Default: "unknown"})
+	})
+	require.Len(t, partitions, 1)
+	assert.Equal(t, traces, partitions[0], "a batch of one partition is not copied")
+}
+
+func TestAttributePartition(t *testing.T) {
+	byAttribute := PartitionByAttribute{Key: "tenant", Default: "unknown"}
+	assert.Equal(t, "acme", attributePartition(newTestTraces(map[string]any{"tenant": "acme"}), byAttribute))
+	assert.Equal(t, "unknown", attributePartition(newTestTraces(map[string]any{}), byAttribute))
+	assert.Equal(t, "unknown", attributePartition(ptrace.NewTraces(), byAttribute))
+}
+
+func TestPartitionByAttributeConfig(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PartitionByAttribute = PartitionByAttribute{Key: "tenant"}
+	assert.ErrorContains(t, cfg.Validate(), "partition_by_attribute.default cannot be empty when a key is set")
+}
+
+func TestRetryWritesOnlyMissingParts(t *testing.T) {
+	exp, client := newTestExporter(t, newPartitionedConfig(), pipeline.SignalTraces)
+	failing := true
+	client.fail = func(_, blob string) error {
+		if failing && strings.HasPrefix(blob, "ndjson/globex/") {
+			return errTestUpload
+		}
+		return nil
+	}
+	traces := newTestTraces(map[string]any{"tenant": "acme"}, map[string]any{"tenant": "globex"})
+
+	require.ErrorIs(t, exp.ConsumeTraces(context.Background(), traces), errTestUpload)
+	assert.Equal(t, []string{"json/acme/traces.json", "ndjson/acme/traces.ndjson", "json/globex/traces.json"}, uploadedBlobs(client.recorded()))
+
+	failing = false
+	require.NoError(t, exp.ConsumeTraces(context.Background(), traces))
+	assert.Equal(t, []string{"ndjson/globex/traces.ndjson"}, uploadedBlobs(client.recorded()[3:]))
+
+	// Once the batch is stored, writing it again is a new write of all its parts
+	require.NoError(t, exp.ConsumeTraces(context.Background(), traces))
+	assert.Len(t, client.recorded(), 8)
+}
+
+func TestRetryOfOtherBatchWritesAllParts(t *testing.T) {
+	exp, client := newTestExporter(t, newPartitionedConfig(), pipeline.SignalTraces)
+	client.fail = func(_, blob string) error {
+		if strings.HasPrefix(blob, "ndjson/globex/") {
+			return errTestUpload
+		}
+		return nil
+	}
+	require.ErrorIs(t, exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{"tenant": "acme"}, map[string]any{"tenant": "globex"})), errTestUpload)
+
+	client.fail = nil
+	require.NoError(t, exp.ConsumeTraces(context.Background(), newTestTraces(map[string]any{"tenant": "acme", "host": "b"}, map[string]any{"tenant": "globex"})))
+	assert.Equal(t, []string{"json/acme/traces.json", "ndjson/acme/traces.ndjson", "json/globex/traces.json", "ndjson/globex/traces.ndjson"}, uploadedBlobs(client.recorded()[3:]))
+}
+
+// resourceCount returns the number of resources in a JSON traces upload.
+func resourceCount(t *testing.T, upload mockUpload) int {
+	t.Helper()
+	td, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(upload.data)
+	require.NoError(t, err)
+	return td.ResourceSpans().Len()
+}
+
+func TestServicePartition(t *testing.T) {
+	byService := PartitionByService{Enabled: true, DefaultNamespace: "default", DefaultName: "unknown_service"}
+	tests := []struct {
+		name  string
+		attrs map[string]any
+		want  string
+	}{
+		{name: "namespace and name", attrs: map[string]any{"service.namespace": "shop", "service.name": "checkout"}, want: "shop/checkout"},
+		{name: "without namespace", attrs: map[string]any{"service.name": "billing"}, want: "default/billing"},
+		{name: "empty values", attrs: map[string]any{"service.namespace": "", "service.name": ""}, want: "default/unknown_service"},
+		{name: "slashes", attrs: map[string]any{"service.namespace": "a/b", "service.name": `c\d`}, want: "a_b/c_d"},
+		{name: "control characters", attrs: map[string]any{"service.namespace": "shop\n", "service.name": "check\tout"}, want: "shop_/check_out"},
+		{name: "dot segments", attrs: map[string]any{"service.namespace": "..", "service.name": "."}, want: "__/_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pcommon.NewMap()
+			require.NoError(t, attrs.FromRaw(tt.attrs))
+			assert.Equal(t, tt.want, servicePartition(attrs, byService))
+		})
+	}
+}
+
+func TestPartitionByService(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PartitionByService.Enabled = true
+	cfg.BlobNameFormat.TemplateEnabled = true
+	cfg.BlobNameFormat.TracesFormat = "{{ servicePartition }}/traces.json"
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	traces := newTestTraces(
+		map[string]any{"service.namespace": "shop", "service.name": "checkout"},
+		map[string]any{"service.name": "billing"},
+		map[string]any{"service.namespace": "shop", "service.name": "checkout", "host": "b"},
+	)
+	require.NoError(t, exp.ConsumeTraces(context.Background(), traces))
+
+	uploads := client.recorded()
+	assert.Equal(t, []string{"shop/checkout/traces.json", "default/billing/traces.json"}, uploadedBlobs(uploads))
+	assert.Equal(t, 2, resourceCount(t, uploads[0]))
+}
+
+func TestPartitionByServiceAndAttribute(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PartitionByService.Enabled = true
+	cfg.PartitionByAttribute.Key = "tenant"
+	cfg.BlobNameFormat.TemplateEnabled = true
+	cfg.BlobNameFormat.TracesFormat = "{{ attributePartition }}/{{ servicePartition }}/traces.json"
+	exp, client := newTestExporter(t, cfg, pipeline.SignalTraces)
+
+	traces := newTestTraces(
+		map[string]any{"tenant": "acme", "service.name": "checkout"},
+		map[string]any{"tenant": "globex", "service.name": "checkout"},
+		map[string]any{"tenant": "acme", "service.name": "billing"},
+	)
+	require.NoError(t, exp.ConsumeTraces(context.Background(), traces))
+
+	assert.Equal(t, []string{
+		"acme/default/checkout/traces.json",
+		"globex/default/checkout/traces.json",
+		"acme/default/billing/traces.json",
+	}, uploadedBlobs(client.recorded()))
+}
@@ -0,0 +1,117 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+// retryUpload calls upload until it succeeds, following the exponential backoff of the
+// retry_on_failure settings. Retrying single uploads rather than whole batches keeps the blob name
+// stable and avoids rewriting blobs that were already stored, e.g. the typed blob when only the raw
+// OTLP copy failed. Errors that retrying cannot fix are returned at once as permanent errors.
+// Once shutdown_retry_timeout elapsed during shutdown, the upload is not retried anymore. The backoff
+// is the container_policies entry of containerName, if any, and retry_on_failure otherwise. Every
+// attempt is bounded by timeout, and no attempt is made once ctx is done.
+func (e *azureBlobExporter) retryUpload(ctx context.Context, containerName string, upload func(context.Context) error) error {
+	cfg := e.config.BackOffConfig
+	if policy, ok := e.config.ContainerPolicies[containerName]; ok {
+		cfg = policy.RetryOnFailure
+	}
+	start := time.Now()
+	interval := cfg.InitialInterval
+	var err error
+	for attempt := 1; ; attempt++ {
+		// The backoff timer may have won the select against an already cancelled ctx
+		if ctx.Err() != nil {
+			if err == nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("upload failed after %d attempts: %w", attempt-1, errors.Join(err, ctx.Err()))
+		}
+
+		err = e.attemptUpload(ctx, upload)
+		if err == nil {
+			return nil
+		}
+		if !isRetryableUploadError(err) {
+			return consumererror.NewPermanent(err)
+		}
+		if !cfg.Enabled {
+			return err
+		}
+
+		wait := randomizeInterval(interval, cfg.RandomizationFactor)
+		if cfg.MaxElapsedTime > 0 && time.Since(start)+wait > cfg.MaxElapsedTime {
+			return fmt.Errorf("upload failed after %d attempts: %w", attempt, err)
+		}
+		e.logger.Debug("Upload failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("interval", wait),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("upload failed after %d attempts: %w", attempt, errors.Join(err, ctx.Err()))
+		case <-e.retriesAborted:
+			return fmt.Errorf("upload failed after %d attempts, retries were aborted on shutdown: %w", attempt, err)
+		case <-time.After(wait):
+		}
+
+		interval = time.Duration(float64(interval) * cfg.Multiplier)
+		if cfg.MaxInterval > 0 && interval > cfg.MaxInterval {
+			interval = cfg.MaxInterval
+		}
+	}
+}
+
+// attemptUpload calls upload with ctx bounded by timeout. An attempt running into the timeout, while
+// ctx itself is not done, is retried like other network failures.
+func (e *azureBlobExporter) attemptUpload(ctx context.Context, upload func(context.Context) error) error {
+	if e.config.Timeout <= 0 {
+		return upload(ctx)
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+	err := upload(attemptCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("upload timed out after %s: %w", e.config.Timeout, err)
+	}
+	return err
+}
+
+// isRetryableUploadError reports whether an upload error may be transient. Throttling, timeouts,
+// server errors and network failures are retried; other status codes, such as 403 when the
+// identity lacks a role assignment, fail the same way on every attempt. An append whose position
+// moved could duplicate data if it was retried.
+func isRetryableUploadError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, errAppendPositionMoved) {
+		return false
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusRequestTimeout ||
+			respErr.StatusCode == http.StatusTooManyRequests ||
+			respErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// randomizeInterval returns interval jittered by ± factor.
+func randomizeInterval(interval time.Duration, factor float64) time.Duration {
+	if factor <= 0 {
+		return interval
+	}
+	delta := factor * float64(interval)
+	return time.Duration(float64(interval) - delta + rand.Float64()*2*delta)
+}
@@ -0,0 +1,211 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// failTimes returns a mockClient fail function failing the first n calls with err, and counting
+// every call in attempts.
+func failTimes(n int64, err error, attempts *atomic.Int64) func(string, string) error {
+	return func(string, string) error {
+		if attempts.Add(1) <= n {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestRetryUpload(t *testing.T) {
+	unavailable := &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	forbidden := &azcore.ResponseError{StatusCode: http.StatusForbidden}
+	tests := []struct {
+		name          string
+		failures      int64
+		err           error
+		maxElapsed    time.Duration
+		wantAttempts  int64
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{
+			name:         "succeeds at once",
+			wantAttempts: 1,
+		},
+		{
+			name:         "succeeds after transient failures",
+			failures:     3,
+			err:          unavailable,
+			wantAttempts: 4,
+		},
+		{
+			name:          "permanent error is not retried",
+			failures:      3,
+			err:           forbidden,
+			wantAttempts:  1,
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:       "max elapsed time stops retrying",
+			failures:   1000,
+			err:        unavailable,
+			maxElapsed: 50 * time.Millisecond,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.BackOffConfig.Enabled = true
+			cfg.BackOffConfig.InitialInterval = time.Millisecond
+			cfg.BackOffConfig.MaxInterval = 5 * time.Millisecond
+			cfg.BackOffConfig.RandomizationFactor = 0
+			cfg.BackOffConfig.MaxElapsedTime = tt.maxElapsed
+			exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+			var attempts atomic.Int64
+			client.fail = failTimes(tt.failures, tt.err, &attempts)
+
+			err := exp.ConsumeLogs(context.Background(), newTestLogs(1))
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantAttempts, attempts.Load())
+				assert.Len(t, client.recorded(), 1)
+				return
+			}
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.wantPermanent, consumererror.IsPermanent(err))
+			assert.Empty(t, client.recorded())
+			if tt.wantAttempts > 0 {
+				assert.Equal(t, tt.wantAttempts, attempts.Load())
+				return
+			}
+			assert.Greater(t, attempts.Load(), int64(1), "the upload is retried until the max elapsed time")
+			assert.Less(t, attempts.Load(), tt.failures, "retrying stops at the max elapsed time")
+			assert.ErrorContains(t, err, "upload failed after")
+		})
+	}
+}
+
+func TestRetryUploadDisabled(t *testing.T) {
+	exp, client := newTestExporter(t, newTestConfig(), pipeline.SignalLogs)
+	var attempts atomic.Int64
+	client.fail = failTimes(1, &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}, &attempts)
+
+	require.Error(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+	assert.Equal(t, int64(1), attempts.Load(), "retry_on_failure is disabled")
+}
+
+func TestRetryUploadContainerPolicy(t *testing.T) {
+	cfg := newTestConfig()
+	retry := cfg.BackOffConfig
+	retry.Enabled = true
+	retry.InitialInterval = time.Millisecond
+	retry.RandomizationFactor = 0
+	cfg.ContainerPolicies = map[string]ContainerPolicy{"logs": {RetryOnFailure: retry}}
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+	var attempts atomic.Int64
+	client.fail = failTimes(2, &azcore.ResponseError{StatusCode: http.StatusTooManyRequests}, &attempts)
+
+	require.NoError(t, exp.ConsumeLogs(context.Background(), newTestLogs(1)))
+	assert.Equal(t, int64(3), attempts.Load(), "the container policy retries although retry_on_failure is disabled")
+}
+
+// blockingClient is a mockClient whose uploads and appends block until their context is done,
+// counting the calls in attempts.
+type blockingClient struct {
+	*mockClient
+	attempts atomic.Int64
+}
+
+func (c *blockingClient) UploadStream(ctx context.Context, _, _ string, _ io.Reader, _ *azblob.UploadStreamOptions) (azblob.UploadStreamResponse, error) {
+	c.attempts.Add(1)
+	<-ctx.Done()
+	return azblob.UploadStreamResponse{}, ctx.Err()
+}
+
+func (c *blockingClient) AppendBlock(ctx context.Context, _, _ string, _ []byte, _ *appendblob.AppendBlockOptions) error {
+	c.attempts.Add(1)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestUploadTimeout(t *testing.T) {
+	for _, appendBlob := range []bool{false, true} {
+		t.Run(fmt.Sprintf("append %t", appendBlob), func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Timeout = 20 * time.Millisecond
+			cfg.AppendBlob.Enabled = appendBlob
+			exp, mock := newTestExporter(t, cfg, pipeline.SignalLogs)
+			client := &blockingClient{mockClient: mock}
+			exp.client = client
+
+			start := time.Now()
+			err := exp.ConsumeLogs(context.Background(), newTestLogs(1))
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.ErrorContains(t, err, "upload timed out after 20ms")
+			assert.Less(t, time.Since(start), time.Second, "the hung upload is abandoned at the timeout")
+			assert.Equal(t, int64(1), client.attempts.Load())
+		})
+	}
+}
+
+func TestUploadTimeoutRetried(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Timeout = 5 * time.Millisecond
+	cfg.BackOffConfig.Enabled = true
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	cfg.BackOffConfig.RandomizationFactor = 0
+	cfg.BackOffConfig.MaxElapsedTime = 100 * time.Millisecond
+	exp, mock := newTestExporter(t, cfg, pipeline.SignalLogs)
+	client := &blockingClient{mockClient: mock}
+	exp.client = client
+
+	err := exp.ConsumeLogs(context.Background(), newTestLogs(1))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, consumererror.IsPermanent(err))
+	assert.Greater(t, client.attempts.Load(), int64(1), "timed out attempts are retried")
+}
+
+func TestUploadContextCanceled(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Timeout = time.Hour
+	cfg.BackOffConfig.Enabled = true
+	cfg.BackOffConfig.InitialInterval = time.Millisecond
+	exp, mock := newTestExporter(t, cfg, pipeline.SignalLogs)
+	client := &blockingClient{mockClient: mock}
+	exp.client = client
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	err := exp.ConsumeLogs(ctx, newTestLogs(1))
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NotContains(t, err.Error(), "timed out", "the caller canceled the upload")
+	assert.Less(t, time.Since(start), time.Second, "cancellation aborts the in-flight upload")
+	assert.Equal(t, int64(1), client.attempts.Load(), "a canceled upload is not retried")
+}
+
+func TestUploadTimeoutDefault(t *testing.T) {
+	cfg := newTestConfig()
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+
+	cfg.Timeout = -time.Second
+	assert.ErrorContains(t, cfg.Validate(), "timeout cannot be negative")
+}
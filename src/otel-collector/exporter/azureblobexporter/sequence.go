@@ -0,0 +1,95 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// sequenceAttribute holds the position of a record in the order the exporter wrote it.
+const sequenceAttribute = "azureblob.sequence"
+
+// withSequence returns a copy of telemetryData with every span, log record and metric data point
+// numbered from next on, and the sequence number to continue from.
+func withSequence(telemetryData any, next int64) (any, int64) {
+	stamp := func(attrs pcommon.Map) {
+		attrs.PutInt(sequenceAttribute, next)
+		next++
+	}
+
+	switch td := telemetryData.(type) {
+	case ptrace.Traces:
+		stamped := ptrace.NewTraces()
+		td.CopyTo(stamped)
+		rss := stamped.ResourceSpans()
+		for i := 0; i < rss.Len(); i++ {
+			sss := rss.At(i).ScopeSpans()
+			for j := 0; j < sss.Len(); j++ {
+				spans := sss.At(j).Spans()
+				for k := 0; k < spans.Len(); k++ {
+					stamp(spans.At(k).Attributes())
+				}
+			}
+		}
+		return stamped, next
+	case plog.Logs:
+		stamped := plog.NewLogs()
+		td.CopyTo(stamped)
+		rls := stamped.ResourceLogs()
+		for i := 0; i < rls.Len(); i++ {
+			sls := rls.At(i).ScopeLogs()
+			for j := 0; j < sls.Len(); j++ {
+				records := sls.At(j).LogRecords()
+				for k := 0; k < records.Len(); k++ {
+					stamp(records.At(k).Attributes())
+				}
+			}
+		}
+		return stamped, next
+	case pmetric.Metrics:
+		stamped := pmetric.NewMetrics()
+		td.CopyTo(stamped)
+		rms := stamped.ResourceMetrics()
+		for i := 0; i < rms.Len(); i++ {
+			sms := rms.At(i).ScopeMetrics()
+			for j := 0; j < sms.Len(); j++ {
+				metrics := sms.At(j).Metrics()
+				for k := 0; k < metrics.Len(); k++ {
+					forEachDataPointAttributes(metrics.At(k), stamp)
+				}
+			}
+		}
+		return stamped, next
+	default:
+		return telemetryData, next
+	}
+}
+
+func forEachDataPointAttributes(m pmetric.Metric, fn func(pcommon.Map)) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := 0; i < m.Gauge().DataPoints().Len(); i++ {
+			fn(m.Gauge().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		for i := 0; i < m.Sum().DataPoints().Len(); i++ {
+			fn(m.Sum().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		for i := 0; i < m.Histogram().DataPoints().Len(); i++ {
+			fn(m.Histogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := 0; i < m.ExponentialHistogram().DataPoints().Len(); i++ {
+			fn(m.ExponentialHistogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		for i := 0; i < m.Summary().DataPoints().Len(); i++ {
+			fn(m.Summary().DataPoints().At(i).Attributes())
+		}
+	}
+}
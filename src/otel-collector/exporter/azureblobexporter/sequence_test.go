@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+func TestSequenceAcrossRetry(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PreserveOrder = true
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+	first, second := newTestLogs(1, 2, 3), newTestLogs(4, 5)
+
+	client.fail = failContainer("logs")
+	require.ErrorIs(t, exp.ConsumeLogs(context.Background(), first), errTestUpload)
+
+	client.fail = nil
+	require.NoError(t, exp.ConsumeLogs(context.Background(), first))
+	require.NoError(t, exp.ConsumeLogs(context.Background(), second))
+
+	var got []int64
+	for _, upload := range client.recorded() {
+		got = append(got, logSequences(t, upload.data)...)
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, got)
+}
+
+func TestSequenceKeptWhenRetriedLater(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PreserveOrder = true
+	exp, client := newTestExporter(t, cfg, pipeline.SignalLogs)
+	first, second := newTestLogs(1, 2, 3), newTestLogs(4, 5)
+
+	client.fail = failContainer("logs")
+	require.ErrorIs(t, exp.ConsumeLogs(context.Background(), first), errTestUpload)
+
+	client.fail = nil
+	require.NoError(t, exp.ConsumeLogs(context.Background(), second))
+	require.NoError(t, exp.ConsumeLogs(context.Background(), first))
+
+	uploads := client.recorded()
+	require.Len(t, uploads, 2)
+	assert.Equal(t, []int64{4, 5}, logSequences(t, uploads[0].data))
+	assert.Equal(t, []int64{1, 2, 3}, logSequences(t, uploads[1].data))
+}
+
+// logSequences returns the sequence numbers of the log records of a JSON blob, in blob order.
+func logSequences(t *testing.T, data []byte) []int64 {
+	t.Helper()
+	ld, err := (&plog.JSONUnmarshaler{}).UnmarshalLogs(data)
+	require.NoError(t, err)
+	var sequences []int64
+	records := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	for i := 0; i < records.Len(); i++ {
+		v, ok := records.At(i).Attributes().Get(sequenceAttribute)
+		require.True(t, ok)
+		sequences = append(sequences, v.Int())
+	}
+	return sequences
+}
@@ -0,0 +1,73 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+const (
+	// startupConfigBlobPrefix is the well-known prefix of the blobs holding the startup config.
+	startupConfigBlobPrefix = "_startup/"
+	redacted                = "[REDACTED]"
+)
+
+// redactedConfig returns a copy of cfg with all secrets replaced.
+func redactedConfig(cfg *Config) *Config {
+	c := *cfg
+	if c.Auth.ConnectionString != "" {
+		c.Auth.ConnectionString = redacted
+	}
+	if c.Auth.ClientSecret != "" {
+		c.Auth.ClientSecret = redacted
+	}
+	if c.Auth.SASToken != "" {
+		c.Auth.SASToken = redacted
+	}
+	if c.CPK.Key != "" {
+		c.CPK.Key = redacted
+	}
+	if u, err := url.Parse(c.URL); err == nil && u.RawQuery != "" {
+		// A SAS-bearing URL carries its signature in the query.
+		u.RawQuery = redacted
+		c.URL = u.String()
+	}
+	if u, err := url.Parse(c.ClientOptions.ProxyURL); err == nil && u.User != nil {
+		// Proxies may take credentials in the URL.
+		u.User = nil
+		c.ClientOptions.ProxyURL = strings.Replace(u.String(), "://", "://"+redacted+"@", 1)
+	}
+	return &c
+}
+
+// marshalStartupConfig renders the redacted config as JSON using the same keys as the collector
+// configuration file.
+func marshalStartupConfig(cfg *Config) ([]byte, error) {
+	conf := confmap.New()
+	if err := conf.Marshal(redactedConfig(cfg)); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(conf.ToStringMap(), "", "  ")
+}
+
+// writeStartupConfig uploads the redacted config to a well-known blob in containerName, replacing
+// the one written by the previous start of this exporter.
+func (e *azureBlobExporter) writeStartupConfig(ctx context.Context, containerName string) error {
+	data, err := marshalStartupConfig(e.config)
+	if err != nil {
+		return err
+	}
+	blobName := startupConfigBlobPrefix + e.id.String() + ".json"
+	return e.retryUpload(ctx, containerName, func(ctx context.Context) error {
+		_, err := e.client.UploadStream(ctx, containerName, blobName, bytes.NewReader(data), &azblob.UploadStreamOptions{CPKInfo: e.cpk, CPKScopeInfo: e.cpkScope})
+		return err
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/fedeoliv/custom-otel-collector/exporter/azureblobexporter/internal/metadata"
+)
+
+// exporterTelemetry holds the internal metrics recorded by the exporter.
+type exporterTelemetry struct {
+	deduplicatedSpans           metric.Int64Counter
+	invalidIDs                  metric.Int64Counter
+	overflowUploads             metric.Int64Counter
+	deadLetters                 metric.Int64Counter
+	prefixConflicts             metric.Int64Counter
+	templatedContainerFallbacks metric.Int64Counter
+	credentialSource            metric.Int64Gauge
+}
+
+func newExporterTelemetry(set component.TelemetrySettings) (*exporterTelemetry, error) {
+	meter := set.MeterProvider.Meter(metadata.ScopeName)
+
+	deduplicatedSpans, err := meter.Int64Counter(
+		"otelcol_exporter_azureblob_deduplicated_spans",
+		metric.WithDescription("Number of duplicate spans dropped from a batch before export"),
+		metric.WithUnit("{spans}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidIDs, err := meter.Int64Counter(
+		"otelcol_exporter_azureblob_invalid_ids",
+		metric.WithDescription("Number of spans and log records with invalid trace or span IDs"),
+		metric.WithUnit("{records}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	overflowUploads, err := meter.Int64Counter(
+		"otelcol_exporter_azureblob_overflow_uploads",
+		metric.WithDescription("Number of blobs written to the overflow container after the primary container failed"),
+		metric.WithUnit("{blobs}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters, err := meter.Int64Counter(
+		"otelcol_exporter_azureblob_dead_letters",
+		metric.WithDescription("Number of blobs handed to the dead letter destination after their upload failed, by destination"),
+		metric.WithUnit("{blobs}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixConflicts, err := meter.Int64Counter(
+		"otelcol_exporter_azureblob_attribute_prefix_conflicts",
+		metric.WithDescription("Number of attribute keys that kept their prefix because the stripped key already existed"),
+		metric.WithUnit("{attributes}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	templatedContainerFallbacks, err := meter.Int64Counter(
+		"otelcol_exporter_azureblob_templated_container_fallbacks",
+		metric.WithDescription("Number of batches written to default_container because their container template rendered an empty or invalid name"),
+		metric.WithUnit("{batches}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialSource, err := meter.Int64Gauge(
+		"otelcol_exporter_azureblob_credential_source",
+		metric.WithDescription("Set to 1 for the source of the default credential chain that authenticated on start"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exporterTelemetry{
+		deduplicatedSpans:           deduplicatedSpans,
+		invalidIDs:                  invalidIDs,
+		overflowUploads:             overflowUploads,
+		deadLetters:                 deadLetters,
+		prefixConflicts:             prefixConflicts,
+		templatedContainerFallbacks: templatedContainerFallbacks,
+		credentialSource:            credentialSource,
+	}, nil
+}
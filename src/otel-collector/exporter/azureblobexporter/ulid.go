@@ -0,0 +1,36 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+// crockfordBase32 is the alphabet of ULIDs, which sorts in the same order as the encoded values.
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID for t: 48 bits of Unix milliseconds followed by 80 random bits, encoded as
+// 26 Crockford base32 characters. ULIDs of different milliseconds sort lexicographically by time.
+func newULID(t time.Time) string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(t.UnixMilli())<<16)
+	_, _ = rand.Read(id[6:])
+
+	// The 128 bits are read as 130 bits with two leading zero bits, five bits per character
+	out := make([]byte, 26)
+	for i := range out {
+		bit := i*5 - 2
+		var v byte
+		for j := 0; j < 5; j++ {
+			v <<= 1
+			if b := bit + j; b >= 0 && id[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockfordBase32[v]
+	}
+	return string(out)
+}
@@ -0,0 +1,329 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/xextension/storage"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+const watermarkKey = "watermark"
+
+// watermark tracks the highest record timestamp exported for a signal and persists it in a
+// storage extension, so reprocessed data that was already exported is skipped after a restart.
+type watermark struct {
+	mu              sync.Mutex
+	client          storage.Client
+	value           pcommon.Timestamp
+	allowedLateness pcommon.Timestamp
+
+	// highest is the highest timestamp exported so far. value only follows it up to the lowest
+	// timestamp of the batches still being written, counted in inFlight.
+	highest  pcommon.Timestamp
+	inFlight map[pcommon.Timestamp]int
+}
+
+// newWatermark loads the persisted watermark of signal from the storage extension configured in cfg.
+func newWatermark(ctx context.Context, host component.Host, cfg Watermark, id component.ID, signal pipeline.Signal) (*watermark, error) {
+	ext, ok := host.GetExtensions()[cfg.Storage]
+	if !ok {
+		return nil, fmt.Errorf("watermark storage extension %q not found", cfg.Storage)
+	}
+	storageExt, ok := ext.(storage.Extension)
+	if !ok {
+		return nil, fmt.Errorf("extension %q is not a storage extension", cfg.Storage)
+	}
+
+	client, err := storageExt.GetClient(ctx, component.KindExporter, id, signal.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watermark storage client: %w", err)
+	}
+
+	raw, err := client.Get(ctx, watermarkKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watermark: %w", err)
+	}
+
+	w := &watermark{
+		client:          client,
+		allowedLateness: pcommon.Timestamp(cfg.AllowedLateness.Nanoseconds()),
+		inFlight:        map[pcommon.Timestamp]int{},
+	}
+	if len(raw) == 8 {
+		w.value = pcommon.Timestamp(binary.BigEndian.Uint64(raw))
+		w.highest = w.value
+	}
+	return w, nil
+}
+
+// threshold returns the timestamp at or below which records are skipped. Records arriving out of
+// order are still exported while they are within the allowed lateness of the watermark.
+func (w *watermark) threshold() pcommon.Timestamp {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.value <= w.allowedLateness {
+		return 0
+	}
+	return w.value - w.allowedLateness
+}
+
+// begin marks a batch with the record timestamps r as being written. Until end is called for it,
+// the watermark stays below its lowest timestamp, so a batch of another queue consumer finishing
+// first cannot persist a watermark that skips this one when it is replayed.
+func (w *watermark) begin(r timestampRange) {
+	if r.low == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight[r.low]++
+}
+
+// end releases a batch passed to begin and, when it was exported, advances the watermark to its
+// highest timestamp as far as the batches still being written allow, and persists it.
+func (w *watermark) end(ctx context.Context, r timestampRange, exported bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if r.low != 0 {
+		if w.inFlight[r.low]--; w.inFlight[r.low] <= 0 {
+			delete(w.inFlight, r.low)
+		}
+	}
+	if exported {
+		w.highest = max(w.highest, r.high)
+	}
+
+	next := w.highest
+	for low := range w.inFlight {
+		next = min(next, low-1)
+	}
+	if next <= w.value {
+		return nil
+	}
+	w.value = next
+
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], uint64(next))
+	return w.client.Set(ctx, watermarkKey, raw[:])
+}
+
+func (w *watermark) close(ctx context.Context) error {
+	return w.client.Close(ctx)
+}
+
+// timestampRange is the lowest and highest record timestamp of a batch, zero when it holds none.
+type timestampRange struct {
+	low, high pcommon.Timestamp
+}
+
+func (r *timestampRange) add(ts pcommon.Timestamp) {
+	if r.low == 0 || ts < r.low {
+		r.low = ts
+	}
+	r.high = max(r.high, ts)
+}
+
+// merge widens r to also cover o.
+func (r *timestampRange) merge(o timestampRange) {
+	if o.low != 0 {
+		r.add(o.low)
+		r.add(o.high)
+	}
+}
+
+// skipOlder reports whether a record timestamp is at or below threshold and otherwise adds it to
+// r. Unset timestamps carry no ordering information and are always exported.
+func skipOlder(ts, threshold pcommon.Timestamp, r *timestampRange) bool {
+	if ts == 0 {
+		return false
+	}
+	if ts <= threshold {
+		return true
+	}
+	r.add(ts)
+	return false
+}
+
+// filterTracesAfter drops the spans starting at or before threshold. It returns the range of start
+// timestamps of the remaining spans and the number of dropped spans; td is only copied when spans
+// are dropped.
+func filterTracesAfter(td ptrace.Traces, threshold pcommon.Timestamp) (ptrace.Traces, timestampRange, int) {
+	var r timestampRange
+	skipped := 0
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if skipOlder(spans.At(k).StartTimestamp(), threshold, &r) {
+					skipped++
+				}
+			}
+		}
+	}
+	if skipped == 0 {
+		return td, r, 0
+	}
+
+	filtered := ptrace.NewTraces()
+	td.CopyTo(filtered)
+	removeSpans(filtered, func(span ptrace.Span) bool {
+		return skipOlder(span.StartTimestamp(), threshold, &r)
+	})
+	return filtered, r, skipped
+}
+
+// filterLogsAfter drops the log records at or before threshold, using the observed timestamp for
+// records without one. It returns the range of timestamps of the remaining records and the number
+// of dropped records; ld is only copied when records are dropped.
+func filterLogsAfter(ld plog.Logs, threshold pcommon.Timestamp) (plog.Logs, timestampRange, int) {
+	logTimestamp := func(lr plog.LogRecord) pcommon.Timestamp {
+		if lr.Timestamp() != 0 {
+			return lr.Timestamp()
+		}
+		return lr.ObservedTimestamp()
+	}
+
+	var r timestampRange
+	skipped := 0
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		sls := rls.At(i).ScopeLogs()
+		for j := 0; j < sls.Len(); j++ {
+			records := sls.At(j).LogRecords()
+			for k := 0; k < records.Len(); k++ {
+				if skipOlder(logTimestamp(records.At(k)), threshold, &r) {
+					skipped++
+				}
+			}
+		}
+	}
+	if skipped == 0 {
+		return ld, r, 0
+	}
+
+	filtered := plog.NewLogs()
+	ld.CopyTo(filtered)
+	filtered.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(lr plog.LogRecord) bool {
+				return skipOlder(logTimestamp(lr), threshold, &r)
+			})
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return filtered, r, skipped
+}
+
+// filterMetricsAfter drops the data points at or before threshold, along with metrics left
+// without data points. It returns the range of timestamps of the remaining data points and the
+// number of dropped data points; md is only copied when data points are dropped.
+func filterMetricsAfter(md pmetric.Metrics, threshold pcommon.Timestamp) (pmetric.Metrics, timestampRange, int) {
+	var r timestampRange
+	skipped := 0
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			metrics := sms.At(j).Metrics()
+			for k := 0; k < metrics.Len(); k++ {
+				forEachDataPointTimestamp(metrics.At(k), func(ts pcommon.Timestamp) {
+					if skipOlder(ts, threshold, &r) {
+						skipped++
+					}
+				})
+			}
+		}
+	}
+	if skipped == 0 {
+		return md, r, 0
+	}
+
+	filtered := pmetric.NewMetrics()
+	md.CopyTo(filtered)
+	filtered.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+				return removeDataPointsAtOrBefore(m, threshold)
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return filtered, r, skipped
+}
+
+func forEachDataPointTimestamp(m pmetric.Metric, fn func(pcommon.Timestamp)) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := 0; i < m.Gauge().DataPoints().Len(); i++ {
+			fn(m.Gauge().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeSum:
+		for i := 0; i < m.Sum().DataPoints().Len(); i++ {
+			fn(m.Sum().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeHistogram:
+		for i := 0; i < m.Histogram().DataPoints().Len(); i++ {
+			fn(m.Histogram().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := 0; i < m.ExponentialHistogram().DataPoints().Len(); i++ {
+			fn(m.ExponentialHistogram().DataPoints().At(i).Timestamp())
+		}
+	case pmetric.MetricTypeSummary:
+		for i := 0; i < m.Summary().DataPoints().Len(); i++ {
+			fn(m.Summary().DataPoints().At(i).Timestamp())
+		}
+	}
+}
+
+// removeDataPointsAtOrBefore removes the data points of m at or before threshold and reports
+// whether that left m without data points.
+func removeDataPointsAtOrBefore(m pmetric.Metric, threshold pcommon.Timestamp) bool {
+	old := func(ts pcommon.Timestamp) bool {
+		return ts != 0 && ts <= threshold
+	}
+
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		dps := m.Gauge().DataPoints()
+		before := dps.Len()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool { return old(dp.Timestamp()) })
+		return before > 0 && dps.Len() == 0
+	case pmetric.MetricTypeSum:
+		dps := m.Sum().DataPoints()
+		before := dps.Len()
+		dps.RemoveIf(func(dp pmetric.NumberDataPoint) bool { return old(dp.Timestamp()) })
+		return before > 0 && dps.Len() == 0
+	case pmetric.MetricTypeHistogram:
+		dps := m.Histogram().DataPoints()
+		before := dps.Len()
+		dps.RemoveIf(func(dp pmetric.HistogramDataPoint) bool { return old(dp.Timestamp()) })
+		return before > 0 && dps.Len() == 0
+	case pmetric.MetricTypeExponentialHistogram:
+		dps := m.ExponentialHistogram().DataPoints()
+		before := dps.Len()
+		dps.RemoveIf(func(dp pmetric.ExponentialHistogramDataPoint) bool { return old(dp.Timestamp()) })
+		return before > 0 && dps.Len() == 0
+	case pmetric.MetricTypeSummary:
+		dps := m.Summary().DataPoints()
+		before := dps.Len()
+		dps.RemoveIf(func(dp pmetric.SummaryDataPoint) bool { return old(dp.Timestamp()) })
+		return before > 0 && dps.Len() == 0
+	}
+	return false
+}
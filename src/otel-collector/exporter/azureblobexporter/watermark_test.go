@@ -0,0 +1,188 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureblobexporter
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/extension/xextension/storage"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pipeline"
+)
+
+// memoryClient is a storage client keeping its entries in memory.
+type memoryClient struct {
+	storage.Client
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func (c *memoryClient) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key], nil
+}
+
+func (c *memoryClient) Set(_ context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (*memoryClient) Close(context.Context) error {
+	return nil
+}
+
+// persisted returns the watermark stored in c, 0 when none was stored.
+func (c *memoryClient) persisted() pcommon.Timestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if raw := c.entries[watermarkKey]; len(raw) == 8 {
+		return pcommon.Timestamp(binary.BigEndian.Uint64(raw))
+	}
+	return 0
+}
+
+func newTestWatermark() (*watermark, *memoryClient) {
+	client := &memoryClient{entries: map[string][]byte{}}
+	return &watermark{client: client, inFlight: map[pcommon.Timestamp]int{}}, client
+}
+
+func TestWatermarkAdvance(t *testing.T) {
+	w, client := newTestWatermark()
+	ctx := context.Background()
+
+	batch := timestampRange{low: 100, high: 200}
+	w.begin(batch)
+	require.NoError(t, w.end(ctx, batch, true))
+	assert.Equal(t, pcommon.Timestamp(200), w.threshold())
+	assert.Equal(t, pcommon.Timestamp(200), client.persisted())
+
+	older := timestampRange{low: 50, high: 150}
+	w.begin(older)
+	require.NoError(t, w.end(ctx, older, true))
+	assert.Equal(t, pcommon.Timestamp(200), w.threshold(), "the watermark never moves back")
+}
+
+func TestWatermarkFailedBatch(t *testing.T) {
+	w, client := newTestWatermark()
+	ctx := context.Background()
+
+	batch := timestampRange{low: 100, high: 200}
+	w.begin(batch)
+	require.NoError(t, w.end(ctx, batch, false))
+	assert.Zero(t, w.threshold())
+	assert.Zero(t, client.persisted())
+}
+
+func TestWatermarkInFlight(t *testing.T) {
+	w, client := newTestWatermark()
+	ctx := context.Background()
+
+	slow := timestampRange{low: 100, high: 150}
+	fast := timestampRange{low: 300, high: 400}
+	w.begin(slow)
+	w.begin(fast)
+
+	require.NoError(t, w.end(ctx, fast, true))
+	assert.Equal(t, pcommon.Timestamp(99), w.threshold(), "the watermark stays below the batch still being written")
+	assert.Equal(t, pcommon.Timestamp(99), client.persisted())
+
+	require.NoError(t, w.end(ctx, slow, true))
+	assert.Equal(t, pcommon.Timestamp(400), w.threshold())
+	assert.Equal(t, pcommon.Timestamp(400), client.persisted())
+}
+
+func TestWatermarkInFlightFailure(t *testing.T) {
+	w, _ := newTestWatermark()
+	ctx := context.Background()
+
+	failing := timestampRange{low: 100, high: 150}
+	fast := timestampRange{low: 300, high: 400}
+	w.begin(failing)
+	w.begin(fast)
+	require.NoError(t, w.end(ctx, fast, true))
+	require.NoError(t, w.end(ctx, failing, false))
+	assert.Equal(t, pcommon.Timestamp(400), w.threshold())
+}
+
+func TestWatermarkAllowedLateness(t *testing.T) {
+	w, _ := newTestWatermark()
+	w.allowedLateness = 50
+	batch := timestampRange{low: 100, high: 200}
+	w.begin(batch)
+	require.NoError(t, w.end(context.Background(), batch, true))
+	assert.Equal(t, pcommon.Timestamp(150), w.threshold())
+
+	w.allowedLateness = 500
+	assert.Zero(t, w.threshold())
+}
+
+func TestFilterLogsAfter(t *testing.T) {
+	ld := newTestLogs(100, 200, 0, 300)
+
+	filtered, r, skipped := filterLogsAfter(ld, 200)
+	assert.Equal(t, 2, skipped)
+	assert.Equal(t, 2, filtered.LogRecordCount(), "records without a timestamp are kept")
+	assert.Equal(t, timestampRange{low: 300, high: 300}, r)
+	assert.Equal(t, 4, ld.LogRecordCount(), "the input is not modified")
+
+	same, r, skipped := filterLogsAfter(ld, 50)
+	assert.Zero(t, skipped)
+	assert.Equal(t, ld, same)
+	assert.Equal(t, timestampRange{low: 100, high: 300}, r)
+}
+
+func TestFilterMetricsAfter(t *testing.T) {
+	filtered, r, skipped := filterMetricsAfter(newTestMetrics(100, 200, 300), 200)
+	assert.Equal(t, 2, skipped)
+	assert.Equal(t, 1, filtered.DataPointCount())
+	assert.Equal(t, timestampRange{low: 300, high: 300}, r)
+
+	filtered, _, skipped = filterMetricsAfter(newTestMetrics(100), 200)
+	assert.Equal(t, 1, skipped)
+	assert.Zero(t, filtered.MetricCount(), "metrics left without data points are removed")
+}
+
+func TestWatermarkSkipsAfterAdvance(t *testing.T) {
+	exp, client := newTestExporter(t, newTestConfig(), pipeline.SignalLogs)
+	w, store := newTestWatermark()
+	exp.watermark = w
+	ctx := context.Background()
+
+	require.NoError(t, exp.ConsumeLogs(ctx, newTestLogs(100, 200)))
+	require.Len(t, client.recorded(), 1)
+	assert.Equal(t, pcommon.Timestamp(200), store.persisted())
+
+	require.NoError(t, exp.ConsumeLogs(ctx, newTestLogs(150, 200)))
+	assert.Len(t, client.recorded(), 1, "records at or below the watermark are skipped")
+
+	require.NoError(t, exp.ConsumeLogs(ctx, newTestLogs(150, 300)))
+	uploads := client.recorded()
+	require.Len(t, uploads, 2)
+	assert.NotContains(t, string(uploads[1].data), `"150"`)
+	assert.Equal(t, pcommon.Timestamp(300), store.persisted())
+}
+
+func TestWatermarkNotAdvancedOnFailure(t *testing.T) {
+	exp, client := newTestExporter(t, newTestConfig(), pipeline.SignalLogs)
+	w, store := newTestWatermark()
+	exp.watermark = w
+	client.fail = failContainer("logs")
+	ctx := context.Background()
+
+	require.Error(t, exp.ConsumeLogs(ctx, newTestLogs(100, 200)))
+	assert.Zero(t, store.persisted())
+
+	client.fail = nil
+	require.NoError(t, exp.ConsumeLogs(ctx, newTestLogs(100, 200)))
+	assert.Len(t, client.recorded(), 1, "a failed batch is exported when it is consumed again")
+	assert.Equal(t, pcommon.Timestamp(200), store.persisted())
+}
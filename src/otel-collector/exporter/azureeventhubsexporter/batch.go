@@ -0,0 +1,291 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureeventhubsexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// telemetryOps are the operations the send path needs on the data of a signal.
+type telemetryOps[T any] struct {
+	// records names the unit data is split by, e.g. "spans"
+	records      string
+	marshal      func(T) ([]byte, error)
+	count        func(T) int
+	slice        func(data T, from, to int) T
+	split        func(data T, name string) ([]string, map[string]T)
+	partitionKey func(T) string
+}
+
+// eventPiece is a part of a batch waiting to be added as an event, with its payload once marshalled.
+type eventPiece[T any] struct {
+	data    T
+	payload []byte
+}
+
+// sendTelemetry sends data as events packed into batches of at most max_batch_bytes and batch_size
+// events. Records that do not fit into an event on their own are dropped, and reported with a
+// permanent error once the rest of data has been sent.
+func sendTelemetry[T any](ctx context.Context, e *azureEventHubsExporter, data T, ops telemetryOps[T]) error {
+	marshal := func(data T) ([]byte, error) {
+		payload, err := ops.marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", e.signal, err)
+		}
+		return payload, nil
+	}
+
+	var payload []byte
+	if e.config.SizeRouting.ThresholdBytes > 0 {
+		// Route on the size of the whole batch, as the azureblob exporter sees it
+		var err error
+		if payload, err = marshal(data); err != nil {
+			return err
+		}
+		if !e.config.SizeRouting.accepts(len(payload)) {
+			e.logger.Debug("Batch above size routing threshold, leaving it to the blob exporter",
+				zap.Int("size", len(payload)),
+				zap.Int("threshold_bytes", e.config.SizeRouting.ThresholdBytes))
+			return nil
+		}
+	}
+
+	var keys []string
+	var groups map[string]T
+	if e.config.PartitionKey.Source == "attribute" {
+		keys, groups = ops.split(data, e.config.PartitionKey.Value)
+		if len(keys) != 1 {
+			// The payload is only reused for a group holding all of data
+			payload = nil
+		}
+	} else {
+		key := ops.partitionKey(data)
+		keys, groups = []string{key}, map[string]T{key: data}
+	}
+
+	dropped := 0
+	for _, key := range keys {
+		n, err := sendPartition(ctx, e, eventPiece[T]{data: groups[key], payload: payload}, key, ops, marshal)
+		dropped += n
+		if err != nil {
+			return err
+		}
+	}
+	if dropped > 0 {
+		return consumererror.NewPermanent(fmt.Errorf("dropped %d %s exceeding the maximum event size", dropped, ops.records))
+	}
+	return nil
+}
+
+// sendPartition sends whole with the given partition key, where an empty key lets Event Hubs
+// distribute the events round-robin. Data that does not fit into an event is halved by records
+// until it does, keeping the order of the records. It returns the number of records that do not
+// fit on their own.
+func sendPartition[T any](
+	ctx context.Context,
+	e *azureEventHubsExporter,
+	whole eventPiece[T],
+	key string,
+	ops telemetryOps[T],
+	marshal func(T) ([]byte, error),
+) (int, error) {
+	options := &azeventhubs.EventDataBatchOptions{
+		MaxBytes: uint64(e.config.MaxBatchBytes),
+	}
+	if key != "" {
+		options.PartitionKey = &key
+	}
+
+	var batch eventBatch
+	flush := func() error {
+		if batch == nil || batch.NumEvents() == 0 {
+			return nil
+		}
+		err := e.client.SendEventDataBatch(ctx, batch)
+		batch = nil
+		if err != nil {
+			return fmt.Errorf("failed to send event batch: %w", err)
+		}
+		return nil
+	}
+	// add adds payload as an event, sending the current batch first if the event does not fit into
+	// it. It reports false when the event does not fit into an empty batch either.
+	add := func(payload []byte) (bool, error) {
+		for {
+			if batch == nil {
+				var err error
+				if batch, err = e.client.NewEventDataBatch(ctx, options); err != nil {
+					return false, fmt.Errorf("failed to create event batch: %w", err)
+				}
+			}
+			err := batch.AddEventData(&azeventhubs.EventData{Body: payload}, nil)
+			switch {
+			case errors.Is(err, azeventhubs.ErrEventDataTooLarge) && batch.NumEvents() > 0:
+				if err := flush(); err != nil {
+					return false, err
+				}
+			case errors.Is(err, azeventhubs.ErrEventDataTooLarge):
+				return false, nil
+			case err != nil:
+				return false, fmt.Errorf("failed to add event to batch: %w", err)
+			case int(batch.NumEvents()) >= e.config.BatchSize:
+				return true, flush()
+			default:
+				return true, nil
+			}
+		}
+	}
+
+	dropped := 0
+	pending := []eventPiece[T]{whole}
+	for len(pending) > 0 {
+		piece := pending[0]
+		pending = pending[1:]
+
+		if piece.payload == nil {
+			var err error
+			if piece.payload, err = marshal(piece.data); err != nil {
+				return dropped, err
+			}
+		}
+		if len(piece.payload) <= e.config.MaxEventSize {
+			added, err := add(piece.payload)
+			if err != nil {
+				return dropped, err
+			}
+			if added {
+				continue
+			}
+		}
+
+		if n := ops.count(piece.data); n > 1 {
+			halves := []eventPiece[T]{{data: ops.slice(piece.data, 0, n/2)}, {data: ops.slice(piece.data, n/2, n)}}
+			pending = append(halves, pending...)
+			continue
+		}
+		dropped++
+		e.logger.Warn("Dropping record exceeding the maximum event size",
+			zap.String("signal", e.signal.String()),
+			zap.Int("size", len(piece.payload)),
+			zap.Int("max_event_size", e.config.MaxEventSize),
+			zap.Int("max_batch_bytes", e.config.MaxBatchBytes))
+	}
+	return dropped, flush()
+}
+
+// sliceTraces copies the spans with an index in [from, to) into new traces, keeping their
+// resource and scope.
+func sliceTraces(td ptrace.Traces, from, to int) ptrace.Traces {
+	out := ptrace.NewTraces()
+	index := 0
+	for i := 0; i < td.ResourceSpans().Len() && index < to; i++ {
+		rs := td.ResourceSpans().At(i)
+		var dest ptrace.ResourceSpans
+		hasDest := false
+		for j := 0; j < rs.ScopeSpans().Len() && index < to; j++ {
+			ss := rs.ScopeSpans().At(j)
+			n := ss.Spans().Len()
+			if n == 0 || index+n <= from {
+				index += n
+				continue
+			}
+			if !hasDest {
+				hasDest = true
+				dest = out.ResourceSpans().AppendEmpty()
+				rs.Resource().CopyTo(dest.Resource())
+				dest.SetSchemaUrl(rs.SchemaUrl())
+			}
+			scope := dest.ScopeSpans().AppendEmpty()
+			ss.Scope().CopyTo(scope.Scope())
+			scope.SetSchemaUrl(ss.SchemaUrl())
+			for k := 0; k < n; k++ {
+				if index >= from && index < to {
+					ss.Spans().At(k).CopyTo(scope.Spans().AppendEmpty())
+				}
+				index++
+			}
+		}
+	}
+	return out
+}
+
+// sliceLogs copies the log records with an index in [from, to) into new logs, see sliceTraces.
+func sliceLogs(ld plog.Logs, from, to int) plog.Logs {
+	out := plog.NewLogs()
+	index := 0
+	for i := 0; i < ld.ResourceLogs().Len() && index < to; i++ {
+		rl := ld.ResourceLogs().At(i)
+		var dest plog.ResourceLogs
+		hasDest := false
+		for j := 0; j < rl.ScopeLogs().Len() && index < to; j++ {
+			sl := rl.ScopeLogs().At(j)
+			n := sl.LogRecords().Len()
+			if n == 0 || index+n <= from {
+				index += n
+				continue
+			}
+			if !hasDest {
+				hasDest = true
+				dest = out.ResourceLogs().AppendEmpty()
+				rl.Resource().CopyTo(dest.Resource())
+				dest.SetSchemaUrl(rl.SchemaUrl())
+			}
+			scope := dest.ScopeLogs().AppendEmpty()
+			sl.Scope().CopyTo(scope.Scope())
+			scope.SetSchemaUrl(sl.SchemaUrl())
+			for k := 0; k < n; k++ {
+				if index >= from && index < to {
+					sl.LogRecords().At(k).CopyTo(scope.LogRecords().AppendEmpty())
+				}
+				index++
+			}
+		}
+	}
+	return out
+}
+
+// sliceMetrics copies the metrics with an index in [from, to) into new metrics, see sliceTraces.
+// The data points of a metric are never split.
+func sliceMetrics(md pmetric.Metrics, from, to int) pmetric.Metrics {
+	out := pmetric.NewMetrics()
+	index := 0
+	for i := 0; i < md.ResourceMetrics().Len() && index < to; i++ {
+		rm := md.ResourceMetrics().At(i)
+		var dest pmetric.ResourceMetrics
+		hasDest := false
+		for j := 0; j < rm.ScopeMetrics().Len() && index < to; j++ {
+			sm := rm.ScopeMetrics().At(j)
+			n := sm.Metrics().Len()
+			if n == 0 || index+n <= from {
+				index += n
+				continue
+			}
+			if !hasDest {
+				hasDest = true
+				dest = out.ResourceMetrics().AppendEmpty()
+				rm.Resource().CopyTo(dest.Resource())
+				dest.SetSchemaUrl(rm.SchemaUrl())
+			}
+			scope := dest.ScopeMetrics().AppendEmpty()
+			sm.Scope().CopyTo(scope.Scope())
+			scope.SetSchemaUrl(sm.SchemaUrl())
+			for k := 0; k < n; k++ {
+				if index >= from && index < to {
+					sm.Metrics().At(k).CopyTo(scope.Metrics().AppendEmpty())
+				}
+				index++
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,234 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureeventhubsexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.uber.org/zap"
+)
+
+// mockBatch holds the events added to it until their bodies exceed the MaxBytes of its options.
+type mockBatch struct {
+	options *azeventhubs.EventDataBatchOptions
+	events  [][]byte
+	size    int
+}
+
+func (b *mockBatch) AddEventData(event *azeventhubs.EventData, _ *azeventhubs.AddEventDataOptions) error {
+	if b.size+len(event.Body) > int(b.options.MaxBytes) {
+		return azeventhubs.ErrEventDataTooLarge
+	}
+	b.events = append(b.events, event.Body)
+	b.size += len(event.Body)
+	return nil
+}
+
+func (b *mockBatch) NumEvents() int32 {
+	return int32(len(b.events))
+}
+
+// mockProducer records the batches sent by an exporter. When fail is set, sending fails with it.
+type mockProducer struct {
+	sent []*mockBatch
+	fail error
+}
+
+func (p *mockProducer) NewEventDataBatch(_ context.Context, options *azeventhubs.EventDataBatchOptions) (eventBatch, error) {
+	return &mockBatch{options: options}, nil
+}
+
+func (p *mockProducer) SendEventDataBatch(_ context.Context, batch eventBatch) error {
+	if p.fail != nil {
+		return p.fail
+	}
+	p.sent = append(p.sent, batch.(*mockBatch))
+	return nil
+}
+
+func (p *mockProducer) Close(context.Context) error {
+	return nil
+}
+
+// newTestExporter returns a traces exporter of cfg sending to a mock producer.
+func newTestExporter(t *testing.T, cfg *Config) (*azureEventHubsExporter, *mockProducer) {
+	t.Helper()
+	exp, err := newExporter(cfg, component.TelemetrySettings{Logger: zap.NewNop()}, pipeline.SignalTraces)
+	require.NoError(t, err)
+	producer := &mockProducer{}
+	exp.client = producer
+	return exp, producer
+}
+
+// newTestConfig returns the default configuration with a connection string.
+func newTestConfig() *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Auth.ConnectionString = "Endpoint=sb://test.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=dGVzdA=="
+	return cfg
+}
+
+// newTestTraces returns traces with a span per entry of tenants, named span-0, span-1 and so on, each
+// in a resource whose tenant attribute is the entry.
+func newTestTraces(tenants ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	for i, tenant := range tenants {
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("tenant", tenant)
+		span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		span.SetName(fmt.Sprintf("span-%d", i))
+		span.SetTraceID(pcommon.TraceID([16]byte{1, byte(i + 1)}))
+		span.SetSpanID(pcommon.SpanID([8]byte{1, byte(i + 1)}))
+	}
+	return td
+}
+
+// eventSize returns the largest payload of the spans of td sent as an event of their own.
+func eventSize(t *testing.T, exp *azureEventHubsExporter, td ptrace.Traces) int {
+	t.Helper()
+	largest := 0
+	for i := 0; i < td.SpanCount(); i++ {
+		payload, err := exp.marshaller.MarshalTraces(sliceTraces(td, i, i+1))
+		require.NoError(t, err)
+		largest = max(largest, len(payload))
+	}
+	return largest
+}
+
+// sentSpans returns the span names of every event of the sent batches, one slice per batch.
+func sentSpans(t *testing.T, producer *mockProducer) [][]string {
+	t.Helper()
+	var batches [][]string
+	for _, batch := range producer.sent {
+		var names []string
+		for _, event := range batch.events {
+			td, err := (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(event)
+			require.NoError(t, err)
+			var spans []string
+			for i := 0; i < td.ResourceSpans().Len(); i++ {
+				ss := td.ResourceSpans().At(i).ScopeSpans().At(0).Spans()
+				for j := 0; j < ss.Len(); j++ {
+					spans = append(spans, ss.At(j).Name())
+				}
+			}
+			names = append(names, strings.Join(spans, ","))
+		}
+		batches = append(batches, names)
+	}
+	return batches
+}
+
+func TestSendSingleEvent(t *testing.T) {
+	exp, producer := newTestExporter(t, newTestConfig())
+
+	require.NoError(t, exp.pushTraces(context.Background(), newTestTraces("acme", "acme", "globex")))
+
+	assert.Equal(t, [][]string{{"span-0,span-1,span-2"}}, sentSpans(t, producer))
+	assert.Equal(t, uint64(1024*1024), producer.sent[0].options.MaxBytes)
+}
+
+func TestSendSplitsExportsIntoEvents(t *testing.T) {
+	td := newTestTraces("acme", "acme", "acme", "acme", "acme")
+	cfg := newTestConfig()
+	exp, producer := newTestExporter(t, cfg)
+	// Only a single span fits into an event
+	cfg.MaxEventSize = eventSize(t, exp, td)
+	cfg.BatchSize = 2
+
+	require.NoError(t, exp.pushTraces(context.Background(), td))
+
+	assert.Equal(t, [][]string{{"span-0", "span-1"}, {"span-2", "span-3"}, {"span-4"}}, sentSpans(t, producer))
+}
+
+func TestSendFlushesFullBatches(t *testing.T) {
+	td := newTestTraces("acme", "acme", "acme", "acme", "acme")
+	cfg := newTestConfig()
+	exp, producer := newTestExporter(t, cfg)
+	size := eventSize(t, exp, td)
+	cfg.MaxEventSize = size
+	// Two events fit into a batch, a third does not
+	cfg.MaxBatchBytes = 2*size + 1
+
+	require.NoError(t, exp.pushTraces(context.Background(), td))
+
+	assert.Equal(t, [][]string{{"span-0", "span-1"}, {"span-2", "span-3"}, {"span-4"}}, sentSpans(t, producer))
+	for _, batch := range producer.sent {
+		assert.Equal(t, uint64(cfg.MaxBatchBytes), batch.options.MaxBytes)
+	}
+}
+
+func TestSendDropsOversizedRecords(t *testing.T) {
+	td := newTestTraces("acme", "acme", "acme")
+	cfg := newTestConfig()
+	exp, producer := newTestExporter(t, cfg)
+	cfg.MaxEventSize = eventSize(t, exp, td)
+	spans := td.ResourceSpans().At(1).ScopeSpans().At(0).Spans()
+	spans.At(0).Attributes().PutStr("payload", strings.Repeat("x", cfg.MaxEventSize))
+
+	err := exp.pushTraces(context.Background(), td)
+
+	require.Error(t, err)
+	assert.True(t, consumererror.IsPermanent(err))
+	assert.ErrorContains(t, err, "dropped 1 spans exceeding the maximum event size")
+	assert.Equal(t, [][]string{{"span-0", "span-2"}}, sentSpans(t, producer))
+}
+
+func TestSendByAttributePartitionKey(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PartitionKey = PartitionKeyConfig{Source: "attribute", Value: "tenant"}
+	exp, producer := newTestExporter(t, cfg)
+
+	require.NoError(t, exp.pushTraces(context.Background(), newTestTraces("acme", "globex", "acme")))
+
+	require.Len(t, producer.sent, 2)
+	assert.Equal(t, [][]string{{"span-0,span-2"}, {"span-1"}}, sentSpans(t, producer))
+	assert.Equal(t, "acme", *producer.sent[0].options.PartitionKey)
+	assert.Equal(t, "globex", *producer.sent[1].options.PartitionKey)
+}
+
+func TestSendFailure(t *testing.T) {
+	errSend := errors.New("send failed")
+	exp, producer := newTestExporter(t, newTestConfig())
+	producer.fail = errSend
+
+	err := exp.pushTraces(context.Background(), newTestTraces("acme"))
+
+	assert.ErrorIs(t, err, errSend)
+	assert.False(t, consumererror.IsPermanent(err))
+}
+
+func TestSliceTraces(t *testing.T) {
+	td := ptrace.NewTraces()
+	for i, count := range []int{2, 0, 3} {
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutInt("resource", int64(i))
+		spans := rs.ScopeSpans().AppendEmpty().Spans()
+		for j := 0; j < count; j++ {
+			spans.AppendEmpty().SetName(fmt.Sprintf("%d-%d", i, j))
+		}
+	}
+
+	sliced := sliceTraces(td, 1, 4)
+
+	require.Equal(t, 2, sliced.ResourceSpans().Len(), "resources without spans in range are left out")
+	first := sliced.ResourceSpans().At(0)
+	resource, _ := first.Resource().Attributes().Get("resource")
+	assert.Equal(t, int64(0), resource.Int())
+	assert.Equal(t, "0-1", first.ScopeSpans().At(0).Spans().At(0).Name())
+	second := sliced.ResourceSpans().At(1).ScopeSpans().At(0).Spans()
+	require.Equal(t, 2, second.Len())
+	assert.Equal(t, "2-0", second.At(0).Name())
+	assert.Equal(t, "2-1", second.At(1).Name())
+}
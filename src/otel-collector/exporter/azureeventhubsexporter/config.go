@@ -5,9 +5,13 @@ package azureeventhubsexporter
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
 )
 
 type TelemetryConfig struct {
@@ -23,7 +27,7 @@ type Encodings struct {
 }
 
 type Authentication struct {
-	// Type is the authentication type. supported values are connection_string, service_principal, system_managed_identity, user_managed_identity, workload_identity, and default_credentials
+	// Type is the authentication type. supported values are connection_string, service_principal, system_managed_identity, user_managed_identity, workload_identity, shared_access_signature, and default_credentials
 	Type AuthType `mapstructure:"type"`
 
 	// TenantID is the tenant id for the AAD App. It's only needed when type is service_principal or workload_identity.
@@ -32,6 +36,11 @@ type Authentication struct {
 	// ClientID is the AAD Application client id. It's needed when type is service_principal, user_managed_identity or workload_identity
 	ClientID string `mapstructure:"client_id"`
 
+	// ResourceID is the Azure resource ID of the user-assigned managed identity, e.g.
+	// /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.ManagedIdentity/userAssignedIdentities/<name>.
+	// It can replace client_id when type is user_managed_identity.
+	ResourceID string `mapstructure:"resource_id"`
+
 	// ClientSecret only needed when auth type is service_principal
 	ClientSecret string `mapstructure:"client_secret"`
 
@@ -40,6 +49,34 @@ type Authentication struct {
 
 	// FederatedTokenFile is the path to the file containing the federated token. It's needed when type is workload_identity.
 	FederatedTokenFile string `mapstructure:"federated_token_file"`
+
+	// SASToken is the shared access signature for the namespace or Event Hub, e.g. "SharedAccessSignature sr=...&sig=...&se=...&skn=...".
+	// It's needed when type is shared_access_signature.
+	SASToken string `mapstructure:"sas_token"`
+}
+
+// ignoredFields returns the names of the fields that are set but not used by the auth type.
+func (a Authentication) ignoredFields() []string {
+	fields := []struct {
+		name   string
+		set    bool
+		usedBy []AuthType
+	}{
+		{"tenant_id", a.TenantID != "", []AuthType{ServicePrincipal, WorkloadIdentity}},
+		{"client_id", a.ClientID != "", []AuthType{ServicePrincipal, UserManagedIdentity, WorkloadIdentity}},
+		{"resource_id", a.ResourceID != "", []AuthType{UserManagedIdentity}},
+		{"client_secret", a.ClientSecret != "", []AuthType{ServicePrincipal}},
+		{"connection_string", a.ConnectionString != "", []AuthType{ConnectionString}},
+		{"federated_token_file", a.FederatedTokenFile != "", []AuthType{WorkloadIdentity}},
+		{"sas_token", a.SASToken != "", []AuthType{SharedAccessSignature}},
+	}
+	var ignored []string
+	for _, f := range fields {
+		if f.set && !slices.Contains(f.usedBy, a.Type) {
+			ignored = append(ignored, f.name)
+		}
+	}
+	return ignored
 }
 
 type AuthType string
@@ -50,18 +87,31 @@ const (
 	UserManagedIdentity   AuthType = "user_managed_identity"
 	ServicePrincipal      AuthType = "service_principal"
 	WorkloadIdentity      AuthType = "workload_identity"
+	SharedAccessSignature AuthType = "shared_access_signature"
 	DefaultCredentials    AuthType = "default_credentials"
 )
 
 type PartitionKeyConfig struct {
 	// Source determines how the partition key is generated
-	// Options: "static", "resource_attribute", "trace_id", "span_id", "random"
+	// Options: "static", "resource_attribute", "attribute", "trace_id", "span_id", "random"
 	Source string `mapstructure:"source"`
 
-	// Value is used when source is "static" or specifies the attribute name when source is "resource_attribute"
+	// Value is used when source is "static" or specifies the attribute name when source is "resource_attribute" or "attribute"
 	Value string `mapstructure:"value"`
 }
 
+// SizeRouting splits batches between this exporter and a sibling azureblob exporter by marshalled size.
+type SizeRouting struct {
+	// ThresholdBytes is the marshalled batch size from which batches are left to blob storage. Only smaller
+	// batches are sent to Event Hubs, so an azureblob exporter configured with the same threshold archives the rest. Zero disables routing.
+	ThresholdBytes int `mapstructure:"threshold_bytes"`
+}
+
+// accepts reports whether a payload of the given size is routed to Event Hubs.
+func (r SizeRouting) accepts(size int) bool {
+	return r.ThresholdBytes <= 0 || size < r.ThresholdBytes
+}
+
 // Config contains the main configuration options for the Azure Event Hubs exporter
 type Config struct {
 	// Namespace is the Event Hubs namespace endpoint (e.g., "my-namespace.servicebus.windows.net")
@@ -73,6 +123,10 @@ type Config struct {
 	// Auth contains authentication configuration
 	Auth Authentication `mapstructure:"auth"`
 
+	// StrictAuthValidation fails validation when auth fields are set that the auth type does not use,
+	// instead of logging a warning on start.
+	StrictAuthValidation bool `mapstructure:"strict_auth_validation"`
+
 	// FormatType is the format of encoded telemetry data. Supported values are json and proto.
 	FormatType string `mapstructure:"format"`
 
@@ -88,7 +142,17 @@ type Config struct {
 	// BatchSize is the number of events to batch before sending (default: 100)
 	BatchSize int `mapstructure:"batch_size"`
 
+	// MaxBatchBytes is the maximum size of an event batch in bytes (default: 1MB, max: 1MB for Event Hubs)
+	MaxBatchBytes int `mapstructure:"max_batch_bytes"`
+
+	// SizeRouting only sends batches below a size threshold, leaving larger ones to the blob exporter.
+	SizeRouting SizeRouting `mapstructure:"size_routing"`
+
 	configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+
+	// QueueSettings buffers exports in a sending queue, which survives restarts when it is backed by a
+	// storage extension.
+	QueueSettings exporterhelper.QueueBatchConfig `mapstructure:"sending_queue"`
 }
 
 func (c *Config) Validate() error {
@@ -106,18 +170,29 @@ func (c *Config) Validate() error {
 			return errors.New("tenant_id, client_id and client_secret cannot be empty when auth type is service_principal")
 		}
 	case UserManagedIdentity:
-		if c.Auth.ClientID == "" {
-			return errors.New("client_id cannot be empty when auth type is user_managed_identity")
+		if c.Auth.ClientID == "" && c.Auth.ResourceID == "" {
+			return errors.New("client_id or resource_id must be set when auth type is user_managed_identity")
+		}
+		if c.Auth.ClientID != "" && c.Auth.ResourceID != "" {
+			return errors.New("client_id and resource_id cannot both be set when auth type is user_managed_identity")
 		}
 	case WorkloadIdentity:
 		if c.Auth.TenantID == "" || c.Auth.ClientID == "" || c.Auth.FederatedTokenFile == "" {
 			return errors.New("tenant_id, client_id and federated_token_file cannot be empty when auth type is workload_identity")
 		}
+	case SharedAccessSignature:
+		if !strings.Contains(c.Auth.SASToken, "sig=") {
+			return errors.New("sas_token must be a shared access signature with a sig parameter when auth type is shared_access_signature")
+		}
 	case DefaultCredentials:
 		// No additional fields required for default credentials
 		// DefaultAzureCredential will automatically detect credentials from environment
 	}
 
+	if ignored := c.Auth.ignoredFields(); len(ignored) > 0 && c.StrictAuthValidation {
+		return fmt.Errorf("%s cannot be set when auth type is %s", strings.Join(ignored, ", "), c.Auth.Type)
+	}
+
 	if c.FormatType != "json" && c.FormatType != "proto" {
 		return errors.New("unknown format type: " + c.FormatType)
 	}
@@ -130,6 +205,14 @@ func (c *Config) Validate() error {
 		return errors.New("batch_size must be greater than 0")
 	}
 
+	if c.MaxBatchBytes <= 0 || c.MaxBatchBytes > 1024*1024 {
+		return errors.New("max_batch_bytes must be between 1 and 1048576 bytes (1MB)")
+	}
+
+	if c.SizeRouting.ThresholdBytes < 0 {
+		return errors.New("size_routing.threshold_bytes cannot be negative")
+	}
+
 	// Validate partition key configuration
 	switch c.PartitionKey.Source {
 	case "static":
@@ -140,6 +223,10 @@ func (c *Config) Validate() error {
 		if c.PartitionKey.Value == "" {
 			return errors.New("partition_key.value must specify the attribute name when source is resource_attribute")
 		}
+	case "attribute":
+		if c.PartitionKey.Value == "" {
+			return errors.New("partition_key.value must specify the attribute name when source is attribute")
+		}
 	case "trace_id", "span_id", "random":
 		// These don't require additional configuration
 	default:
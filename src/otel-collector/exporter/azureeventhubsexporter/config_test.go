@@ -0,0 +1,200 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureeventhubsexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pipeline"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// testNamespace is the Event Hubs namespace of the credential based auth types.
+const testNamespace = "test.servicebus.windows.net"
+
+func TestAuthValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    Authentication
+		wantErr string
+	}{
+		{name: "connection string", auth: Authentication{Type: ConnectionString, ConnectionString: "Endpoint=sb://test/"}},
+		{
+			name:    "connection string missing",
+			auth:    Authentication{Type: ConnectionString},
+			wantErr: "connection_string cannot be empty when auth type is connection_string",
+		},
+		{name: "service principal", auth: Authentication{Type: ServicePrincipal, TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}},
+		{
+			name:    "service principal missing secret",
+			auth:    Authentication{Type: ServicePrincipal, TenantID: "tenant", ClientID: "client"},
+			wantErr: "tenant_id, client_id and client_secret cannot be empty when auth type is service_principal",
+		},
+		{name: "system managed identity", auth: Authentication{Type: SystemManagedIdentity}},
+		{name: "user managed identity client id", auth: Authentication{Type: UserManagedIdentity, ClientID: "client"}},
+		{name: "user managed identity resource id", auth: Authentication{Type: UserManagedIdentity, ResourceID: "/subscriptions/sub/identity"}},
+		{
+			name:    "user managed identity missing id",
+			auth:    Authentication{Type: UserManagedIdentity},
+			wantErr: "client_id or resource_id must be set when auth type is user_managed_identity",
+		},
+		{
+			name:    "user managed identity both ids",
+			auth:    Authentication{Type: UserManagedIdentity, ClientID: "client", ResourceID: "/subscriptions/sub/identity"},
+			wantErr: "client_id and resource_id cannot both be set when auth type is user_managed_identity",
+		},
+		{name: "workload identity", auth: Authentication{Type: WorkloadIdentity, TenantID: "tenant", ClientID: "client", FederatedTokenFile: "/var/run/token"}},
+		{
+			name:    "workload identity missing token file",
+			auth:    Authentication{Type: WorkloadIdentity, TenantID: "tenant", ClientID: "client"},
+			wantErr: "tenant_id, client_id and federated_token_file cannot be empty when auth type is workload_identity",
+		},
+		{name: "shared access signature", auth: Authentication{Type: SharedAccessSignature, SASToken: "sr=test&sig=abc&se=1&skn=send"}},
+		{
+			name:    "shared access signature without sig",
+			auth:    Authentication{Type: SharedAccessSignature, SASToken: "sr=test&se=1"},
+			wantErr: "sas_token must be a shared access signature with a sig parameter",
+		},
+		{name: "default credentials", auth: Authentication{Type: DefaultCredentials}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Namespace = testNamespace
+			cfg.Auth = tt.auth
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestAuthValidateNamespace(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Auth = Authentication{Type: DefaultCredentials}
+	assert.EqualError(t, cfg.Validate(), "namespace cannot be empty when auth type is not connection_string")
+
+	assert.NoError(t, newTestConfig().Validate(), "the connection string carries the namespace")
+}
+
+// TestCreateEventHubsClient builds the producer client of every auth type. Building a client does not
+// connect, so it only checks that the credential of the branch can be created from the configuration.
+func TestCreateEventHubsClient(t *testing.T) {
+	tests := []struct {
+		name string
+		auth Authentication
+	}{
+		{name: "connection string", auth: Authentication{Type: ConnectionString, ConnectionString: newTestConfig().Auth.ConnectionString}},
+		{name: "shared access signature", auth: Authentication{Type: SharedAccessSignature, SASToken: "sr=test&sig=abc&se=1&skn=send"}},
+		{name: "service principal", auth: Authentication{Type: ServicePrincipal, TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}},
+		{name: "system managed identity", auth: Authentication{Type: SystemManagedIdentity}},
+		{name: "user managed identity client id", auth: Authentication{Type: UserManagedIdentity, ClientID: "client"}},
+		{name: "user managed identity resource id", auth: Authentication{Type: UserManagedIdentity, ResourceID: "/subscriptions/sub/identity"}},
+		{name: "workload identity", auth: Authentication{Type: WorkloadIdentity, TenantID: "tenant", ClientID: "client", FederatedTokenFile: "/var/run/token"}},
+		{name: "default credentials", auth: Authentication{Type: DefaultCredentials}},
+	}
+	for _, tt := range tests {
+		for _, signal := range []pipeline.Signal{pipeline.SignalTraces, pipeline.SignalLogs, pipeline.SignalMetrics} {
+			t.Run(tt.name+"/"+signal.String(), func(t *testing.T) {
+				cfg := createDefaultConfig().(*Config)
+				cfg.Namespace = testNamespace
+				cfg.Auth = tt.auth
+				require.NoError(t, cfg.Validate())
+				exp, err := newExporter(cfg, componenttest.NewNopTelemetrySettings(), signal)
+				require.NoError(t, err)
+
+				client, err := exp.createEventHubsClient()
+				require.NoError(t, err)
+				require.NotNil(t, client)
+				assert.NoError(t, client.Close(context.Background()))
+			})
+		}
+	}
+}
+
+func TestCreateEventHubsClientErrors(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Auth.Type = "certificate"
+	exp, err := newExporter(cfg, componenttest.NewNopTelemetrySettings(), pipeline.SignalTraces)
+	require.NoError(t, err)
+	_, err = exp.createEventHubsClient()
+	assert.EqualError(t, err, "unsupported authentication type: certificate")
+
+	exp, err = newExporter(newTestConfig(), componenttest.NewNopTelemetrySettings(), pipeline.Signal{})
+	require.NoError(t, err)
+	_, err = exp.createEventHubsClient()
+	assert.ErrorContains(t, err, "unsupported signal type")
+}
+
+func TestSASConnectionString(t *testing.T) {
+	want := "Endpoint=sb://test.servicebus.windows.net/;SharedAccessSignature=SharedAccessSignature sr=test&sig=abc"
+	tests := []struct {
+		name      string
+		namespace string
+		token     string
+	}{
+		{name: "bare namespace", namespace: testNamespace, token: "sr=test&sig=abc"},
+		{name: "namespace with scheme", namespace: "sb://test.servicebus.windows.net/", token: "sr=test&sig=abc"},
+		{name: "token with prefix", namespace: "https://test.servicebus.windows.net", token: "SharedAccessSignature sr=test&sig=abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, want, sasConnectionString(tt.namespace, tt.token))
+		})
+	}
+}
+
+func TestIgnoredAuthFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		auth        Authentication
+		wantIgnored []string
+	}{
+		{name: "used fields", auth: Authentication{Type: ServicePrincipal, TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}},
+		{
+			name:        "secret with managed identity",
+			auth:        Authentication{Type: UserManagedIdentity, ClientID: "client", ClientSecret: "secret"},
+			wantIgnored: []string{"client_secret"},
+		},
+		{
+			name:        "fields with default credentials",
+			auth:        Authentication{Type: DefaultCredentials, TenantID: "tenant", SASToken: "sig=abc"},
+			wantIgnored: []string{"tenant_id", "sas_token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Namespace = testNamespace
+			cfg.Auth = tt.auth
+			assert.Equal(t, tt.wantIgnored, cfg.Auth.ignoredFields())
+			require.NoError(t, cfg.Validate())
+
+			core, logs := observer.New(zap.WarnLevel)
+			exp, err := newExporter(cfg, component.TelemetrySettings{Logger: zap.New(core)}, pipeline.SignalTraces)
+			require.NoError(t, err)
+			require.NoError(t, exp.start(context.Background(), componenttest.NewNopHost()))
+			t.Cleanup(func() { require.NoError(t, exp.shutdown(context.Background())) })
+			warnings := logs.FilterMessage("Authentication fields are not used by the auth type and are ignored").All()
+			if tt.wantIgnored == nil {
+				assert.Empty(t, warnings)
+				return
+			}
+			require.Len(t, warnings, 1)
+			assert.Contains(t, warnings[0].Context, zap.Strings("fields", tt.wantIgnored))
+
+			cfg.StrictAuthValidation = true
+			assert.ErrorContains(t, cfg.Validate(), "cannot be set when auth type is "+string(tt.auth.Type))
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"hash/fnv"
+	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
@@ -23,7 +24,7 @@ import (
 type azureEventHubsExporter struct {
 	config     *Config
 	logger     *zap.Logger
-	client     *azeventhubs.ProducerClient
+	client     eventHubsProducer
 	signal     pipeline.Signal
 	marshaller marshaller
 }
@@ -57,11 +58,17 @@ func createMarshaller(config *Config) (marshaller, error) {
 }
 
 func (e *azureEventHubsExporter) start(ctx context.Context, host component.Host) error {
+	if ignored := e.config.Auth.ignoredFields(); len(ignored) > 0 {
+		e.logger.Warn("Authentication fields are not used by the auth type and are ignored",
+			zap.String("type", string(e.config.Auth.Type)),
+			zap.Strings("fields", ignored))
+	}
+
 	client, err := e.createEventHubsClient()
 	if err != nil {
 		return fmt.Errorf("failed to create Event Hubs client: %w", err)
 	}
-	e.client = client
+	e.client = &producerClient{client: client}
 	return nil
 }
 
@@ -92,6 +99,12 @@ func (e *azureEventHubsExporter) createEventHubsClient() (*azeventhubs.ProducerC
 			eventHubName,
 			nil,
 		)
+	case SharedAccessSignature:
+		return azeventhubs.NewProducerClientFromConnectionString(
+			sasConnectionString(e.config.Namespace, e.config.Auth.SASToken),
+			eventHubName,
+			nil,
+		)
 	case DefaultCredentials:
 		cred, err := azidentity.NewDefaultAzureCredential(nil)
 		if err != nil {
@@ -115,9 +128,11 @@ func (e *azureEventHubsExporter) createEventHubsClient() (*azeventhubs.ProducerC
 			nil,
 		)
 	case UserManagedIdentity:
-		cred, err := azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
-			ID: azidentity.ClientID(e.config.Auth.ClientID),
-		})
+		var id azidentity.ManagedIDKind = azidentity.ClientID(e.config.Auth.ClientID)
+		if e.config.Auth.ResourceID != "" {
+			id = azidentity.ResourceID(e.config.Auth.ResourceID)
+		}
+		cred, err := azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{ID: id})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create user managed identity credential: %w", err)
 		}
@@ -163,69 +178,56 @@ func (e *azureEventHubsExporter) createEventHubsClient() (*azeventhubs.ProducerC
 	}
 }
 
-func (e *azureEventHubsExporter) pushTraces(ctx context.Context, td ptrace.Traces) error {
-	data, err := e.marshaller.MarshalTraces(td)
-	if err != nil {
-		return fmt.Errorf("failed to marshal traces: %w", err)
+// sasConnectionString returns a connection string for namespace that authenticates with the shared
+// access signature token. The namespace may be given with or without scheme.
+func sasConnectionString(namespace, token string) string {
+	namespace = strings.TrimSuffix(namespace, "/")
+	if i := strings.Index(namespace, "://"); i >= 0 {
+		namespace = namespace[i+len("://"):]
+	}
+	if !strings.HasPrefix(token, "SharedAccessSignature ") {
+		token = "SharedAccessSignature " + token
 	}
+	return "Endpoint=sb://" + namespace + "/;SharedAccessSignature=" + token
+}
 
-	partitionKey := e.generatePartitionKey(td.ResourceSpans())
-	return e.sendEvent(ctx, data, partitionKey)
+func (e *azureEventHubsExporter) pushTraces(ctx context.Context, td ptrace.Traces) error {
+	return sendTelemetry(ctx, e, td, telemetryOps[ptrace.Traces]{
+		records: "spans",
+		marshal: e.marshaller.MarshalTraces,
+		count:   ptrace.Traces.SpanCount,
+		slice:   sliceTraces,
+		split:   splitTracesByAttribute,
+		partitionKey: func(td ptrace.Traces) string {
+			return e.generatePartitionKey(td.ResourceSpans())
+		},
+	})
 }
 
 func (e *azureEventHubsExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
-	data, err := e.marshaller.MarshalLogs(ld)
-	if err != nil {
-		return fmt.Errorf("failed to marshal logs: %w", err)
-	}
-
-	partitionKey := e.generatePartitionKeyFromLogs(ld.ResourceLogs())
-	return e.sendEvent(ctx, data, partitionKey)
+	return sendTelemetry(ctx, e, ld, telemetryOps[plog.Logs]{
+		records: "log records",
+		marshal: e.marshaller.MarshalLogs,
+		count:   plog.Logs.LogRecordCount,
+		slice:   sliceLogs,
+		split:   splitLogsByAttribute,
+		partitionKey: func(ld plog.Logs) string {
+			return e.generatePartitionKeyFromLogs(ld.ResourceLogs())
+		},
+	})
 }
 
 func (e *azureEventHubsExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) error {
-	data, err := e.marshaller.MarshalMetrics(md)
-	if err != nil {
-		return fmt.Errorf("failed to marshal metrics: %w", err)
-	}
-
-	partitionKey := e.generatePartitionKeyFromMetrics(md.ResourceMetrics())
-	return e.sendEvent(ctx, data, partitionKey)
-}
-
-func (e *azureEventHubsExporter) sendEvent(ctx context.Context, data []byte, partitionKey string) error {
-	if len(data) > e.config.MaxEventSize {
-		return fmt.Errorf("event size %d exceeds maximum allowed size %d", len(data), e.config.MaxEventSize)
-	}
-
-	eventData := &azeventhubs.EventData{
-		Body: data,
-	}
-
-	var options *azeventhubs.EventDataBatchOptions
-	if partitionKey != "" {
-		options = &azeventhubs.EventDataBatchOptions{
-			PartitionKey: &partitionKey,
-		}
-	}
-
-	// Create a batch with a single event
-	batch, err := e.client.NewEventDataBatch(ctx, options)
-	if err != nil {
-		return fmt.Errorf("failed to create event batch: %w", err)
-	}
-
-	err = batch.AddEventData(eventData, nil)
-	if err != nil {
-		return fmt.Errorf("failed to add event to batch: %w", err)
-	}
-
-	err = e.client.SendEventDataBatch(ctx, batch, nil)
-	if err != nil {
-		return fmt.Errorf("failed to send event batch: %w", err)
-	}
-
-	return nil
+	return sendTelemetry(ctx, e, md, telemetryOps[pmetric.Metrics]{
+		records: "metrics",
+		marshal: e.marshaller.MarshalMetrics,
+		count:   pmetric.Metrics.MetricCount,
+		slice:   sliceMetrics,
+		split:   splitMetricsByAttribute,
+		partitionKey: func(md pmetric.Metrics) string {
+			return e.generatePartitionKeyFromMetrics(md.ResourceMetrics())
+		},
+	})
 }
 
 func (e *azureEventHubsExporter) generatePartitionKey(resourceSpans ptrace.ResourceSpansSlice) string {
@@ -326,3 +328,36 @@ func (e *azureEventHubsExporter) generateRandomPartitionKey() string {
 	h.Write(randomBytes)
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// eventHubsProducer sends event batches to an Event Hub. It is implemented by producerClient.
+type eventHubsProducer interface {
+	NewEventDataBatch(ctx context.Context, options *azeventhubs.EventDataBatchOptions) (eventBatch, error)
+	SendEventDataBatch(ctx context.Context, batch eventBatch) error
+	Close(ctx context.Context) error
+}
+
+// eventBatch is the part of azeventhubs.EventDataBatch used by the exporter.
+type eventBatch interface {
+	AddEventData(event *azeventhubs.EventData, options *azeventhubs.AddEventDataOptions) error
+	NumEvents() int32
+}
+
+type producerClient struct {
+	client *azeventhubs.ProducerClient
+}
+
+func (c *producerClient) NewEventDataBatch(ctx context.Context, options *azeventhubs.EventDataBatchOptions) (eventBatch, error) {
+	batch, err := c.client.NewEventDataBatch(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (c *producerClient) SendEventDataBatch(ctx context.Context, batch eventBatch) error {
+	return c.client.SendEventDataBatch(ctx, batch.(*azeventhubs.EventDataBatch), nil)
+}
+
+func (c *producerClient) Close(ctx context.Context) error {
+	return c.client.Close(ctx)
+}
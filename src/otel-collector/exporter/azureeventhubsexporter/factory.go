@@ -48,10 +48,20 @@ func createDefaultConfig() component.Config {
 		},
 		MaxEventSize:  1024 * 1024, // 1MB
 		BatchSize:     100,
+		MaxBatchBytes: 1024 * 1024, // 1MB
 		BackOffConfig: configretry.NewDefaultBackOffConfig(),
+		QueueSettings: newDefaultQueueConfig(),
 	}
 }
 
+// newDefaultQueueConfig returns the exporterhelper queue defaults with the queue disabled, so exports
+// stay synchronous unless sending_queue is enabled.
+func newDefaultQueueConfig() exporterhelper.QueueBatchConfig {
+	queue := exporterhelper.NewDefaultQueueConfig()
+	queue.Enabled = false
+	return queue
+}
+
 func createTracesExporter(
 	ctx context.Context,
 	set exporter.Settings,
@@ -71,6 +81,7 @@ func createTracesExporter(
 		exporterhelper.WithStart(exp.start),
 		exporterhelper.WithShutdown(exp.shutdown),
 		exporterhelper.WithRetry(c.BackOffConfig),
+		exporterhelper.WithQueue(c.QueueSettings),
 	)
 }
 
@@ -93,6 +104,7 @@ func createLogsExporter(
 		exporterhelper.WithStart(exp.start),
 		exporterhelper.WithShutdown(exp.shutdown),
 		exporterhelper.WithRetry(c.BackOffConfig),
+		exporterhelper.WithQueue(c.QueueSettings),
 	)
 }
 
@@ -115,5 +127,6 @@ func createMetricsExporter(
 		exporterhelper.WithStart(exp.start),
 		exporterhelper.WithShutdown(exp.shutdown),
 		exporterhelper.WithRetry(c.BackOffConfig),
+		exporterhelper.WithQueue(c.QueueSettings),
 	)
 }
@@ -0,0 +1,32 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureeventhubsexporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+)
+
+func TestSendingQueueConfig(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	assert.False(t, cfg.QueueSettings.Enabled, "exports are synchronous by default")
+
+	conf := confmap.NewFromStringMap(map[string]any{
+		"sending_queue": map[string]any{
+			"enabled":       true,
+			"num_consumers": 2,
+			"storage":       "file_storage",
+		},
+	})
+	require.NoError(t, conf.Unmarshal(cfg))
+	assert.True(t, cfg.QueueSettings.Enabled)
+	assert.Equal(t, 2, cfg.QueueSettings.NumConsumers)
+	require.NotNil(t, cfg.QueueSettings.StorageID)
+	assert.Equal(t, component.MustNewID("file_storage"), *cfg.QueueSettings.StorageID)
+	assert.NoError(t, cfg.QueueSettings.Validate())
+}
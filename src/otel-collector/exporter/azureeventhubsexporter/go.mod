@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/Azure/azure-sdk-for-go/sdk/azidentity v1.9.0
 	github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs v1.2.1
+	github.com/stretchr/testify v1.11.1
 	go.opentelemetry.io/collector/component v0.106.1
 	go.opentelemetry.io/collector/config/configretry v0.106.1
 	go.opentelemetry.io/collector/consumer v0.106.1
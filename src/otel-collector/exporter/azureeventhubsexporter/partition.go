@@ -0,0 +1,163 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureeventhubsexporter
+
+import (
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// attributeKey returns the value of the attribute named name on the record, falling back to its
+// resource. It is empty when neither has the attribute, which sends the record without a key.
+func attributeKey(record, resource pcommon.Map, name string) string {
+	if val, ok := record.Get(name); ok {
+		return val.AsString()
+	}
+	if val, ok := resource.Get(name); ok {
+		return val.AsString()
+	}
+	return ""
+}
+
+// splitTracesByAttribute groups the spans of td by their partition key attribute. The keys are
+// returned in order of first appearance. A batch with a single key is returned as is.
+func splitTracesByAttribute(td ptrace.Traces, name string) ([]string, map[string]ptrace.Traces) {
+	var keys []string
+	seen := map[string]bool{}
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			spans := rs.ScopeSpans().At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				key := attributeKey(spans.At(k).Attributes(), rs.Resource().Attributes(), name)
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	if len(keys) <= 1 {
+		return keys, map[string]ptrace.Traces{firstKey(keys): td}
+	}
+
+	groups := make(map[string]ptrace.Traces, len(keys))
+	for _, key := range keys {
+		groups[key] = ptrace.NewTraces()
+	}
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			// The resource and scope are copied once for every group the scope has spans of
+			scopes := map[string]ptrace.ScopeSpans{}
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				key := attributeKey(span.Attributes(), rs.Resource().Attributes(), name)
+				scope, ok := scopes[key]
+				if !ok {
+					dest := groups[key].ResourceSpans().AppendEmpty()
+					rs.Resource().CopyTo(dest.Resource())
+					dest.SetSchemaUrl(rs.SchemaUrl())
+					scope = dest.ScopeSpans().AppendEmpty()
+					ss.Scope().CopyTo(scope.Scope())
+					scope.SetSchemaUrl(ss.SchemaUrl())
+					scopes[key] = scope
+				}
+				span.CopyTo(scope.Spans().AppendEmpty())
+			}
+		}
+	}
+	return keys, groups
+}
+
+// splitLogsByAttribute groups the log records of ld by their partition key attribute, see
+// splitTracesByAttribute.
+func splitLogsByAttribute(ld plog.Logs, name string) ([]string, map[string]plog.Logs) {
+	var keys []string
+	seen := map[string]bool{}
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			logRecords := rl.ScopeLogs().At(j).LogRecords()
+			for k := 0; k < logRecords.Len(); k++ {
+				key := attributeKey(logRecords.At(k).Attributes(), rl.Resource().Attributes(), name)
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	if len(keys) <= 1 {
+		return keys, map[string]plog.Logs{firstKey(keys): ld}
+	}
+
+	groups := make(map[string]plog.Logs, len(keys))
+	for _, key := range keys {
+		groups[key] = plog.NewLogs()
+	}
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			scopes := map[string]plog.ScopeLogs{}
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				logRecord := sl.LogRecords().At(k)
+				key := attributeKey(logRecord.Attributes(), rl.Resource().Attributes(), name)
+				scope, ok := scopes[key]
+				if !ok {
+					dest := groups[key].ResourceLogs().AppendEmpty()
+					rl.Resource().CopyTo(dest.Resource())
+					dest.SetSchemaUrl(rl.SchemaUrl())
+					scope = dest.ScopeLogs().AppendEmpty()
+					sl.Scope().CopyTo(scope.Scope())
+					scope.SetSchemaUrl(sl.SchemaUrl())
+					scopes[key] = scope
+				}
+				logRecord.CopyTo(scope.LogRecords().AppendEmpty())
+			}
+		}
+	}
+	return keys, groups
+}
+
+// splitMetricsByAttribute groups the resource metrics of md by their partition key attribute. Only
+// resource attributes are looked up, as splitting a metric across events would break up its data points.
+func splitMetricsByAttribute(md pmetric.Metrics, name string) ([]string, map[string]pmetric.Metrics) {
+	var keys []string
+	seen := map[string]bool{}
+	noAttributes := pcommon.NewMap()
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		key := attributeKey(noAttributes, md.ResourceMetrics().At(i).Resource().Attributes(), name)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) <= 1 {
+		return keys, map[string]pmetric.Metrics{firstKey(keys): md}
+	}
+
+	groups := make(map[string]pmetric.Metrics, len(keys))
+	for _, key := range keys {
+		groups[key] = pmetric.NewMetrics()
+	}
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		key := attributeKey(noAttributes, rm.Resource().Attributes(), name)
+		rm.CopyTo(groups[key].ResourceMetrics().AppendEmpty())
+	}
+	return keys, groups
+}
+
+// firstKey returns the only key of a batch, or the empty key for a batch without records.
+func firstKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
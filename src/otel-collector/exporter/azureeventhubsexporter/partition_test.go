@@ -0,0 +1,187 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azureeventhubsexporter
+
+import (
+	"context"
+	"hash/fnv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// testPartitions is the number of partitions of the Event Hub of partitionedProducer.
+const testPartitions = 4
+
+// partitionedProducer is a mockProducer that assigns every sent batch to a partition like Event Hubs
+// does: by the hash of its partition key, or round-robin when it has none.
+type partitionedProducer struct {
+	*mockProducer
+	next       int
+	partitions []int
+}
+
+func (p *partitionedProducer) SendEventDataBatch(ctx context.Context, batch eventBatch) error {
+	if err := p.mockProducer.SendEventDataBatch(ctx, batch); err != nil {
+		return err
+	}
+	if key := batch.(*mockBatch).options.PartitionKey; key != nil {
+		h := fnv.New32a()
+		h.Write([]byte(*key))
+		p.partitions = append(p.partitions, int(h.Sum32()%testPartitions))
+		return nil
+	}
+	p.partitions = append(p.partitions, p.next)
+	p.next = (p.next + 1) % testPartitions
+	return nil
+}
+
+// newPartitionedExporter returns a traces exporter keyed by the tenant attribute sending to a partitionedProducer.
+func newPartitionedExporter(t *testing.T) (*azureEventHubsExporter, *partitionedProducer) {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.PartitionKey = PartitionKeyConfig{Source: "attribute", Value: "tenant"}
+	require.NoError(t, cfg.Validate())
+	exp, mock := newTestExporter(t, cfg)
+	producer := &partitionedProducer{mockProducer: mock}
+	exp.client = producer
+	return exp, producer
+}
+
+func TestPartitionKeySameKeySamePartition(t *testing.T) {
+	exp, producer := newPartitionedExporter(t)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, exp.pushTraces(context.Background(), newTestTraces("acme", "globex", "initech", "acme")))
+	}
+
+	partitions := map[string]map[int]bool{}
+	for i, batch := range producer.sent {
+		require.NotNil(t, batch.options.PartitionKey)
+		key := *batch.options.PartitionKey
+		if partitions[key] == nil {
+			partitions[key] = map[int]bool{}
+		}
+		partitions[key][producer.partitions[i]] = true
+	}
+	assert.Len(t, partitions, 3)
+	for key, got := range partitions {
+		assert.Len(t, got, 1, "the events of %s target a single partition", key)
+	}
+}
+
+func TestPartitionKeyRoundRobinFallback(t *testing.T) {
+	exp, producer := newPartitionedExporter(t)
+	td := newTestTraces("acme", "")
+	td.ResourceSpans().At(1).Resource().Attributes().Remove("tenant")
+
+	for i := 0; i < testPartitions; i++ {
+		require.NoError(t, exp.pushTraces(context.Background(), td))
+	}
+
+	keyed := map[int]bool{}
+	roundRobin := map[int]bool{}
+	for i, batch := range producer.sent {
+		if batch.options.PartitionKey == nil {
+			roundRobin[producer.partitions[i]] = true
+			continue
+		}
+		assert.Equal(t, "acme", *batch.options.PartitionKey)
+		keyed[producer.partitions[i]] = true
+	}
+	assert.Len(t, keyed, 1)
+	assert.Len(t, roundRobin, testPartitions, "records without the attribute are spread over every partition")
+}
+
+func TestPartitionKeySpanAttribute(t *testing.T) {
+	exp, producer := newPartitionedExporter(t)
+	td := newTestTraces("acme", "acme")
+	td.ResourceSpans().At(1).ScopeSpans().At(0).Spans().At(0).Attributes().PutStr("tenant", "globex")
+
+	require.NoError(t, exp.pushTraces(context.Background(), td))
+
+	require.Len(t, producer.sent, 2)
+	assert.Equal(t, [][]string{{"span-0"}, {"span-1"}}, sentSpans(t, producer.mockProducer))
+	assert.Equal(t, "acme", *producer.sent[0].options.PartitionKey)
+	assert.Equal(t, "globex", *producer.sent[1].options.PartitionKey, "the span attribute takes precedence over the resource")
+}
+
+func TestSplitLogsByAttribute(t *testing.T) {
+	ld := plog.NewLogs()
+	for _, tenant := range []string{"acme", "globex", ""} {
+		rl := ld.ResourceLogs().AppendEmpty()
+		if tenant != "" {
+			rl.Resource().Attributes().PutStr("tenant", tenant)
+		}
+		records := rl.ScopeLogs().AppendEmpty().LogRecords()
+		records.AppendEmpty().Body().SetStr("resource")
+		record := records.AppendEmpty()
+		record.Body().SetStr("record")
+		record.Attributes().PutStr("tenant", "acme")
+	}
+
+	keys, groups := splitLogsByAttribute(ld, "tenant")
+
+	assert.Equal(t, []string{"acme", "globex", ""}, keys)
+	assert.Equal(t, 4, groups["acme"].LogRecordCount())
+	assert.Equal(t, 1, groups["globex"].LogRecordCount())
+	assert.Equal(t, 1, groups[""].LogRecordCount())
+}
+
+func TestSplitMetricsByAttribute(t *testing.T) {
+	md := pmetric.NewMetrics()
+	for _, tenant := range []string{"acme", "globex", "acme"} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("tenant", tenant)
+		dp := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.Attributes().PutStr("tenant", "ignored")
+	}
+
+	keys, groups := splitMetricsByAttribute(md, "tenant")
+
+	assert.Equal(t, []string{"acme", "globex"}, keys, "data point attributes are not looked up")
+	assert.Equal(t, 2, groups["acme"].ResourceMetrics().Len())
+	assert.Equal(t, 1, groups["globex"].ResourceMetrics().Len())
+}
+
+func TestSplitTracesByAttributeSingleKey(t *testing.T) {
+	td := newTestTraces("acme", "acme")
+
+	keys, groups := splitTracesByAttribute(td, "tenant")
+
+	assert.Equal(t, []string{"acme"}, keys)
+	assert.Equal(t, map[string]ptrace.Traces{"acme": td}, groups, "a batch with a single key is not copied")
+}
+
+func TestPartitionKeyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PartitionKeyConfig
+		wantErr string
+	}{
+		{name: "attribute", config: PartitionKeyConfig{Source: "attribute", Value: "tenant"}},
+		{
+			name:    "attribute without name",
+			config:  PartitionKeyConfig{Source: "attribute"},
+			wantErr: "partition_key.value must specify the attribute name when source is attribute",
+		},
+		{name: "unknown source", config: PartitionKeyConfig{Source: "tenant"}, wantErr: "unknown partition_key.source: tenant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.PartitionKey = tt.config
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"go.opentelemetry.io/collector/confmap/provider/httpprovider"
 	"go.opentelemetry.io/collector/confmap/provider/httpsprovider"
 	"go.opentelemetry.io/collector/confmap/provider/yamlprovider"
+	"go.opentelemetry.io/collector/connector"
 	"go.opentelemetry.io/collector/exporter"
 	debugexporter "go.opentelemetry.io/collector/exporter/debugexporter"
 	"go.opentelemetry.io/collector/extension"
@@ -23,9 +24,14 @@ import (
 	azureblobexporter "github.com/fedeoliv/custom-otel-collector/exporter/azureblobexporter"
 	azureeventhubsexporter "github.com/fedeoliv/custom-otel-collector/exporter/azureeventhubsexporter"
 	"github.com/fedeoliv/custom-otel-collector/processor/trustgatewayprocessor"
+	filelogreceiver "github.com/fedeoliv/custom-otel-collector/receiver/filelogreceiver"
+	routingconnector "github.com/open-telemetry/opentelemetry-collector-contrib/connector/routingconnector"
 	azuremonitorexporter "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/azuremonitorexporter"
+	bearertokenauthextension "github.com/open-telemetry/opentelemetry-collector-contrib/extension/bearertokenauthextension"
 	healthcheckextension "github.com/open-telemetry/opentelemetry-collector-contrib/extension/healthcheckextension"
+	filestorage "github.com/open-telemetry/opentelemetry-collector-contrib/extension/storage/filestorage"
 	probabilisticsamplerprocessor "github.com/open-telemetry/opentelemetry-collector-contrib/processor/probabilisticsamplerprocessor"
+	prometheusreceiver "github.com/open-telemetry/opentelemetry-collector-contrib/receiver/prometheusreceiver"
 )
 
 func main() {
@@ -73,7 +79,9 @@ func components() (otelcol.Factories, error) {
 
 	// Receivers
 	factories.Receivers = map[component.Type]receiver.Factory{
-		otlpreceiver.NewFactory().Type(): otlpreceiver.NewFactory(),
+		otlpreceiver.NewFactory().Type():       otlpreceiver.NewFactory(),
+		prometheusreceiver.NewFactory().Type(): prometheusreceiver.NewFactory(),
+		filelogreceiver.NewFactory().Type():    filelogreceiver.NewFactory(),
 	}
 
 	// Exporters
@@ -92,9 +100,16 @@ func components() (otelcol.Factories, error) {
 		probabilisticsamplerprocessor.NewFactory().Type(): probabilisticsamplerprocessor.NewFactory(),
 	}
 
+	// Connectors
+	factories.Connectors = map[component.Type]connector.Factory{
+		routingconnector.NewFactory().Type(): routingconnector.NewFactory(),
+	}
+
 	// Extensions
 	factories.Extensions = map[component.Type]extension.Factory{
-		healthcheckextension.NewFactory().Type(): healthcheckextension.NewFactory(),
+		healthcheckextension.NewFactory().Type():     healthcheckextension.NewFactory(),
+		bearertokenauthextension.NewFactory().Type(): bearertokenauthextension.NewFactory(),
+		filestorage.NewFactory().Type():              filestorage.NewFactory(),
 	}
 
 	return factories, nil
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/confmap/provider/fileprovider"
+	"go.opentelemetry.io/collector/otelcol"
+)
+
+const testConfig = `
+extensions:
+  file_storage:
+    directory: %[1]s
+
+receivers:
+  prometheus:
+    config:
+      scrape_configs:
+        - job_name: app
+          scrape_interval: 1h
+          static_configs:
+            - targets: [127.0.0.1:1]
+  filelog:
+    include: [%[1]s/*.log]
+    storage: file_storage
+
+exporters:
+  debug:
+
+service:
+  extensions: [file_storage]
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    metrics:
+      receivers: [prometheus]
+      exporters: [debug]
+    logs:
+      receivers: [filelog]
+      exporters: [debug]
+`
+
+func TestComponents(t *testing.T) {
+	factories, err := components()
+	require.NoError(t, err)
+
+	for _, typ := range []string{"otlp", "prometheus", "filelog"} {
+		assert.Contains(t, factories.Receivers, component.MustNewType(typ))
+	}
+	assert.Contains(t, factories.Connectors, component.MustNewType("routing"))
+	for _, typ := range []string{"file_storage", "bearertokenauth"} {
+		assert.Contains(t, factories.Extensions, component.MustNewType(typ))
+	}
+}
+
+// tenantRoutingConfig routes the traces validated by the trust gateway processor by tenant.
+const tenantRoutingConfig = `
+receivers:
+  otlp:
+    protocols:
+      grpc:
+
+processors:
+  trustgateway:
+    valid_api_keys: [acme-key-1, globex-key-1]
+    tenant:
+      source: api_key
+      api_keys:
+        acme: [acme-key-1]
+        globex: [globex-key-1]
+
+connectors:
+  routing:
+    default_pipelines: [traces/shared]
+    table:
+      - condition: attributes["otelcol.tenant"] == "acme"
+        pipelines: [traces/acme]
+      - condition: attributes["otelcol.tenant"] == "globex"
+        pipelines: [traces/globex]
+
+exporters:
+  debug:
+  debug/acme:
+  debug/globex:
+
+service:
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces/in:
+      receivers: [otlp]
+      processors: [trustgateway]
+      exporters: [routing]
+    traces/acme:
+      receivers: [routing]
+      exporters: [debug/acme]
+    traces/globex:
+      receivers: [routing]
+      exporters: [debug/globex]
+    traces/shared:
+      receivers: [routing]
+      exporters: [debug]
+`
+
+// bearerTokenConfig authenticates the OTLP/HTTP receiver, listening on %[1]s, with the bearertokenauth extension.
+const bearerTokenConfig = `
+extensions:
+  bearertokenauth:
+    token: test-token
+
+receivers:
+  otlp:
+    protocols:
+      http:
+        endpoint: %[1]s
+        auth:
+          authenticator: bearertokenauth
+
+exporters:
+  debug:
+
+service:
+  extensions: [bearertokenauth]
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    traces:
+      receivers: [otlp]
+      exporters: [debug]
+`
+
+// newTestCollector returns a collector of the components of this build loading config.
+func newTestCollector(t *testing.T, config string) *otelcol.Collector {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+
+	col, err := otelcol.NewCollector(otelcol.CollectorSettings{
+		BuildInfo: component.NewDefaultBuildInfo(),
+		Factories: components,
+		ConfigProviderSettings: otelcol.ConfigProviderSettings{
+			ResolverSettings: confmap.ResolverSettings{
+				URIs:              []string{"file:" + path},
+				ProviderFactories: []confmap.ProviderFactory{fileprovider.NewFactory()},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return col
+}
+
+func TestCollectorWithPrometheusAndFilelog(t *testing.T) {
+	dir := t.TempDir()
+	col := newTestCollector(t, fmt.Sprintf(testConfig, dir))
+	require.NoError(t, col.DryRun(context.Background()))
+
+	done := make(chan error, 1)
+	go func() { done <- col.Run(context.Background()) }()
+	assert.Eventually(t, func() bool { return col.GetState() == otelcol.StateRunning }, 10*time.Second, 10*time.Millisecond)
+	col.Shutdown()
+	require.NoError(t, <-done)
+}
+
+func TestCollectorWithTenantRouting(t *testing.T) {
+	col := newTestCollector(t, tenantRoutingConfig)
+	require.NoError(t, col.DryRun(context.Background()))
+}
+
+// freeEndpoint returns a local address nothing listens on.
+func freeEndpoint(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	endpoint := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return endpoint
+}
+
+// runTestCollector runs a collector loading config until it is running, and returns the function
+// shutting it down.
+func runTestCollector(t *testing.T, config string) func() {
+	t.Helper()
+	col := newTestCollector(t, config)
+	require.NoError(t, col.DryRun(context.Background()))
+
+	done := make(chan error, 1)
+	go func() { done <- col.Run(context.Background()) }()
+	require.Eventually(t, func() bool { return col.GetState() == otelcol.StateRunning }, 10*time.Second, 10*time.Millisecond)
+	return func() {
+		col.Shutdown()
+		require.NoError(t, <-done)
+	}
+}
+
+func TestCollectorWithBearerTokenAuth(t *testing.T) {
+	endpoint := freeEndpoint(t)
+	t.Cleanup(runTestCollector(t, fmt.Sprintf(bearerTokenConfig, endpoint)))
+
+	for _, tt := range []struct {
+		token      string
+		wantStatus int
+	}{
+		{token: "", wantStatus: http.StatusUnauthorized},
+		{token: "wrong-token", wantStatus: http.StatusUnauthorized},
+		{token: "test-token", wantStatus: http.StatusOK},
+	} {
+		req, err := http.NewRequest(http.MethodPost, "http://"+endpoint+"/v1/traces", strings.NewReader("{}"))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, tt.wantStatus, resp.StatusCode, "token %q", tt.token)
+	}
+}
+
+// persistentQueueConfig exports the logs received by the OTLP/HTTP receiver, listening on %[1]s, to
+// the blob endpoint %[2]s through a sending queue kept by file_storage in %[3]s.
+const persistentQueueConfig = `
+extensions:
+  file_storage:
+    directory: %[3]s
+
+receivers:
+  otlp:
+    protocols:
+      http:
+        endpoint: %[1]s
+
+exporters:
+  azureblob:
+    auth:
+      type: connection_string
+      connection_string: "DefaultEndpointsProtocol=http;AccountName=test;AccountKey=dGVzdA==;BlobEndpoint=%[2]s/test;"
+    container:
+      logs: logs
+    retry_on_failure:
+      enabled: false
+    sending_queue:
+      enabled: true
+      num_consumers: 1
+      storage: file_storage
+
+service:
+  extensions: [file_storage]
+  telemetry:
+    metrics:
+      level: none
+  pipelines:
+    logs:
+      receivers: [otlp]
+      exporters: [azureblob]
+`
+
+// blobServer is a blob endpoint storing the bodies of the blobs put to it. Until release is closed,
+// puts block.
+type blobServer struct {
+	*httptest.Server
+	release chan struct{}
+	mu      sync.Mutex
+	blobs   []string
+}
+
+func newBlobServer(t *testing.T) *blobServer {
+	s := &blobServer{release: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.blobs = append(s.blobs, string(body))
+		s.mu.Unlock()
+		<-s.release
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *blobServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.blobs...)
+}
+
+// postLog sends a log record with body to the OTLP/HTTP receiver at endpoint. The connection is not
+// kept alive, as open connections delay the shutdown of the receiver.
+func postLog(t *testing.T, endpoint, body string) {
+	t.Helper()
+	payload := fmt.Sprintf(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"stringValue":%q}}]}]}]}`, body)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Post("http://"+endpoint+"/v1/logs", "application/json", strings.NewReader(payload))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// TestCollectorWithPersistentQueue restarts a collector while its blob endpoint hangs, and checks that
+// the logs still queued are exported by the next run, which opens the same file_storage directory.
+func TestCollectorWithPersistentQueue(t *testing.T) {
+	storage := t.TempDir()
+
+	first := newBlobServer(t)
+	endpoint := freeEndpoint(t)
+	stop := runTestCollector(t, fmt.Sprintf(persistentQueueConfig, endpoint, first.URL, storage))
+	for _, body := range []string{"first", "second", "third"} {
+		postLog(t, endpoint, body)
+	}
+	require.Eventually(t, func() bool { return len(first.received()) == 1 }, 10*time.Second, 10*time.Millisecond)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		stop()
+	}()
+	// The single consumer is blocked by the first upload. It is released once the receiver stopped and
+	// the exporter, shut down right after it, stopped reading the queue, so the others stay queued.
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", endpoint)
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	}, 10*time.Second, 10*time.Millisecond)
+	time.Sleep(500 * time.Millisecond)
+	close(first.release)
+	<-stopped
+	require.Len(t, first.received(), 1)
+	assert.Contains(t, first.received()[0], "first")
+
+	second := newBlobServer(t)
+	close(second.release)
+	endpoint = freeEndpoint(t)
+	t.Cleanup(runTestCollector(t, fmt.Sprintf(persistentQueueConfig, endpoint, second.URL, storage)))
+	require.Eventually(t, func() bool { return len(second.received()) == 2 }, 10*time.Second, 10*time.Millisecond)
+	blobs := strings.Join(second.received(), "\n")
+	assert.Contains(t, blobs, "second")
+	assert.Contains(t, blobs, "third")
+	assert.NotContains(t, blobs, "first", "the exported log is removed from the queue")
+}
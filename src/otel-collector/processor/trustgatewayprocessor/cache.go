@@ -0,0 +1,77 @@
+package trustgatewayprocessor
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// validationCache remembers fingerprints of resources that recently passed validation, together
+// with the rule that matched them.
+// It is bounded to maxEntries, evicting the oldest entry when full.
+type validationCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+}
+
+type cacheEntry struct {
+	fingerprint string
+	rule        string
+	expiresAt   time.Time
+}
+
+func newValidationCache(ttl time.Duration, maxEntries int) *validationCache {
+	return &validationCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// lookup returns the matched rule of a fingerprint that passed validation within the TTL
+func (c *validationCache) lookup(fingerprint string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[fingerprint]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.remove(elem)
+		return "", false
+	}
+	return entry.rule, true
+}
+
+// add records a fingerprint that passed validation and the rule that matched it
+func (c *validationCache) add(fingerprint, rule string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[fingerprint]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.rule = rule
+		entry.expiresAt = expiresAt
+		c.order.MoveToBack(elem)
+		return
+	}
+
+	for c.order.Len() >= c.maxEntries {
+		c.remove(c.order.Front())
+	}
+	c.entries[fingerprint] = c.order.PushBack(&cacheEntry{fingerprint: fingerprint, rule: rule, expiresAt: expiresAt})
+}
+
+func (c *validationCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).fingerprint)
+}
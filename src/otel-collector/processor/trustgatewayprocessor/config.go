@@ -1,6 +1,15 @@
 package trustgatewayprocessor
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"time"
+
 	"go.opentelemetry.io/collector/component"
 )
 
@@ -10,11 +19,287 @@ type Config struct {
 	RequiredHeaders []string `mapstructure:"required_headers"`
 	// ValidAPIKeys are the valid API keys for authentication
 	ValidAPIKeys []string `mapstructure:"valid_api_keys"`
+	// APIKeysHashed treats the valid_api_keys and key_list_by_service entries as hex SHA-256 digests of the keys
+	APIKeysHashed bool `mapstructure:"api_keys_hashed"`
+	// APIKeyHeader is the attribute holding the API key
+	APIKeyHeader string `mapstructure:"api_key_header"`
+	// CaseInsensitiveHeaders matches the required headers and the API key header regardless of case
+	CaseInsensitiveHeaders bool `mapstructure:"case_insensitive_headers"`
+	// KeyListByService maps service.name values to their valid API keys, replacing ValidAPIKeys when set
+	KeyListByService map[string][]string `mapstructure:"key_list_by_service"`
+	// APIKeyFormat rejects malformed API keys before they are compared with the valid keys
+	APIKeyFormat APIKeyFormatConfig `mapstructure:"api_key_format"`
+	// HeaderRules checks the values of attributes, by attribute name. Unlike RequiredHeaders, which
+	// only checks presence, the attribute must hold an accepted value
+	HeaderRules map[string]HeaderRule `mapstructure:"header_rules"`
+	// ValidationCache skips re-validating resources that recently passed validation
+	ValidationCache ValidationCacheConfig `mapstructure:"validation_cache"`
+	// AnnotateMatchedRule stamps the trustgateway.matched_rule resource attribute with the rules that accepted the telemetry
+	AnnotateMatchedRule bool `mapstructure:"annotate_matched_rule"`
+	// StripValidatedAttributes removes the required headers, the API key and the token from the
+	// resources that passed validation, so credentials are not exported downstream
+	StripValidatedAttributes bool `mapstructure:"strip_validated_attributes"`
+	// IdentityAttributes copies the validated identity into resource attributes, so it survives the
+	// asynchronous parts of the pipeline. It maps the token claims in jwt validation mode, or the
+	// auth attributes of the client context otherwise, to resource attribute names
+	IdentityAttributes map[string]string `mapstructure:"identity_attributes"`
+	// Tenant stamps the otelcol.tenant resource attribute on the telemetry that passed validation, so
+	// a routing connector can send every tenant to its own pipelines
+	Tenant TenantConfig `mapstructure:"tenant"`
+	// DropMode is batch to reject a batch whose first resource fails validation, or resource to
+	// validate every resource and drop only the failing ones
+	DropMode string `mapstructure:"drop_mode"`
+	// OnValidationFailure is drop to silently drop rejected telemetry, or error to return a permanent
+	// error so that the receiver reports the rejection to the sender
+	OnValidationFailure string `mapstructure:"on_validation_failure"`
+	// RejectionSamples forwards a sample of the rejected resources to a sink extension for rule tuning
+	RejectionSamples RejectionSamplesConfig `mapstructure:"rejection_samples"`
+	// ValidationMode is api_key to check the API key against the valid key lists, or jwt to verify a
+	// signed token instead
+	ValidationMode string `mapstructure:"validation_mode"`
+	// JWT defines how tokens are verified in jwt validation mode
+	JWT JWTConfig `mapstructure:"jwt"`
+	// ValidationScopeName also validates the attributes of the instrumentation scopes with this name,
+	// for SDKs that put the API key on the scope of a specific library. Their attributes take
+	// precedence over the resource attributes, and the attributes of other scopes are ignored
+	ValidationScopeName string `mapstructure:"validation_scope_name"`
+}
+
+// JWTConfig defines how bearer tokens are verified. Exactly one of JWKSURL, PublicKey and
+// HMACSecret provides the verification keys.
+type JWTConfig struct {
+	// TokenAttribute is the attribute holding the token, optionally prefixed with "Bearer "
+	TokenAttribute string `mapstructure:"token_attribute"`
+	// Issuer is the expected iss claim, empty to accept any issuer
+	Issuer string `mapstructure:"issuer"`
+	// Audience is the expected aud claim, empty to accept any audience
+	Audience string `mapstructure:"audience"`
+	// JWKSURL is the URL of the JSON Web Key Set holding the verification keys
+	JWKSURL string `mapstructure:"jwks_url"`
+	// JWKSRefreshInterval is how often the JWKS is fetched again to pick up rotated keys
+	JWKSRefreshInterval time.Duration `mapstructure:"jwks_refresh_interval"`
+	// PublicKey is a PEM encoded RSA, ECDSA or Ed25519 public key
+	PublicKey string `mapstructure:"public_key"`
+	// HMACSecret is the shared secret of HS256, HS384 and HS512 signed tokens
+	HMACSecret string `mapstructure:"hmac_secret"`
+}
+
+// TenantConfig defines where the tenant of validated telemetry is read from
+type TenantConfig struct {
+	// Source is api_key to look up the tenant of the validated API key, or header to read it from an
+	// attribute, empty to disable tenant tagging
+	Source string `mapstructure:"source"`
+	// Header is the attribute holding the tenant in header source
+	Header string `mapstructure:"header"`
+	// APIKeys maps tenants to their API keys in api_key source, in the form of valid_api_keys
+	APIKeys map[string][]string `mapstructure:"api_keys"`
+}
+
+// RejectionSamplesConfig defines where and how often rejected resources are sampled
+type RejectionSamplesConfig struct {
+	// Sink is the extension receiving the samples, nil to disable sampling. It must implement the
+	// consumer interface of the pipeline signal, e.g. consumer.Traces
+	Sink *component.ID `mapstructure:"sink"`
+	// Fraction is the share of rejected resources forwarded to the sink, between 0 and 1
+	Fraction float64 `mapstructure:"fraction"`
+}
+
+// HeaderRule defines the accepted values of an attribute. Every configured check must pass.
+type HeaderRule struct {
+	// Value is the exact expected value
+	Value string `mapstructure:"value"`
+	// Pattern is a regular expression the whole value must match
+	Pattern string `mapstructure:"pattern"`
+	// Glob is a shell pattern the value must match, e.g. "acme-*"
+	Glob string `mapstructure:"glob"`
+	// Allowed lists the accepted values
+	Allowed []string `mapstructure:"allowed"`
+}
+
+// APIKeyFormatConfig defines the expected shape of API key values
+type APIKeyFormatConfig struct {
+	// Pattern is a regular expression the whole key must match
+	Pattern string `mapstructure:"pattern"`
+	// MinLength is the minimum key length, 0 for no minimum
+	MinLength int `mapstructure:"min_length"`
+	// MaxLength is the maximum key length, 0 for no maximum
+	MaxLength int `mapstructure:"max_length"`
+}
+
+// enabled reports whether any format rule is configured
+func (f APIKeyFormatConfig) enabled() bool {
+	return f.Pattern != "" || f.MinLength > 0 || f.MaxLength > 0
 }
 
+// ValidationCacheConfig defines how successful validations are cached
+type ValidationCacheConfig struct {
+	// Enabled turns on caching of successful validations
+	Enabled bool `mapstructure:"enabled"`
+	// TTL is how long a successful validation is reused
+	TTL time.Duration `mapstructure:"ttl"`
+	// MaxEntries bounds the number of cached resource fingerprints
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+const (
+	dropModeBatch    = "batch"
+	dropModeResource = "resource"
+
+	onValidationFailureDrop  = "drop"
+	onValidationFailureError = "error"
+
+	validationModeAPIKey = "api_key"
+	validationModeJWT    = "jwt"
+
+	tenantSourceAPIKey = "api_key"
+	tenantSourceHeader = "header"
+)
+
 var _ component.Config = (*Config)(nil)
 
 // Validate checks if the processor configuration is valid
 func (cfg *Config) Validate() error {
+	if cfg.APIKeyHeader == "" {
+		return errors.New("api_key_header cannot be empty")
+	}
+	if _, err := regexp.Compile(cfg.APIKeyFormat.Pattern); err != nil {
+		return fmt.Errorf("invalid api_key_format.pattern: %w", err)
+	}
+	if cfg.APIKeyFormat.MinLength < 0 || cfg.APIKeyFormat.MaxLength < 0 {
+		return errors.New("api_key_format lengths cannot be negative")
+	}
+	if cfg.APIKeyFormat.MaxLength > 0 && cfg.APIKeyFormat.MinLength > cfg.APIKeyFormat.MaxLength {
+		return errors.New("api_key_format.min_length cannot be greater than max_length")
+	}
+	for name, rule := range cfg.HeaderRules {
+		if rule.Value == "" && rule.Pattern == "" && rule.Glob == "" && len(rule.Allowed) == 0 {
+			return fmt.Errorf("header_rules.%s needs a value, pattern, glob or allowed check", name)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid header_rules.%s.pattern: %w", name, err)
+		}
+		if _, err := path.Match(rule.Glob, ""); err != nil {
+			return fmt.Errorf("invalid header_rules.%s.glob: %w", name, err)
+		}
+	}
+	for service, keys := range cfg.KeyListByService {
+		if len(keys) == 0 {
+			return fmt.Errorf("key_list_by_service has no keys for service %q", service)
+		}
+	}
+	if cfg.APIKeysHashed {
+		keys := slices.Clone(cfg.ValidAPIKeys)
+		for _, serviceKeys := range cfg.KeyListByService {
+			keys = append(keys, serviceKeys...)
+		}
+		for _, tenantKeys := range cfg.Tenant.APIKeys {
+			keys = append(keys, tenantKeys...)
+		}
+		for _, key := range keys {
+			if digest, err := hex.DecodeString(key); err != nil || len(digest) != sha256.Size {
+				return errors.New("api_keys_hashed requires every API key to be a hex encoded SHA-256 digest")
+			}
+		}
+	}
+	for source, attribute := range cfg.IdentityAttributes {
+		if attribute == "" {
+			return fmt.Errorf("identity_attributes maps %q to an empty attribute name", source)
+		}
+	}
+	if err := cfg.validateTenant(); err != nil {
+		return err
+	}
+	if cfg.ValidationCache.Enabled {
+		if cfg.ValidationCache.TTL <= 0 {
+			return errors.New("validation_cache.ttl must be positive")
+		}
+		if cfg.ValidationCache.MaxEntries <= 0 {
+			return errors.New("validation_cache.max_entries must be positive")
+		}
+	}
+	if cfg.DropMode != dropModeBatch && cfg.DropMode != dropModeResource {
+		return fmt.Errorf("unknown drop_mode %q, must be %q or %q", cfg.DropMode, dropModeBatch, dropModeResource)
+	}
+	if cfg.OnValidationFailure != onValidationFailureDrop && cfg.OnValidationFailure != onValidationFailureError {
+		return fmt.Errorf("unknown on_validation_failure %q, must be %q or %q", cfg.OnValidationFailure, onValidationFailureDrop, onValidationFailureError)
+	}
+	if cfg.OnValidationFailure == onValidationFailureError && cfg.DropMode == dropModeResource {
+		return errors.New("on_validation_failure error cannot be used with drop_mode resource, which forwards the valid resources")
+	}
+	if cfg.RejectionSamples.Fraction < 0 || cfg.RejectionSamples.Fraction > 1 {
+		return errors.New("rejection_samples.fraction must be between 0 and 1")
+	}
+	if cfg.ValidationMode != validationModeAPIKey && cfg.ValidationMode != validationModeJWT {
+		return fmt.Errorf("unknown validation_mode %q, must be %q or %q", cfg.ValidationMode, validationModeAPIKey, validationModeJWT)
+	}
+	if cfg.ValidationMode == validationModeJWT {
+		return cfg.validateJWT()
+	}
+	return nil
+}
+
+// validateTenant checks the tenant tagging settings
+func (cfg *Config) validateTenant() error {
+	switch cfg.Tenant.Source {
+	case "":
+		return nil
+	case tenantSourceHeader:
+		if cfg.Tenant.Header == "" {
+			return errors.New("tenant source header requires tenant.header")
+		}
+	case tenantSourceAPIKey:
+		if cfg.ValidationMode != validationModeAPIKey {
+			return errors.New("tenant source api_key requires validation_mode api_key")
+		}
+		if len(cfg.ValidAPIKeys) == 0 && len(cfg.KeyListByService) == 0 {
+			// Without a key list, any key passes validation and tenants would be assigned to unchecked keys
+			return errors.New("tenant source api_key requires valid_api_keys or key_list_by_service")
+		}
+		if len(cfg.Tenant.APIKeys) == 0 {
+			return errors.New("tenant source api_key requires tenant.api_keys")
+		}
+		for tenant, keys := range cfg.Tenant.APIKeys {
+			if normalizeTenant(tenant) == "" {
+				return fmt.Errorf("tenant.api_keys has tenant %q without letters or digits", tenant)
+			}
+			if len(keys) == 0 {
+				return fmt.Errorf("tenant.api_keys has no keys for tenant %q", tenant)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown tenant.source %q, must be %q or %q", cfg.Tenant.Source, tenantSourceAPIKey, tenantSourceHeader)
+	}
+	return nil
+}
+
+// validateJWT checks the settings of jwt validation mode
+func (cfg *Config) validateJWT() error {
+	if cfg.JWT.TokenAttribute == "" {
+		return errors.New("jwt.token_attribute cannot be empty")
+	}
+	sources := 0
+	for _, source := range []string{cfg.JWT.JWKSURL, cfg.JWT.PublicKey, cfg.JWT.HMACSecret} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return errors.New("validation_mode jwt requires exactly one of jwt.jwks_url, jwt.public_key and jwt.hmac_secret")
+	}
+	if cfg.JWT.JWKSURL != "" && cfg.JWT.JWKSRefreshInterval <= 0 {
+		return errors.New("jwt.jwks_refresh_interval must be positive")
+	}
+	if cfg.JWT.PublicKey != "" {
+		if _, err := parsePublicKey(cfg.JWT.PublicKey); err != nil {
+			return fmt.Errorf("invalid jwt.public_key: %w", err)
+		}
+	}
+	if len(cfg.ValidAPIKeys) > 0 || len(cfg.KeyListByService) > 0 {
+		return errors.New("valid_api_keys and key_list_by_service cannot be used with validation_mode jwt, which verifies a token instead")
+	}
+	if cfg.ValidationCache.Enabled {
+		// A cached validation could outlive the exp claim of the token
+		return errors.New("validation_cache cannot be used with validation_mode jwt")
+	}
 	return nil
 }
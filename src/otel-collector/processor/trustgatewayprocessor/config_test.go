@@ -0,0 +1,106 @@
+package trustgatewayprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{name: "default", configure: func(*Config) {}},
+		{
+			name: "hashed keys",
+			configure: func(cfg *Config) {
+				cfg.APIKeysHashed = true
+				cfg.ValidAPIKeys = []string{keyAcmeDigest}
+				cfg.KeyListByService = map[string][]string{"checkout": {keyGlobexDigest}}
+			},
+		},
+		{
+			name: "hashed keys with plaintext key",
+			configure: func(cfg *Config) {
+				cfg.APIKeysHashed = true
+				cfg.ValidAPIKeys = []string{keyAcmeDigest, "key-globex"}
+			},
+			wantErr: "api_keys_hashed requires every API key to be a hex encoded SHA-256 digest",
+		},
+		{
+			name: "hashed keys with short digest",
+			configure: func(cfg *Config) {
+				cfg.APIKeysHashed = true
+				cfg.KeyListByService = map[string][]string{"checkout": {keyAcmeDigest[:32]}}
+			},
+			wantErr: "api_keys_hashed requires every API key to be a hex encoded SHA-256 digest",
+		},
+		{
+			name: "validation cache without ttl",
+			configure: func(cfg *Config) {
+				cfg.ValidationCache = ValidationCacheConfig{Enabled: true, MaxEntries: 10}
+			},
+			wantErr: "validation_cache.ttl must be positive",
+		},
+		{
+			name: "validation cache without entries",
+			configure: func(cfg *Config) {
+				cfg.ValidationCache = ValidationCacheConfig{Enabled: true, TTL: time.Minute}
+			},
+			wantErr: "validation_cache.max_entries must be positive",
+		},
+		{
+			name:      "unknown drop mode",
+			configure: func(cfg *Config) { cfg.DropMode = "span" },
+			wantErr:   `unknown drop_mode "span", must be "batch" or "resource"`,
+		},
+		{
+			name:      "unknown validation failure handling",
+			configure: func(cfg *Config) { cfg.OnValidationFailure = "retry" },
+			wantErr:   `unknown on_validation_failure "retry", must be "drop" or "error"`,
+		},
+		{
+			name: "validation failure error with resource drop mode",
+			configure: func(cfg *Config) {
+				cfg.OnValidationFailure = onValidationFailureError
+				cfg.DropMode = dropModeResource
+			},
+			wantErr: "on_validation_failure error cannot be used with drop_mode resource",
+		},
+		{
+			name:      "empty API key header",
+			configure: func(cfg *Config) { cfg.APIKeyHeader = "" },
+			wantErr:   "api_key_header cannot be empty",
+		},
+		{
+			name:      "header rule without check",
+			configure: func(cfg *Config) { cfg.HeaderRules = map[string]HeaderRule{"X-App-Token": {}} },
+			wantErr:   "header_rules.X-App-Token needs a value, pattern, glob or allowed check",
+		},
+		{
+			name:      "header rule with invalid pattern",
+			configure: func(cfg *Config) { cfg.HeaderRules = map[string]HeaderRule{"X-App-Token": {Pattern: "acme-("}} },
+			wantErr:   "invalid header_rules.X-App-Token.pattern",
+		},
+		{
+			name:      "header rule with invalid glob",
+			configure: func(cfg *Config) { cfg.HeaderRules = map[string]HeaderRule{"X-App-Token": {Glob: "acme-["}} },
+			wantErr:   "invalid header_rules.X-App-Token.glob",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tt.configure(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package trustgatewayprocessor
 
 import (
 	"context"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
@@ -29,6 +30,22 @@ func createDefaultConfig() component.Config {
 	return &Config{
 		RequiredHeaders: []string{"X-App-Token"},
 		ValidAPIKeys:    []string{},
+		APIKeyHeader:    "X-API-Key",
+		ValidationCache: ValidationCacheConfig{
+			TTL:        time.Minute,
+			MaxEntries: 10000,
+		},
+		StripValidatedAttributes: true,
+		DropMode:                 dropModeBatch,
+		OnValidationFailure:      onValidationFailureDrop,
+		RejectionSamples: RejectionSamplesConfig{
+			Fraction: 0.01,
+		},
+		ValidationMode: validationModeAPIKey,
+		JWT: JWTConfig{
+			TokenAttribute:      "Authorization",
+			JWKSRefreshInterval: time.Hour,
+		},
 	}
 }
 
@@ -38,9 +55,9 @@ func createTracesProcessor(
 	cfg component.Config,
 	nextConsumer consumer.Traces,
 ) (processor.Traces, error) {
-	proc := &trustGatewayProcessor{
-		config: cfg.(*Config),
-		logger: set.Logger,
+	proc, err := newTrustGatewayProcessor(cfg.(*Config), set.TelemetrySettings)
+	if err != nil {
+		return nil, err
 	}
 	return processorhelper.NewTraces(
 		ctx,
@@ -49,6 +66,8 @@ func createTracesProcessor(
 		nextConsumer,
 		proc.processTraces,
 		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithStart(proc.startTraces),
+		processorhelper.WithShutdown(proc.shutdown),
 	)
 }
 
@@ -58,9 +77,9 @@ func createMetricsProcessor(
 	cfg component.Config,
 	nextConsumer consumer.Metrics,
 ) (processor.Metrics, error) {
-	proc := &trustGatewayProcessor{
-		config: cfg.(*Config),
-		logger: set.Logger,
+	proc, err := newTrustGatewayProcessor(cfg.(*Config), set.TelemetrySettings)
+	if err != nil {
+		return nil, err
 	}
 	return processorhelper.NewMetrics(
 		ctx,
@@ -69,6 +88,8 @@ func createMetricsProcessor(
 		nextConsumer,
 		proc.processMetrics,
 		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithStart(proc.startMetrics),
+		processorhelper.WithShutdown(proc.shutdown),
 	)
 }
 
@@ -78,9 +99,9 @@ func createLogsProcessor(
 	cfg component.Config,
 	nextConsumer consumer.Logs,
 ) (processor.Logs, error) {
-	proc := &trustGatewayProcessor{
-		config: cfg.(*Config),
-		logger: set.Logger,
+	proc, err := newTrustGatewayProcessor(cfg.(*Config), set.TelemetrySettings)
+	if err != nil {
+		return nil, err
 	}
 	return processorhelper.NewLogs(
 		ctx,
@@ -89,5 +110,7 @@ func createLogsProcessor(
 		nextConsumer,
 		proc.processLogs,
 		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithStart(proc.startLogs),
+		processorhelper.WithShutdown(proc.shutdown),
 	)
 }
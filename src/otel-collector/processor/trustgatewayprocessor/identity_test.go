@@ -0,0 +1,160 @@
+package trustgatewayprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+)
+
+// testAuthData is the auth data an authenticator extension puts on the client context
+type testAuthData map[string]any
+
+func (a testAuthData) GetAttribute(name string) any {
+	return a[name]
+}
+
+func (a testAuthData) GetAttributeNames() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	return names
+}
+
+// newIdentityConfig returns a configuration copying the tenant and scopes of the identity into resource attributes
+func newIdentityConfig() *Config {
+	cfg := newTestConfig()
+	cfg.IdentityAttributes = map[string]string{"tenant": "auth.tenant", "scopes": "auth.scopes"}
+	return cfg
+}
+
+// authContext returns a context carrying auth as the auth data of the client
+func authContext(auth testAuthData) context.Context {
+	return client.NewContext(context.Background(), client.Info{Auth: auth})
+}
+
+// TestIdentityAttributesAsyncConsumer runs the processor in front of a consumer that only reads the
+// traces after the export returned, the way an exporter with a sending queue does, without the client context
+func TestIdentityAttributesAsyncConsumer(t *testing.T) {
+	queue := make(chan ptrace.Traces, 1)
+	next, err := consumer.NewTraces(func(_ context.Context, td ptrace.Traces) error {
+		queue <- td
+		return nil
+	})
+	require.NoError(t, err)
+	cfg := newIdentityConfig()
+	require.NoError(t, cfg.Validate())
+	set := processor.Settings{
+		ID:                component.NewID(component.MustNewType("trustgateway")),
+		TelemetrySettings: componenttest.NewNopTelemetrySettings(),
+		BuildInfo:         component.NewDefaultBuildInfo(),
+	}
+	proc, err := createTracesProcessor(context.Background(), set, cfg, next)
+	require.NoError(t, err)
+	require.NoError(t, proc.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, proc.Shutdown(context.Background())) })
+
+	ctx, cancel := context.WithCancel(authContext(testAuthData{"tenant": "acme", "scopes": []any{"traces:write"}}))
+	require.NoError(t, proc.ConsumeTraces(ctx, newTestTraces(map[string]any{"X-API-Key": "key-acme"})))
+	cancel()
+
+	select {
+	case td := <-queue:
+		assert.Equal(t, map[string]any{"auth.tenant": "acme", "auth.scopes": []any{"traces:write"}},
+			td.ResourceSpans().At(0).Resource().Attributes().AsRaw())
+	case <-time.After(time.Second):
+		require.Fail(t, "the traces were not passed on")
+	}
+}
+
+func TestIdentityAttributesFromClientAuth(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]any
+	}{
+		{
+			name: "tenant and scopes",
+			ctx:  authContext(testAuthData{"tenant": "acme", "scopes": []any{"traces:write", "logs:write"}, "subject": "svc"}),
+			want: map[string]any{"auth.tenant": "acme", "auth.scopes": []any{"traces:write", "logs:write"}},
+		},
+		{
+			name: "missing scopes",
+			ctx:  authContext(testAuthData{"tenant": "acme"}),
+			want: map[string]any{"auth.tenant": "acme"},
+		},
+		{name: "no auth data", ctx: client.NewContext(context.Background(), client.Info{}), want: map[string]any{}},
+		{name: "no client info", ctx: context.Background(), want: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor(t, newIdentityConfig())
+			td, err := p.processTraces(tt.ctx, newTestTraces(map[string]any{"X-API-Key": "key-acme"}))
+			require.NoError(t, err)
+			require.Equal(t, 1, td.ResourceSpans().Len())
+			attrs := td.ResourceSpans().At(0).Resource().Attributes()
+			attrs.Remove("X-API-Key")
+			assert.Equal(t, tt.want, attrs.AsRaw())
+		})
+	}
+}
+
+func TestIdentityAttributesFromClaims(t *testing.T) {
+	cfg := newJWTConfig(JWTConfig{HMACSecret: testHMACSecret})
+	cfg.IdentityAttributes = map[string]string{"sub": "auth.subject", "scope": "auth.scopes", "tenant": "auth.tenant"}
+	p := newTestProcessor(t, cfg)
+	claims := validClaims()
+	claims["scope"] = []any{"traces:write"}
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testHMACSecret), claims, "")
+	td := newTestTraces(map[string]any{"Authorization": "Bearer " + token})
+
+	// The claims are read from the verified token, not from the client context
+	td, err := p.processTraces(authContext(testAuthData{"tenant": "globex"}), td)
+	require.NoError(t, err)
+	require.Equal(t, 1, td.ResourceSpans().Len())
+	attrs := td.ResourceSpans().At(0).Resource().Attributes().AsRaw()
+	assert.Equal(t, "acme", attrs["auth.subject"])
+	assert.Equal(t, []any{"traces:write"}, attrs["auth.scopes"])
+	assert.NotContains(t, attrs, "auth.tenant")
+}
+
+func TestIdentityAttributesBatchMode(t *testing.T) {
+	cfg := newIdentityConfig()
+	cfg.DropMode = dropModeBatch
+	p := newTestProcessor(t, cfg)
+	td := newTestTraces(map[string]any{"X-API-Key": "key-acme"}, map[string]any{"X-API-Key": "key-unknown"})
+
+	td, err := p.processTraces(authContext(testAuthData{"tenant": "acme"}), td)
+	require.NoError(t, err)
+	require.Equal(t, 2, td.ResourceSpans().Len())
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		tenant, ok := resourceAttribute(td, i, "auth.tenant")
+		assert.True(t, ok)
+		assert.Equal(t, "acme", tenant, "every resource carries the identity of the validated first resource")
+	}
+}
+
+func TestIdentityAttributesRejected(t *testing.T) {
+	p := newTestProcessor(t, newIdentityConfig())
+	td := newTestTraces(map[string]any{"X-API-Key": "key-unknown"})
+
+	td, err := p.processTraces(authContext(testAuthData{"tenant": "acme"}), td)
+	require.NoError(t, err)
+	assert.Zero(t, td.ResourceSpans().Len())
+}
+
+func TestIdentityAttributesValidate(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.IdentityAttributes = map[string]string{"tenant": ""}
+	assert.EqualError(t, cfg.Validate(), `identity_attributes maps "tenant" to an empty attribute name`)
+}
@@ -0,0 +1,370 @@
+package trustgatewayprocessor
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// errInvalidToken is returned for tokens that fail signature or claim verification
+var errInvalidToken = errors.New("invalid token")
+
+const (
+	// jwksFetchTimeout bounds a single JWKS request
+	jwksFetchTimeout = 10 * time.Second
+	// jwksMinRefreshInterval rate limits the refreshes triggered by tokens signed with an unknown key
+	jwksMinRefreshInterval = time.Minute
+)
+
+// asymmetricMethods are the signing methods accepted for JWKS and public key verification. HMAC
+// methods are excluded so a public key can never be used as a shared secret.
+var asymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// tokenVerifier verifies the signature and the exp, nbf, iss and aud claims of bearer tokens
+type tokenVerifier struct {
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+	jwks    *jwksCache
+}
+
+func newTokenVerifier(cfg JWTConfig, logger *zap.Logger) (*tokenVerifier, error) {
+	methods := asymmetricMethods
+	opts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	v := &tokenVerifier{}
+	switch {
+	case cfg.HMACSecret != "":
+		methods = []string{"HS256", "HS384", "HS512"}
+		secret := []byte(cfg.HMACSecret)
+		v.keyFunc = func(*jwt.Token) (any, error) { return secret, nil }
+	case cfg.PublicKey != "":
+		key, err := parsePublicKey(cfg.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		v.keyFunc = func(*jwt.Token) (any, error) { return key, nil }
+	default:
+		v.jwks = newJWKSCache(cfg.JWKSURL, cfg.JWKSRefreshInterval, logger)
+		v.keyFunc = func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return v.jwks.key(kid)
+		}
+	}
+	v.parser = jwt.NewParser(append(opts, jwt.WithValidMethods(methods))...)
+	return v, nil
+}
+
+// verify checks the token, which may carry a "Bearer " prefix
+func (v *tokenVerifier) verify(token string) error {
+	if _, err := v.parser.Parse(trimBearer(token), v.keyFunc); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidToken, err)
+	}
+	return nil
+}
+
+// claims returns the claims of a token without verifying it, for tokens that passed verify
+func (v *tokenVerifier) claims(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := v.parser.ParseUnverified(trimBearer(token), claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// trimBearer removes the "Bearer " prefix of a token, if any
+func trimBearer(token string) string {
+	if len(token) > len("Bearer ") && strings.EqualFold(token[:len("Bearer ")], "Bearer ") {
+		return token[len("Bearer "):]
+	}
+	return token
+}
+
+// start fetches the JWKS, if any, and keeps it fresh until shutdown
+func (v *tokenVerifier) start() {
+	if v.jwks != nil {
+		v.jwks.start()
+	}
+}
+
+func (v *tokenVerifier) shutdown() {
+	if v.jwks != nil {
+		v.jwks.shutdown()
+	}
+}
+
+// parsePublicKey parses a PEM encoded PKIX public key
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// jwksCache holds the signing keys of a JSON Web Key Set by key ID. It is refreshed every interval
+// and, at most once per jwksMinRefreshInterval, when a token names an unknown key, so rotated keys
+// are picked up before the next scheduled refresh.
+type jwksCache struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	logger   *zap.Logger
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	lastRefresh time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newJWKSCache(url string, interval time.Duration, logger *zap.Logger) *jwksCache {
+	return &jwksCache{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: jwksFetchTimeout},
+		logger:   logger,
+		keys:     map[string]crypto.PublicKey{},
+	}
+}
+
+// start fetches the key set and starts the periodic refresh. A failed fetch does not fail start, as
+// tokens are rejected until a later refresh succeeds.
+func (c *jwksCache) start() {
+	c.refresh()
+
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.refresh()
+			}
+		}
+	}()
+}
+
+func (c *jwksCache) shutdown() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+	c.stop = nil
+}
+
+// key returns the key with the given ID. Without an ID, the key set must hold a single key.
+func (c *jwksCache) key(kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.lookup(kid)
+	stale := !ok && time.Since(c.lastRefresh) >= jwksMinRefreshInterval
+	if stale {
+		c.lastRefresh = time.Now()
+	}
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	if stale {
+		c.refresh()
+		c.mu.Lock()
+		key, ok = c.lookup(kid)
+		c.mu.Unlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("no JWKS key with kid %q", kid)
+}
+
+// lookup returns the key with the given ID. The caller must hold mu.
+func (c *jwksCache) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// refresh replaces the keys with the current key set, keeping the previous keys when the fetch
+// fails. The key set is fetched without holding mu, so validation is not blocked meanwhile.
+func (c *jwksCache) refresh() {
+	keys, err := c.fetch()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastRefresh = time.Now()
+	if err != nil {
+		c.logger.Warn("Failed to fetch JWKS, keeping the previous keys", zap.String("url", c.url), zap.Error(err))
+		return
+	}
+	c.keys = keys
+	c.logger.Debug("Fetched JWKS", zap.String("url", c.url), zap.Int("keys", len(keys)))
+}
+
+// jsonWebKey is a key of a JSON Web Key Set, see RFC 7517 and RFC 7518
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (c *jwksCache) fetch() (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types rather than rejecting the whole set
+			c.logger.Debug("Skipping JWKS key", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no usable signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA, EC or Ed25519 key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeKeyParam(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeKeyParam(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, err := decodeKeyParam(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeKeyParam(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC key coordinates")
+		}
+		// Parsing the uncompressed point checks that it lies on the curve
+		if _, err := ecdhCurve.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid EC key: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", k.Crv)
+		}
+		x, err := decodeKeyParam(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeKeyParam decodes a base64url encoded key parameter
+func decodeKeyParam(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key parameter: %w", err)
+	}
+	return b, nil
+}
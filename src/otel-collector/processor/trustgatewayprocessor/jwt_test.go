@@ -0,0 +1,324 @@
+package trustgatewayprocessor
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testHMACSecret = "test-secret"
+
+// validClaims returns claims that pass verification against newJWTConfig
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss": "https://issuer.example",
+		"aud": "otel-gateway",
+		"sub": "acme",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// newJWTConfig returns a jwt validation mode configuration expecting the issuer and audience of
+// validClaims, with tokens in the Authorization attribute
+func newJWTConfig(keys JWTConfig) *Config {
+	cfg := newTestConfig()
+	cfg.ValidAPIKeys = nil
+	cfg.ValidationMode = validationModeJWT
+	keys.TokenAttribute = "Authorization"
+	keys.Issuer = "https://issuer.example"
+	keys.Audience = "otel-gateway"
+	cfg.JWT = keys
+	return cfg
+}
+
+// signToken signs claims with key using method
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// newECKey generates a P-256 key and returns it with its PEM encoded public key
+func newECKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerifyHMACToken(t *testing.T) {
+	v, err := newTokenVerifier(newJWTConfig(JWTConfig{HMACSecret: testHMACSecret}).JWT, zap.NewNop())
+	require.NoError(t, err)
+	secret := []byte(testHMACSecret)
+
+	withClaim := func(name string, value any) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: signToken(t, jwt.SigningMethodHS256, secret, validClaims(), "")},
+		{name: "bearer prefix", token: "Bearer " + signToken(t, jwt.SigningMethodHS512, secret, validClaims(), "")},
+		{name: "lowercase bearer prefix", token: "bearer " + signToken(t, jwt.SigningMethodHS256, secret, validClaims(), "")},
+		{name: "other secret", token: signToken(t, jwt.SigningMethodHS256, []byte("other"), validClaims(), ""), wantErr: true},
+		{name: "expired", token: signToken(t, jwt.SigningMethodHS256, secret, withClaim("exp", time.Now().Add(-time.Minute).Unix()), ""), wantErr: true},
+		{name: "without exp", token: signToken(t, jwt.SigningMethodHS256, secret, withClaim("exp", nil), ""), wantErr: true},
+		{name: "not yet valid", token: signToken(t, jwt.SigningMethodHS256, secret, withClaim("nbf", time.Now().Add(time.Hour).Unix()), ""), wantErr: true},
+		{name: "other issuer", token: signToken(t, jwt.SigningMethodHS256, secret, withClaim("iss", "https://other.example"), ""), wantErr: true},
+		{name: "other audience", token: signToken(t, jwt.SigningMethodHS256, secret, withClaim("aud", "other"), ""), wantErr: true},
+		{name: "unsigned", token: signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims(), ""), wantErr: true},
+		{name: "malformed", token: "not-a-token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.verify(tt.token)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, errInvalidToken)
+		})
+	}
+}
+
+func TestVerifyPublicKeyToken(t *testing.T) {
+	key, publicKey := newECKey(t)
+	other, _ := newECKey(t)
+	v, err := newTokenVerifier(newJWTConfig(JWTConfig{PublicKey: publicKey}).JWT, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.NoError(t, v.verify(signToken(t, jwt.SigningMethodES256, key, validClaims(), "")))
+	assert.ErrorIs(t, v.verify(signToken(t, jwt.SigningMethodES256, other, validClaims(), "")), errInvalidToken)
+	// The public key must not be accepted as an HMAC secret
+	assert.ErrorIs(t, v.verify(signToken(t, jwt.SigningMethodHS256, []byte(publicKey), validClaims(), "")), errInvalidToken)
+}
+
+// jwksServer serves the JSON Web Key Set of keys, which can be replaced, and counts its requests
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]*ecdsa.PrivateKey
+	requests int
+}
+
+func newJWKSServer(t *testing.T, keys map[string]*ecdsa.PrivateKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		set := struct {
+			Keys []jsonWebKey `json:"keys"`
+		}{}
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kty: "EC",
+				Kid: kid,
+				Use: "sig",
+				Crv: "P-256",
+				X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+				Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// count returns the number of requests served so far
+func (s *jwksServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *jwksServer) setKeys(keys map[string]*ecdsa.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func TestVerifyJWKSToken(t *testing.T) {
+	first, _ := newECKey(t)
+	server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"first": first})
+	v, err := newTokenVerifier(newJWTConfig(JWTConfig{JWKSURL: server.URL, JWKSRefreshInterval: time.Hour}).JWT, zap.NewNop())
+	require.NoError(t, err)
+	v.start()
+	t.Cleanup(v.shutdown)
+
+	assert.NoError(t, v.verify(signToken(t, jwt.SigningMethodES256, first, validClaims(), "first")))
+	assert.NoError(t, v.verify(signToken(t, jwt.SigningMethodES256, first, validClaims(), "")), "a single key is used for tokens without kid")
+	assert.ErrorIs(t, v.verify(signToken(t, jwt.SigningMethodES256, first, validClaims(), "unknown")), errInvalidToken)
+
+	t.Run("rotated key", func(t *testing.T) {
+		second, _ := newECKey(t)
+		server.setKeys(map[string]*ecdsa.PrivateKey{"first": first, "second": second})
+		token := signToken(t, jwt.SigningMethodES256, second, validClaims(), "second")
+
+		requests := server.count()
+		assert.ErrorIs(t, v.verify(token), errInvalidToken, "unknown keys refresh at most once per minute")
+		assert.Equal(t, requests, server.count())
+
+		v.jwks.mu.Lock()
+		v.jwks.lastRefresh = time.Now().Add(-jwksMinRefreshInterval)
+		v.jwks.mu.Unlock()
+		assert.NoError(t, v.verify(token))
+		assert.Equal(t, requests+1, server.count())
+	})
+}
+
+func TestJWKSKeepsKeysOnFailedRefresh(t *testing.T) {
+	key, _ := newECKey(t)
+	server := newJWKSServer(t, map[string]*ecdsa.PrivateKey{"key": key})
+	cache := newJWKSCache(server.URL, time.Hour, zap.NewNop())
+	cache.refresh()
+
+	server.setKeys(nil)
+	cache.refresh()
+
+	_, err := cache.key("key")
+	assert.NoError(t, err)
+}
+
+func TestJSONWebKey(t *testing.T) {
+	key, _ := newECKey(t)
+	x := base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32)))
+	y := base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32)))
+
+	tests := []struct {
+		name    string
+		jwk     jsonWebKey
+		want    crypto.PublicKey
+		wantErr string
+	}{
+		{name: "ec", jwk: jsonWebKey{Kty: "EC", Crv: "P-256", X: x, Y: y}, want: &key.PublicKey},
+		{name: "ec point off the curve", jwk: jsonWebKey{Kty: "EC", Crv: "P-256", X: x, Y: x}, wantErr: "invalid EC key"},
+		{name: "ec unknown curve", jwk: jsonWebKey{Kty: "EC", Crv: "P-192", X: x, Y: y}, wantErr: `unsupported EC curve "P-192"`},
+		{name: "rsa exponent too small", jwk: jsonWebKey{Kty: "RSA", N: x, E: "AQ"}, wantErr: "invalid RSA key"},
+		{name: "ed25519 wrong size", jwk: jsonWebKey{Kty: "OKP", Crv: "Ed25519", X: "AQ"}, wantErr: "invalid Ed25519 key"},
+		{name: "symmetric", jwk: jsonWebKey{Kty: "oct"}, wantErr: `unsupported key type "oct"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.jwk.publicKey()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.(*ecdsa.PublicKey).Equal(got))
+		})
+	}
+}
+
+func TestProcessJWTTraces(t *testing.T) {
+	cfg := newJWTConfig(JWTConfig{HMACSecret: testHMACSecret})
+	cfg.DropMode = dropModeResource
+	p := newTestProcessor(t, cfg)
+	valid := signToken(t, jwt.SigningMethodHS256, []byte(testHMACSecret), validClaims(), "")
+	td := newTestTraces(
+		map[string]any{"Authorization": "Bearer " + valid, "service.name": "valid"},
+		map[string]any{"Authorization": "Bearer invalid", "service.name": "invalid"},
+		map[string]any{"service.name": "missing"},
+	)
+
+	td, err := p.processTraces(context.Background(), td)
+	require.NoError(t, err)
+	require.Equal(t, 1, td.ResourceSpans().Len())
+	service, _ := resourceAttribute(td, 0, "service.name")
+	assert.Equal(t, "valid", service)
+	_, ok := resourceAttribute(td, 0, "Authorization")
+	assert.False(t, ok, "the token is stripped from validated telemetry")
+}
+
+func TestJWTConfigValidate(t *testing.T) {
+	_, publicKey := newECKey(t)
+	tests := []struct {
+		name      string
+		configure func(*Config)
+		wantErr   string
+	}{
+		{name: "hmac secret", configure: func(*Config) {}},
+		{
+			name:      "public key",
+			configure: func(cfg *Config) { cfg.JWT.HMACSecret, cfg.JWT.PublicKey = "", publicKey },
+		},
+		{
+			name:      "several key sources",
+			configure: func(cfg *Config) { cfg.JWT.PublicKey = publicKey },
+			wantErr:   "validation_mode jwt requires exactly one of jwt.jwks_url, jwt.public_key and jwt.hmac_secret",
+		},
+		{
+			name:      "invalid public key",
+			configure: func(cfg *Config) { cfg.JWT.HMACSecret, cfg.JWT.PublicKey = "", "not a key" },
+			wantErr:   "invalid jwt.public_key: no PEM block found",
+		},
+		{
+			name: "jwks without refresh interval",
+			configure: func(cfg *Config) {
+				cfg.JWT.HMACSecret, cfg.JWT.JWKSURL, cfg.JWT.JWKSRefreshInterval = "", "https://issuer.example/jwks", 0
+			},
+			wantErr: "jwt.jwks_refresh_interval must be positive",
+		},
+		{
+			name:      "without token attribute",
+			configure: func(cfg *Config) { cfg.JWT.TokenAttribute = "" },
+			wantErr:   "jwt.token_attribute cannot be empty",
+		},
+		{
+			name:      "with api keys",
+			configure: func(cfg *Config) { cfg.ValidAPIKeys = []string{"key-acme"} },
+			wantErr:   "valid_api_keys and key_list_by_service cannot be used with validation_mode jwt",
+		},
+		{
+			name:      "with validation cache",
+			configure: func(cfg *Config) { cfg.ValidationCache.Enabled = true },
+			wantErr:   "validation_cache cannot be used with validation_mode jwt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newJWTConfig(JWTConfig{HMACSecret: testHMACSecret})
+			tt.configure(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -2,24 +2,213 @@ package trustgatewayprocessor
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
 
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 )
 
+var (
+	// errMalformedAPIKey is returned for API keys that do not match the configured format
+	errMalformedAPIKey = errors.New("malformed API key")
+	// errMissingHeader is returned when a required header or the API key header is missing
+	errMissingHeader = errors.New("missing required header")
+	// errInvalidAPIKey is returned for API keys that are not in the valid key list
+	errInvalidAPIKey = errors.New("invalid API key")
+	// errUnknownService is returned when key_list_by_service has no key list for the resource
+	errUnknownService = errors.New("no API key list for service")
+	// errEmptyResource is returned for batches without resources
+	errEmptyResource = errors.New("no resources found")
+	// errHeaderRule is returned when an attribute value is not accepted by its header rule
+	errHeaderRule = errors.New("attribute value not accepted")
+)
+
+// matchedRuleAttribute is the resource attribute stamped with the rules that accepted the telemetry
+const matchedRuleAttribute = "trustgateway.matched_rule"
+
+// serviceNameAttribute selects the key list when key_list_by_service is configured
+const serviceNameAttribute = "service.name"
+
 type trustGatewayProcessor struct {
 	config *Config
 	logger *zap.Logger
+	cache  *validationCache
+	// apiKeyPattern is the compiled api_key_format pattern, anchored to match the whole key
+	apiKeyPattern *regexp.Regexp
+	// headerRuleNames are the attributes with header rules, sorted so rejections are deterministic
+	headerRuleNames []string
+	// headerPatterns are the compiled header rule patterns by attribute name, anchored to match the whole value
+	headerPatterns map[string]*regexp.Regexp
+	// malformedAPIKeys counts batches rejected for a malformed API key
+	malformedAPIKeys metric.Int64Counter
+	// validated and rejected count the batches, or resources in resource drop mode, by validation outcome
+	validated metric.Int64Counter
+	rejected  metric.Int64Counter
+	// rulesFingerprint identifies the configured rules so cached results never outlive a rule change
+	rulesFingerprint []byte
+	// sink receives samples of rejected resources, resolved from rejection_samples.sink on start
+	tracesSink  consumer.Traces
+	metricsSink consumer.Metrics
+	logsSink    consumer.Logs
+	// verifier checks the token in jwt validation mode, nil otherwise
+	verifier *tokenVerifier
+	// tenantNames are the tenants of tenant.api_keys, sorted so a key listed for several tenants
+	// always resolves to the same one
+	tenantNames []string
+}
+
+func newTrustGatewayProcessor(config *Config, set component.TelemetrySettings) (*trustGatewayProcessor, error) {
+	meter := set.MeterProvider.Meter("github.com/fedeoliv/custom-otel-collector/processor/trustgatewayprocessor")
+	malformedAPIKeys, err := meter.Int64Counter(
+		"otelcol_processor_trustgateway_malformed_api_keys",
+		metric.WithDescription("Number of batches, or resources in resource drop mode, rejected because their API key does not match api_key_format"),
+		metric.WithUnit("{batches}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	validated, err := meter.Int64Counter(
+		"otelcol_processor_trustgateway_validated",
+		metric.WithDescription("Number of batches, or resources in resource drop mode, that passed validation"),
+		metric.WithUnit("{batches}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	rejected, err := meter.Int64Counter(
+		"otelcol_processor_trustgateway_rejected",
+		metric.WithDescription("Number of batches, or resources in resource drop mode, rejected by validation, by reason"),
+		metric.WithUnit("{batches}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &trustGatewayProcessor{
+		config:           config,
+		logger:           set.Logger,
+		malformedAPIKeys: malformedAPIKeys,
+		validated:        validated,
+		rejected:         rejected,
+	}
+	if config.APIKeyFormat.Pattern != "" {
+		p.apiKeyPattern, err = regexp.Compile("^(?:" + config.APIKeyFormat.Pattern + ")$")
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.headerPatterns = make(map[string]*regexp.Regexp)
+	for name, rule := range config.HeaderRules {
+		p.headerRuleNames = append(p.headerRuleNames, name)
+		if rule.Pattern != "" {
+			p.headerPatterns[name], err = regexp.Compile("^(?:" + rule.Pattern + ")$")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	slices.Sort(p.headerRuleNames)
+	for tenant := range config.Tenant.APIKeys {
+		p.tenantNames = append(p.tenantNames, tenant)
+	}
+	slices.Sort(p.tenantNames)
+	if config.ValidationMode == validationModeJWT {
+		p.verifier, err = newTokenVerifier(config.JWT, set.Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.ValidationCache.Enabled {
+		p.cache = newValidationCache(config.ValidationCache.TTL, config.ValidationCache.MaxEntries)
+		h := sha256.New()
+		for _, header := range config.RequiredHeaders {
+			h.Write([]byte(header))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
+		for _, key := range config.ValidAPIKeys {
+			h.Write([]byte(key))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
+		services := make([]string, 0, len(config.KeyListByService))
+		for service := range config.KeyListByService {
+			services = append(services, service)
+		}
+		slices.Sort(services)
+		for _, service := range services {
+			h.Write([]byte(service))
+			h.Write([]byte{0})
+			for _, key := range config.KeyListByService[service] {
+				h.Write([]byte(key))
+				h.Write([]byte{0})
+			}
+			h.Write([]byte{1})
+		}
+		for _, name := range p.headerRuleNames {
+			rule := config.HeaderRules[name]
+			for _, part := range append([]string{name, rule.Value, rule.Pattern, rule.Glob}, rule.Allowed...) {
+				h.Write([]byte(part))
+				h.Write([]byte{0})
+			}
+			h.Write([]byte{1})
+		}
+		p.rulesFingerprint = h.Sum(nil)
+	}
+	return p, nil
+}
+
+// shutdown stops the JWKS refresh of jwt validation mode
+func (p *trustGatewayProcessor) shutdown(context.Context) error {
+	if p.verifier != nil {
+		p.verifier.shutdown()
+	}
+	return nil
 }
 
 // processTraces validates traces based on resource attributes
 func (p *trustGatewayProcessor) processTraces(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
-	if err := p.validateTelemetry(td.ResourceSpans()); err != nil {
+	if p.config.DropMode == dropModeResource {
+		samples := ptrace.NewTraces()
+		td.ResourceSpans().RemoveIf(func(r ptrace.ResourceSpans) bool {
+			err := p.validateResource(ctx, r.Resource(), p.validationScopes(r))
+			if err == nil {
+				return false
+			}
+			p.logger.Warn("Trace validation failed, dropping resource", zap.Error(err))
+			if p.tracesSink != nil {
+				sampleResource(samples.ResourceSpans(), r, p.config.RejectionSamples.Fraction, err)
+			}
+			return true
+		})
+		p.forwardTracesSamples(ctx, samples)
+		return td, nil
+	}
+
+	if err := p.validateTelemetry(ctx, td.ResourceSpans()); err != nil {
 		p.logger.Warn("Trace validation failed", zap.Error(err))
+		p.sampleRejectedTraces(ctx, td, err)
+		if p.config.OnValidationFailure == onValidationFailureError {
+			// A permanent error makes the receiver reject the request instead of acknowledging it
+			return td, consumererror.NewPermanent(err)
+		}
 		// Return empty traces on validation failure
 		return ptrace.NewTraces(), nil
 	}
@@ -29,8 +218,30 @@ func (p *trustGatewayProcessor) processTraces(ctx context.Context, td ptrace.Tra
 
 // processMetrics validates metrics based on resource attributes
 func (p *trustGatewayProcessor) processMetrics(ctx context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
-	if err := p.validateTelemetry(md.ResourceMetrics()); err != nil {
+	if p.config.DropMode == dropModeResource {
+		samples := pmetric.NewMetrics()
+		md.ResourceMetrics().RemoveIf(func(r pmetric.ResourceMetrics) bool {
+			err := p.validateResource(ctx, r.Resource(), p.validationScopes(r))
+			if err == nil {
+				return false
+			}
+			p.logger.Warn("Metric validation failed, dropping resource", zap.Error(err))
+			if p.metricsSink != nil {
+				sampleResource(samples.ResourceMetrics(), r, p.config.RejectionSamples.Fraction, err)
+			}
+			return true
+		})
+		p.forwardMetricsSamples(ctx, samples)
+		return md, nil
+	}
+
+	if err := p.validateTelemetry(ctx, md.ResourceMetrics()); err != nil {
 		p.logger.Warn("Metric validation failed", zap.Error(err))
+		p.sampleRejectedMetrics(ctx, md, err)
+		if p.config.OnValidationFailure == onValidationFailureError {
+			// A permanent error makes the receiver reject the request instead of acknowledging it
+			return md, consumererror.NewPermanent(err)
+		}
 		// Return empty metrics on validation failure
 		return pmetric.NewMetrics(), nil
 	}
@@ -40,8 +251,30 @@ func (p *trustGatewayProcessor) processMetrics(ctx context.Context, md pmetric.M
 
 // processLogs validates logs based on resource attributes
 func (p *trustGatewayProcessor) processLogs(ctx context.Context, ld plog.Logs) (plog.Logs, error) {
-	if err := p.validateTelemetry(ld.ResourceLogs()); err != nil {
+	if p.config.DropMode == dropModeResource {
+		samples := plog.NewLogs()
+		ld.ResourceLogs().RemoveIf(func(r plog.ResourceLogs) bool {
+			err := p.validateResource(ctx, r.Resource(), p.validationScopes(r))
+			if err == nil {
+				return false
+			}
+			p.logger.Warn("Log validation failed, dropping resource", zap.Error(err))
+			if p.logsSink != nil {
+				sampleResource(samples.ResourceLogs(), r, p.config.RejectionSamples.Fraction, err)
+			}
+			return true
+		})
+		p.forwardLogsSamples(ctx, samples)
+		return ld, nil
+	}
+
+	if err := p.validateTelemetry(ctx, ld.ResourceLogs()); err != nil {
 		p.logger.Warn("Log validation failed", zap.Error(err))
+		p.sampleRejectedLogs(ctx, ld, err)
+		if p.config.OnValidationFailure == onValidationFailureError {
+			// A permanent error makes the receiver reject the request instead of acknowledging it
+			return ld, consumererror.NewPermanent(err)
+		}
 		// Return empty logs on validation failure
 		return plog.NewLogs(), nil
 	}
@@ -51,66 +284,490 @@ func (p *trustGatewayProcessor) processLogs(ctx context.Context, ld plog.Logs) (
 
 // validateTelemetry checks if the telemetry data contains valid authentication tokens
 // The custom headers are expected to be passed as resource attributes by the sender
-func (p *trustGatewayProcessor) validateTelemetry(resources interface{}) error {
-	// Check if we have any required headers configured
-	if len(p.config.RequiredHeaders) == 0 && len(p.config.ValidAPIKeys) == 0 {
+func (p *trustGatewayProcessor) validateTelemetry(ctx context.Context, resources interface{}) (err error) {
+	defer func() { p.recordValidation(ctx, err) }()
+
+	if !p.hasRules() {
 		p.logger.Debug("No validation rules configured, allowing all telemetry")
 		return nil
 	}
 
 	var attrs pcommon.Map
-	
+
 	// Extract attributes based on telemetry type
 	switch r := resources.(type) {
 	case ptrace.ResourceSpansSlice:
 		if r.Len() == 0 {
-			return fmt.Errorf("no resource spans found")
+			return fmt.Errorf("%w: no resource spans", errEmptyResource)
 		}
-		attrs = r.At(0).Resource().Attributes()
+		attrs = validationAttributes(r.At(0).Resource(), p.validationScopes(r.At(0)))
 	case pmetric.ResourceMetricsSlice:
 		if r.Len() == 0 {
-			return fmt.Errorf("no resource metrics found")
+			return fmt.Errorf("%w: no resource metrics", errEmptyResource)
 		}
-		attrs = r.At(0).Resource().Attributes()
+		attrs = validationAttributes(r.At(0).Resource(), p.validationScopes(r.At(0)))
 	case plog.ResourceLogsSlice:
 		if r.Len() == 0 {
-			return fmt.Errorf("no resource logs found")
+			return fmt.Errorf("%w: no resource logs", errEmptyResource)
 		}
-		attrs = r.At(0).Resource().Attributes()
+		attrs = validationAttributes(r.At(0).Resource(), p.validationScopes(r.At(0)))
 	default:
 		return fmt.Errorf("unknown resource type")
 	}
 
+	rule, err := p.validateResourceAttributes(ctx, attrs)
+	if err != nil {
+		return err
+	}
+	// The batch is accepted as a whole, so every resource is annotated and stripped, and carries the
+	// identity of the validated first resource
+	identity := p.identity(ctx, attrs)
+	firstTenant := p.tenant(attrs)
+	first := true
+	p.eachResource(resources, func(resource pcommon.Resource, scopes []pcommon.Map) {
+		tenant := firstTenant
+		if !first {
+			tenant = p.resourceTenant(validationAttributes(resource, scopes), firstTenant)
+		}
+		first = false
+		p.finishResource(resource, scopes, rule, identity, tenant)
+	})
+	return nil
+}
+
+// resourceTenant returns the tenant of a resource after the first of a batch accepted in batch drop
+// mode. The resource gets its own tenant only when its own attributes pass validation, so a batch
+// mixing tenants is routed per tenant, while a resource claiming another tenant without valid
+// credentials stays with the tenant of the validated first resource
+func (p *trustGatewayProcessor) resourceTenant(attrs pcommon.Map, firstTenant string) string {
+	if p.config.Tenant.Source == "" {
+		return ""
+	}
+	if p.checkAPIKeyFormat(attrs) != nil {
+		return firstTenant
+	}
+	if _, err := p.validateAttributes(attrs); err != nil {
+		return firstTenant
+	}
+	return p.tenant(attrs)
+}
+
+// validateResource validates a single resource in resource drop mode and stamps its matched rule.
+// scopes are the attributes of its validation_scope_name scopes.
+func (p *trustGatewayProcessor) validateResource(ctx context.Context, resource pcommon.Resource, scopes []pcommon.Map) (err error) {
+	defer func() { p.recordValidation(ctx, err) }()
+
+	if !p.hasRules() {
+		return nil
+	}
+	attrs := validationAttributes(resource, scopes)
+	rule, err := p.validateResourceAttributes(ctx, attrs)
+	if err != nil {
+		return err
+	}
+	p.finishResource(resource, scopes, rule, p.identity(ctx, attrs), p.tenant(attrs))
+	return nil
+}
+
+// validationScopes returns the attributes of the scopes named validation_scope_name of a
+// ResourceSpans, ResourceMetrics or ResourceLogs, in order
+func (p *trustGatewayProcessor) validationScopes(resource interface{}) []pcommon.Map {
+	if p.config.ValidationScopeName == "" {
+		return nil
+	}
+	var scopes []pcommon.Map
+	add := func(scope pcommon.InstrumentationScope) {
+		if scope.Name() == p.config.ValidationScopeName {
+			scopes = append(scopes, scope.Attributes())
+		}
+	}
+	switch r := resource.(type) {
+	case ptrace.ResourceSpans:
+		for i := 0; i < r.ScopeSpans().Len(); i++ {
+			add(r.ScopeSpans().At(i).Scope())
+		}
+	case pmetric.ResourceMetrics:
+		for i := 0; i < r.ScopeMetrics().Len(); i++ {
+			add(r.ScopeMetrics().At(i).Scope())
+		}
+	case plog.ResourceLogs:
+		for i := 0; i < r.ScopeLogs().Len(); i++ {
+			add(r.ScopeLogs().At(i).Scope())
+		}
+	}
+	return scopes
+}
+
+// validationAttributes returns the attributes a resource is validated against: its own attributes,
+// overlaid with the attributes of its first validation_scope_name scope, if any
+func validationAttributes(resource pcommon.Resource, scopes []pcommon.Map) pcommon.Map {
+	if len(scopes) == 0 {
+		return resource.Attributes()
+	}
+	attrs := pcommon.NewMap()
+	resource.Attributes().CopyTo(attrs)
+	scopes[0].Range(func(k string, v pcommon.Value) bool {
+		v.CopyTo(attrs.PutEmpty(k))
+		return true
+	})
+	return attrs
+}
+
+// finishResource stamps the matched rule, the identity and the tenant on a resource that passed
+// validation and strips its credentials, including those carried by its validation scopes
+func (p *trustGatewayProcessor) finishResource(resource pcommon.Resource, scopes []pcommon.Map, rule string, identity map[string]any, tenant string) {
+	if p.config.AnnotateMatchedRule && rule != "" {
+		resource.Attributes().PutStr(matchedRuleAttribute, rule)
+	}
+	if tenant != "" {
+		resource.Attributes().PutStr(tenantAttribute, tenant)
+	} else if p.config.Tenant.Source != "" {
+		// A tenant sent by the client must not steer the routing
+		resource.Attributes().Remove(tenantAttribute)
+	}
+	for attribute, value := range identity {
+		if err := resource.Attributes().PutEmpty(attribute).FromRaw(value); err != nil {
+			p.logger.Debug("Failed to copy identity attribute", zap.String("attribute", attribute), zap.Error(err))
+		}
+	}
+	if p.config.StripValidatedAttributes {
+		p.stripValidatedAttributes(resource.Attributes())
+		for _, scope := range scopes {
+			p.stripValidatedAttributes(scope)
+		}
+	}
+}
+
+// stripValidatedAttributes removes the required headers, the API key and, in jwt validation mode,
+// the token, ignoring their case when case_insensitive_headers is enabled
+func (p *trustGatewayProcessor) stripValidatedAttributes(attrs pcommon.Map) {
+	names := append(slices.Clone(p.config.RequiredHeaders), p.config.APIKeyHeader)
+	if p.verifier != nil {
+		names = append(names, p.config.JWT.TokenAttribute)
+	}
+	if !p.config.CaseInsensitiveHeaders {
+		for _, name := range names {
+			attrs.Remove(name)
+		}
+		return
+	}
+	attrs.RemoveIf(func(key string, _ pcommon.Value) bool {
+		return slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(key, name) })
+	})
+}
+
+// recordValidation counts a validation outcome, labelling rejections with their reason
+func (p *trustGatewayProcessor) recordValidation(ctx context.Context, err error) {
+	if err == nil {
+		p.validated.Add(ctx, 1)
+		return
+	}
+	p.rejected.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", rejectionReason(err))))
+}
+
+// rejectionReason maps a validation error to the reason attribute of the rejected counter
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, errMissingHeader):
+		return "missing_header"
+	case errors.Is(err, errInvalidAPIKey):
+		return "invalid_api_key"
+	case errors.Is(err, errMalformedAPIKey):
+		return "malformed_api_key"
+	case errors.Is(err, errUnknownService):
+		return "unknown_service"
+	case errors.Is(err, errEmptyResource):
+		return "empty_resource"
+	case errors.Is(err, errInvalidToken):
+		return "invalid_token"
+	case errors.Is(err, errHeaderRule):
+		return "header_rule"
+	default:
+		return "other"
+	}
+}
+
+// hasRules reports whether any validation rule is configured
+func (p *trustGatewayProcessor) hasRules() bool {
+	return len(p.config.RequiredHeaders) > 0 || len(p.config.ValidAPIKeys) > 0 || len(p.config.KeyListByService) > 0 || len(p.config.HeaderRules) > 0 ||
+		p.config.APIKeyFormat.enabled() || p.verifier != nil
+}
+
+// validateResourceAttributes runs the format check and the validation rules against the
+// attributes of one resource and returns the matched rule
+func (p *trustGatewayProcessor) validateResourceAttributes(ctx context.Context, attrs pcommon.Map) (string, error) {
+	// Reject malformed keys before the more expensive cache and key list lookups
+	if err := p.checkAPIKeyFormat(attrs); err != nil {
+		p.malformedAPIKeys.Add(ctx, 1)
+		return "", err
+	}
+	return p.validateAttributes(attrs)
+}
+
+// identity returns the identity_attributes values of the validated attributes, keyed by resource
+// attribute name. In jwt validation mode they are read from the token claims, which were verified by
+// validation, otherwise from the auth data an authenticator extension put on the client context.
+func (p *trustGatewayProcessor) identity(ctx context.Context, attrs pcommon.Map) map[string]any {
+	if len(p.config.IdentityAttributes) == 0 {
+		return nil
+	}
+	var lookup func(name string) any
+	if p.verifier != nil {
+		tokenVal, ok := p.header(attrs, p.config.JWT.TokenAttribute)
+		if !ok {
+			return nil
+		}
+		claims, err := p.verifier.claims(tokenVal.AsString())
+		if err != nil {
+			return nil
+		}
+		lookup = func(name string) any { return claims[name] }
+	} else {
+		auth := client.FromContext(ctx).Auth
+		if auth == nil {
+			return nil
+		}
+		lookup = auth.GetAttribute
+	}
+
+	identity := make(map[string]any, len(p.config.IdentityAttributes))
+	for source, attribute := range p.config.IdentityAttributes {
+		if value := lookup(source); value != nil {
+			identity[attribute] = value
+		}
+	}
+	return identity
+}
+
+// eachResource calls fn with every resource of the batch and the attributes of its validation scopes
+func (p *trustGatewayProcessor) eachResource(resources interface{}, fn func(pcommon.Resource, []pcommon.Map)) {
+	switch r := resources.(type) {
+	case ptrace.ResourceSpansSlice:
+		for i := 0; i < r.Len(); i++ {
+			fn(r.At(i).Resource(), p.validationScopes(r.At(i)))
+		}
+	case pmetric.ResourceMetricsSlice:
+		for i := 0; i < r.Len(); i++ {
+			fn(r.At(i).Resource(), p.validationScopes(r.At(i)))
+		}
+	case plog.ResourceLogsSlice:
+		for i := 0; i < r.Len(); i++ {
+			fn(r.At(i).Resource(), p.validationScopes(r.At(i)))
+		}
+	}
+}
+
+// checkAPIKeyFormat checks the API key attribute, if present, against the configured format
+func (p *trustGatewayProcessor) checkAPIKeyFormat(attrs pcommon.Map) error {
+	if !p.config.APIKeyFormat.enabled() {
+		return nil
+	}
+	apiKeyVal, ok := p.header(attrs, p.config.APIKeyHeader)
+	if !ok {
+		return nil
+	}
+
+	apiKey := apiKeyVal.AsString()
+	format := p.config.APIKeyFormat
+	if len(apiKey) < format.MinLength || (format.MaxLength > 0 && len(apiKey) > format.MaxLength) {
+		return fmt.Errorf("%w: length %d is out of the allowed range", errMalformedAPIKey, len(apiKey))
+	}
+	if p.apiKeyPattern != nil && !p.apiKeyPattern.MatchString(apiKey) {
+		return fmt.Errorf("%w: does not match the configured pattern", errMalformedAPIKey)
+	}
+	return nil
+}
+
+// validateAttributes validates the resource attributes and returns the matched rule, reusing a
+// cached result when the same attributes passed validation within the cache TTL
+func (p *trustGatewayProcessor) validateAttributes(attrs pcommon.Map) (string, error) {
+	if p.cache == nil {
+		return p.checkAttributes(attrs)
+	}
+
+	fingerprint := p.fingerprint(attrs)
+	if rule, ok := p.cache.lookup(fingerprint); ok {
+		p.logger.Debug("Telemetry validation cache hit")
+		return rule, nil
+	}
+
+	rule, err := p.checkAttributes(attrs)
+	if err != nil {
+		return "", err
+	}
+	p.cache.add(fingerprint, rule)
+	return rule, nil
+}
+
+// fingerprint hashes the configured rules together with the attributes they inspect
+func (p *trustGatewayProcessor) fingerprint(attrs pcommon.Map) string {
+	h := sha256.New()
+	h.Write(p.rulesFingerprint)
+	writeAttr := func(key string) {
+		h.Write([]byte(key))
+		if val, ok := p.header(attrs, key); ok {
+			h.Write([]byte{1})
+			h.Write([]byte(val.AsString()))
+		}
+		h.Write([]byte{0})
+	}
+	for _, header := range p.config.RequiredHeaders {
+		writeAttr(header)
+	}
+	for _, name := range p.headerRuleNames {
+		writeAttr(name)
+	}
+	writeAttr(p.config.APIKeyHeader)
+	writeAttr(serviceNameAttribute)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkAttributes runs the configured validation rules against the resource attributes and
+// returns the rules that matched, e.g. "required_headers,valid_api_keys[2]". Keys are identified
+// by their position in their key list so the attribute never carries the key itself.
+func (p *trustGatewayProcessor) checkAttributes(attrs pcommon.Map) (string, error) {
+	var matched []string
+
 	// Validate required headers are present
 	for _, header := range p.config.RequiredHeaders {
-		val, ok := attrs.Get(header)
+		val, ok := p.header(attrs, header)
 		if !ok {
-			return fmt.Errorf("missing required header: %s", header)
+			return "", fmt.Errorf("%w: %s", errMissingHeader, header)
 		}
 		p.logger.Debug("Found required header", zap.String("header", header), zap.String("value", val.AsString()))
 	}
+	if len(p.config.RequiredHeaders) > 0 {
+		matched = append(matched, "required_headers")
+	}
+
+	// Validate attribute values against their header rules
+	for _, name := range p.headerRuleNames {
+		if err := p.checkHeaderRule(attrs, name); err != nil {
+			return "", err
+		}
+	}
+	if len(p.headerRuleNames) > 0 {
+		matched = append(matched, "header_rules")
+	}
+
+	// Verify the token instead of the API key in jwt validation mode
+	if p.verifier != nil {
+		tokenVal, ok := p.header(attrs, p.config.JWT.TokenAttribute)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", errMissingHeader, p.config.JWT.TokenAttribute)
+		}
+		if err := p.verifier.verify(tokenVal.AsString()); err != nil {
+			return "", err
+		}
+		p.logger.Info("Telemetry validation passed")
+		return strings.Join(append(matched, "jwt"), ","), nil
+	}
 
 	// Validate API key if configured
-	if len(p.config.ValidAPIKeys) > 0 {
-		apiKeyVal, ok := attrs.Get("X-API-Key")
+	validKeys, keyListRule, err := p.validKeysFor(attrs)
+	if err != nil {
+		return "", err
+	}
+	if len(validKeys) > 0 {
+		apiKeyVal, ok := p.header(attrs, p.config.APIKeyHeader)
 		if !ok {
-			return fmt.Errorf("missing X-API-Key header")
-		}
-		
-		apiKey := apiKeyVal.AsString()
-		valid := false
-		for _, validKey := range p.config.ValidAPIKeys {
-			if apiKey == validKey {
-				valid = true
-				p.logger.Debug("API key validated successfully")
-				break
-			}
+			return "", fmt.Errorf("%w: %s", errMissingHeader, p.config.APIKeyHeader)
 		}
-		if !valid {
-			return fmt.Errorf("invalid API key")
+
+		match := p.matchAPIKey(apiKeyVal.AsString(), validKeys)
+		if match < 0 {
+			return "", errInvalidAPIKey
 		}
+		matched = append(matched, fmt.Sprintf("%s[%d]", keyListRule, match))
+		p.logger.Debug("API key validated successfully")
 	}
 
 	p.logger.Info("Telemetry validation passed")
+	return strings.Join(matched, ","), nil
+}
+
+// matchAPIKey returns the position of apiKey in validKeys, or -1 when it is not a valid key. With
+// api_keys_hashed, the key is hashed before it is compared with the digests.
+func (p *trustGatewayProcessor) matchAPIKey(apiKey string, validKeys []string) int {
+	presented := []byte(apiKey)
+	if p.config.APIKeysHashed {
+		digest := sha256.Sum256(presented)
+		presented = []byte(hex.EncodeToString(digest[:]))
+	}
+	// Compare with every key in constant time, so the time taken does not reveal how much of the key
+	// matched or which key it matched
+	match := -1
+	for i, validKey := range validKeys {
+		if p.config.APIKeysHashed {
+			validKey = strings.ToLower(validKey)
+		}
+		if subtle.ConstantTimeCompare(presented, []byte(validKey)) == 1 && match < 0 {
+			match = i
+		}
+	}
+	return match
+}
+
+// checkHeaderRule checks the value of the named attribute against its header rule. The value is
+// left out of the error, as it may be a credential.
+func (p *trustGatewayProcessor) checkHeaderRule(attrs pcommon.Map, name string) error {
+	val, ok := p.header(attrs, name)
+	if !ok {
+		return fmt.Errorf("%w: %s", errMissingHeader, name)
+	}
+	value := val.AsString()
+	rule := p.config.HeaderRules[name]
+	if rule.Value != "" && value != rule.Value {
+		return fmt.Errorf("%w: %s does not equal the expected value", errHeaderRule, name)
+	}
+	if pattern, ok := p.headerPatterns[name]; ok && !pattern.MatchString(value) {
+		return fmt.Errorf("%w: %s does not match the pattern %q", errHeaderRule, name, rule.Pattern)
+	}
+	if rule.Glob != "" {
+		if matched, _ := path.Match(rule.Glob, value); !matched {
+			return fmt.Errorf("%w: %s does not match the glob %q", errHeaderRule, name, rule.Glob)
+		}
+	}
+	if len(rule.Allowed) > 0 && !slices.Contains(rule.Allowed, value) {
+		return fmt.Errorf("%w: %s is not in the allowed values", errHeaderRule, name)
+	}
 	return nil
 }
+
+// validKeysFor returns the API keys valid for the resource attributes and the name of their rule:
+// the key list of the resource's service when key_list_by_service is configured, valid_api_keys
+// otherwise. Services without a key list are rejected.
+func (p *trustGatewayProcessor) validKeysFor(attrs pcommon.Map) ([]string, string, error) {
+	if len(p.config.KeyListByService) == 0 {
+		return p.config.ValidAPIKeys, "valid_api_keys", nil
+	}
+
+	serviceVal, ok := attrs.Get(serviceNameAttribute)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: missing %s attribute", errUnknownService, serviceNameAttribute)
+	}
+	service := serviceVal.AsString()
+	keys, ok := p.config.KeyListByService[service]
+	if !ok {
+		return nil, "", fmt.Errorf("%w %q", errUnknownService, service)
+	}
+	return keys, fmt.Sprintf("key_list_by_service[%s]", service), nil
+}
+
+// header returns the attribute holding the named header, ignoring its case when
+// case_insensitive_headers is enabled
+func (p *trustGatewayProcessor) header(attrs pcommon.Map, name string) (pcommon.Value, bool) {
+	if val, ok := attrs.Get(name); ok || !p.config.CaseInsensitiveHeaders {
+		return val, ok
+	}
+	var found pcommon.Value
+	var ok bool
+	attrs.Range(func(key string, val pcommon.Value) bool {
+		if strings.EqualFold(key, name) {
+			found, ok = val, true
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
@@ -0,0 +1,561 @@
+package trustgatewayprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestConfig returns the default configuration accepting the API keys key-acme, key-globex and
+// key-other, without required headers
+func newTestConfig() *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.RequiredHeaders = nil
+	cfg.ValidAPIKeys = []string{"key-acme", "key-globex", "key-other"}
+	return cfg
+}
+
+// newTestProcessor validates cfg and creates a processor from it
+func newTestProcessor(t *testing.T, cfg *Config) *trustGatewayProcessor {
+	t.Helper()
+	require.NoError(t, cfg.Validate())
+	p, err := newTrustGatewayProcessor(cfg, componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, p.shutdown(context.Background())) })
+	return p
+}
+
+// newTestTraces returns traces with a span per resource, each resource carrying the given attributes
+func newTestTraces(resources ...map[string]any) ptrace.Traces {
+	td := ptrace.NewTraces()
+	for _, attrs := range resources {
+		rs := td.ResourceSpans().AppendEmpty()
+		_ = rs.Resource().Attributes().FromRaw(attrs)
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span")
+	}
+	return td
+}
+
+// resourceAttribute returns the attribute key of the i-th resource of td
+func resourceAttribute(td ptrace.Traces, i int, key string) (string, bool) {
+	val, ok := td.ResourceSpans().At(i).Resource().Attributes().Get(key)
+	if !ok {
+		return "", false
+	}
+	return val.AsString(), true
+}
+
+// SHA-256 digests of key-acme and key-globex
+const (
+	keyAcmeDigest   = "6371cb7a907cc8f10740229732c4b6164b285169494b85304468313513427878"
+	keyGlobexDigest = "6434a00879acb6a4faed427b6463c32e2e5e3736404258155c408f29558d268d"
+)
+
+func TestAPIKeysHashed(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+		digest string
+		want   bool
+	}{
+		{name: "first key", apiKey: "key-acme", digest: keyAcmeDigest, want: true},
+		{name: "second key", apiKey: "key-globex", digest: keyGlobexDigest, want: true},
+		{name: "upper case digest", apiKey: "key-acme", digest: "6371CB7A907CC8F10740229732C4B6164B285169494B85304468313513427878", want: true},
+		{name: "unknown key", apiKey: "key-other", digest: keyAcmeDigest},
+		{name: "digest presented as key", apiKey: keyAcmeDigest, digest: keyAcmeDigest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.APIKeysHashed = true
+			cfg.ValidAPIKeys = []string{tt.digest, keyGlobexDigest}
+			p := newTestProcessor(t, cfg)
+
+			td, err := p.processTraces(context.Background(), newTestTraces(map[string]any{"X-API-Key": tt.apiKey}))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, td.ResourceSpans().Len() == 1)
+		})
+	}
+}
+
+// TestMatchAPIKeyTiming is a smoke test that matching the first key takes about as long as matching
+// no key, as every key is compared. A short-circuiting loop is orders of magnitude faster for the
+// first key.
+func TestMatchAPIKeyTiming(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+	p := newTestProcessor(t, newTestConfig())
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = "key-acme-0000000000000000000000000000000000000000"
+	}
+	measure := func(apiKey string) time.Duration {
+		start := time.Now()
+		for range 200 {
+			p.matchAPIKey(apiKey, keys)
+		}
+		return time.Since(start)
+	}
+	measure(keys[0]) // warm up
+
+	first := measure(keys[0])
+	none := measure("key-acme-0000000000000000000000000000000000000001")
+	assert.Equal(t, 0, p.matchAPIKey(keys[0], keys))
+	assert.Less(t, float64(none)/float64(first), 4.0, "matching the first key takes %v, matching no key %v", first, none)
+}
+
+// newCachingProcessor returns a processor caching validations for ttl in up to maxEntries entries, and
+// the debug logs it writes
+func newCachingProcessor(t *testing.T, cfg *Config, maxEntries int) (*trustGatewayProcessor, *observer.ObservedLogs) {
+	t.Helper()
+	cfg.ValidationCache = ValidationCacheConfig{Enabled: true, TTL: time.Minute, MaxEntries: maxEntries}
+	require.NoError(t, cfg.Validate())
+	core, logs := observer.New(zapcore.DebugLevel)
+	set := componenttest.NewNopTelemetrySettings()
+	set.Logger = zap.New(core)
+	p, err := newTrustGatewayProcessor(cfg, set)
+	require.NoError(t, err)
+	return p, logs
+}
+
+// cacheHits returns the number of validations served from the cache
+func cacheHits(logs *observer.ObservedLogs) int {
+	return logs.FilterMessage("Telemetry validation cache hit").Len()
+}
+
+func TestValidationCache(t *testing.T) {
+	p, logs := newCachingProcessor(t, newTestConfig(), 10)
+	now := time.Now()
+	p.cache.now = func() time.Time { return now }
+	process := func(apiKey string) bool {
+		td, err := p.processTraces(context.Background(), newTestTraces(map[string]any{"X-API-Key": apiKey, "host.name": "host"}))
+		require.NoError(t, err)
+		return td.ResourceSpans().Len() == 1
+	}
+
+	require.True(t, process("key-acme"))
+	assert.Equal(t, 0, cacheHits(logs), "the first batch is validated")
+	require.True(t, process("key-acme"))
+	assert.Equal(t, 1, cacheHits(logs), "the same sender is served from the cache")
+
+	require.True(t, process("key-globex"))
+	assert.Equal(t, 1, cacheHits(logs), "another key misses the cache")
+
+	require.False(t, process("key-unknown"))
+	require.False(t, process("key-unknown"))
+	assert.Equal(t, 1, cacheHits(logs), "rejections are not cached")
+
+	now = now.Add(time.Minute + time.Second)
+	require.True(t, process("key-acme"))
+	assert.Equal(t, 1, cacheHits(logs), "expired entries are validated again")
+	require.True(t, process("key-acme"))
+	assert.Equal(t, 2, cacheHits(logs))
+}
+
+func TestValidationCacheBounded(t *testing.T) {
+	p, logs := newCachingProcessor(t, newTestConfig(), 2)
+	for _, apiKey := range []string{"key-acme", "key-globex", "key-other", "key-acme"} {
+		td, err := p.processTraces(context.Background(), newTestTraces(map[string]any{"X-API-Key": apiKey}))
+		require.NoError(t, err)
+		require.Equal(t, 1, td.ResourceSpans().Len())
+	}
+	assert.Len(t, p.cache.entries, 2)
+	assert.Equal(t, 0, cacheHits(logs), "the oldest entry was evicted")
+}
+
+func TestValidationCacheKeyListChange(t *testing.T) {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("X-API-Key", "key-acme")
+	before, _ := newCachingProcessor(t, newTestConfig(), 10)
+	cfg := newTestConfig()
+	cfg.ValidAPIKeys = []string{"key-globex"}
+	after, _ := newCachingProcessor(t, cfg, 10)
+
+	assert.NotEqual(t, before.fingerprint(attrs), after.fingerprint(attrs), "a key list change invalidates the cached validations")
+}
+
+// processServices processes resources with the given attributes as the signal and returns the
+// service.name of the forwarded resources
+func processServices(t *testing.T, p *trustGatewayProcessor, signal string, resources []map[string]any) []string {
+	t.Helper()
+	var services []string
+	collect := func(attrs pcommon.Map) {
+		if service, ok := attrs.Get("service.name"); ok {
+			services = append(services, service.AsString())
+		}
+	}
+	switch signal {
+	case "traces":
+		td, err := p.processTraces(context.Background(), newTestTraces(resources...))
+		require.NoError(t, err)
+		for i := 0; i < td.ResourceSpans().Len(); i++ {
+			collect(td.ResourceSpans().At(i).Resource().Attributes())
+		}
+	case "metrics":
+		md := pmetric.NewMetrics()
+		for _, attrs := range resources {
+			rm := md.ResourceMetrics().AppendEmpty()
+			require.NoError(t, rm.Resource().Attributes().FromRaw(attrs))
+			rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty().SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(1)
+		}
+		md, err := p.processMetrics(context.Background(), md)
+		require.NoError(t, err)
+		for i := 0; i < md.ResourceMetrics().Len(); i++ {
+			collect(md.ResourceMetrics().At(i).Resource().Attributes())
+		}
+	case "logs":
+		ld := plog.NewLogs()
+		for _, attrs := range resources {
+			rl := ld.ResourceLogs().AppendEmpty()
+			require.NoError(t, rl.Resource().Attributes().FromRaw(attrs))
+			rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("log")
+		}
+		ld, err := p.processLogs(context.Background(), ld)
+		require.NoError(t, err)
+		for i := 0; i < ld.ResourceLogs().Len(); i++ {
+			collect(ld.ResourceLogs().At(i).Resource().Attributes())
+		}
+	}
+	return services
+}
+
+func TestDropMode(t *testing.T) {
+	mixed := []map[string]any{
+		{"service.name": "valid-first", "X-API-Key": "key-acme"},
+		{"service.name": "invalid-key", "X-API-Key": "key-unknown"},
+		{"service.name": "valid-second", "X-API-Key": "key-globex"},
+		{"service.name": "missing-key"},
+	}
+	invalidFirst := []map[string]any{mixed[1], mixed[0]}
+	tests := []struct {
+		name      string
+		dropMode  string
+		resources []map[string]any
+		want      []string
+	}{
+		{name: "resource mode drops only invalid resources", dropMode: dropModeResource, resources: mixed, want: []string{"valid-first", "valid-second"}},
+		{name: "resource mode keeps valid resource after invalid first", dropMode: dropModeResource, resources: invalidFirst, want: []string{"valid-first"}},
+		{name: "batch mode forwards batch with valid first resource", dropMode: dropModeBatch, resources: mixed, want: []string{"valid-first", "invalid-key", "valid-second", "missing-key"}},
+		{name: "batch mode drops batch with invalid first resource", dropMode: dropModeBatch, resources: invalidFirst},
+	}
+	for _, tt := range tests {
+		for _, signal := range []string{"traces", "metrics", "logs"} {
+			t.Run(tt.name+"/"+signal, func(t *testing.T) {
+				cfg := newTestConfig()
+				cfg.DropMode = tt.dropMode
+				p := newTestProcessor(t, cfg)
+				assert.Equal(t, tt.want, processServices(t, p, signal, tt.resources))
+			})
+		}
+	}
+}
+
+func TestOnValidationFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		resources []map[string]any
+		wantErr   error
+		wantSpans int
+	}{
+		{name: "drop", mode: onValidationFailureDrop, resources: []map[string]any{{"X-API-Key": "key-unknown"}}},
+		{name: "error", mode: onValidationFailureError, resources: []map[string]any{{"X-API-Key": "key-unknown"}}, wantErr: errInvalidAPIKey, wantSpans: 1},
+		{name: "error with missing key", mode: onValidationFailureError, resources: []map[string]any{{}, {"X-API-Key": "key-acme"}}, wantErr: errMissingHeader, wantSpans: 2},
+		{name: "error with valid key", mode: onValidationFailureError, resources: []map[string]any{{"X-API-Key": "key-acme"}}, wantSpans: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.OnValidationFailure = tt.mode
+			p := newTestProcessor(t, cfg)
+			td := newTestTraces(tt.resources...)
+			want := ptrace.NewTraces()
+			td.CopyTo(want)
+
+			got, err := p.processTraces(context.Background(), td)
+			assert.Equal(t, tt.wantSpans, got.SpanCount())
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.True(t, consumererror.IsPermanent(err), "the receiver rejects the request")
+			assert.Equal(t, want, got, "rejected telemetry is returned untouched")
+		})
+	}
+}
+
+func TestOnValidationFailureErrorAllSignals(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.OnValidationFailure = onValidationFailureError
+	p := newTestProcessor(t, cfg)
+
+	md := pmetric.NewMetrics()
+	md.ResourceMetrics().AppendEmpty().Resource().Attributes().PutStr("X-API-Key", "key-unknown")
+	_, err := p.processMetrics(context.Background(), md)
+	assert.ErrorIs(t, err, errInvalidAPIKey)
+	assert.True(t, consumererror.IsPermanent(err))
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().Resource().Attributes().PutStr("X-API-Key", "key-unknown")
+	_, err = p.processLogs(context.Background(), ld)
+	assert.ErrorIs(t, err, errInvalidAPIKey)
+	assert.True(t, consumererror.IsPermanent(err))
+}
+
+func TestAPIKeyHeader(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          string
+		caseInsensitive bool
+		requiredHeaders []string
+		attrs           map[string]any
+		want            bool
+	}{
+		{name: "default header", attrs: map[string]any{"X-API-Key": "key-acme"}, want: true},
+		{name: "custom header", header: "api-key", attrs: map[string]any{"api-key": "key-acme"}, want: true},
+		{name: "custom header ignores default", header: "api-key", attrs: map[string]any{"X-API-Key": "key-acme"}},
+		{name: "mixed case is case sensitive by default", attrs: map[string]any{"x-api-key": "key-acme"}},
+		{name: "mixed case", caseInsensitive: true, attrs: map[string]any{"x-api-KEY": "key-acme"}, want: true},
+		{name: "mixed case custom header", header: "api-key", caseInsensitive: true, attrs: map[string]any{"Api-Key": "key-acme"}, want: true},
+		{name: "mixed case invalid key", caseInsensitive: true, attrs: map[string]any{"x-api-key": "key-unknown"}},
+		{name: "mixed case required header", caseInsensitive: true, requiredHeaders: []string{"X-App-Token"}, attrs: map[string]any{"x-api-key": "key-acme", "x-app-token": "token"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			if tt.header != "" {
+				cfg.APIKeyHeader = tt.header
+			}
+			cfg.CaseInsensitiveHeaders = tt.caseInsensitive
+			cfg.RequiredHeaders = tt.requiredHeaders
+			p := newTestProcessor(t, cfg)
+
+			td, err := p.processTraces(context.Background(), newTestTraces(tt.attrs))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, td.ResourceSpans().Len() == 1)
+		})
+	}
+}
+
+func TestHeaderRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    HeaderRule
+		attrs   map[string]any
+		wantErr error
+		wantMsg string
+	}{
+		{name: "exact value", rule: HeaderRule{Value: "acme-token"}, attrs: map[string]any{"X-App-Token": "acme-token"}},
+		{name: "other value", rule: HeaderRule{Value: "acme-token"}, attrs: map[string]any{"X-App-Token": "globex-token"}, wantErr: errHeaderRule, wantMsg: "X-App-Token does not equal the expected value"},
+		{name: "matching pattern", rule: HeaderRule{Pattern: "acme-[0-9]+"}, attrs: map[string]any{"X-App-Token": "acme-42"}},
+		{name: "partial pattern match", rule: HeaderRule{Pattern: "acme-[0-9]+"}, attrs: map[string]any{"X-App-Token": "acme-42x"}, wantErr: errHeaderRule, wantMsg: `X-App-Token does not match the pattern "acme-[0-9]+"`},
+		{name: "matching glob", rule: HeaderRule{Glob: "acme-*"}, attrs: map[string]any{"X-App-Token": "acme-token"}},
+		{name: "non-matching glob", rule: HeaderRule{Glob: "acme-*"}, attrs: map[string]any{"X-App-Token": "globex-token"}, wantErr: errHeaderRule, wantMsg: `X-App-Token does not match the glob "acme-*"`},
+		{name: "allowed value", rule: HeaderRule{Allowed: []string{"a", "b"}}, attrs: map[string]any{"X-App-Token": "b"}},
+		{name: "value not allowed", rule: HeaderRule{Allowed: []string{"a", "b"}}, attrs: map[string]any{"X-App-Token": "c"}, wantErr: errHeaderRule, wantMsg: "X-App-Token is not in the allowed values"},
+		{name: "absent attribute", rule: HeaderRule{Glob: "acme-*"}, attrs: map[string]any{}, wantErr: errMissingHeader, wantMsg: "X-App-Token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ValidAPIKeys = nil
+			cfg.OnValidationFailure = onValidationFailureError
+			cfg.HeaderRules = map[string]HeaderRule{"X-App-Token": tt.rule}
+			p := newTestProcessor(t, cfg)
+
+			_, err := p.processTraces(context.Background(), newTestTraces(tt.attrs))
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.ErrorContains(t, err, tt.wantMsg)
+		})
+	}
+}
+
+func TestStripValidatedAttributes(t *testing.T) {
+	tests := []struct {
+		name            string
+		strip           bool
+		caseInsensitive bool
+		attrs           map[string]any
+		want            map[string]any
+	}{
+		{
+			name:  "stripped",
+			strip: true,
+			attrs: map[string]any{"X-API-Key": "key-acme", "X-App-Token": "token", "service.name": "checkout", "host.name": "host"},
+			want:  map[string]any{"service.name": "checkout", "host.name": "host"},
+		},
+		{
+			name:  "kept when disabled",
+			attrs: map[string]any{"X-API-Key": "key-acme", "X-App-Token": "token", "service.name": "checkout"},
+			want:  map[string]any{"X-API-Key": "key-acme", "X-App-Token": "token", "service.name": "checkout"},
+		},
+		{
+			name:            "mixed case",
+			strip:           true,
+			caseInsensitive: true,
+			attrs:           map[string]any{"x-api-key": "key-acme", "X-APP-TOKEN": "token", "service.name": "checkout"},
+			want:            map[string]any{"service.name": "checkout"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, dropMode := range []string{dropModeBatch, dropModeResource} {
+				cfg := newTestConfig()
+				cfg.RequiredHeaders = []string{"X-App-Token"}
+				cfg.StripValidatedAttributes = tt.strip
+				cfg.CaseInsensitiveHeaders = tt.caseInsensitive
+				cfg.DropMode = dropMode
+				p := newTestProcessor(t, cfg)
+
+				td, err := p.processTraces(context.Background(), newTestTraces(tt.attrs, tt.attrs))
+				require.NoError(t, err)
+				require.Equal(t, 2, td.ResourceSpans().Len(), dropMode)
+				for i := 0; i < td.ResourceSpans().Len(); i++ {
+					assert.Equal(t, tt.want, td.ResourceSpans().At(i).Resource().Attributes().AsRaw(), dropMode)
+				}
+			}
+		})
+	}
+}
+
+func TestStripValidatedScopeAttributes(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ValidationScopeName = "auth"
+	p := newTestProcessor(t, cfg)
+	td := newTestTraces(map[string]any{"service.name": "checkout"})
+	scope := td.ResourceSpans().At(0).ScopeSpans().At(0).Scope()
+	scope.SetName("auth")
+	scope.Attributes().PutStr("X-API-Key", "key-acme")
+	scope.Attributes().PutStr("library", "auth")
+
+	td, err := p.processTraces(context.Background(), td)
+	require.NoError(t, err)
+	require.Equal(t, 1, td.ResourceSpans().Len())
+	assert.Equal(t, map[string]any{"library": "auth"}, td.ResourceSpans().At(0).ScopeSpans().At(0).Scope().Attributes().AsRaw())
+	assert.Equal(t, map[string]any{"service.name": "checkout"}, td.ResourceSpans().At(0).Resource().Attributes().AsRaw())
+}
+
+func TestValidationScopeName(t *testing.T) {
+	tests := []struct {
+		name      string
+		scopeName string
+		resource  map[string]any
+		scopes    map[string]map[string]any
+		want      bool
+	}{
+		{name: "key on target scope", scopeName: "auth", scopes: map[string]map[string]any{"auth": {"X-API-Key": "key-acme"}}, want: true},
+		{name: "key on other scope", scopeName: "auth", scopes: map[string]map[string]any{"http": {"X-API-Key": "key-acme"}}},
+		{name: "key on scope without name set", scopes: map[string]map[string]any{"auth": {"X-API-Key": "key-acme"}}},
+		{name: "key on resource", scopeName: "auth", resource: map[string]any{"X-API-Key": "key-acme"}, scopes: map[string]map[string]any{"auth": {}}, want: true},
+		{
+			name:      "target scope takes precedence",
+			scopeName: "auth",
+			resource:  map[string]any{"X-API-Key": "key-acme"},
+			scopes:    map[string]map[string]any{"auth": {"X-API-Key": "key-unknown"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.ValidationScopeName = tt.scopeName
+			p := newTestProcessor(t, cfg)
+			attrs := map[string]any{"service.name": "checkout"}
+			for k, v := range tt.resource {
+				attrs[k] = v
+			}
+			td := ptrace.NewTraces()
+			rs := td.ResourceSpans().AppendEmpty()
+			require.NoError(t, rs.Resource().Attributes().FromRaw(attrs))
+			for name, scopeAttrs := range tt.scopes {
+				ss := rs.ScopeSpans().AppendEmpty()
+				ss.Scope().SetName(name)
+				require.NoError(t, ss.Scope().Attributes().FromRaw(scopeAttrs))
+				ss.Spans().AppendEmpty().SetName("span")
+			}
+
+			td, err := p.processTraces(context.Background(), td)
+			require.NoError(t, err)
+			if tt.want {
+				assert.Equal(t, 1, td.ResourceSpans().Len())
+			} else {
+				assert.Zero(t, td.ResourceSpans().Len())
+			}
+		})
+	}
+}
+
+func TestValidationScopesAllSignals(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ValidationScopeName = "auth"
+	p := newTestProcessor(t, cfg)
+
+	rs := ptrace.NewResourceSpans()
+	rm := pmetric.NewResourceMetrics()
+	rl := plog.NewResourceLogs()
+	for _, name := range []string{"http", "auth", "auth"} {
+		rs.ScopeSpans().AppendEmpty().Scope().SetName(name)
+		rm.ScopeMetrics().AppendEmpty().Scope().SetName(name)
+		rl.ScopeLogs().AppendEmpty().Scope().SetName(name)
+	}
+	rs.ScopeSpans().At(1).Scope().Attributes().PutStr("X-API-Key", "key-acme")
+	rm.ScopeMetrics().At(1).Scope().Attributes().PutStr("X-API-Key", "key-acme")
+	rl.ScopeLogs().At(1).Scope().Attributes().PutStr("X-API-Key", "key-acme")
+
+	for _, resource := range []interface{}{rs, rm, rl} {
+		scopes := p.validationScopes(resource)
+		require.Len(t, scopes, 2, "%T", resource)
+		assert.Equal(t, map[string]any{"X-API-Key": "key-acme"}, scopes[0].AsRaw(), "%T", resource)
+	}
+}
+
+// TestValidateRealAttributes guards against attributes not being read from the resource, which made
+// validation pass or fail regardless of the sent headers
+func TestValidateRealAttributes(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs map[string]any
+		want  bool
+	}{
+		{name: "key and token", attrs: map[string]any{"service.name": "checkout", "X-API-Key": "key-acme", "X-App-Token": "token"}, want: true},
+		{name: "missing token", attrs: map[string]any{"service.name": "checkout", "X-API-Key": "key-acme"}},
+		{name: "missing key", attrs: map[string]any{"service.name": "checkout", "X-App-Token": "token"}},
+		{name: "invalid key", attrs: map[string]any{"service.name": "checkout", "X-API-Key": "key-unknown", "X-App-Token": "token"}},
+		{name: "non-string token", attrs: map[string]any{"service.name": "checkout", "X-API-Key": "key-acme", "X-App-Token": int64(42)}, want: true},
+		{name: "no attributes", attrs: map[string]any{}},
+	}
+	for _, tt := range tests {
+		for _, signal := range []string{"traces", "metrics", "logs"} {
+			t.Run(tt.name+"/"+signal, func(t *testing.T) {
+				cfg := createDefaultConfig().(*Config)
+				cfg.ValidAPIKeys = []string{"key-acme"}
+				require.Equal(t, []string{"X-App-Token"}, cfg.RequiredHeaders)
+				p := newTestProcessor(t, cfg)
+
+				services := processServices(t, p, signal, []map[string]any{tt.attrs})
+				if tt.want {
+					assert.Equal(t, []string{"checkout"}, services)
+				} else {
+					assert.Empty(t, services)
+				}
+			})
+		}
+	}
+}
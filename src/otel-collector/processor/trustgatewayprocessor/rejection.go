@@ -0,0 +1,153 @@
+package trustgatewayprocessor
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// rejectionReasonAttribute is the resource attribute stamped on samples with the reason of their rejection
+const rejectionReasonAttribute = "trustgateway.rejection_reason"
+
+// startTraces resolves the traces sink of rejection_samples and starts the token verifier
+func (p *trustGatewayProcessor) startTraces(_ context.Context, host component.Host) (err error) {
+	if p.tracesSink, err = rejectionSink[consumer.Traces](host, p.config.RejectionSamples.Sink, "traces"); err != nil {
+		return err
+	}
+	p.startVerifier()
+	return nil
+}
+
+// startMetrics resolves the metrics sink of rejection_samples and starts the token verifier
+func (p *trustGatewayProcessor) startMetrics(_ context.Context, host component.Host) (err error) {
+	if p.metricsSink, err = rejectionSink[consumer.Metrics](host, p.config.RejectionSamples.Sink, "metrics"); err != nil {
+		return err
+	}
+	p.startVerifier()
+	return nil
+}
+
+// startLogs resolves the logs sink of rejection_samples and starts the token verifier
+func (p *trustGatewayProcessor) startLogs(_ context.Context, host component.Host) (err error) {
+	if p.logsSink, err = rejectionSink[consumer.Logs](host, p.config.RejectionSamples.Sink, "logs"); err != nil {
+		return err
+	}
+	p.startVerifier()
+	return nil
+}
+
+// startVerifier fetches the JWKS of jwt validation mode, if any, and starts its refresh
+func (p *trustGatewayProcessor) startVerifier() {
+	if p.verifier != nil {
+		p.verifier.start()
+	}
+}
+
+// rejectionSink returns the extension id as a consumer of type T, or the zero value when no sink is configured
+func rejectionSink[T any](host component.Host, id *component.ID, signal string) (T, error) {
+	var sink T
+	if id == nil {
+		return sink, nil
+	}
+	ext, ok := host.GetExtensions()[*id]
+	if !ok {
+		return sink, fmt.Errorf("rejection_samples.sink extension %q not found", *id)
+	}
+	sink, ok = ext.(T)
+	if !ok {
+		return sink, fmt.Errorf("rejection_samples.sink extension %q cannot consume %s", *id, signal)
+	}
+	return sink, nil
+}
+
+// resource is a ResourceSpans, ResourceMetrics or ResourceLogs
+type resource[T any] interface {
+	Resource() pcommon.Resource
+	CopyTo(dest T)
+}
+
+// sampleResource appends a copy of the rejected resource r to samples, stamped with the reason of
+// its rejection, for the configured fraction of calls
+func sampleResource[T resource[T]](samples interface{ AppendEmpty() T }, r T, fraction float64, reason error) {
+	if rand.Float64() >= fraction {
+		return
+	}
+	sample := samples.AppendEmpty()
+	r.CopyTo(sample)
+	sample.Resource().Attributes().PutStr(rejectionReasonAttribute, reason.Error())
+}
+
+// sampleRejectedTraces forwards a sample of the resources of rejected traces to the sink
+func (p *trustGatewayProcessor) sampleRejectedTraces(ctx context.Context, td ptrace.Traces, reason error) {
+	if p.tracesSink == nil {
+		return
+	}
+	samples := ptrace.NewTraces()
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		sampleResource(samples.ResourceSpans(), td.ResourceSpans().At(i), p.config.RejectionSamples.Fraction, reason)
+	}
+	p.forwardTracesSamples(ctx, samples)
+}
+
+// sampleRejectedMetrics forwards a sample of the resources of rejected metrics to the sink
+func (p *trustGatewayProcessor) sampleRejectedMetrics(ctx context.Context, md pmetric.Metrics, reason error) {
+	if p.metricsSink == nil {
+		return
+	}
+	samples := pmetric.NewMetrics()
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		sampleResource(samples.ResourceMetrics(), md.ResourceMetrics().At(i), p.config.RejectionSamples.Fraction, reason)
+	}
+	p.forwardMetricsSamples(ctx, samples)
+}
+
+// sampleRejectedLogs forwards a sample of the resources of rejected logs to the sink
+func (p *trustGatewayProcessor) sampleRejectedLogs(ctx context.Context, ld plog.Logs, reason error) {
+	if p.logsSink == nil {
+		return
+	}
+	samples := plog.NewLogs()
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		sampleResource(samples.ResourceLogs(), ld.ResourceLogs().At(i), p.config.RejectionSamples.Fraction, reason)
+	}
+	p.forwardLogsSamples(ctx, samples)
+}
+
+// forwardTracesSamples sends the sampled resources to the sink. Sink failures are only logged, as
+// the telemetry is rejected either way.
+func (p *trustGatewayProcessor) forwardTracesSamples(ctx context.Context, samples ptrace.Traces) {
+	if samples.ResourceSpans().Len() == 0 {
+		return
+	}
+	if err := p.tracesSink.ConsumeTraces(ctx, samples); err != nil {
+		p.logger.Warn("Failed to forward rejected trace samples", zap.Error(err))
+	}
+}
+
+// forwardMetricsSamples sends the sampled resources to the sink
+func (p *trustGatewayProcessor) forwardMetricsSamples(ctx context.Context, samples pmetric.Metrics) {
+	if samples.ResourceMetrics().Len() == 0 {
+		return
+	}
+	if err := p.metricsSink.ConsumeMetrics(ctx, samples); err != nil {
+		p.logger.Warn("Failed to forward rejected metric samples", zap.Error(err))
+	}
+}
+
+// forwardLogsSamples sends the sampled resources to the sink
+func (p *trustGatewayProcessor) forwardLogsSamples(ctx context.Context, samples plog.Logs) {
+	if samples.ResourceLogs().Len() == 0 {
+		return
+	}
+	if err := p.logsSink.ConsumeLogs(ctx, samples); err != nil {
+		p.logger.Warn("Failed to forward rejected log samples", zap.Error(err))
+	}
+}
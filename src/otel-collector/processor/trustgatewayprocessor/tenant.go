@@ -0,0 +1,52 @@
+package trustgatewayprocessor
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// tenantAttribute is the resource attribute stamped with the tenant of validated telemetry
+const tenantAttribute = "otelcol.tenant"
+
+// tenant returns the normalized tenant of the validated attributes, or "" when tenant tagging is
+// disabled or the attributes name no tenant
+func (p *trustGatewayProcessor) tenant(attrs pcommon.Map) string {
+	switch p.config.Tenant.Source {
+	case tenantSourceHeader:
+		if val, ok := p.header(attrs, p.config.Tenant.Header); ok {
+			return normalizeTenant(val.AsString())
+		}
+	case tenantSourceAPIKey:
+		apiKeyVal, ok := p.header(attrs, p.config.APIKeyHeader)
+		if !ok {
+			return ""
+		}
+		for _, tenant := range p.tenantNames {
+			if p.matchAPIKey(apiKeyVal.AsString(), p.config.Tenant.APIKeys[tenant]) >= 0 {
+				return normalizeTenant(tenant)
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeTenant lowercases a tenant and replaces every run of characters other than letters and
+// digits with a single hyphen, trimming leading and trailing ones, so that "Acme Corp" and
+// "acme_corp" both become "acme-corp" and the tenant can be used in container names
+func normalizeTenant(tenant string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, c := range strings.ToLower(tenant) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	return b.String()
+}
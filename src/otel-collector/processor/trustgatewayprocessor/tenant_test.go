@@ -0,0 +1,210 @@
+package trustgatewayprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTenantConfig returns a configuration deriving the tenant from the API keys key-acme and key-globex
+func newTenantConfig() *Config {
+	cfg := newTestConfig()
+	cfg.Tenant = TenantConfig{
+		Source: tenantSourceAPIKey,
+		APIKeys: map[string][]string{
+			"Acme Corp": {"key-acme"},
+			"globex":    {"key-globex"},
+		},
+	}
+	return cfg
+}
+
+func TestTenantFromAPIKey(t *testing.T) {
+	p := newTestProcessor(t, newTenantConfig())
+	td := newTestTraces(map[string]any{"X-API-Key": "key-acme"})
+
+	td, err := p.processTraces(context.Background(), td)
+	require.NoError(t, err)
+	tenant, ok := resourceAttribute(td, 0, tenantAttribute)
+	require.True(t, ok)
+	assert.Equal(t, "acme-corp", tenant)
+	_, ok = resourceAttribute(td, 0, "X-API-Key")
+	assert.False(t, ok, "the tenant is derived before the key is stripped")
+}
+
+func TestTenantFromHeader(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Tenant = TenantConfig{Source: tenantSourceHeader, Header: "X-Tenant"}
+	p := newTestProcessor(t, cfg)
+	td := newTestTraces(map[string]any{"X-API-Key": "key-other", "X-Tenant": "Globex_Inc"})
+
+	td, err := p.processTraces(context.Background(), td)
+	require.NoError(t, err)
+	tenant, _ := resourceAttribute(td, 0, tenantAttribute)
+	assert.Equal(t, "globex-inc", tenant)
+}
+
+func TestTenantSentByClient(t *testing.T) {
+	p := newTestProcessor(t, newTenantConfig())
+	td := newTestTraces(
+		map[string]any{"X-API-Key": "key-other", tenantAttribute: "globex"},
+		map[string]any{"X-API-Key": "key-acme", tenantAttribute: "globex"},
+	)
+
+	td, err := p.processTraces(context.Background(), td)
+	require.NoError(t, err)
+	_, ok := resourceAttribute(td, 0, tenantAttribute)
+	assert.False(t, ok, "a key without tenant removes the tenant sent by the client")
+	tenant, _ := resourceAttribute(td, 1, tenantAttribute)
+	assert.Equal(t, "acme-corp", tenant, "the tenant of the key overwrites the one sent by the client")
+}
+
+func TestTenantMixedBatch(t *testing.T) {
+	for _, dropMode := range []string{dropModeBatch, dropModeResource} {
+		t.Run(dropMode, func(t *testing.T) {
+			cfg := newTenantConfig()
+			cfg.DropMode = dropMode
+			p := newTestProcessor(t, cfg)
+			td := newTestTraces(
+				map[string]any{"X-API-Key": "key-acme"},
+				map[string]any{"X-API-Key": "key-globex"},
+				map[string]any{"X-API-Key": "key-other"},
+			)
+
+			td, err := p.processTraces(context.Background(), td)
+			require.NoError(t, err)
+			require.Equal(t, 3, td.ResourceSpans().Len())
+			tenant, _ := resourceAttribute(td, 0, tenantAttribute)
+			assert.Equal(t, "acme-corp", tenant)
+			tenant, _ = resourceAttribute(td, 1, tenantAttribute)
+			assert.Equal(t, "globex", tenant, "resources do not inherit the tenant of the first one")
+			_, ok := resourceAttribute(td, 2, tenantAttribute)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestTenantClaimedWithoutCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		claimed map[string]any
+	}{
+		{
+			name:    "header without key",
+			source:  tenantSourceHeader,
+			claimed: map[string]any{"X-Tenant": "globex"},
+		},
+		{
+			name:    "header with invalid key",
+			source:  tenantSourceHeader,
+			claimed: map[string]any{"X-API-Key": "key-unknown", "X-Tenant": "globex"},
+		},
+		{
+			name:    "client tenant attribute",
+			source:  tenantSourceAPIKey,
+			claimed: map[string]any{tenantAttribute: "globex"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTenantConfig()
+			if tt.source == tenantSourceHeader {
+				cfg.Tenant = TenantConfig{Source: tenantSourceHeader, Header: "X-Tenant"}
+			}
+			p := newTestProcessor(t, cfg)
+			td := newTestTraces(
+				map[string]any{"X-API-Key": "key-acme", "X-Tenant": "Acme Corp"},
+				tt.claimed,
+			)
+
+			td, err := p.processTraces(context.Background(), td)
+			require.NoError(t, err)
+			require.Equal(t, 2, td.ResourceSpans().Len(), "batch drop mode accepts the batch on its first resource")
+			tenant, _ := resourceAttribute(td, 0, tenantAttribute)
+			assert.Equal(t, "acme-corp", tenant)
+			tenant, _ = resourceAttribute(td, 1, tenantAttribute)
+			assert.Equal(t, "acme-corp", tenant, "an unvalidated resource cannot claim another tenant")
+		})
+	}
+}
+
+func TestTenantRejectedBatch(t *testing.T) {
+	p := newTestProcessor(t, newTenantConfig())
+	td := newTestTraces(map[string]any{"X-API-Key": "key-unknown"})
+
+	td, err := p.processTraces(context.Background(), td)
+	require.NoError(t, err)
+	assert.Zero(t, td.ResourceSpans().Len())
+}
+
+func TestNormalizeTenant(t *testing.T) {
+	tests := map[string]string{
+		"acme":         "acme",
+		"Acme Corp":    "acme-corp",
+		"acme_corp":    "acme-corp",
+		"  Acme--Corp": "acme-corp",
+		"Team 42!":     "team-42",
+		"___":          "",
+	}
+	for tenant, want := range tests {
+		assert.Equal(t, want, normalizeTenant(tenant), tenant)
+	}
+}
+
+func TestTenantValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenant  TenantConfig
+		keys    []string
+		wantErr string
+	}{
+		{
+			name:   "disabled",
+			tenant: TenantConfig{},
+		},
+		{
+			name:    "header without attribute",
+			tenant:  TenantConfig{Source: tenantSourceHeader},
+			wantErr: "tenant source header requires tenant.header",
+		},
+		{
+			name:    "api_key without key list",
+			tenant:  TenantConfig{Source: tenantSourceAPIKey, APIKeys: map[string][]string{"acme": {"key-acme"}}},
+			keys:    []string{},
+			wantErr: "tenant source api_key requires valid_api_keys or key_list_by_service",
+		},
+		{
+			name:    "api_key without tenants",
+			tenant:  TenantConfig{Source: tenantSourceAPIKey},
+			wantErr: "tenant source api_key requires tenant.api_keys",
+		},
+		{
+			name:    "tenant without letters",
+			tenant:  TenantConfig{Source: tenantSourceAPIKey, APIKeys: map[string][]string{"--": {"key-acme"}}},
+			wantErr: `tenant.api_keys has tenant "--" without letters or digits`,
+		},
+		{
+			name:    "unknown source",
+			tenant:  TenantConfig{Source: "cookie"},
+			wantErr: `unknown tenant.source "cookie"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.Tenant = tt.tenant
+			if tt.keys != nil {
+				cfg.ValidAPIKeys = tt.keys
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package filelogreceiver implements a receiver that can be used by the
+// OpenTelemetry collector to receive logs using the stanza log agent.
+//
+// It is vendored from the contrib filelogreceiver v0.136.0, see README.md.
+package filelogreceiver // import "github.com/fedeoliv/custom-otel-collector/receiver/filelogreceiver"
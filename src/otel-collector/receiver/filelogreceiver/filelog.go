@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package filelogreceiver // import "github.com/fedeoliv/custom-otel-collector/receiver/filelogreceiver"
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/receiver"
+
+	"github.com/fedeoliv/custom-otel-collector/receiver/filelogreceiver/internal/metadata"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/stanza/adapter"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/stanza/operator"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/stanza/operator/input/file"
+)
+
+// NewFactory creates a factory for filelog receiver
+func NewFactory() receiver.Factory {
+	return adapter.NewFactory(ReceiverType{}, metadata.LogsStability)
+}
+
+// ReceiverType implements stanza.LogReceiverType
+// to create a file tailing receiver
+type ReceiverType struct{}
+
+// Type is the receiver type
+func (ReceiverType) Type() component.Type {
+	return metadata.Type
+}
+
+// CreateDefaultConfig creates a config with type and version
+func (ReceiverType) CreateDefaultConfig() component.Config {
+	return createDefaultConfig()
+}
+
+func createDefaultConfig() *FileLogConfig {
+	cfg := &FileLogConfig{
+		BaseConfig: adapter.BaseConfig{
+			Operators: []operator.Config{},
+		},
+		InputConfig: *file.NewConfig(),
+	}
+	// The defaults of consumerretry.NewDefaultConfig, which is internal to the contrib repository.
+	cfg.RetryOnFailure.Enabled = false
+	cfg.RetryOnFailure.InitialInterval = 1 * time.Second
+	cfg.RetryOnFailure.MaxInterval = 30 * time.Second
+	cfg.RetryOnFailure.MaxElapsedTime = 5 * time.Minute
+	return cfg
+}
+
+// BaseConfig gets the base config from config, for now
+func (ReceiverType) BaseConfig(cfg component.Config) adapter.BaseConfig {
+	return cfg.(*FileLogConfig).BaseConfig
+}
+
+// FileLogConfig defines configuration for the filelog receiver
+type FileLogConfig struct {
+	InputConfig        file.Config `mapstructure:",squash"`
+	adapter.BaseConfig `mapstructure:",squash"`
+
+	// prevent unkeyed literal initialization
+	_ struct{}
+}
+
+// InputConfig unmarshals the input operator
+func (ReceiverType) InputConfig(cfg component.Config) operator.Config {
+	return operator.NewConfig(&cfg.(*FileLogConfig).InputConfig)
+}
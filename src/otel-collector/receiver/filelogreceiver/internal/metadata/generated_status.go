@@ -0,0 +1,17 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package metadata
+
+import (
+	"go.opentelemetry.io/collector/component"
+)
+
+var (
+	Type      = component.MustNewType("filelog")
+	ScopeName = "github.com/fedeoliv/custom-otel-collector/receiver/filelogreceiver"
+)
+
+const (
+	LogsStability = component.StabilityLevelBeta
+)